@@ -212,6 +212,12 @@ func main() {
 				Value:   1,
 				EnvVars: []string{"NEXAPI_REDIS_DB"},
 			},
+			&cli.DurationFlag{
+				Name:    "ephemeral-device-ttl",
+				Usage:   "How long an ephemeral device is kept after its last update or status report",
+				Value:   handlers.DefaultEphemeralDeviceTTL,
+				EnvVars: []string{"NEXAPI_EPHEMERAL_DEVICE_TTL"},
+			},
 		},
 
 		Action: func(cCtx *cli.Context) error {
@@ -230,7 +236,7 @@ func main() {
 
 				ipam := ipam.NewIPAM(logger.Sugar(), cCtx.String("ipam-address"))
 
-				fflags := fflags.NewFFlags(logger.Sugar())
+				fflags := fflags.NewFFlags(logger.Sugar(), db)
 
 				store := inmem.New()
 
@@ -238,6 +244,8 @@ func main() {
 				if err != nil {
 					log.Fatal(err)
 				}
+				api.SetEphemeralDeviceTTL(cCtx.Duration("ephemeral-device-ttl"))
+				api.Start(ctx, wg)
 				scopes := []string{"openid", "profile", "email"}
 				scopes = append(scopes, cCtx.StringSlice("scopes")...)
 
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/client"
+)
+
+// createApiToken creates an API token for the current user.
+func createApiToken(c *client.APIClient, encodeOut string, token public.ModelsAddApiToken) error {
+	res, _, err := c.ApiTokensApi.CreateApiToken(context.Background()).Token(token).Execute()
+	if err != nil {
+		return fmt.Errorf("create api token failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "TOKEN ID", "TOKEN")
+		}
+		fmt.Fprintf(w, fs, res.Id, res.Token)
+		w.Flush()
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// listApiTokens lists the API tokens of the current user.
+func listApiTokens(c *client.APIClient, encodeOut string) error {
+	tokens, _, err := c.ApiTokensApi.ListApiTokens(context.Background()).Execute()
+	if err != nil {
+		return fmt.Errorf("list api tokens failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "TOKEN ID", "PREFIX", "SCOPES", "EXPIRES AT", "LAST USED AT", "DESCRIPTION")
+		}
+		for _, token := range tokens {
+			fmt.Fprintf(w, fs, token.Id, token.Prefix, strings.Join(token.Scopes, ","), token.ExpiresAt, token.LastUsedAt, token.Description)
+		}
+		w.Flush()
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, tokens)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// deleteApiToken revokes an API token of the current user.
+func deleteApiToken(c *client.APIClient, encodeOut, tokenID string) error {
+	res, _, err := c.ApiTokensApi.DeleteApiToken(context.Background(), tokenID).Execute()
+	if err != nil {
+		return fmt.Errorf("delete api token failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully deleted api token %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
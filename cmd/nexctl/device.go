@@ -4,21 +4,37 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
 )
 
-func listOrgDevices(c *public.APIClient, organizationID uuid.UUID, encodeOut string) error {
-	devices, _, err := c.DevicesApi.ListDevicesInOrganization(context.Background(), organizationID.String()).Execute()
+// formatLabels formats the labels of a device as key=value pairs sorted by key
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func listOrgDevices(c *public.APIClient, organizationID uuid.UUID, labelSelector string, encodeOut string) error {
+	request := c.DevicesApi.ListDevicesInOrganization(context.Background(), organizationID.String())
+	if labelSelector != "" {
+		request = request.LabelSelector(labelSelector)
+	}
+	devices, _, err := request.Execute()
 	if err != nil {
 		log.Fatal(err)
 	}
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
 		w := newTabWriter()
-		fs := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
+		fs := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
 		if encodeOut != encodeNoHeader {
-			fmt.Fprintf(w, fs, "DEVICE ID", "HOSTNAME", "NODE ADDRESS IPV4", "NODE ADDRESS IPV6", "ENDPOINT IP", "PUBLIC KEY", "ORGANIZATION ID", "OS", "RELAY")
+			fmt.Fprintf(w, fs, "DEVICE ID", "HOSTNAME", "NODE ADDRESS IPV4", "NODE ADDRESS IPV6", "ENDPOINT IP", "PUBLIC KEY", "ORGANIZATION ID", "OS", "RELAY", "PENDING", "LABELS", "ONLINE", "LAST SEEN")
 		}
 		for _, dev := range devices {
 			localIp := ""
@@ -28,7 +44,7 @@ func listOrgDevices(c *public.APIClient, organizationID uuid.UUID, encodeOut str
 					break
 				}
 			}
-			fmt.Fprintf(w, fs, dev.Id, dev.Hostname, dev.TunnelIp, dev.TunnelIpV6, localIp, dev.PublicKey, dev.OrganizationId, dev.Os, fmt.Sprintf("%t", dev.Relay))
+			fmt.Fprintf(w, fs, dev.Id, dev.Hostname, dev.TunnelIp, dev.TunnelIpV6, localIp, dev.PublicKey, dev.OrganizationId, dev.Os, fmt.Sprintf("%t", dev.Relay), fmt.Sprintf("%t", dev.Pending), formatLabels(dev.Labels), fmt.Sprintf("%t", dev.Online), dev.LastSeen)
 		}
 		w.Flush()
 
@@ -43,20 +59,24 @@ func listOrgDevices(c *public.APIClient, organizationID uuid.UUID, encodeOut str
 	return nil
 }
 
-func listAllDevices(c *public.APIClient, encodeOut string) error {
-	devices, _, err := c.DevicesApi.ListDevices(context.Background()).Execute()
+func listAllDevices(c *public.APIClient, labelSelector string, encodeOut string) error {
+	request := c.DevicesApi.ListDevices(context.Background())
+	if labelSelector != "" {
+		request = request.LabelSelector(labelSelector)
+	}
+	devices, _, err := request.Execute()
 	if err != nil {
 		log.Fatal(err)
 	}
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
 		w := newTabWriter()
-		fs := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
+		fs := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
 		if encodeOut != encodeNoHeader {
 			fmt.Fprintf(w, fs, "DEVICE ID", "HOSTNAME", "NODE ADDRESS",
 				"ENDPOINT IP", "PUBLIC KEY", "ORGANIZATION ID",
 				"LOCAL IP", "ALLOWED IPS", "TUNNEL IPV4", "TUNNEL IPV6",
 				"CHILD PREFIX", "ORG PREFIX IPV4", "ORG PREFIX IPV6",
-				"REFLEXIVE IPv4", "ENDPOINT LOCAL IPv4", "OS", "SECURITY GROUP ID", "RELAY")
+				"REFLEXIVE IPv4", "ENDPOINT LOCAL IPv4", "OS", "SECURITY GROUP ID", "RELAY", "LABELS", "ONLINE", "LAST SEEN")
 		}
 		for _, dev := range devices {
 			localIp := ""
@@ -71,7 +91,8 @@ func listAllDevices(c *public.APIClient, encodeOut string) error {
 
 			fmt.Fprintf(w, fs, dev.Id, dev.Hostname, dev.TunnelIp, localIp, dev.PublicKey, dev.OrganizationId,
 				localIp, dev.AllowedIps, dev.TunnelIp, dev.TunnelIpV6, dev.ChildPrefix, dev.OrganizationPrefix,
-				dev.OrganizationPrefixV6, reflexiveIp4, dev.EndpointLocalAddressIp4, dev.Os, dev.SecurityGroupId, fmt.Sprintf("%t", dev.Relay))
+				dev.OrganizationPrefixV6, reflexiveIp4, dev.EndpointLocalAddressIp4, dev.Os, dev.SecurityGroupId, fmt.Sprintf("%t", dev.Relay),
+				formatLabels(dev.Labels), fmt.Sprintf("%t", dev.Online), dev.LastSeen)
 		}
 		w.Flush()
 
@@ -86,6 +107,40 @@ func listAllDevices(c *public.APIClient, encodeOut string) error {
 	return nil
 }
 
+func getDeviceStatus(c *public.APIClient, encodeOut, devID string) error {
+	devUUID, err := uuid.Parse(devID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", devUUID, err)
+	}
+
+	status, _, err := c.DevicesApi.GetDeviceStatus(context.Background(), devUUID.String()).Execute()
+	if err != nil {
+		log.Fatalf("device status get failed: %v\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("device %s last seen %s running nexd %s\n", status.DeviceId, status.LastSeen, status.NexdVersion)
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "PEER DEVICE ID", "ENDPOINT", "REACHABLE", "LATEST HANDSHAKE")
+		}
+		for _, peer := range status.Peers {
+			fmt.Fprintf(w, fs, peer.DeviceId, peer.Endpoint, fmt.Sprintf("%t", peer.Reachable), peer.LatestHandshake)
+		}
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, status)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
 func deleteDevice(c *public.APIClient, encodeOut, devID string) error {
 	devUUID, err := uuid.Parse(devID)
 	if err != nil {
@@ -94,7 +149,7 @@ func deleteDevice(c *public.APIClient, encodeOut, devID string) error {
 
 	res, _, err := c.DevicesApi.DeleteDevice(context.Background(), devUUID.String()).Execute()
 	if err != nil {
-		log.Fatalf("device delete failed: %v\n", err)
+		log.Fatalf("device delete failed: %v\n", validationError(err))
 	}
 
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
@@ -109,3 +164,79 @@ func deleteDevice(c *public.APIClient, encodeOut, devID string) error {
 
 	return nil
 }
+
+func approveDevice(c *public.APIClient, encodeOut, devID string) error {
+	devUUID, err := uuid.Parse(devID)
+	if err != nil {
+		log.Fatalf("failed to parse a valid UUID from %s %v", devUUID, err)
+	}
+
+	res, _, err := c.DevicesApi.ApproveDevice(context.Background(), devUUID.String()).Execute()
+	if err != nil {
+		log.Fatalf("device approve failed: %v\n", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully approved device %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func updateDevices(c *public.APIClient, encodeOut string, devIDs []string, securityGroupID string, labels map[string]string) error {
+	if securityGroupID == "" && labels == nil {
+		log.Fatalf("device update requires --security-group-id or --label")
+	}
+	var sgID string
+	if securityGroupID != "" {
+		sgUUID, err := uuid.Parse(securityGroupID)
+		if err != nil {
+			log.Fatalf("failed to parse a valid UUID from %s %v", securityGroupID, err)
+		}
+		sgID = sgUUID.String()
+	}
+
+	var devices []public.ModelsDevice
+	for _, devID := range devIDs {
+		devUUID, err := uuid.Parse(devID)
+		if err != nil {
+			log.Fatalf("failed to parse a valid UUID from %s %v", devID, err)
+		}
+
+		// the update always sets the symmetric nat flag, so send the current value along
+		device, _, err := c.DevicesApi.GetDevice(context.Background(), devUUID.String()).Execute()
+		if err != nil {
+			log.Fatalf("device update failed: %v\n", validationError(err))
+		}
+
+		res, _, err := c.DevicesApi.UpdateDevice(context.Background(), devUUID.String()).Update(public.ModelsUpdateDevice{
+			SecurityGroupId: sgID,
+			SymmetricNat:    device.SymmetricNat,
+			Labels:          labels,
+		}).Execute()
+		if err != nil {
+			log.Fatalf("device update failed: %v\n", validationError(err))
+		}
+		devices = append(devices, *res)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		for _, dev := range devices {
+			fmt.Printf("successfully updated device %s\n", dev.Id)
+		}
+		return nil
+	}
+
+	err := FormatOutput(encodeOut, devices)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
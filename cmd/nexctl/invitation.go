@@ -42,7 +42,7 @@ func createInvitation(c *client.APIClient, encodeOut, userID string, orgID strin
 		OrganizationId: orgUUID.String(),
 	}).Execute()
 	if err != nil {
-		log.Fatalf("create invitation failed: %v\n", err)
+		log.Fatalf("create invitation failed: %v\n", validationError(err))
 	}
 
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
@@ -132,6 +132,38 @@ func init() {
 					},
 				},
 			},
+			{
+				Name:  "rotate-keys",
+				Usage: "Rotate the WireGuard keys of the device, the device keeps its ID and tunnel addresses",
+				Action: func(cCtx *cli.Context) error {
+					if err := checkVersion(); err != nil {
+						return err
+					}
+					result, err := callNexd("RotateKeys", "")
+					if err != nil {
+						fmt.Printf("%s\n", err)
+						return err
+					}
+					fmt.Printf("%s", result)
+					return nil
+				},
+			},
+			{
+				Name:  "leave",
+				Usage: "Delete the device from its organization, remove its tunnel interface, routes and firewall rules and stop nexd",
+				Action: func(cCtx *cli.Context) error {
+					if err := checkVersion(); err != nil {
+						return err
+					}
+					result, err := callNexd("Leave", "")
+					if err != nil {
+						fmt.Printf("%s\n", err)
+						return err
+					}
+					fmt.Printf("%s", result)
+					return nil
+				},
+			},
 			{
 				Name:  "proxy",
 				Usage: "Commands for interacting nexd's proxy configuration",
@@ -228,6 +260,20 @@ func init() {
 					},
 				},
 			},
+			{
+				Name:  "security-group",
+				Usage: "Commands for interacting with the security group enforced by nexd",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "stats",
+						Usage: "display the packet counters of the security group rules",
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							return cmdSecurityGroupStats(cCtx, encodeOut)
+						},
+					},
+				},
+			},
 		},
 	})
 }
@@ -8,10 +8,12 @@ import (
 	"os"
 	"sort"
 	"text/tabwriter"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
 	"github.com/nexodus-io/nexodus/internal/client"
+	"github.com/nexodus-io/nexodus/internal/nexodus"
 	"github.com/urfave/cli/v2"
 )
 
@@ -56,6 +58,11 @@ func main() {
 				Name:  "password",
 				Usage: "Password",
 			},
+			&cli.StringFlag{
+				Name:    "token",
+				Usage:   "API token, used instead of the username and password",
+				EnvVars: []string{"NEXCTL_TOKEN"},
+			},
 			&cli.StringFlag{
 				Name:     "output",
 				Value:    encodeColumn,
@@ -140,6 +147,222 @@ func main() {
 							return deleteOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationID)
 						},
 					},
+					{
+						Name:  "update",
+						Usage: "Update an organization",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name: "name",
+							},
+							&cli.StringFlag{
+								Name: "description",
+							},
+							&cli.BoolFlag{
+								Name: "hub-organization",
+							},
+							&cli.BoolFlag{
+								Name:  "approval-required",
+								Usage: "new devices wait for an admin to approve them with nexctl device approve before joining the mesh",
+							},
+							&cli.IntFlag{
+								Name:  "revision",
+								Usage: "only update the organization if it is still at this revision",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							update := public.ModelsUpdateOrganization{
+								Name:     cCtx.String("name"),
+								Revision: int32(cCtx.Int("revision")),
+							}
+							if cCtx.IsSet("description") {
+								description := cCtx.String("description")
+								update.Description = &description
+							}
+							if cCtx.IsSet("hub-organization") {
+								hubZone := cCtx.Bool("hub-organization")
+								update.HubZone = &hubZone
+							}
+							if cCtx.IsSet("approval-required") {
+								approvalRequired := cCtx.Bool("approval-required")
+								update.ApprovalRequired = &approvalRequired
+							}
+							return updateOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationID, update)
+						},
+					},
+					{
+						Name:  "connectivity",
+						Usage: "Show the connectivity between the devices of an organization",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							return getOrganizationConnectivity(mustCreateAPIClient(cCtx), encodeOut, organizationID)
+						},
+					},
+					{
+						Name:  "events",
+						Usage: "List the changes made to an organization and its resources",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "resource-type",
+								Usage: "only the events of this resource type, for example device or security_group",
+							},
+							&cli.StringFlag{
+								Name:  "resource-id",
+								Usage: "only the events of this resource",
+							},
+							&cli.StringFlag{
+								Name:  "action",
+								Usage: "only the events of this action, for example create, update or delete",
+							},
+							&cli.StringFlag{
+								Name:  "actor-id",
+								Usage: "only the events of this user",
+							},
+							&cli.StringFlag{
+								Name:  "since",
+								Usage: "only the events after this RFC3339 time",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Usage: "maximum number of events",
+								Value: 100,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							filter := eventsFilter{
+								ResourceType: cCtx.String("resource-type"),
+								ResourceID:   cCtx.String("resource-id"),
+								Action:       cCtx.String("action"),
+								ActorID:      cCtx.String("actor-id"),
+								Since:        cCtx.String("since"),
+								Limit:        cCtx.Int("limit"),
+							}
+							return listOrganizationEvents(mustCreateAPIClient(cCtx), encodeOut, organizationID, filter)
+						},
+					},
+					{
+						Name:  "readdress",
+						Usage: "Move an organization and its devices to new prefixes",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "cidr",
+								Usage: "new IPv4 prefix of the organization",
+							},
+							&cli.StringFlag{
+								Name:  "cidr-v6",
+								Usage: "new IPv6 prefix of the organization",
+							},
+							&cli.StringFlag{
+								Name:  "grace-period",
+								Usage: "how long the previous addresses of the devices stay valid, e.g. 30m",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							return readdressOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationID, public.ModelsReaddressOrganization{
+								Cidr:        cCtx.String("cidr"),
+								CidrV6:      cCtx.String("cidr-v6"),
+								GracePeriod: cCtx.String("grace-period"),
+							})
+						},
+					},
+					{
+						Name:  "transfer",
+						Usage: "Transfer the ownership of an organization to one of its members",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "new-owner",
+								Usage:    "ID of the user becoming the owner, they must be a member of the organization",
+								Required: true,
+							},
+							&cli.BoolFlag{
+								Name:  "remove-previous-owner",
+								Usage: "remove the previous owner from the organization instead of keeping them as a member",
+								Value: false,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							organizationID := cCtx.String("organization-id")
+							newOwner := cCtx.String("new-owner")
+							removePreviousOwner := cCtx.Bool("remove-previous-owner")
+							return transferOrganization(mustCreateAPIClient(cCtx), encodeOut, organizationID, newOwner, removePreviousOwner)
+						},
+					},
+					{
+						Name:  "roles",
+						Usage: "Commands relating to the roles of the users of an organization",
+						Subcommands: []*cli.Command{
+							{
+								Name:  "list",
+								Usage: "List the roles of the users of an organization",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "organization-id",
+										Required: true,
+									},
+								},
+								Action: func(cCtx *cli.Context) error {
+									encodeOut := cCtx.String("output")
+									organizationID := cCtx.String("organization-id")
+									return listOrganizationRoles(mustCreateAPIClient(cCtx), encodeOut, organizationID)
+								},
+							},
+							{
+								Name:  "set",
+								Usage: "Set the role of a user in an organization",
+								Flags: []cli.Flag{
+									&cli.StringFlag{
+										Name:     "organization-id",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:     "user-id",
+										Required: true,
+									},
+									&cli.StringFlag{
+										Name:     "role",
+										Usage:    "one of admin, member or read-only",
+										Required: true,
+									},
+								},
+								Action: func(cCtx *cli.Context) error {
+									encodeOut := cCtx.String("output")
+									organizationID := cCtx.String("organization-id")
+									userID := cCtx.String("user-id")
+									role := cCtx.String("role")
+									return setOrganizationRole(mustCreateAPIClient(cCtx), encodeOut, organizationID, userID, role)
+								},
+							},
+						},
+					},
 				},
 			},
 			{
@@ -155,18 +378,39 @@ func main() {
 								Value:    "",
 								Required: false,
 							},
+							&cli.StringFlag{
+								Name:     "label-selector",
+								Usage:    "Only list the devices matching a label selector such as env=prod,role!=db",
+								Required: false,
+							},
 						},
 						Action: func(cCtx *cli.Context) error {
 							encodeOut := cCtx.String("output")
 							orgID := cCtx.String("organization-id")
+							labelSelector := cCtx.String("label-selector")
 							if orgID != "" {
 								id, err := uuid.Parse(orgID)
 								if err != nil {
 									log.Fatal(err)
 								}
-								return listOrgDevices(mustCreateAPIClient(cCtx), id, encodeOut)
+								return listOrgDevices(mustCreateAPIClient(cCtx), id, labelSelector, encodeOut)
 							}
-							return listAllDevices(mustCreateAPIClient(cCtx), encodeOut)
+							return listAllDevices(mustCreateAPIClient(cCtx), labelSelector, encodeOut)
+						},
+					},
+					{
+						Name:  "status",
+						Usage: "Get the health last reported by a device",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "device-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							devID := cCtx.String("device-id")
+							return getDeviceStatus(mustCreateAPIClient(cCtx), encodeOut, devID)
 						},
 					},
 					{
@@ -184,6 +428,52 @@ func main() {
 							return deleteDevice(mustCreateAPIClient(cCtx), encodeOut, devID)
 						},
 					},
+					{
+						Name:  "approve",
+						Usage: "Approve a device waiting for approval, it then joins the mesh",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "device-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							devID := cCtx.String("device-id")
+							return approveDevice(mustCreateAPIClient(cCtx), encodeOut, devID)
+						},
+					},
+					{
+						Name:  "update",
+						Usage: "Update a device",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{
+								Name:     "device-id",
+								Usage:    "ID of the device to update, can be repeated to update a set of devices",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "security-group-id",
+								Usage:    "ID of a security group of the device's organization to attach to the device",
+								Required: false,
+							},
+							&cli.StringSliceFlag{
+								Name:     "label",
+								Usage:    "Label in the form key=value, can be repeated, the labels replace the current labels of the device",
+								Required: false,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							devIDs := cCtx.StringSlice("device-id")
+							sgID := cCtx.String("security-group-id")
+							labels, err := nexodus.ParseLabels(cCtx.StringSlice("label"))
+							if err != nil {
+								log.Fatal(err)
+							}
+							return updateDevices(mustCreateAPIClient(cCtx), encodeOut, devIDs, sgID, labels)
+						},
+					},
 				},
 			},
 			{
@@ -393,6 +683,360 @@ func main() {
 							return updateSecurityGroup(mustCreateAPIClient(cCtx), encodeOut, sgID, orgID, name, description, inboundRules, outboundRules)
 						},
 					},
+					{
+						Name:  "simulate",
+						Usage: "Evaluate a flow between devices against their security groups",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "source-device-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "destination-device-id",
+								Usage:    "the destination device, either this or --destination-ip is required",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "destination-ip",
+								Usage:    "the destination IPv4 or IPv6 address, either this or --destination-device-id is required",
+								Required: false,
+							},
+							&cli.StringFlag{
+								Name:     "protocol",
+								Usage:    "the protocol of the flow: tcp, udp, icmp or icmpv6",
+								Value:    "tcp",
+								Required: false,
+							},
+							&cli.IntFlag{
+								Name:     "port",
+								Usage:    "the destination port of tcp and udp flows",
+								Required: false,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							simulation := public.ModelsSimulateSecurityGroup{
+								SourceDeviceId:      cCtx.String("source-device-id"),
+								DestinationDeviceId: cCtx.String("destination-device-id"),
+								DestinationIp:       cCtx.String("destination-ip"),
+								IpProtocol:          cCtx.String("protocol"),
+								Port:                int32(cCtx.Int("port")),
+							}
+							if simulation.DestinationDeviceId == "" && simulation.DestinationIp == "" {
+								return fmt.Errorf("either --destination-device-id or --destination-ip is required")
+							}
+							return simulateSecurityGroup(mustCreateAPIClient(cCtx), encodeOut, orgID, simulation)
+						},
+					},
+				},
+			},
+			{
+				Name:  "token",
+				Usage: "commands relating to API tokens",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List the API tokens of the current user",
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							return listApiTokens(mustCreateAPIClient(cCtx), encodeOut)
+						},
+					},
+					{
+						Name:  "create",
+						Usage: "Create an API token that makes API calls as the current user",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name: "description",
+							},
+							&cli.StringSliceFlag{
+								Name:     "scope",
+								Usage:    "a scope granted to the token, such as read:devices or write:organizations",
+								Required: true,
+							},
+							&cli.DurationFlag{
+								Name:  "expires-in",
+								Usage: "how long the token is valid, it does not expire when not set",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							token := public.ModelsAddApiToken{
+								Description: cCtx.String("description"),
+								Scopes:      cCtx.StringSlice("scope"),
+							}
+							if expiresIn := cCtx.Duration("expires-in"); expiresIn > 0 {
+								token.ExpiresAt = time.Now().Add(expiresIn).UTC().Format(time.RFC3339)
+							}
+							return createApiToken(mustCreateAPIClient(cCtx), encodeOut, token)
+						},
+					},
+					{
+						Name:  "delete",
+						Usage: "Revoke an API token",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "token-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							tokenID := cCtx.String("token-id")
+							return deleteApiToken(mustCreateAPIClient(cCtx), encodeOut, tokenID)
+						},
+					},
+				},
+			},
+			{
+				Name:  "registration-key",
+				Usage: "commands relating to the registration keys of organizations",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List the registration keys of an organization",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							return listRegKeys(mustCreateAPIClient(cCtx), encodeOut, orgID)
+						},
+					},
+					{
+						Name:  "create",
+						Usage: "Create a key that registers devices into an organization with nexd --reg-key",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name: "description",
+							},
+							&cli.DurationFlag{
+								Name:  "expires-in",
+								Usage: "how long the key registers new devices, it does not expire when not set",
+							},
+							&cli.BoolFlag{
+								Name:  "single-use",
+								Usage: "the key registers one device only",
+							},
+							&cli.IntFlag{
+								Name:  "max-devices",
+								Usage: "the maximum number of devices registered with the key at a time, unlimited when not set",
+							},
+							&cli.StringFlag{
+								Name:  "security-group-id",
+								Usage: "the security group of the devices, the default security group of the organization when not set",
+							},
+							&cli.BoolFlag{
+								Name:  "relay",
+								Usage: "the key registers relay devices only",
+							},
+							&cli.BoolFlag{
+								Name:  "discovery",
+								Usage: "the devices registered with the key are discovery nodes",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							regKey := public.ModelsAddRegKey{
+								Description:     cCtx.String("description"),
+								SingleUse:       cCtx.Bool("single-use"),
+								MaxDevices:      int32(cCtx.Int("max-devices")),
+								SecurityGroupId: cCtx.String("security-group-id"),
+								Relay:           cCtx.Bool("relay"),
+								Discovery:       cCtx.Bool("discovery"),
+							}
+							if expiresIn := cCtx.Duration("expires-in"); expiresIn > 0 {
+								regKey.ExpiresAt = time.Now().Add(expiresIn).UTC().Format(time.RFC3339)
+							}
+							return createRegKey(mustCreateAPIClient(cCtx), encodeOut, orgID, regKey)
+						},
+					},
+					{
+						Name:  "delete",
+						Usage: "Delete a registration key, the devices registered with it can no longer authenticate",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "reg-key-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							regKeyID := cCtx.String("reg-key-id")
+							return deleteRegKey(mustCreateAPIClient(cCtx), encodeOut, orgID, regKeyID)
+						},
+					},
+				},
+			},
+			{
+				Name:  "webhook",
+				Usage: "commands relating to the webhooks of organizations",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List the webhooks of an organization",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							return listWebhooks(mustCreateAPIClient(cCtx), encodeOut, orgID)
+						},
+					},
+					{
+						Name:  "create",
+						Usage: "Create a webhook that receives the events of an organization",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "url",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name: "description",
+							},
+							&cli.StringSliceFlag{
+								Name:  "event-type",
+								Usage: "the type of the events to deliver, such as device.create or security_group.*, every event is delivered when not set",
+							},
+							&cli.StringFlag{
+								Name:  "secret",
+								Usage: "the key of the signature of the payloads, a random one is generated when not set",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							webhook := public.ModelsAddWebhook{
+								Url:         cCtx.String("url"),
+								Description: cCtx.String("description"),
+								EventTypes:  cCtx.StringSlice("event-type"),
+								Secret:      cCtx.String("secret"),
+							}
+							return createWebhook(mustCreateAPIClient(cCtx), encodeOut, orgID, webhook)
+						},
+					},
+					{
+						Name:  "update",
+						Usage: "Update a webhook",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "webhook-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name: "url",
+							},
+							&cli.StringFlag{
+								Name: "description",
+							},
+							&cli.StringSliceFlag{
+								Name: "event-type",
+							},
+							&cli.BoolFlag{
+								Name: "enabled",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							webhookID := cCtx.String("webhook-id")
+							update := public.ModelsUpdateWebhook{
+								EventTypes: cCtx.StringSlice("event-type"),
+							}
+							if cCtx.IsSet("url") {
+								url := cCtx.String("url")
+								update.Url = &url
+							}
+							if cCtx.IsSet("description") {
+								description := cCtx.String("description")
+								update.Description = &description
+							}
+							if cCtx.IsSet("enabled") {
+								enabled := cCtx.Bool("enabled")
+								update.Enabled = &enabled
+							}
+							return updateWebhook(mustCreateAPIClient(cCtx), encodeOut, orgID, webhookID, update)
+						},
+					},
+					{
+						Name:  "delete",
+						Usage: "Delete a webhook and its delivery history",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "webhook-id",
+								Required: true,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							webhookID := cCtx.String("webhook-id")
+							return deleteWebhook(mustCreateAPIClient(cCtx), encodeOut, orgID, webhookID)
+						},
+					},
+					{
+						Name:  "deliveries",
+						Usage: "List the deliveries of the events to a webhook",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "organization-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "webhook-id",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:  "status",
+								Usage: "only the deliveries with this status: pending, succeeded or failed",
+							},
+							&cli.IntFlag{
+								Name:  "limit",
+								Value: 100,
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							encodeOut := cCtx.String("output")
+							orgID := cCtx.String("organization-id")
+							webhookID := cCtx.String("webhook-id")
+							return listWebhookDeliveries(mustCreateAPIClient(cCtx), encodeOut, orgID, webhookID, cCtx.String("status"), cCtx.Int("limit"))
+						},
+					},
 				},
 			},
 			{
@@ -478,6 +1122,9 @@ func createClientOptions(cCtx *cli.Context) []client.Option {
 		cCtx.String("username"),
 		cCtx.String("password"),
 	)}
+	if token := cCtx.String("token"); token != "" {
+		options = []client.Option{client.WithBearerToken(token)}
+	}
 	if cCtx.Bool("insecure-skip-tls-verify") { // #nosec G402
 		options = append(options, client.WithTLSConfig(&tls.Config{
 			InsecureSkipVerify: true,
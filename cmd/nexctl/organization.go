@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
@@ -114,3 +116,221 @@ func deleteOrganization(c *client.APIClient, encodeOut, OrganizationID string) e
 
 	return nil
 }
+
+func updateOrganization(c *client.APIClient, encodeOut, organizationID string, update public.ModelsUpdateOrganization) error {
+	res, _, err := c.OrganizationsApi.UpdateOrganization(context.Background(), organizationID).Update(update).Execute()
+	if err != nil {
+		return fmt.Errorf("organization update failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully updated organization %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func getOrganizationConnectivity(c *client.APIClient, encodeOut, organizationID string) error {
+	matrix, _, err := c.OrganizationsApi.GetOrganizationConnectivity(context.Background(), organizationID).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		hostnames := map[string]string{}
+		for _, dev := range matrix.Devices {
+			hostnames[dev.Id] = dev.Hostname
+			if dev.Hostname == "" {
+				hostnames[dev.Id] = dev.Id
+			}
+		}
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "SOURCE", "DESTINATION", "PATH", "STATUS", "LATENCY")
+		}
+		for _, path := range matrix.Paths {
+			latency := ""
+			if path.LatencyMs > 0 {
+				latency = fmt.Sprintf("%.2fms", path.LatencyMs)
+			}
+			fmt.Fprintf(w, fs, hostnames[path.Source], hostnames[path.Destination], path.Method, path.Status, latency)
+		}
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, matrix)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+// eventsFilter selects the organization events to list, empty fields match every event
+type eventsFilter struct {
+	ResourceType string
+	ResourceID   string
+	Action       string
+	ActorID      string
+	Since        string
+	Limit        int
+}
+
+func listOrganizationEvents(c *client.APIClient, encodeOut, organizationID string, filter eventsFilter) error {
+	req := c.OrganizationsApi.ListOrganizationEvents(context.Background(), organizationID).Limit(int32(filter.Limit))
+	if filter.ResourceType != "" {
+		req = req.ResourceType(filter.ResourceType)
+	}
+	if filter.ResourceID != "" {
+		req = req.ResourceId(filter.ResourceID)
+	}
+	if filter.Action != "" {
+		req = req.Action(filter.Action)
+	}
+	if filter.ActorID != "" {
+		req = req.ActorId(filter.ActorID)
+	}
+	if filter.Since != "" {
+		req = req.Since(filter.Since)
+	}
+	events, _, err := req.Execute()
+	if err != nil {
+		return fmt.Errorf("organization events failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "TIME", "ACTOR", "ACTION", "RESOURCE TYPE", "RESOURCE ID", "CHANGED FIELDS")
+		}
+		for _, event := range events {
+			fields := make([]string, 0, len(event.Changes))
+			for field := range event.Changes {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			fmt.Fprintf(w, fs, event.CreatedAt, event.ActorId, event.Action, event.ResourceType, event.ResourceId, strings.Join(fields, ","))
+		}
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, events)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func readdressOrganization(c *client.APIClient, encodeOut, organizationID string, readdress public.ModelsReaddressOrganization) error {
+	if readdress.Cidr == "" && readdress.CidrV6 == "" {
+		return fmt.Errorf("at least one of --cidr or --cidr-v6 is required")
+	}
+	res, _, err := c.OrganizationsApi.ReaddressOrganization(context.Background(), organizationID).Readdress(readdress).Execute()
+	if err != nil {
+		return fmt.Errorf("organization readdress failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully started re-addressing organization %s to %s %s, the previous addresses are valid until %s\n", res.Id, res.Cidr, res.CidrV6, res.ReaddressDeadline)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func transferOrganization(c *client.APIClient, encodeOut, organizationID, newOwner string, removePreviousOwner bool) error {
+	res, _, err := c.OrganizationsApi.TransferOrganization(context.Background(), organizationID).Transfer(public.ModelsTransferOrganization{
+		NewOwnerId:          newOwner,
+		RemovePreviousOwner: removePreviousOwner,
+	}).Execute()
+	if err != nil {
+		return fmt.Errorf("organization transfer failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully transferred organization %s to user %s\n", res.Id, res.OwnerId)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func listOrganizationRoles(c *client.APIClient, encodeOut, organizationID string) error {
+	roles, _, err := c.OrganizationsApi.ListOrganizationRoles(context.Background(), organizationID).Execute()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "USER ID", "ROLE")
+		}
+
+		for _, role := range roles {
+			fmt.Fprintf(w, fs, role.UserId, role.Role)
+		}
+
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, roles)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
+
+func setOrganizationRole(c *client.APIClient, encodeOut, organizationID, userID, role string) error {
+	switch role {
+	case "admin", "member", "read-only":
+	default:
+		return fmt.Errorf("invalid role %q, must be one of admin, member or read-only", role)
+	}
+
+	res, _, err := c.OrganizationsApi.UpdateOrganizationRole(context.Background(), organizationID, userID).Update(public.ModelsUpdateUserOrganization{
+		Role: role,
+	}).Execute()
+	if err != nil {
+		return fmt.Errorf("organization role update failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("user %s is now %s of organization %s\n", res.UserId, res.Role, res.OrganizationId)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		log.Fatalf("failed to print output: %v", err)
+	}
+
+	return nil
+}
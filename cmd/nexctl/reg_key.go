@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/client"
+)
+
+// createRegKey creates a registration key for an organization.
+func createRegKey(c *client.APIClient, encodeOut, organizationID string, regKey public.ModelsAddRegKey) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	res, _, err := c.RegKeysApi.CreateRegKey(context.Background(), orgID.String()).RegKey(regKey).Execute()
+	if err != nil {
+		return fmt.Errorf("create registration key failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "REGISTRATION KEY ID", "KEY")
+		}
+		fmt.Fprintf(w, fs, res.Id, res.Token)
+		w.Flush()
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// listRegKeys lists the registration keys of an organization.
+func listRegKeys(c *client.APIClient, encodeOut, organizationID string) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	regKeys, _, err := c.RegKeysApi.ListRegKeys(context.Background(), orgID.String()).Execute()
+	if err != nil {
+		return fmt.Errorf("list registration keys failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%d\t%t\t%d\t%s\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", "REGISTRATION KEY ID", "PREFIX", "DEVICES", "SINGLE USE", "MAX DEVICES", "SECURITY GROUP ID", "EXPIRES AT", "DESCRIPTION")
+		}
+		for _, regKey := range regKeys {
+			fmt.Fprintf(w, fs, regKey.Id, regKey.Prefix, regKey.Devices, regKey.SingleUse, regKey.MaxDevices, regKey.SecurityGroupId, regKey.ExpiresAt, regKey.Description)
+		}
+		w.Flush()
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, regKeys)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// deleteRegKey deletes a registration key of an organization.
+func deleteRegKey(c *client.APIClient, encodeOut, organizationID, regKeyID string) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	res, _, err := c.RegKeysApi.DeleteRegKey(context.Background(), orgID.String(), regKeyID).Execute()
+	if err != nil {
+		return fmt.Errorf("delete registration key failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully deleted registration key %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/google/uuid"
@@ -17,11 +18,6 @@ func createSecurityGroup(c *client.APIClient, encodeOut, name, description, orga
 		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
 	}
 
-	err = checkICMPRules(inboundRules, outboundRules)
-	if err != nil {
-		return fmt.Errorf("create security group failed: %w", err)
-	}
-
 	res, _, err := c.SecurityGroupApi.CreateSecurityGroup(context.Background(), orgID.String()).SecurityGroup(public.ModelsAddSecurityGroup{
 		GroupName:        name,
 		GroupDescription: description,
@@ -30,7 +26,7 @@ func createSecurityGroup(c *client.APIClient, encodeOut, name, description, orga
 		OutboundRules:    outboundRules,
 	}).Execute()
 	if err != nil {
-		return fmt.Errorf("create security group failed: %w", err)
+		return fmt.Errorf("create security group failed: %w", validationError(err))
 	}
 
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
@@ -53,11 +49,6 @@ func updateSecurityGroup(c *client.APIClient, encodeOut, secGroupID, organizatio
 		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
 	}
 
-	err = checkICMPRules(inboundRules, outboundRules)
-	if err != nil {
-		return fmt.Errorf("create security group failed: %w", err)
-	}
-
 	res, _, err := c.SecurityGroupApi.UpdateSecurityGroup(context.Background(), orgID.String(), secGroupID).Update(public.ModelsUpdateSecurityGroup{
 		GroupName:        name,
 		GroupDescription: description,
@@ -65,7 +56,7 @@ func updateSecurityGroup(c *client.APIClient, encodeOut, secGroupID, organizatio
 		OutboundRules:    outboundRules,
 	}).Execute()
 	if err != nil {
-		return fmt.Errorf("update security group failed: %w", err)
+		return fmt.Errorf("update security group failed: %w", validationError(err))
 	}
 
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
@@ -142,6 +133,66 @@ func deleteSecurityGroup(c *client.APIClient, encodeOut, secGroupID, organizatio
 	return nil
 }
 
+// simulateSecurityGroup evaluates a flow against the security groups of the source and destination devices.
+func simulateSecurityGroup(c *client.APIClient, encodeOut, organizationID string, simulation public.ModelsSimulateSecurityGroup) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+	res, _, err := c.SecurityGroupApi.SimulateSecurityGroup(context.Background(), orgID.String()).Simulation(simulation).Execute()
+	if err != nil {
+		return fmt.Errorf("security group simulation failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%t\t%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "DIRECTION", "DEVICE ID", "SECURITY GROUP ID", "ALLOWED", "MATCHED RULE", "REASON")
+		}
+		verdicts := []struct {
+			direction string
+			verdict   public.ModelsSecurityGroupVerdict
+		}{
+			{"outbound", res.Outbound},
+			{"inbound", res.Inbound},
+		}
+		for _, v := range verdicts {
+			if v.verdict.DeviceId == "" {
+				// the destination is not a device of the organization
+				continue
+			}
+			rule := ""
+			if v.verdict.Rule.IpProtocol != "" {
+				b, err := json.Marshal(v.verdict.Rule)
+				if err != nil {
+					return fmt.Errorf("failed to print output: %w", err)
+				}
+				rule = string(b)
+			}
+			fmt.Fprintf(w, fs, v.direction, v.verdict.DeviceId, v.verdict.SecurityGroupId, v.verdict.Allowed, rule, v.verdict.Reason)
+		}
+		w.Flush()
+
+		if encodeOut == encodeNoHeader {
+			return nil
+		}
+		if res.Allowed {
+			fmt.Println("\nthe flow is allowed")
+		} else {
+			fmt.Println("\nthe flow is denied")
+		}
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
 func jsonStringToSecurityRules(jsonString string) ([]public.ModelsSecurityRule, error) {
 	var rules []public.ModelsSecurityRule
 	err := json.Unmarshal([]byte(jsonString), &rules)
@@ -151,27 +202,21 @@ func jsonStringToSecurityRules(jsonString string) ([]public.ModelsSecurityRule,
 	return rules, nil
 }
 
-// checkICMPRules prevents the user from defining ICMP rules with ports set to anything but 0.
-func checkICMPRules(inboundRules []public.ModelsSecurityRule, outboundRules []public.ModelsSecurityRule) error {
-	for _, rule := range inboundRules {
-		err := checkICMPRule(rule)
-		if err != nil {
-			return err
-		}
-	}
-	for _, rule := range outboundRules {
-		err := checkICMPRule(rule)
-		if err != nil {
-			return err
+// validationError adds the field and the reason reported by the API to a validation error,
+// or the reason to an error caused by the role of the user in the organization.
+func validationError(err error) error {
+	var apiError *public.GenericOpenAPIError
+	if errors.As(err, &apiError) {
+		switch model := apiError.Model().(type) {
+		case public.ModelsValidationError:
+			if model.Field != "" {
+				return fmt.Errorf("%w: %s %s", err, model.Field, model.Error)
+			}
+		case public.ModelsNotAllowedError:
+			if model.Reason != "" {
+				return fmt.Errorf("%w: %s", err, model.Reason)
+			}
 		}
 	}
-	return nil
-}
-
-// checkICMPRule checks an ICMP rules with ports set to anything but 0.
-func checkICMPRule(rule public.ModelsSecurityRule) error {
-	if rule.IpProtocol == "icmp" && (rule.FromPort != 0 || rule.ToPort != 0) {
-		return fmt.Errorf("error: ICMP rule should have FromPort and ToPort set to 0 or left undefined")
-	}
-	return nil
+	return err
 }
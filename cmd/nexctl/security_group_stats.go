@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+type SecurityGroupStats struct {
+	SecurityGroupId string
+	Rules           []SecurityRuleStats
+}
+
+type SecurityRuleStats struct {
+	Direction string
+	Rule      int
+	Action    string
+	Match     string
+	Packets   uint64
+	Bytes     uint64
+}
+
+func cmdSecurityGroupStats(cCtx *cli.Context, encodeOut string) error {
+	if err := checkVersion(); err != nil {
+		return err
+	}
+
+	result, err := callNexd("SecurityGroupStats", "")
+	if err != nil {
+		return fmt.Errorf("Failed to get the security group stats: %w\n", err)
+	}
+
+	var stats SecurityGroupStats
+	err = json.Unmarshal([]byte(result), &stats)
+	if err != nil {
+		return fmt.Errorf("Failed to marshall the security group stats: %w\n", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		if stats.SecurityGroupId == "" {
+			fmt.Println("nexd is not enforcing a security group")
+			return nil
+		}
+
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\t%d\t%d\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Printf("Security Group: %s\n\n", stats.SecurityGroupId)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", "DIRECTION", "RULE", "ACTION", "MATCH", "PACKETS", "BYTES")
+		}
+		for _, rule := range stats.Rules {
+			index := fmt.Sprintf("%d", rule.Rule)
+			if rule.Rule < 0 {
+				index = "-"
+			}
+			fmt.Fprintf(w, fs, rule.Direction, index, rule.Action, rule.Match, rule.Packets, rule.Bytes)
+		}
+		w.Flush()
+
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, stats)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
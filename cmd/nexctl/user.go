@@ -59,7 +59,7 @@ func deleteUser(c *client.APIClient, encodeOut, userID string) error {
 func deleteUserFromOrg(c *client.APIClient, encodeOut, userID, orgID string) error {
 	res, _, err := c.UsersApi.DeleteUserFromOrganization(context.Background(), userID, orgID).Execute()
 	if err != nil {
-		log.Fatalf("user removal failed: %v\n", err)
+		log.Fatalf("user removal failed: %v\n", validationError(err))
 	}
 
 	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/client"
+)
+
+// createWebhook creates a webhook for the events of an organization.
+func createWebhook(c *client.APIClient, encodeOut, organizationID string, webhook public.ModelsAddWebhook) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	res, _, err := c.WebhooksApi.CreateWebhook(context.Background(), orgID.String()).Webhook(webhook).Execute()
+	if err != nil {
+		return fmt.Errorf("create webhook failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "WEBHOOK ID", "SECRET")
+		}
+		fmt.Fprintf(w, fs, res.Id, res.Secret)
+		w.Flush()
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// listWebhooks lists the webhooks of an organization.
+func listWebhooks(c *client.APIClient, encodeOut, organizationID string) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	webhooks, _, err := c.WebhooksApi.ListWebhooks(context.Background(), orgID.String()).Execute()
+	if err != nil {
+		return fmt.Errorf("list webhooks failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%t\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "WEBHOOK ID", "URL", "EVENT TYPES", "ENABLED", "DESCRIPTION")
+		}
+		for _, webhook := range webhooks {
+			eventTypes := "*"
+			if len(webhook.EventTypes) > 0 {
+				eventTypes = strings.Join(webhook.EventTypes, ",")
+			}
+			fmt.Fprintf(w, fs, webhook.Id, webhook.Url, eventTypes, webhook.Enabled, webhook.Description)
+		}
+		w.Flush()
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, webhooks)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// updateWebhook updates a webhook of an organization.
+func updateWebhook(c *client.APIClient, encodeOut, organizationID, webhookID string, update public.ModelsUpdateWebhook) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	res, _, err := c.WebhooksApi.UpdateWebhook(context.Background(), orgID.String(), webhookID).Update(update).Execute()
+	if err != nil {
+		return fmt.Errorf("update webhook failed: %w", validationError(err))
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully updated webhook %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// deleteWebhook deletes a webhook of an organization.
+func deleteWebhook(c *client.APIClient, encodeOut, organizationID, webhookID string) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	res, _, err := c.WebhooksApi.DeleteWebhook(context.Background(), orgID.String(), webhookID).Execute()
+	if err != nil {
+		return fmt.Errorf("delete webhook failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		fmt.Printf("successfully deleted webhook %s\n", res.Id)
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, res)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
+
+// listWebhookDeliveries lists the deliveries of the events to a webhook, newest first.
+func listWebhookDeliveries(c *client.APIClient, encodeOut, organizationID, webhookID, status string, limit int) error {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to parse a valid UUID from %s %w", organizationID, err)
+	}
+
+	req := c.WebhooksApi.ListWebhookDeliveries(context.Background(), orgID.String(), webhookID).Limit(int32(limit))
+	if status != "" {
+		req = req.Status(status)
+	}
+	deliveries, _, err := req.Execute()
+	if err != nil {
+		return fmt.Errorf("list webhook deliveries failed: %w", err)
+	}
+
+	if encodeOut == encodeColumn || encodeOut == encodeNoHeader {
+		w := newTabWriter()
+		fs := "%s\t%s\t%s\t%s\t%d\t%d\t%s\n"
+		if encodeOut != encodeNoHeader {
+			fmt.Fprintf(w, fs, "TIME", "DELIVERY ID", "EVENT TYPE", "STATUS", "ATTEMPTS", "RESPONSE CODE", "ERROR")
+		}
+		for _, delivery := range deliveries {
+			fmt.Fprintf(w, fs, delivery.CreatedAt, delivery.Id, delivery.EventType, delivery.Status, delivery.Attempts, delivery.ResponseCode, delivery.Error)
+		}
+		w.Flush()
+		return nil
+	}
+
+	err = FormatOutput(encodeOut, deliveries)
+	if err != nil {
+		return fmt.Errorf("failed to print output: %w", err)
+	}
+
+	return nil
+}
@@ -83,12 +83,18 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 		stun.SetServers(stunServers)
 	}
 
+	labels, err := nexodus.ParseLabels(cCtx.StringSlice("label"))
+	if err != nil {
+		return err
+	}
+
 	nex, err := nexodus.NewNexodus(
 		logger.Sugar(),
 		logLevel,
 		serviceURL,
 		cCtx.String("username"),
 		cCtx.String("password"),
+		cCtx.String("reg-key"),
 		cCtx.Int("listen-port"),
 		cCtx.String("public-key"),
 		cCtx.String("private-key"),
@@ -98,12 +104,17 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 		cCtx.Bool("stun"),
 		relayNode,
 		cCtx.Bool("relay-only"),
+		cCtx.Bool("ephemeral"),
+		labels,
+		cCtx.Duration("key-rotation-interval"),
+		cCtx.Bool("deregister-on-exit"),
 		cCtx.Bool("insecure-skip-tls-verify"),
 		Version,
 		userspaceMode,
 		cCtx.String("state-dir"),
 		ctx,
 		cCtx.String("org-id"),
+		cCtx.String("security-group-log"),
 	)
 	if err != nil {
 		logger.Fatal(err.Error())
@@ -139,7 +150,7 @@ func nexdRun(cCtx *cli.Context, logger *zap.Logger, logLevel *zap.AtomicLevel, m
 	if err := nex.Start(ctx, wg); err != nil {
 		logger.Fatal(err.Error())
 	}
-	<-ctx.Done()
+	<-nex.Done()
 	nex.Stop()
 	wg.Wait()
 
@@ -318,6 +329,43 @@ func main() {
 				Required: false,
 				Category: agentOptions,
 			},
+			&cli.BoolFlag{
+				Name:     "ephemeral",
+				Usage:    "Register an ephemeral device, the api-server deletes it once it stops reporting its status, for CI runners or autoscaled containers",
+				Value:    false,
+				EnvVars:  []string{"NEXD_EPHEMERAL"},
+				Required: false,
+				Category: agentOptions,
+			},
+			&cli.StringSliceFlag{
+				Name:     "label",
+				Usage:    "Set a label on this device using a `value` in the form key=value, can be repeated",
+				EnvVars:  []string{"NEXD_LABEL"},
+				Required: false,
+				Category: agentOptions,
+				Action: func(ctx *cli.Context, labels []string) error {
+					if _, err := nexodus.ParseLabels(labels); err != nil {
+						return fmt.Errorf("the labels passed in --label are not valid: %w", err)
+					}
+					return nil
+				},
+			},
+			&cli.DurationFlag{
+				Name:     "key-rotation-interval",
+				Usage:    "Rotate the WireGuard keys of this device once they are older than this `duration`, for example 2160h for 90 days (0 disables the rotation)",
+				Value:    0,
+				EnvVars:  []string{"NEXD_KEY_ROTATION_INTERVAL"},
+				Required: false,
+				Category: agentOptions,
+			},
+			&cli.BoolFlag{
+				Name:     "deregister-on-exit",
+				Usage:    "Delete this device from the organization and remove its tunnel interface, routes and firewall rules when nexd exits",
+				Value:    false,
+				EnvVars:  []string{"NEXD_DEREGISTER_ON_EXIT"},
+				Required: false,
+				Category: agentOptions,
+			},
 			&cli.StringFlag{
 				Name:     "username",
 				Value:    "",
@@ -334,6 +382,14 @@ func main() {
 				Required: false,
 				Category: nexServiceOptions,
 			},
+			&cli.StringFlag{
+				Name:     "reg-key",
+				Value:    "",
+				Usage:    "Registration key `string` of an organization, used to register the device instead of a username and password",
+				EnvVars:  []string{"NEXD_REG_KEY"},
+				Required: false,
+				Category: nexServiceOptions,
+			},
 			&cli.BoolFlag{
 				Name:     "insecure-skip-tls-verify",
 				Value:    false,
@@ -355,6 +411,13 @@ func main() {
 				EnvVars:  []string{"NEXD_STUN_SERVER"},
 				Category: nexServiceOptions,
 			},
+			&cli.StringFlag{
+				Name:     "security-group-log",
+				Usage:    "Log the packets denied by the security group, `value` is either log to write them to the kernel log or nflog:<group> to send them to an nflog group",
+				EnvVars:  []string{"NEXD_SECURITY_GROUP_LOG"},
+				Required: false,
+				Category: agentOptions,
+			},
 			&cli.StringFlag{
 				Name:     "org-id",
 				Usage:    "Organization ID to use when registering with the nexodus service",
@@ -0,0 +1,71 @@
+Feature: Security Groups API
+  Background:
+    Given a user named "Sam" with password "testpass"
+
+  Scenario: Using the watch option to stream security group change events
+
+    Given I am logged in as "Sam"
+    When I GET path "/api/organizations"
+    Then the response code should be 200
+    Given I store the ${response[0].id} as ${organization_id}
+
+    When I GET path "/api/organizations/${organization_id}/security_groups"
+    Then the response code should be 200
+    Given I store the ${response[0]} as ${default_group}
+
+    When I GET path "/api/organizations/${organization_id}/security_groups?watch=true&gt_revision=0" as a json event stream
+    Then the response code should be 200
+    And the response header "Content-Type" should match "application/json;stream=watch"
+
+    Given I wait up to "3" seconds for a response event
+    Then the response should match json:
+      """
+      {
+        "type": "change",
+        "value": ${default_group}
+      }
+      """
+
+    # The book mark event signals that you have now received the full list of security groups.
+    Given I wait up to "3" seconds for a response event
+    Then the response should match json:
+      """
+      { "type": "bookmark" }
+      """
+
+    When I POST path "/api/organizations/${organization_id}/security_groups" with json body:
+      """
+      {
+        "group_name": "web",
+        "group_description": "allow http",
+        "org_id": "${organization_id}",
+        "inbound_rules": [
+          { "ip_protocol": "tcp", "from_port": 80, "to_port": 80, "ip_ranges": [] }
+        ]
+      }
+      """
+    Then the response code should be 201
+    Given I store the ${response} as ${web_group}
+    Given I store the ${response.id} as ${web_group_id}
+
+    Given I wait up to "3" seconds for a response event
+    Then the response should match json:
+      """
+      {
+        "type": "change",
+        "value": ${web_group}
+      }
+      """
+
+    When I DELETE path "/api/organizations/${organization_id}/security_groups/${web_group_id}"
+    Then the response code should be 200
+    Given I store the ${response} as ${deleted_group}
+
+    Given I wait up to "3" seconds for a response event
+    Then the response should match json:
+      """
+      {
+        "type": "delete",
+        "value": ${deleted_group}
+      }
+      """
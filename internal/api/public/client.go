@@ -50,6 +50,8 @@ type APIClient struct {
 
 	// API Services
 
+	ApiTokensApi *ApiTokensApiService
+
 	AuthApi *AuthApiService
 
 	DevicesApi *DevicesApiService
@@ -60,9 +62,13 @@ type APIClient struct {
 
 	OrganizationsApi *OrganizationsApiService
 
+	RegKeysApi *RegKeysApiService
+
 	SecurityGroupApi *SecurityGroupApiService
 
 	UsersApi *UsersApiService
+
+	WebhooksApi *WebhooksApiService
 }
 
 type service struct {
@@ -81,13 +87,16 @@ func NewAPIClient(cfg *Configuration) *APIClient {
 	c.common.client = c
 
 	// API Services
+	c.ApiTokensApi = (*ApiTokensApiService)(&c.common)
 	c.AuthApi = (*AuthApiService)(&c.common)
 	c.DevicesApi = (*DevicesApiService)(&c.common)
 	c.FFlagApi = (*FFlagApiService)(&c.common)
 	c.InvitationApi = (*InvitationApiService)(&c.common)
 	c.OrganizationsApi = (*OrganizationsApiService)(&c.common)
+	c.RegKeysApi = (*RegKeysApiService)(&c.common)
 	c.SecurityGroupApi = (*SecurityGroupApiService)(&c.common)
 	c.UsersApi = (*UsersApiService)(&c.common)
+	c.WebhooksApi = (*WebhooksApiService)(&c.common)
 
 	return c
 }
@@ -16,14 +16,17 @@ type ModelsAddDevice struct {
 	Discovery               bool             `json:"discovery,omitempty"`
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
-	Hostname                string           `json:"hostname,omitempty"`
-	OrganizationId          string           `json:"organization_id,omitempty"`
-	Os                      string           `json:"os,omitempty"`
-	PublicKey               string           `json:"public_key,omitempty"`
-	Relay                   bool             `json:"relay,omitempty"`
-	SecurityGroupId         string           `json:"security_group_id,omitempty"`
-	SymmetricNat            bool             `json:"symmetric_nat,omitempty"`
-	TunnelIp                string           `json:"tunnel_ip,omitempty"`
-	TunnelIpV6              string           `json:"tunnel_ip_v6,omitempty"`
-	UserId                  string           `json:"user_id,omitempty"`
+	// Ephemeral devices are deleted automatically once they go away
+	Ephemeral       bool              `json:"ephemeral,omitempty"`
+	Hostname        string            `json:"hostname,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	OrganizationId  string            `json:"organization_id,omitempty"`
+	Os              string            `json:"os,omitempty"`
+	PublicKey       string            `json:"public_key,omitempty"`
+	Relay           bool              `json:"relay,omitempty"`
+	SecurityGroupId string            `json:"security_group_id,omitempty"`
+	SymmetricNat    bool              `json:"symmetric_nat,omitempty"`
+	TunnelIp        string            `json:"tunnel_ip,omitempty"`
+	TunnelIpV6      string            `json:"tunnel_ip_v6,omitempty"`
+	UserId          string            `json:"user_id,omitempty"`
 }
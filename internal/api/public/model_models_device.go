@@ -12,23 +12,39 @@ package public
 
 // ModelsDevice struct for ModelsDevice
 type ModelsDevice struct {
-	AllowedIps              []string         `json:"allowed_ips,omitempty"`
-	ChildPrefix             []string         `json:"child_prefix,omitempty"`
+	AllowedIps  []string `json:"allowed_ips,omitempty"`
+	ChildPrefix []string `json:"child_prefix,omitempty"`
+	// DeviceToken is the credential nexd authenticates with once the device is registered with a registration key, it is only returned when the device is created, only its hash is stored
+	DeviceToken             string           `json:"device_token,omitempty"`
 	Discovery               bool             `json:"discovery,omitempty"`
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
-	Hostname                string           `json:"hostname,omitempty"`
-	Id                      string           `json:"id,omitempty"`
-	OrganizationId          string           `json:"organization_id,omitempty"`
-	OrganizationPrefix      string           `json:"organization_prefix,omitempty"`
-	OrganizationPrefixV6    string           `json:"organization_prefix_v6,omitempty"`
-	Os                      string           `json:"os,omitempty"`
-	PublicKey               string           `json:"public_key,omitempty"`
-	Relay                   bool             `json:"relay,omitempty"`
-	Revision                int32            `json:"revision,omitempty"`
-	SecurityGroupId         string           `json:"security_group_id,omitempty"`
-	SymmetricNat            bool             `json:"symmetric_nat,omitempty"`
-	TunnelIp                string           `json:"tunnel_ip,omitempty"`
-	TunnelIpV6              string           `json:"tunnel_ip_v6,omitempty"`
-	UserId                  string           `json:"user_id,omitempty"`
+	// Ephemeral devices are deleted once they have not been updated or reported their status for the ephemeral device TTL of the apiserver
+	Ephemeral bool   `json:"ephemeral,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+	Id        string `json:"id,omitempty"`
+	// Labels are free-form key/value pairs that can be used to select the devices
+	Labels   map[string]string `json:"labels,omitempty"`
+	LastSeen string            `json:"last_seen,omitempty"`
+	// Online and LastSeen are computed from the last status reported by the device, they are not included in the watch events
+	Online               bool   `json:"online,omitempty"`
+	OrganizationId       string `json:"organization_id,omitempty"`
+	OrganizationPrefix   string `json:"organization_prefix,omitempty"`
+	OrganizationPrefixV6 string `json:"organization_prefix_v6,omitempty"`
+	Os                   string `json:"os,omitempty"`
+	// Pending devices were registered in an organization that requires approval, they are not sent to the other devices until an admin approves them
+	Pending bool `json:"pending,omitempty"`
+	// PreviousTunnelIP and PreviousTunnelIpV6 are the addresses the device had before its organization was re-addressed, they stay valid until the end of the grace period
+	PreviousTunnelIp   string `json:"previous_tunnel_ip,omitempty"`
+	PreviousTunnelIpV6 string `json:"previous_tunnel_ip_v6,omitempty"`
+	PublicKey          string `json:"public_key,omitempty"`
+	// RegKeyID is the registration key the device was registered with
+	RegKeyId        string `json:"reg_key_id,omitempty"`
+	Relay           bool   `json:"relay,omitempty"`
+	Revision        int32  `json:"revision,omitempty"`
+	SecurityGroupId string `json:"security_group_id,omitempty"`
+	SymmetricNat    bool   `json:"symmetric_nat,omitempty"`
+	TunnelIp        string `json:"tunnel_ip,omitempty"`
+	TunnelIpV6      string `json:"tunnel_ip_v6,omitempty"`
+	UserId          string `json:"user_id,omitempty"`
 }
@@ -12,13 +12,20 @@ package public
 
 // ModelsOrganization struct for ModelsOrganization
 type ModelsOrganization struct {
-	Cidr            string             `json:"cidr,omitempty"`
-	CidrV6          string             `json:"cidr_v6,omitempty"`
-	Description     string             `json:"description,omitempty"`
-	HubZone         bool               `json:"hub_zone,omitempty"`
-	Id              string             `json:"id,omitempty"`
-	Invitations     []ModelsInvitation `json:"invitations,omitempty"`
-	Name            string             `json:"name,omitempty"`
-	OwnerId         string             `json:"owner_id,omitempty"`
-	SecurityGroupId string             `json:"security_group_id,omitempty"`
+	// ApprovalRequired keeps the new devices pending until an admin approves them
+	ApprovalRequired bool               `json:"approval_required,omitempty"`
+	Cidr             string             `json:"cidr,omitempty"`
+	CidrV6           string             `json:"cidr_v6,omitempty"`
+	Description      string             `json:"description,omitempty"`
+	HubZone          bool               `json:"hub_zone,omitempty"`
+	Id               string             `json:"id,omitempty"`
+	Invitations      []ModelsInvitation `json:"invitations,omitempty"`
+	Name             string             `json:"name,omitempty"`
+	OwnerId          string             `json:"owner_id,omitempty"`
+	// PreviousIpCidr and PreviousIpCidrV6 are the prefixes the organization is being re-addressed from, they stay valid until the ReaddressDeadline
+	PreviousCidr      string `json:"previous_cidr,omitempty"`
+	PreviousCidrV6    string `json:"previous_cidr_v6,omitempty"`
+	ReaddressDeadline string `json:"readdress_deadline,omitempty"`
+	Revision          int32  `json:"revision,omitempty"`
+	SecurityGroupId   string `json:"security_group_id,omitempty"`
 }
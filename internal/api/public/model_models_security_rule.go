@@ -12,8 +12,14 @@ package public
 
 // ModelsSecurityRule struct for ModelsSecurityRule
 type ModelsSecurityRule struct {
+	// AllDevices matches the tunnel addresses of all the devices in the organization
+	AllDevices bool `json:"all_devices,omitempty"`
+	// DeviceIds matches the tunnel addresses of the listed devices
+	DeviceIds  []string `json:"device_ids,omitempty"`
 	FromPort   int32    `json:"from_port,omitempty"`
 	IpProtocol string   `json:"ip_protocol,omitempty"`
 	IpRanges   []string `json:"ip_ranges,omitempty"`
-	ToPort     int32    `json:"to_port,omitempty"`
+	// SecurityGroupIds matches the tunnel addresses of the devices in the listed security groups
+	SecurityGroupIds []string `json:"security_group_ids,omitempty"`
+	ToPort           int32    `json:"to_port,omitempty"`
 }
@@ -16,7 +16,10 @@ type ModelsUpdateDevice struct {
 	EndpointLocalAddressIp4 string           `json:"endpoint_local_address_ip4,omitempty"`
 	Endpoints               []ModelsEndpoint `json:"endpoints,omitempty"`
 	Hostname                string           `json:"hostname,omitempty"`
-	OrganizationId          string           `json:"organization_id,omitempty"`
-	Revision                int32            `json:"revision,omitempty"`
-	SymmetricNat            bool             `json:"symmetric_nat,omitempty"`
+	// Labels replace the labels of the device when they are set
+	Labels          map[string]string `json:"labels,omitempty"`
+	OrganizationId  string            `json:"organization_id,omitempty"`
+	Revision        int32             `json:"revision,omitempty"`
+	SecurityGroupId string            `json:"security_group_id,omitempty"`
+	SymmetricNat    bool              `json:"symmetric_nat,omitempty"`
 }
@@ -74,6 +74,29 @@ func TestWithTokenFileOption(t *testing.T) {
 
 }
 
+func TestWithBearerTokenOption(t *testing.T) {
+
+	require := require.New(t)
+	assert := assert.New(t)
+
+	mockRouter := http.NewServeMux()
+	mockServer := httptest.NewServer(mockRouter)
+	defer mockServer.Close()
+
+	// the OIDC routes are not used when the client has a bearer token
+	mockRouter.HandleFunc("/api/users/me", func(resp http.ResponseWriter, request *http.Request) {
+		assert.Equal("Bearer nexapi_test", request.Header.Get("Authorization"))
+		sendJson(resp, 200, "{}")
+	})
+
+	c, err := client.NewAPIClient(context.Background(), mockServer.URL, nil,
+		client.WithBearerToken("nexapi_test"),
+	)
+	require.NoError(err)
+	_, _, err = c.UsersApi.GetUser(context.Background(), "me").Execute()
+	require.NoError(err)
+}
+
 func sendJson(resp http.ResponseWriter, status int, body interface{}) {
 	resp.Header().Add("Content-Type", "application/json")
 	resp.WriteHeader(status)
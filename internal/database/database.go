@@ -19,6 +19,20 @@ import (
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230412_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230413_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migration_20230428_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230503_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230510_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230511_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230512_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230513_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230514_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230515_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230516_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230517_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230518_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230519_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230520_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230521_0000"
+	"github.com/nexodus-io/nexodus/internal/database/migration_20230522_0000"
 	"github.com/nexodus-io/nexodus/internal/database/migrations"
 	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
 	"go.opentelemetry.io/otel"
@@ -114,6 +128,20 @@ func Migrations() *migrations.Migrations {
 			migration_20230412_0000.Migrate(),
 			migration_20230413_0000.Migrate(),
 			migration_20230428_0000.Migrate(),
+			migration_20230503_0000.Migrate(),
+			migration_20230510_0000.Migrate(),
+			migration_20230511_0000.Migrate(),
+			migration_20230512_0000.Migrate(),
+			migration_20230513_0000.Migrate(),
+			migration_20230514_0000.Migrate(),
+			migration_20230515_0000.Migrate(),
+			migration_20230516_0000.Migrate(),
+			migration_20230517_0000.Migrate(),
+			migration_20230518_0000.Migrate(),
+			migration_20230519_0000.Migrate(),
+			migration_20230520_0000.Migrate(),
+			migration_20230521_0000.Migrate(),
+			migration_20230522_0000.Migrate(),
 		},
 	}
 }
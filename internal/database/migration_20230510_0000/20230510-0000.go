@@ -0,0 +1,36 @@
+package migration_20230510_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+// FeatureFlag stores the global default of a feature flag
+type FeatureFlag struct {
+	Name        string `gorm:"primary_key;"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Description string
+	Enabled     bool
+}
+
+// FeatureFlagOverride enables or disables a feature flag for a single organization or user
+type FeatureFlagOverride struct {
+	models.Base
+	FlagName       string     `gorm:"index"`
+	OrganizationId *uuid.UUID `gorm:"type:uuid;index"`
+	UserId         string     `gorm:"index"`
+	Enabled        bool
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230510-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.CreateTableAction(&FeatureFlag{}),
+		migrations.CreateTableAction(&FeatureFlagOverride{}),
+	)
+}
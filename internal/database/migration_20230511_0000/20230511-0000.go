@@ -0,0 +1,26 @@
+package migration_20230511_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+// UserOrganization adds the role of the user in the organization
+type UserOrganization struct {
+	Role string `gorm:"not null;default:member"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230511-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&UserOrganization{}),
+		// the organization owners become admins, the other users keep the default member role
+		ExecAction(`
+			UPDATE user_organizations SET role = 'admin' WHERE EXISTS (
+				SELECT 1 FROM organizations
+				WHERE organizations.id = user_organizations.organization_id
+				AND organizations.owner_id = user_organizations.user_id
+			)
+		`, ``),
+	)
+}
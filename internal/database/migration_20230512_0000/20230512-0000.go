@@ -0,0 +1,32 @@
+package migration_20230512_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Organization struct {
+	Revision uint64 `gorm:"type:bigserial;index:"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230512-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Organization{}),
+		ExecActionIf(`
+			CREATE OR REPLACE FUNCTION organizations_revision_trigger() RETURNS TRIGGER LANGUAGE plpgsql AS '
+			BEGIN
+			NEW.revision := nextval(''organizations_revision_seq'');
+			RETURN NEW;
+			END;'
+		`, `
+			DROP FUNCTION IF EXISTS organizations_revision_trigger
+		`, NotOnSqlLite),
+		ExecActionIf(`
+			CREATE OR REPLACE TRIGGER organizations_revision_trigger BEFORE INSERT OR UPDATE ON organizations
+			FOR EACH ROW EXECUTE PROCEDURE organizations_revision_trigger();
+		`, `
+			DROP TRIGGER IF EXISTS organizations_revision_trigger ON organizations
+		`, NotOnSqlLite),
+	)
+}
@@ -0,0 +1,27 @@
+package migration_20230513_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	. "github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Organization struct {
+	PreviousIpCidr    string
+	PreviousIpCidrV6  string
+	ReaddressDeadline *time.Time
+}
+
+type Device struct {
+	PreviousTunnelIP   string
+	PreviousTunnelIpV6 string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230513-0000"
+	return CreateMigrationFromActions(migrationId,
+		AddTableColumnsAction(&Organization{}),
+		AddTableColumnsAction(&Device{}),
+	)
+}
@@ -0,0 +1,31 @@
+package migration_20230514_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type PeerStatus struct {
+	DeviceID        uuid.UUID
+	Endpoint        string
+	Reachable       bool
+	LatestHandshake string
+}
+
+// DeviceStatus stores the health last reported by a device
+type DeviceStatus struct {
+	DeviceID    uuid.UUID `gorm:"type:uuid;primary_key"`
+	LastSeen    time.Time `gorm:"index"`
+	NexdVersion string
+	Peers       []PeerStatus `gorm:"type:JSONB; serializer:json"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230514-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.CreateTableAction(&DeviceStatus{}),
+	)
+}
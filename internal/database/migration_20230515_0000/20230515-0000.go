@@ -0,0 +1,34 @@
+package migration_20230515_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type AuditChange struct {
+	Before interface{}
+	After  interface{}
+}
+
+// AuditEvent records a change made through the api
+type AuditEvent struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key"`
+	CreatedAt      time.Time `gorm:"index"`
+	ActorID        string
+	OrganizationID uuid.UUID `gorm:"type:uuid;index"`
+	ResourceType   string
+	ResourceID     string
+	Action         string
+	Changes        map[string]AuditChange `gorm:"type:JSONB; serializer:json"`
+	TraceID        string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230515-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.CreateTableAction(&AuditEvent{}),
+	)
+}
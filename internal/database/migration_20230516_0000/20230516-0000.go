@@ -0,0 +1,46 @@
+package migration_20230516_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+// Webhook delivers the events of an organization to an HTTP endpoint
+type Webhook struct {
+	models.Base
+	OrganizationID uuid.UUID `gorm:"type:uuid;index"`
+	URL            string
+	Description    string
+	EventTypes     pq.StringArray `gorm:"type:text[]"`
+	Enabled        bool
+	Secret         string
+}
+
+// WebhookDelivery is the delivery of an event to a webhook
+type WebhookDelivery struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key"`
+	CreatedAt    time.Time
+	WebhookID    uuid.UUID `gorm:"type:uuid;index"`
+	EventID      uuid.UUID `gorm:"type:uuid"`
+	EventType    string
+	Payload      string
+	Status       string
+	Attempts     int
+	NextAttempt  *time.Time `gorm:"index"`
+	LastAttempt  *time.Time
+	ResponseCode int
+	Error        string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230516-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.CreateTableAction(&Webhook{}),
+		migrations.CreateTableAction(&WebhookDelivery{}),
+	)
+}
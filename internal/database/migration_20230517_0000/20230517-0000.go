@@ -0,0 +1,29 @@
+package migration_20230517_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/lib/pq"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+// ApiToken is a personal access token of a user
+type ApiToken struct {
+	models.Base
+	UserID      string `gorm:"index"`
+	Description string
+	Scopes      pq.StringArray `gorm:"type:text[]"`
+	Prefix      string
+	TokenHash   string `gorm:"uniqueIndex"`
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230517-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.CreateTableAction(&ApiToken{}),
+	)
+}
@@ -0,0 +1,42 @@
+package migration_20230518_0000
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+// RegKey is a registration key of an organization
+type RegKey struct {
+	models.Base
+	OrganizationID  uuid.UUID `gorm:"type:uuid;index"`
+	OwnerID         string
+	Description     string
+	Prefix          string
+	TokenHash       string `gorm:"uniqueIndex"`
+	ExpiresAt       *time.Time
+	SingleUse       bool
+	MaxDevices      int
+	SecurityGroupID *uuid.UUID `gorm:"type:uuid"`
+	Relay           bool
+	Discovery       bool
+}
+
+type Device struct {
+	RegKeyID *uuid.UUID `gorm:"type:uuid"`
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230518-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.CreateTableAction(&RegKey{}),
+		migrations.AddTableColumnsAction(&Device{}),
+		migrations.ExecAction(
+			`CREATE INDEX devices_reg_key_id ON devices (reg_key_id)`,
+			`DROP INDEX IF EXISTS devices_reg_key_id`,
+		),
+	)
+}
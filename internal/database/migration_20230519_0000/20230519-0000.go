@@ -0,0 +1,22 @@
+package migration_20230519_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Organization struct {
+	ApprovalRequired bool
+}
+
+type Device struct {
+	Pending bool
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230519-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.AddTableColumnsAction(&Organization{}),
+		migrations.AddTableColumnsAction(&Device{}),
+	)
+}
@@ -0,0 +1,17 @@
+package migration_20230520_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Device struct {
+	Ephemeral bool
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230520-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.AddTableColumnsAction(&Device{}),
+	)
+}
@@ -0,0 +1,21 @@
+package migration_20230522_0000
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/nexodus-io/nexodus/internal/database/migrations"
+)
+
+type Device struct {
+	DeviceTokenHash string
+}
+
+func Migrate() *gormigrate.Migration {
+	migrationId := "20230522-0000"
+	return migrations.CreateMigrationFromActions(migrationId,
+		migrations.AddTableColumnsAction(&Device{}),
+		migrations.ExecAction(
+			`CREATE INDEX devices_device_token_hash ON devices (device_token_hash)`,
+			`DROP INDEX IF EXISTS devices_device_token_hash`,
+		),
+	)
+}
@@ -36,6 +36,14 @@ const docTemplate = `{
                 ],
                 "summary": "List Devices",
                 "operationId": "ListDevices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "label selector, for example env=prod,role!=db",
+                        "name": "labelSelector",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -46,6 +54,12 @@ const docTemplate = `{
                             }
                         }
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
@@ -103,6 +117,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -181,7 +201,7 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Deletes an existing device and associated IPAM lease",
+                "description": "Deletes an existing device and associated IPAM lease, the organization admins can delete any device of the organization",
                 "consumes": [
                     "application/json"
                 ],
@@ -279,44 +299,17 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.NotAllowedError"
                         }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
-                    }
-                }
-            }
-        },
-        "/api/fflags": {
-            "get": {
-                "description": "Lists all feature flags",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "FFlag"
-                ],
-                "summary": "List Feature Flags",
-                "operationId": "ListFeatureFlags",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "boolean"
-                            }
-                        }
                     },
                     "429": {
                         "description": "Too Many Requests",
@@ -327,9 +320,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/fflags/{name}": {
-            "get": {
-                "description": "Gets a Feature Flag by name",
+        "/api/devices/{id}/approve": {
+            "post": {
+                "description": "Approves a device registered in an organization that requires approval, the device then joins the mesh. Approving a device that is not pending does nothing. Requires the admin role in the organization of the device.",
                 "consumes": [
                     "application/json"
                 ],
@@ -337,15 +330,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "FFlag"
+                    "Devices"
                 ],
-                "summary": "Get Feature Flag",
-                "operationId": "GetFeatureFlag",
+                "summary": "Approve Device",
+                "operationId": "ApproveDevice",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "feature flag name",
-                        "name": "name",
+                        "description": "Device ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -354,10 +347,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "boolean"
-                            }
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
                     "400": {
@@ -366,47 +356,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/invitations": {
-            "get": {
-                "description": "Lists all invitations",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Invitation"
-                ],
-                "summary": "List Invitations",
-                "operationId": "ListInvitations",
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Invitation"
-                            }
+                            "$ref": "#/definitions/models.NotAllowedError"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -416,11 +379,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
-            },
+            }
+        },
+        "/api/devices/{id}/rotate_key": {
             "post": {
-                "description": "Create an invitation to an organization",
+                "description": "Replaces the WireGuard public key of a device, the device keeps its ID and tunnel addresses and its peers pick up the new key from the device watch",
                 "consumes": [
                     "application/json"
                 ],
@@ -428,26 +399,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "Devices"
                 ],
-                "summary": "Create an invitation",
-                "operationId": "CreateInvitation",
+                "summary": "Rotate Device Key",
+                "operationId": "RotateDeviceKey",
                 "parameters": [
                     {
-                        "description": "Add Invitation",
-                        "name": "Invitation",
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New Public Key",
+                        "name": "key",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddInvitation"
+                            "$ref": "#/definitions/models.RotateDeviceKey"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Invitation"
+                            "$ref": "#/definitions/models.Device"
                         }
                     },
                     "400": {
@@ -456,24 +434,48 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             }
         },
-        "/api/invitations/:invitation/accept": {
-            "post": {
-                "description": "Accept an invitation to an organization",
+        "/api/devices/{id}/status": {
+            "get": {
+                "description": "Gets the health last reported by a device",
                 "consumes": [
                     "application/json"
                 ],
@@ -481,22 +483,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "Devices"
                 ],
-                "summary": "Accept an invitation",
-                "operationId": "AcceptInvitation",
+                "summary": "Get Device Status",
+                "operationId": "GetDeviceStatus",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Invitation ID",
-                        "name": "invitation",
+                        "description": "Device ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceStatus"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -504,6 +509,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -515,13 +526,17 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
-            }
-        },
-        "/api/invitations/{invitation}": {
-            "delete": {
-                "description": "Deletes an existing invitation",
+            },
+            "put": {
+                "description": "Stores the health reported by a device, the time of the report is its last seen time",
                 "consumes": [
                     "application/json"
                 ],
@@ -529,24 +544,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Invitation"
+                    "Devices"
                 ],
-                "summary": "Delete Invitation",
-                "operationId": "DeleteInvitation",
+                "summary": "Report Device Status",
+                "operationId": "ReportDeviceStatus",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Invitation ID",
-                        "name": "invitation",
+                        "description": "Device ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Device Status",
+                        "name": "status",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReportDeviceStatus"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.DeviceStatus"
                         }
                     },
                     "400": {
@@ -555,8 +579,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -576,9 +612,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations": {
+        "/api/fflags": {
             "get": {
-                "description": "Lists all Organizations",
+                "description": "Lists all feature flags and whether they are enabled for the current user",
                 "consumes": [
                     "application/json"
                 ],
@@ -586,26 +622,20 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "FFlag"
                 ],
-                "summary": "List Organizations",
-                "operationId": "ListOrganizations",
+                "summary": "List Feature Flags",
+                "operationId": "ListFeatureFlags",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Organization"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
-                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
@@ -621,7 +651,7 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Creates a named organization with the given CIDR",
+                "description": "Creates a Feature Flag with its global default, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -629,18 +659,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "FFlag"
                 ],
-                "summary": "Create an Organization",
-                "operationId": "CreateOrganization",
+                "summary": "Create Feature Flag",
+                "operationId": "CreateFeatureFlag",
                 "parameters": [
                     {
-                        "description": "Add Organization",
-                        "name": "Organization",
+                        "description": "Add Feature Flag",
+                        "name": "FeatureFlag",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddOrganization"
+                            "$ref": "#/definitions/models.AddFeatureFlag"
                         }
                     }
                 ],
@@ -648,13 +678,13 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.FeatureFlag"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.ValidationError"
                         }
                     },
                     "401": {
@@ -663,14 +693,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "405": {
-                        "description": "Method Not Allowed",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
-                    },
-                    "409": {
-                        "description": "Conflict",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/models.ConflictsError"
                         }
@@ -690,9 +714,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{id}": {
+        "/api/fflags/{name}": {
             "get": {
-                "description": "Gets a Organization by Organization ID",
+                "description": "Gets whether a Feature Flag is enabled for the current user",
                 "consumes": [
                     "application/json"
                 ],
@@ -700,15 +724,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "FFlag"
                 ],
-                "summary": "Get Organizations",
-                "operationId": "GetOrganizations",
+                "summary": "Get Feature Flag",
+                "operationId": "GetFeatureFlag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
+                        "description": "feature flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
@@ -717,7 +741,10 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "boolean"
+                            }
                         }
                     },
                     "400": {
@@ -726,12 +753,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
-                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -743,11 +764,17 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             },
             "delete": {
-                "description": "Deletes an existing organization and associated IPAM prefix",
+                "description": "Deletes a Feature Flag and its overrides, requires the admin role. Built-in flags can not be deleted.",
                 "consumes": [
                     "application/json"
                 ],
@@ -755,28 +782,34 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organizations"
+                    "FFlag"
                 ],
-                "summary": "Delete Organization",
-                "operationId": "DeleteOrganization",
+                "summary": "Delete Feature Flag",
+                "operationId": "DeleteFeatureFlag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
+                        "description": "feature flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Organization"
+                            "$ref": "#/definitions/models.FeatureFlag"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -800,11 +833,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/api/organizations/{id}/devices": {
-            "get": {
-                "description": "Lists all users for this Organization",
+            },
+            "patch": {
+                "description": "Updates the global default and the description of a Feature Flag, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -812,27 +843,33 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "FFlag"
                 ],
-                "summary": "List Users",
-                "operationId": "ListUsersInOrganization",
+                "summary": "Update Feature Flag",
+                "operationId": "UpdateFeatureFlag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
+                        "description": "feature flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Feature Flag Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateFeatureFlag"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.User"
-                            }
+                            "$ref": "#/definitions/models.FeatureFlag"
                         }
                     },
                     "400": {
@@ -847,6 +884,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
@@ -862,9 +905,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/devices": {
+        "/api/fflags/{name}/definition": {
             "get": {
-                "description": "Lists all devices for this Organization",
+                "description": "Gets the global default and the organization and user overrides of a Feature Flag, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -872,21 +915,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "FFlag"
                 ],
-                "summary": "List Devices",
-                "operationId": "ListDevicesInOrganization",
+                "summary": "Get Feature Flag Definition",
+                "operationId": "GetFeatureFlagDefinition",
                 "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "greater than revision",
-                        "name": "gt_revision",
-                        "in": "query"
-                    },
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
+                        "description": "feature flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     }
@@ -895,10 +932,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.Device"
-                            }
+                            "$ref": "#/definitions/models.FeatureFlag"
                         }
                     },
                     "400": {
@@ -913,6 +947,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
@@ -928,9 +968,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/devices/{device_id}": {
-            "get": {
-                "description": "Gets a device in a organization by ID",
+        "/api/fflags/{name}/overrides": {
+            "put": {
+                "description": "Enables or disables a Feature Flag for an organization or a user, replacing any previous override of it, requires the admin role",
                 "consumes": [
                     "application/json"
                 ],
@@ -938,37 +978,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Devices"
+                    "FFlag"
                 ],
-                "summary": "Get Device",
-                "operationId": "GetDeviceInOrganization",
+                "summary": "Set Feature Flag Override",
+                "operationId": "SetFeatureFlagOverride",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
+                        "description": "feature flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Device ID",
-                        "name": "device_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Feature Flag Override",
+                        "name": "override",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SetFeatureFlagOverride"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Device"
+                            "$ref": "#/definitions/models.FeatureFlagOverride"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.ValidationError"
                         }
                     },
                     "401": {
@@ -998,28 +1040,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_group/{id}": {
-            "get": {
-                "description": "Gets a security group in an organization by ID",
+        "/api/fflags/{name}/overrides/{id}": {
+            "delete": {
+                "description": "Deletes an organization or user override of a Feature Flag, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "FFlag"
                 ],
-                "summary": "Get SecurityGroup",
-                "operationId": "GetSecurityGroup",
+                "summary": "Delete Feature Flag Override",
+                "operationId": "DeleteFeatureFlagOverride",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
+                        "description": "feature flag name",
+                        "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Security Group ID",
+                        "description": "Override ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1029,7 +1074,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.FeatureFlagOverride"
                         }
                     },
                     "400": {
@@ -1055,37 +1100,37 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_groups": {
+        "/api/invitations": {
             "get": {
-                "description": "Lists all Security Groups",
+                "description": "Lists all invitations",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
-                ],
-                "summary": "List Security Groups",
-                "operationId": "ListSecurityGroups",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "Invitation"
                 ],
+                "summary": "List Invitations",
+                "operationId": "ListInvitations",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.SecurityGroup"
+                                "$ref": "#/definitions/models.Invitation"
                             }
                         }
                     },
@@ -1104,30 +1149,26 @@ const docTemplate = `{
                 }
             },
             "post": {
-                "description": "Adds a new Security Group",
+                "description": "Create an invitation to an organization",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "Invitation"
                 ],
-                "summary": "Add SecurityGroup",
-                "operationId": "CreateSecurityGroup",
+                "summary": "Create an invitation",
+                "operationId": "CreateInvitation",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Add SecurityGroup",
-                        "name": "SecurityGroup",
+                        "description": "Add Invitation",
+                        "name": "Invitation",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.AddSecurityGroup"
+                            "$ref": "#/definitions/models.AddInvitation"
                         }
                     }
                 ],
@@ -1135,7 +1176,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.Invitation"
                         }
                     },
                     "400": {
@@ -1144,16 +1185,16 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/models.BaseError"
+                            "$ref": "#/definitions/models.NotAllowedError"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/models.ConflictsError"
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     },
                     "429": {
@@ -1161,49 +1202,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/models.BaseError"
-                        }
                     }
                 }
             }
         },
-        "/api/organizations/{organization_id}/security_groups/{security_group_id}": {
-            "delete": {
-                "description": "Deletes an existing SecurityGroup",
+        "/api/invitations/:invitation/accept": {
+            "post": {
+                "description": "Accept an invitation to an organization",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "Invitation"
                 ],
-                "summary": "Delete SecurityGroup",
-                "operationId": "DeleteSecurityGroup",
+                "summary": "Accept an invitation",
+                "operationId": "AcceptInvitation",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Security Group ID",
-                        "name": "security_group_id",
+                        "description": "Invitation ID",
+                        "name": "invitation",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content",
-                        "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
-                        }
+                        "description": "No Content"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -1211,60 +1239,49 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Updates a Security Group by ID",
+            }
+        },
+        "/api/invitations/{invitation}": {
+            "delete": {
+                "description": "Deletes an existing invitation",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "SecurityGroup"
+                    "Invitation"
                 ],
-                "summary": "Update Security Group",
-                "operationId": "UpdateSecurityGroup",
+                "summary": "Delete Invitation",
+                "operationId": "DeleteInvitation",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "organization_id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Security Group ID",
-                        "name": "security_group_id",
+                        "description": "Invitation ID",
+                        "name": "invitation",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Security Group Update",
-                        "name": "update",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UpdateSecurityGroup"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/models.SecurityGroup"
+                            "$ref": "#/definitions/models.Organization"
                         }
                     },
                     "400": {
@@ -1273,20 +1290,20 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "405": {
+                        "description": "Method Not Allowed",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
@@ -1294,9 +1311,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/users": {
+        "/api/organizations": {
             "get": {
-                "description": "Lists all users",
+                "description": "Lists all Organizations",
                 "consumes": [
                     "application/json"
                 ],
@@ -1304,17 +1321,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Organizations"
+                ],
+                "summary": "List Organizations",
+                "operationId": "ListOrganizations",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    }
                 ],
-                "summary": "List Users",
-                "operationId": "ListUsers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.User"
+                                "$ref": "#/definitions/models.Organization"
                             }
                         }
                     },
@@ -1329,13 +1354,17 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
                     }
                 }
-            }
-        },
-        "/api/users/{id}": {
-            "get": {
-                "description": "Gets a user",
+            },
+            "post": {
+                "description": "Creates a named organization with the given CIDR",
                 "consumes": [
                     "application/json"
                 ],
@@ -1343,24 +1372,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Organizations"
                 ],
-                "summary": "Get User",
-                "operationId": "GetUser",
+                "summary": "Create an Organization",
+                "operationId": "CreateOrganization",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Add Organization",
+                        "name": "Organization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddOrganization"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Organization"
                         }
                     },
                     "400": {
@@ -1375,12 +1406,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "405": {
+                        "description": "Method Not Allowed",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
                     "429": {
                         "description": "Too Many Requests",
                         "schema": {
@@ -1394,9 +1431,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete a user",
+            }
+        },
+        "/api/organizations/{id}": {
+            "get": {
+                "description": "Gets a Organization by Organization ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -1404,14 +1443,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Organizations"
                 ],
-                "summary": "Delete User",
-                "operationId": "DeleteUser",
+                "summary": "Get Organizations",
+                "operationId": "GetOrganizations",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID",
+                        "description": "Organization ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -1421,7 +1460,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Organization"
                         }
                     },
                     "400": {
@@ -1430,24 +1469,28 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "429": {
-                        "description": "Too Many Requests",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
                         "schema": {
                             "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
-            }
-        },
-        "/api/users/{id}/organizations/{organization}": {
+            },
             "delete": {
-                "description": "Deletes an existing organization associated to a user",
+                "description": "Deletes an existing organization and associated IPAM prefix, only the owner can delete an organization",
                 "consumes": [
                     "application/json"
                 ],
@@ -1455,22 +1498,15 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Users"
+                    "Organizations"
                 ],
-                "summary": "Remove a User from an Organization",
-                "operationId": "DeleteUserFromOrganization",
+                "summary": "Delete Organization",
+                "operationId": "DeleteOrganization",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "User ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "string",
                         "description": "Organization ID",
-                        "name": "organization",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -1479,7 +1515,7 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.Organization"
                         }
                     },
                     "400": {
@@ -1488,6 +1524,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.BaseError"
                         }
                     },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1497,31 +1545,2290 @@ const docTemplate = `{
                 }
             }
         },
-        "/device/login/start": {
-            "post": {
-                "description": "Starts a device login request",
+        "/api/organizations/{id}/devices": {
+            "get": {
+                "description": "Lists all users for this Organization",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Users"
+                ],
+                "summary": "List Users",
+                "operationId": "ListUsersInOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Start Login",
-                "operationId": "DeviceStart",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.DeviceStartResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
                         }
                     }
                 }
             }
         },
-        "/web/claims": {
-            "get": {
-                "description": "Gets the claims of the users access token",
-                "produces": [
+        "/api/organizations/{organization_id}": {
+            "patch": {
+                "description": "Updates the name, description or hub zone of an organization, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Update Organization",
+                "operationId": "UpdateOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Organization Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/connectivity": {
+            "get": {
+                "description": "Combines the status reported by every device of the organization into the connectivity between every pair of devices",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Get Organization Connectivity",
+                "operationId": "GetOrganizationConnectivity",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConnectivityMatrix"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/devices": {
+            "get": {
+                "description": "Lists all devices for this Organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "List Devices",
+                "operationId": "ListDevicesInOrganization",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "label selector, for example env=prod,role!=db",
+                        "name": "labelSelector",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Device"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/devices/{device_id}": {
+            "get": {
+                "description": "Gets a device in a organization by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Devices"
+                ],
+                "summary": "Get Device",
+                "operationId": "GetDeviceInOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Device ID",
+                        "name": "device_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Device"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/events": {
+            "get": {
+                "description": "Lists the changes made to the organization and its resources, newest first. Only the organization admins can list the events.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List Organization Events",
+                "operationId": "ListOrganizationEvents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "only the events of this resource type",
+                        "name": "resource_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only the events of this resource",
+                        "name": "resource_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only the events of this action",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only the events of this user",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only the events after this RFC3339 time",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum number of events, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "number of events to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.AuditEvent"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/readdress": {
+            "post": {
+                "description": "Assigns new tunnel addresses to every device of the organization, the previous addresses stay valid during the grace period, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Re-address Organization",
+                "operationId": "ReaddressOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Organization Re-addressing",
+                        "name": "readdress",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.ReaddressOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/registration_keys": {
+            "get": {
+                "description": "Lists the registration keys of the organization, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RegKeys"
+                ],
+                "summary": "List Registration Keys",
+                "operationId": "ListRegKeys",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RegKey"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a key that registers devices into the organization without the OIDC session of a user, requires the admin role. The key is only returned by this call.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RegKeys"
+                ],
+                "summary": "Create Registration Key",
+                "operationId": "CreateRegKey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add Registration Key",
+                        "name": "reg_key",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddRegKey"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.RegKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/registration_keys/{id}": {
+            "delete": {
+                "description": "Deletes a registration key, requires the admin role. The device tokens of the devices registered with the key are revoked.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "RegKeys"
+                ],
+                "summary": "Delete Registration Key",
+                "operationId": "DeleteRegKey",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Registration Key ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.RegKey"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/roles": {
+            "get": {
+                "description": "Lists the role of every user in the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "List Organization Roles",
+                "operationId": "ListOrganizationRoles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UserOrganization"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/roles/{user_id}": {
+            "patch": {
+                "description": "Changes the role of a user in the organization, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Update Organization Role",
+                "operationId": "UpdateOrganizationRole",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateUserOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserOrganization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/security_group/{id}": {
+            "get": {
+                "description": "Gets a security group in an organization by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Get SecurityGroup",
+                "operationId": "GetSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/security_groups": {
+            "get": {
+                "description": "Lists all Security Groups",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "List Security Groups",
+                "operationId": "ListSecurityGroups",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "greater than revision",
+                        "name": "gt_revision",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.SecurityGroup"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Adds a new Security Group",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Add SecurityGroup",
+                "operationId": "CreateSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add SecurityGroup",
+                        "name": "SecurityGroup",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddSecurityGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/models.ConflictsError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/security_groups/simulate": {
+            "post": {
+                "description": "Evaluates a flow from a device to another device or IP against the security groups of the devices, without changing them",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Simulate Security Groups",
+                "operationId": "SimulateSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Flow to simulate",
+                        "name": "simulation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.SimulateSecurityGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroupSimulation"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "405": {
+                        "description": "Method Not Allowed",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/security_groups/{security_group_id}": {
+            "delete": {
+                "description": "Deletes an existing SecurityGroup",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Delete SecurityGroup",
+                "operationId": "DeleteSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "security_group_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates a Security Group by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "SecurityGroup"
+                ],
+                "summary": "Update Security Group",
+                "operationId": "UpdateSecurityGroup",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Security Group ID",
+                        "name": "security_group_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Security Group Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateSecurityGroup"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.SecurityGroup"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/transfer": {
+            "post": {
+                "description": "Transfers the ownership of an organization to one of its members, only the owner can transfer an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organizations"
+                ],
+                "summary": "Transfer Organization",
+                "operationId": "TransferOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ownership Transfer",
+                        "name": "transfer",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.TransferOrganization"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Organization"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/webhooks": {
+            "get": {
+                "description": "Lists the webhooks of the organization, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List Webhooks",
+                "operationId": "ListWebhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Webhook"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a webhook that receives the events of the organization, requires the admin role. The secret of the webhook is only returned by this call.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Create Webhook",
+                "operationId": "CreateWebhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add Webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/webhooks/{id}": {
+            "get": {
+                "description": "Gets a webhook of the organization by ID, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Get Webhook",
+                "operationId": "GetWebhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a webhook and its delivery history, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Delete Webhook",
+                "operationId": "DeleteWebhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Updates the URL, description, event types or state of a webhook, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Update Webhook",
+                "operationId": "UpdateWebhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Webhook Update",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/organizations/{organization_id}/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "Lists the deliveries of the events to a webhook, newest first, requires the admin role",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "List Webhook Deliveries",
+                "operationId": "ListWebhookDeliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "only the deliveries with this status: pending, succeeded or failed",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "maximum number of deliveries, 100 by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WebhookDelivery"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tokens": {
+            "get": {
+                "description": "Lists the personal access tokens of the current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ApiTokens"
+                ],
+                "summary": "List API Tokens",
+                "operationId": "ListApiTokens",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ApiToken"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Creates a personal access token that makes API calls as the current user, limited to its scopes. The token is only returned by this call.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ApiTokens"
+                ],
+                "summary": "Create API Token",
+                "operationId": "CreateApiToken",
+                "parameters": [
+                    {
+                        "description": "Add API Token",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.AddApiToken"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.ApiToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.ValidationError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/tokens/{id}": {
+            "delete": {
+                "description": "Revokes a personal access token of the current user, the API calls made with it are rejected right away",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ApiTokens"
+                ],
+                "summary": "Delete API Token",
+                "operationId": "DeleteApiToken",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "API Token ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.ApiToken"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users": {
+            "get": {
+                "description": "Lists all users",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "List Users",
+                "operationId": "ListUsers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}": {
+            "get": {
+                "description": "Gets a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Get User",
+                "operationId": "GetUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Delete User",
+                "operationId": "DeleteUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/{id}/organizations/{organization}": {
+            "delete": {
+                "description": "Deletes an existing organization associated to a user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Remove a User from an Organization",
+                "operationId": "DeleteUserFromOrganization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/models.NotAllowedError"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/models.BaseError"
+                        }
+                    }
+                }
+            }
+        },
+        "/device/login/start": {
+            "post": {
+                "description": "Starts a device login request",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Start Login",
+                "operationId": "DeviceStart",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.DeviceStartResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/web/claims": {
+            "get": {
+                "description": "Gets the claims of the users access token",
+                "produces": [
                     "application/json"
                 ],
                 "tags": [
@@ -1655,6 +3962,29 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "models.AddApiToken": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "terraform"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the token stops being valid, it does not expire when not set",
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read:devices",
+                        "write:devices"
+                    ]
+                }
+            }
+        },
         "models.AddDevice": {
             "type": "object",
             "properties": {
@@ -1680,10 +4010,23 @@ const docTemplate = `{
                         "$ref": "#/definitions/models.Endpoint"
                     }
                 },
+                "ephemeral": {
+                    "description": "Ephemeral devices are deleted automatically once they go away",
+                    "type": "boolean"
+                },
                 "hostname": {
                     "type": "string",
                     "example": "myhost"
                 },
+                "labels": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "env": "prod"
+                    }
+                },
                 "organization_id": {
                     "type": "string",
                     "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
@@ -1717,6 +4060,22 @@ const docTemplate = `{
                 }
             }
         },
+        "models.AddFeatureFlag": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Enables my feature"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "my-feature"
+                }
+            }
+        },
         "models.AddInvitation": {
             "type": "object",
             "properties": {
@@ -1727,64 +4086,208 @@ const docTemplate = `{
                     "description": "The user id to invite (one of username or user_id is required)",
                     "type": "string"
                 },
-                "user_name": {
-                    "description": "The username to invite (one of username or user_id is required)",
+                "user_name": {
+                    "description": "The username to invite (one of username or user_id is required)",
+                    "type": "string"
+                }
+            }
+        },
+        "models.AddOrganization": {
+            "type": "object",
+            "properties": {
+                "cidr": {
+                    "type": "string",
+                    "example": "172.16.42.0/24"
+                },
+                "cidr_v6": {
+                    "type": "string",
+                    "example": "0200::/8"
+                },
+                "description": {
+                    "type": "string",
+                    "example": "The Red Zone"
+                },
+                "hub_zone": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "zone-red"
+                },
+                "security_group_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.AddRegKey": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "cloud-init"
+                },
+                "discovery": {
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the key stops registering new devices, it does not expire when not set",
+                    "type": "string"
+                },
+                "max_devices": {
+                    "type": "integer"
+                },
+                "relay": {
+                    "type": "boolean"
+                },
+                "security_group_id": {
+                    "type": "string"
+                },
+                "single_use": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.AddSecurityGroup": {
+            "type": "object",
+            "properties": {
+                "group_description": {
+                    "type": "string",
+                    "example": "group_description"
+                },
+                "group_name": {
+                    "type": "string",
+                    "example": "group_name"
+                },
+                "inbound_rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SecurityRule"
+                    }
+                },
+                "org_id": {
+                    "type": "string"
+                },
+                "outbound_rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.SecurityRule"
+                    }
+                }
+            }
+        },
+        "models.AddWebhook": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "device.create",
+                        "device.delete",
+                        "security_group.*"
+                    ]
+                },
+                "secret": {
+                    "description": "Secret is the key of the signature of the payloads, a random one is generated when empty",
                     "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/nexodus"
                 }
             }
         },
-        "models.AddOrganization": {
+        "models.ApiToken": {
             "type": "object",
             "properties": {
-                "cidr": {
+                "description": {
                     "type": "string",
-                    "example": "172.16.42.0/24"
+                    "example": "terraform"
                 },
-                "cidr_v6": {
-                    "type": "string",
-                    "example": "0200::/8"
+                "expires_at": {
+                    "type": "string"
                 },
-                "description": {
+                "id": {
                     "type": "string",
-                    "example": "The Red Zone"
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
-                "hub_zone": {
-                    "type": "boolean"
+                "last_used_at": {
+                    "type": "string"
                 },
-                "name": {
+                "prefix": {
+                    "description": "Prefix is the start of the token, it identifies the token without revealing it",
                     "type": "string",
-                    "example": "zone-red"
+                    "example": "nexapi_3fa85f"
                 },
-                "security_group_id": {
+                "scopes": {
+                    "description": "Scopes limit the API calls that can be made with the token, like the scopes of an access token",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "read:devices",
+                        "write:devices"
+                    ]
+                },
+                "token": {
+                    "description": "Token is only returned when the token is created, only its hash is stored",
+                    "type": "string"
+                },
+                "user_id": {
                     "type": "string"
                 }
             }
         },
-        "models.AddSecurityGroup": {
+        "models.AuditChange": {
             "type": "object",
             "properties": {
-                "group_description": {
+                "after": {},
+                "before": {}
+            }
+        },
+        "models.AuditEvent": {
+            "type": "object",
+            "properties": {
+                "action": {
                     "type": "string",
-                    "example": "group_description"
+                    "example": "update"
                 },
-                "group_name": {
-                    "type": "string",
-                    "example": "group_name"
+                "actor_id": {
+                    "description": "ActorID is the id of the user that made the change, it is empty for the changes the apiserver makes on its own",
+                    "type": "string"
                 },
-                "inbound_rules": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SecurityRule"
+                "changes": {
+                    "description": "Changes holds the fields of the resource that were changed by the action",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.AuditChange"
                     }
                 },
-                "org_id": {
+                "created_at": {
                     "type": "string"
                 },
-                "outbound_rules": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.SecurityRule"
-                    }
+                "id": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "description": "OrganizationID is nil for the resources outside of the organizations, such as the api tokens and the\nfeature flags, their events are not listed by any endpoint",
+                    "type": "string"
+                },
+                "resource_id": {
+                    "type": "string"
+                },
+                "resource_type": {
+                    "type": "string",
+                    "example": "device"
+                },
+                "trace_id": {
+                    "type": "string"
                 }
             }
         },
@@ -1810,6 +4313,66 @@ const docTemplate = `{
                 }
             }
         },
+        "models.ConnectivityDevice": {
+            "type": "object",
+            "properties": {
+                "hostname": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "online": {
+                    "type": "boolean"
+                },
+                "relay": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ConnectivityMatrix": {
+            "type": "object",
+            "properties": {
+                "devices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ConnectivityDevice"
+                    }
+                },
+                "paths": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ConnectivityPath"
+                    }
+                }
+            }
+        },
+        "models.ConnectivityPath": {
+            "type": "object",
+            "properties": {
+                "destination": {
+                    "type": "string"
+                },
+                "latency_ms": {
+                    "type": "number",
+                    "example": 12.5
+                },
+                "method": {
+                    "type": "string",
+                    "example": "reflexive"
+                },
+                "source": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "reachable"
+                }
+            }
+        },
         "models.Device": {
             "type": "object",
             "properties": {
@@ -1825,6 +4388,10 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "device_token": {
+                    "description": "DeviceToken is the credential nexd authenticates with once the device is registered with a\nregistration key, it is only returned when the device is created, only its hash is stored",
+                    "type": "string"
+                },
                 "discovery": {
                     "type": "boolean"
                 },
@@ -1837,6 +4404,10 @@ const docTemplate = `{
                         "$ref": "#/definitions/models.Endpoint"
                     }
                 },
+                "ephemeral": {
+                    "description": "Ephemeral devices are deleted once they have not been updated or reported their status for\nthe ephemeral device TTL of the apiserver",
+                    "type": "boolean"
+                },
                 "hostname": {
                     "type": "string"
                 },
@@ -1844,6 +4415,20 @@ const docTemplate = `{
                     "type": "string",
                     "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
+                "labels": {
+                    "description": "Labels are free-form key/value pairs that can be used to select the devices",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "online": {
+                    "description": "Online and LastSeen are computed from the last status reported by the device, they\nare not included in the watch events",
+                    "type": "boolean"
+                },
                 "organization_id": {
                     "type": "string"
                 },
@@ -1856,9 +4441,24 @@ const docTemplate = `{
                 "os": {
                     "type": "string"
                 },
+                "pending": {
+                    "description": "Pending devices were registered in an organization that requires approval, they\nare not sent to the other devices until an admin approves them",
+                    "type": "boolean"
+                },
+                "previous_tunnel_ip": {
+                    "description": "PreviousTunnelIP and PreviousTunnelIpV6 are the addresses the device had before its\norganization was re-addressed, they stay valid until the end of the grace period",
+                    "type": "string"
+                },
+                "previous_tunnel_ip_v6": {
+                    "type": "string"
+                },
                 "public_key": {
                     "type": "string"
                 },
+                "reg_key_id": {
+                    "description": "RegKeyID is the registration key the device was registered with",
+                    "type": "string"
+                },
                 "relay": {
                     "type": "boolean"
                 },
@@ -1897,6 +4497,27 @@ const docTemplate = `{
                 }
             }
         },
+        "models.DeviceStatus": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "last_seen": {
+                    "type": "string"
+                },
+                "nexd_version": {
+                    "type": "string",
+                    "example": "0.0.1"
+                },
+                "peers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PeerStatus"
+                    }
+                }
+            }
+        },
         "models.Endpoint": {
             "type": "object",
             "properties": {
@@ -1916,6 +4537,50 @@ const docTemplate = `{
                 }
             }
         },
+        "models.FeatureFlag": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "Enables the security groups api"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "security-groups"
+                },
+                "overrides": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.FeatureFlagOverride"
+                    }
+                }
+            }
+        },
+        "models.FeatureFlagOverride": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "flag_name": {
+                    "type": "string",
+                    "example": "security-groups"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Invitation": {
             "type": "object",
             "properties": {
@@ -1969,9 +4634,25 @@ const docTemplate = `{
                 }
             }
         },
+        "models.NotAllowedError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
         "models.Organization": {
             "type": "object",
             "properties": {
+                "approval_required": {
+                    "description": "ApprovalRequired keeps the new devices pending until an admin approves them",
+                    "type": "boolean"
+                },
                 "cidr": {
                     "type": "string"
                 },
@@ -1988,23 +4669,165 @@ const docTemplate = `{
                     "type": "string",
                     "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
                 },
-                "invitations": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/models.Invitation"
-                    }
+                "invitations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.Invitation"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "string"
+                },
+                "previous_cidr": {
+                    "description": "PreviousIpCidr and PreviousIpCidrV6 are the prefixes the organization is being re-addressed\nfrom, they stay valid until the ReaddressDeadline",
+                    "type": "string"
+                },
+                "previous_cidr_v6": {
+                    "type": "string"
+                },
+                "readdress_deadline": {
+                    "type": "string"
+                },
+                "revision": {
+                    "type": "integer"
+                },
+                "security_group_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.PeerStatus": {
+            "type": "object",
+            "properties": {
+                "device_id": {
+                    "type": "string"
+                },
+                "endpoint": {
+                    "type": "string",
+                    "example": "10.1.1.1:51820"
+                },
+                "latency_ms": {
+                    "description": "LatencyMs is the round trip time to the peer, 0 when it is not reachable",
+                    "type": "number",
+                    "example": 12.5
+                },
+                "latest_handshake": {
+                    "type": "string"
+                },
+                "method": {
+                    "description": "Method is how the device reaches the peer: local, reflexive or relay",
+                    "type": "string",
+                    "example": "reflexive"
+                },
+                "reachable": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.ReaddressOrganization": {
+            "type": "object",
+            "properties": {
+                "cidr": {
+                    "description": "IpCidr is the new IPv4 prefix, the current one is kept when empty",
+                    "type": "string",
+                    "example": "172.16.43.0/24"
+                },
+                "cidr_v6": {
+                    "description": "IpCidrV6 is the new IPv6 prefix, the current one is kept when empty",
+                    "type": "string",
+                    "example": "0300::/64"
+                },
+                "grace_period": {
+                    "description": "GracePeriod is how long the previous addresses of the devices stay valid, defaults to 1h",
+                    "type": "string",
+                    "example": "30m"
+                }
+            }
+        },
+        "models.RegKey": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string",
+                    "example": "cloud-init"
+                },
+                "devices": {
+                    "description": "Devices is the number of devices registered with the key",
+                    "type": "integer"
+                },
+                "discovery": {
+                    "description": "Discovery is the discovery setting of the devices registered with the key",
+                    "type": "boolean"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt is when the key stops being accepted, the devices already registered keep working with their device token",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "max_devices": {
+                    "description": "MaxDevices is the maximum number of devices registered with the key at a time, unlimited when 0",
+                    "type": "integer"
                 },
-                "name": {
+                "organization_id": {
                     "type": "string"
                 },
                 "owner_id": {
+                    "description": "OwnerID is the user that created the key, the devices registered with the key belong to this user",
                     "type": "string"
                 },
+                "prefix": {
+                    "description": "Prefix is the start of the key, it identifies the key without revealing it",
+                    "type": "string",
+                    "example": "nexreg_3fa85f"
+                },
+                "relay": {
+                    "description": "Relay devices are the only ones registered with the key when set, nexd must be started as a relay accordingly",
+                    "type": "boolean"
+                },
                 "security_group_id": {
+                    "description": "SecurityGroupID is the security group of the devices registered with the key, the default security group of the organization when not set",
+                    "type": "string"
+                },
+                "single_use": {
+                    "description": "SingleUse keys register one device only, they are no longer accepted once the device is registered",
+                    "type": "boolean"
+                },
+                "token": {
+                    "description": "Token is only returned when the key is created, only its hash is stored",
                     "type": "string"
                 }
             }
         },
+        "models.ReportDeviceStatus": {
+            "type": "object",
+            "properties": {
+                "nexd_version": {
+                    "type": "string",
+                    "example": "0.0.1"
+                },
+                "peers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.PeerStatus"
+                    }
+                }
+            }
+        },
+        "models.RotateDeviceKey": {
+            "type": "object",
+            "properties": {
+                "public_key": {
+                    "type": "string",
+                    "example": "kjTkCfSmcLmE4lZ9dQvN6FzBo8SNDAtyWaKUoQhGfHg="
+                }
+            }
+        },
         "models.SecurityGroup": {
             "type": "object",
             "properties": {
@@ -2032,12 +4855,74 @@ const docTemplate = `{
                     "items": {
                         "$ref": "#/definitions/models.SecurityRule"
                     }
+                },
+                "revision": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.SecurityGroupSimulation": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "type": "boolean"
+                },
+                "inbound": {
+                    "description": "Inbound is the verdict of the inbound rules of the destination device, it is not set when the destination is not a device of the organization",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.SecurityGroupVerdict"
+                        }
+                    ]
+                },
+                "outbound": {
+                    "description": "Outbound is the verdict of the outbound rules of the source device",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.SecurityGroupVerdict"
+                        }
+                    ]
+                }
+            }
+        },
+        "models.SecurityGroupVerdict": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "type": "boolean"
+                },
+                "device_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "rule": {
+                    "$ref": "#/definitions/models.SecurityRule"
+                },
+                "rule_index": {
+                    "description": "RuleIndex is the index of the matched rule, -1 when no rule matched",
+                    "type": "integer"
+                },
+                "security_group_id": {
+                    "type": "string"
                 }
             }
         },
         "models.SecurityRule": {
             "type": "object",
             "properties": {
+                "all_devices": {
+                    "description": "AllDevices matches the tunnel addresses of all the devices in the organization",
+                    "type": "boolean"
+                },
+                "device_ids": {
+                    "description": "DeviceIds matches the tunnel addresses of the listed devices",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "from_port": {
                     "type": "integer"
                 },
@@ -2050,11 +4935,74 @@ const docTemplate = `{
                         "type": "string"
                     }
                 },
+                "security_group_ids": {
+                    "description": "SecurityGroupIds matches the tunnel addresses of the devices in the listed security groups",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
                 "to_port": {
                     "type": "integer"
                 }
             }
         },
+        "models.SetFeatureFlagOverride": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "organization_id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                }
+            }
+        },
+        "models.SimulateSecurityGroup": {
+            "type": "object",
+            "properties": {
+                "destination_device_id": {
+                    "description": "DestinationDeviceId or DestinationIp is required, the destination device is looked up by its tunnel address when only DestinationIp is set",
+                    "type": "string",
+                    "example": "cb2e0192-5eb9-41ee-a732-7484602ac883"
+                },
+                "destination_ip": {
+                    "type": "string",
+                    "example": "100.100.0.2"
+                },
+                "ip_protocol": {
+                    "type": "string",
+                    "example": "tcp"
+                },
+                "port": {
+                    "type": "integer",
+                    "example": 22
+                },
+                "source_device_id": {
+                    "type": "string",
+                    "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
+                }
+            }
+        },
+        "models.TransferOrganization": {
+            "type": "object",
+            "properties": {
+                "new_owner_id": {
+                    "description": "NewOwnerId is the user that becomes the owner, they must already be a member of the organization",
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "remove_previous_owner": {
+                    "description": "RemovePreviousOwner removes the previous owner from the organization, otherwise they stay as a member",
+                    "type": "boolean"
+                }
+            }
+        },
         "models.UpdateDevice": {
             "type": "object",
             "properties": {
@@ -2081,6 +5029,16 @@ const docTemplate = `{
                     "type": "string",
                     "example": "myhost"
                 },
+                "labels": {
+                    "description": "Labels replace the labels of the device when they are set",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    },
+                    "example": {
+                        "env": "prod"
+                    }
+                },
                 "organization_id": {
                     "type": "string",
                     "example": "694aa002-5d19-495e-980b-3d8fd508ea10"
@@ -2088,11 +5046,53 @@ const docTemplate = `{
                 "revision": {
                     "type": "integer"
                 },
+                "security_group_id": {
+                    "type": "string",
+                    "example": "cb2e0192-5eb9-41ee-a732-7484602ac883"
+                },
                 "symmetric_nat": {
                     "type": "boolean"
                 }
             }
         },
+        "models.UpdateFeatureFlag": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "models.UpdateOrganization": {
+            "type": "object",
+            "properties": {
+                "approval_required": {
+                    "description": "ApprovalRequired keeps the new devices pending until an admin approves them",
+                    "type": "boolean",
+                    "x-nullable": true
+                },
+                "description": {
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "The Red Zone"
+                },
+                "hub_zone": {
+                    "type": "boolean",
+                    "x-nullable": true
+                },
+                "name": {
+                    "type": "string",
+                    "example": "zone-red"
+                },
+                "revision": {
+                    "description": "Revision is the revision of the organization the update is based on, the update\nfails when the organization has been modified since then",
+                    "type": "integer"
+                }
+            }
+        },
         "models.UpdateSecurityGroup": {
             "type": "object",
             "properties": {
@@ -2116,6 +5116,40 @@ const docTemplate = `{
                 }
             }
         },
+        "models.UpdateUserOrganization": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "example": "read-only"
+                }
+            }
+        },
+        "models.UpdateWebhook": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "device.create",
+                        "device.delete"
+                    ]
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/nexodus"
+                }
+            }
+        },
         "models.User": {
             "type": "object",
             "properties": {
@@ -2160,6 +5194,120 @@ const docTemplate = `{
                     "type": "integer"
                 }
             }
+        },
+        "models.UserOrganization": {
+            "type": "object",
+            "properties": {
+                "organization_id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "role": {
+                    "type": "string",
+                    "example": "member"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                }
+            }
+        },
+        "models.ValidationError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "something bad"
+                },
+                "field": {
+                    "type": "string"
+                }
+            }
+        },
+        "models.Webhook": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "event_types": {
+                    "description": "EventTypes are the types of the events delivered to the webhook, every event is delivered when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "device.create",
+                        "device.delete",
+                        "security_group.*"
+                    ]
+                },
+                "id": {
+                    "type": "string",
+                    "example": "aa22666c-0f57-45cb-a449-16efecc04f2e"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret is the key of the HMAC-SHA256 signature of the payloads, it is only returned when the webhook is created",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/nexodus"
+                }
+            }
+        },
+        "models.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "event_type": {
+                    "type": "string",
+                    "example": "device.create"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_attempt": {
+                    "type": "string"
+                },
+                "next_attempt": {
+                    "description": "NextAttempt is when a pending delivery is attempted again",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "Payload is the body sent to the webhook",
+                    "type": "string"
+                },
+                "response_code": {
+                    "type": "integer",
+                    "example": 200
+                },
+                "status": {
+                    "description": "Status is pending until the delivery succeeds or runs out of attempts",
+                    "type": "string",
+                    "example": "succeeded"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -1,38 +1,64 @@
 package fflags
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-// This implementation a hack to be replaced later with a proper feature
-// flags backend. We will also need to pass in additional api user info
-// into this API so that the answer can differ depending on who is asking.
-// This is needed to allow admin-only access, or only partial rollouts of
-// features, for example.
+// ErrFlagNotFound is returned when a feature flag is not defined
+var ErrFlagNotFound = errors.New("feature flag not found")
+
+// FFlags evaluates the feature flags stored in the database. Every flag
+// has a global default which can be overridden for single organizations
+// or users, allowing partial rollouts of features.
 type FFlags struct {
 	logger *zap.SugaredLogger
+	db     *gorm.DB
 }
 
 type FFlag struct {
 	env          string
 	defaultValue bool
+	description  string
+}
+
+// builtInFlags are the flags checked by the apiserver, they are seeded
+// into the database at startup and can not be deleted.
+var builtInFlags = map[string]FFlag{
+	"multi-organization": {"NEXAPI_FFLAG_MULTI_ORGANIZATION", true, "Allows users to create and be invited to additional organizations"},
+	"security-groups":    {"NEXAPI_FFLAG_SECURITY_GROUPS", false, "Enables the security groups api"},
 }
 
-var hardCodedFlags = map[string]FFlag{
-	"multi-organization": {"NEXAPI_FFLAG_MULTI_ORGANIZATION", true},
-	"security-groups":    {"NEXAPI_FFLAG_SECURITY_GROUPS", false},
+// Subject identifies who a feature flag is evaluated for.
+type Subject struct {
+	UserId string
+	// OrganizationIds are the organizations the request applies to. When empty,
+	// the organizations the user is a member of are used instead.
+	OrganizationIds []uuid.UUID
 }
 
-func NewFFlags(logger *zap.SugaredLogger) *FFlags {
+func NewFFlags(logger *zap.SugaredLogger, db *gorm.DB) *FFlags {
 	return &FFlags{
 		logger: logger,
+		db:     db,
 	}
 }
 
+// IsBuiltIn returns whether the flag is one of the flags checked by the apiserver.
+func IsBuiltIn(name string) bool {
+	_, ok := builtInFlags[name]
+	return ok
+}
+
 func (f *FFlags) getFlagValue(fflag FFlag) bool {
 	if envValue, err := strconv.ParseBool(os.Getenv(fflag.env)); err == nil {
 		return envValue
@@ -40,24 +66,103 @@ func (f *FFlags) getFlagValue(fflag FFlag) bool {
 	return fflag.defaultValue
 }
 
+// Seed stores the built-in flags missing from the database. The initial global
+// default of a flag is read from its NEXAPI_FFLAG_* environment variable, once
+// stored the global default is only changed through the api.
+func (f *FFlags) Seed(ctx context.Context) error {
+	for name, fflag := range builtInFlags {
+		flag := models.FeatureFlag{
+			Name:        name,
+			Description: fflag.description,
+			Enabled:     f.getFlagValue(fflag),
+		}
+		if err := f.db.WithContext(ctx).
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&flag).Error; err != nil {
+			return fmt.Errorf("failed to seed feature flag %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
 // ListFlags returns a map of all currently defined feature flags and
-// whether those features are enabled (true) or not (false).
-func (f *FFlags) ListFlags() map[string]bool {
+// whether those features are enabled (true) or not (false) for the subject.
+func (f *FFlags) ListFlags(ctx context.Context, subject Subject) (map[string]bool, error) {
+	var flags []models.FeatureFlag
+	if err := f.db.WithContext(ctx).Find(&flags).Error; err != nil {
+		return nil, err
+	}
+	overrides, err := f.subjectOverrides(ctx, subject, "")
+	if err != nil {
+		return nil, err
+	}
 	result := map[string]bool{}
-	for name, fflag := range hardCodedFlags {
-		result[name] = f.getFlagValue(fflag)
+	for _, flag := range flags {
+		result[flag.Name] = evaluate(flag, overrides)
 	}
-	return result
+	return result, nil
 }
 
 // GetFlag returns whether the feature named by the string parameter
-// flag is enabled (true) or not (false). An error is returned if
-// the flag name is invalid.
-func (f *FFlags) GetFlag(flag string) (bool, error) {
-	fflag, ok := hardCodedFlags[flag]
-	if !ok {
-		f.logger.Errorf("Invalid feature flag name: %s", flag)
-		return false, fmt.Errorf("Invalid feature flag name: %s", flag)
-	}
-	return f.getFlagValue(fflag), nil
+// flag is enabled (true) or not (false) for the subject. ErrFlagNotFound
+// is returned if the flag name is invalid.
+func (f *FFlags) GetFlag(ctx context.Context, subject Subject, flag string) (bool, error) {
+	var fflag models.FeatureFlag
+	if err := f.db.WithContext(ctx).First(&fflag, "name = ?", flag).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			f.logger.Errorf("Invalid feature flag name: %s", flag)
+			return false, ErrFlagNotFound
+		}
+		return false, err
+	}
+	overrides, err := f.subjectOverrides(ctx, subject, flag)
+	if err != nil {
+		return false, err
+	}
+	return evaluate(fflag, overrides), nil
+}
+
+// subjectOverrides loads the overrides of the subject user and organizations,
+// optionally restricted to a single flag.
+func (f *FFlags) subjectOverrides(ctx context.Context, subject Subject, flag string) ([]models.FeatureFlagOverride, error) {
+	db := f.db.WithContext(ctx)
+	if flag != "" {
+		db = db.Where("flag_name = ?", flag)
+	}
+	switch {
+	case len(subject.OrganizationIds) > 0:
+		db = db.Where("(user_id = ? AND user_id <> '') OR organization_id IN ?", subject.UserId, subject.OrganizationIds)
+	case subject.UserId != "":
+		db = db.Where("user_id = ? OR organization_id IN (SELECT organization_id FROM user_organizations WHERE user_id = ?)", subject.UserId, subject.UserId)
+	default:
+		return nil, nil
+	}
+	var overrides []models.FeatureFlagOverride
+	if err := db.Find(&overrides).Error; err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// evaluate resolves a flag for a subject: a user override wins over the
+// organization overrides, which win over the global default. When the
+// organizations disagree, the flag is enabled if any of them enables it.
+func evaluate(flag models.FeatureFlag, overrides []models.FeatureFlagOverride) bool {
+	var orgEnabled *bool
+	for _, override := range overrides {
+		if override.FlagName != flag.Name {
+			continue
+		}
+		if override.UserId != "" {
+			return override.Enabled
+		}
+		if orgEnabled == nil || override.Enabled {
+			enabled := override.Enabled
+			orgEnabled = &enabled
+		}
+	}
+	if orgEnabled != nil {
+		return *orgEnabled
+	}
+	return flag.Enabled
 }
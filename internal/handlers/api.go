@@ -2,6 +2,10 @@ package handlers
 
 import (
 	"context"
+	"net/http"
+	"sync"
+	"time"
+
 	"github.com/nexodus-io/nexodus/internal/signalbus"
 
 	"github.com/nexodus-io/nexodus/internal/database"
@@ -34,6 +38,10 @@ type API struct {
 	dialect       database.Dialect
 	store         storage.Store
 	signalBus     signalbus.SignalBus
+	// ephemeralDeviceTTL is how long an ephemeral device is kept after its last update or status report
+	ephemeralDeviceTTL time.Duration
+	// webhookClient sends the webhook deliveries
+	webhookClient *http.Client
 }
 
 func NewAPI(parent context.Context, logger *zap.SugaredLogger, db *gorm.DB, ipam ipam.IPAM, fflags *fflags.FFlags, store storage.Store, signalBus signalbus.SignalBus) (*API, error) {
@@ -55,15 +63,68 @@ func NewAPI(parent context.Context, logger *zap.SugaredLogger, db *gorm.DB, ipam
 		dialect:       dialect,
 		store:         store,
 		signalBus:     signalBus,
+
+		ephemeralDeviceTTL: DefaultEphemeralDeviceTTL,
+		webhookClient:      newWebhookClient(isPublicAddr),
 	}
 
 	if err := api.populateStore(ctx); err != nil {
 		return nil, err
 	}
 
+	if err := api.fflags.Seed(ctx); err != nil {
+		return nil, err
+	}
+
 	return api, nil
 }
 
 func (api *API) Logger(ctx context.Context) *zap.SugaredLogger {
 	return util.WithTrace(ctx, api.logger)
 }
+
+// Start runs the background tasks of the API until the context is canceled
+func (api *API) Start(ctx context.Context, wg *sync.WaitGroup) {
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(readdressReapInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := api.completeReaddressing(ctx, now); err != nil {
+					api.logger.Errorw("failed to complete the re-addressing of the organizations", "error", err)
+				}
+			}
+		}
+	})
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(webhookDeliveryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := api.deliverWebhooks(ctx, now); err != nil {
+					api.logger.Errorw("failed to deliver the webhooks", "error", err)
+				}
+			}
+		}
+	})
+	util.GoWithWaitGroup(wg, func() {
+		ticker := time.NewTicker(ephemeralDeviceReapInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := api.reapEphemeralDevices(ctx, now); err != nil {
+					api.logger.Errorw("failed to delete the expired ephemeral devices", "error", err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// ApiTokenPrefix starts every API token, it tells them apart from the access tokens of the identity provider
+const ApiTokenPrefix = "nexapi_"
+
+// apiTokenLastUsedPrecision limits how often the last use of a token is written
+const apiTokenLastUsedPrecision = time.Minute
+
+// apiTokenScopes are the scopes that can be granted to an API token
+var apiTokenScopes = []string{
+	"read:organizations", "write:organizations",
+	"read:devices", "write:devices",
+	"read:users", "write:users",
+}
+
+// ErrInvalidApiToken is returned when an API token is unknown, revoked or expired
+var ErrInvalidApiToken = errors.New("invalid api token")
+
+func hashApiToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSecretToken returns a random token that starts with prefix
+func newSecretToken(prefix string) (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(key), nil
+}
+
+func validateApiTokenScopes(c *gin.Context, scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	// a token can not be granted more than the credentials used to create it
+	var granted []string
+	if scope, ok := c.Get(AuthUserScope); ok {
+		granted = strings.Fields(scope.(string))
+	}
+	for _, scope := range scopes {
+		if !containsString(apiTokenScopes, scope) {
+			return fmt.Errorf("%q is not one of %s", scope, strings.Join(apiTokenScopes, ", "))
+		}
+		if granted != nil && !containsString(granted, scope) {
+			return fmt.Errorf("%q is not granted to the current credentials", scope)
+		}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateApiToken returns the API token and its user, it fails with ErrInvalidApiToken when the
+// token is unknown, revoked, expired or when its user was deleted.
+func (api *API) ValidateApiToken(ctx context.Context, token string) (models.ApiToken, models.User, error) {
+	ctx, span := tracer.Start(ctx, "ValidateApiToken")
+	defer span.End()
+
+	var apiToken models.ApiToken
+	var user models.User
+	db := api.db.WithContext(ctx)
+	if res := db.First(&apiToken, "token_hash = ?", hashApiToken(token)); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return apiToken, user, ErrInvalidApiToken
+		}
+		return apiToken, user, res.Error
+	}
+	now := time.Now()
+	if apiToken.ExpiresAt != nil && !now.Before(*apiToken.ExpiresAt) {
+		return apiToken, user, ErrInvalidApiToken
+	}
+	if res := db.First(&user, "id = ?", apiToken.UserID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return apiToken, user, ErrInvalidApiToken
+		}
+		return apiToken, user, res.Error
+	}
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) >= apiTokenLastUsedPrecision {
+		if res := db.Model(&apiToken).UpdateColumn("last_used_at", now); res.Error != nil {
+			return apiToken, user, res.Error
+		}
+	}
+	span.SetAttributes(attribute.String("id", apiToken.ID.String()))
+	return apiToken, user, nil
+}
+
+// CreateApiToken creates an API token for the current user
+// @Summary      Create API Token
+// @Description  Creates a personal access token that makes API calls as the current user, limited to its scopes. The token is only returned by this call.
+// @Id           CreateApiToken
+// @Tags         ApiTokens
+// @Accept       json
+// @Produce      json
+// @Param        token  body   models.AddApiToken  true "Add API Token"
+// @Success      201  {object}  models.ApiToken
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/tokens [post]
+func (api *API) CreateApiToken(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateApiToken")
+	defer span.End()
+
+	var request models.AddApiToken
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if err := validateApiTokenScopes(c, request.Scopes); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("scopes", err.Error()))
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("expires_at", "must be in the future"))
+		return
+	}
+
+	token, err := newSecretToken(ApiTokenPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	apiToken := models.ApiToken{
+		UserID:      c.GetString(gin.AuthUserKey),
+		Description: request.Description,
+		Scopes:      request.Scopes,
+		Prefix:      token[:len(ApiTokenPrefix)+6],
+		TokenHash:   hashApiToken(token),
+		ExpiresAt:   request.ExpiresAt,
+	}
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Create(&apiToken); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, uuid.Nil, "api_token", apiToken.ID.String(), auditActionCreate, nil, apiToken)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	apiToken.Token = token
+	c.JSON(http.StatusCreated, apiToken)
+}
+
+// ListApiTokens lists the API tokens of the current user
+// @Summary      List API Tokens
+// @Description  Lists the personal access tokens of the current user
+// @Id           ListApiTokens
+// @Tags         ApiTokens
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  []models.ApiToken
+// @Failure		 401  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/tokens [get]
+func (api *API) ListApiTokens(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListApiTokens")
+	defer span.End()
+
+	apiTokens := []models.ApiToken{}
+	if res := api.db.WithContext(ctx).
+		Scopes(FilterAndPaginate(&models.ApiToken{}, c, "created_at")).
+		Where("user_id = ?", c.GetString(gin.AuthUserKey)).
+		Find(&apiTokens); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, apiTokens)
+}
+
+// DeleteApiToken revokes an API token of the current user
+// @Summary      Delete API Token
+// @Description  Revokes a personal access token of the current user, the API calls made with it are rejected right away
+// @Id           DeleteApiToken
+// @Tags         ApiTokens
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "API Token ID"
+// @Success      200  {object}  models.ApiToken
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/tokens/{id} [delete]
+func (api *API) DeleteApiToken(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteApiToken", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var apiToken models.ApiToken
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.First(&apiToken, "id = ? AND user_id = ?", id, c.GetString(gin.AuthUserKey)); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errApiTokenNotFound
+			}
+			return res.Error
+		}
+		if res := tx.Delete(&apiToken); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, uuid.Nil, "api_token", apiToken.ID.String(), auditActionDelete, apiToken, nil)
+	})
+	if err != nil {
+		if errors.Is(err, errApiTokenNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("api token"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, apiToken)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestApiTokens() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	createToken := func(add models.AddApiToken, scope string) (models.ApiToken, int) {
+		reqBody, err := json.Marshal(add)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/tokens", "/tokens", func(c *gin.Context) {
+			if scope != "" {
+				c.Set(AuthUserScope, scope)
+			}
+			suite.api.CreateApiToken(c)
+		}, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		var token models.ApiToken
+		if res.Code == http.StatusCreated {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &token))
+		}
+		return token, res.Code
+	}
+
+	token, code := createToken(models.AddApiToken{Description: "ci", Scopes: []string{"read:devices", "write:devices"}}, "")
+	require.Equal(http.StatusCreated, code)
+	assert.True(strings.HasPrefix(token.Token, ApiTokenPrefix))
+	assert.True(strings.HasPrefix(token.Token, token.Prefix))
+	assert.Equal(TestUserID, token.UserID)
+
+	apiToken, user, err := suite.api.ValidateApiToken(context.Background(), token.Token)
+	require.NoError(err)
+	assert.Equal(token.ID, apiToken.ID)
+	assert.Equal(TestUserID, user.ID)
+	assert.Equal([]string{"read:devices", "write:devices"}, []string(apiToken.Scopes))
+	assert.NotNil(apiToken.LastUsedAt)
+
+	_, _, err = suite.api.ValidateApiToken(context.Background(), ApiTokenPrefix+"unknown")
+	assert.ErrorIs(err, ErrInvalidApiToken)
+
+	// unknown scopes and scopes that the current credentials do not have are rejected
+	_, code = createToken(models.AddApiToken{Scopes: []string{"admin"}}, "")
+	assert.Equal(http.StatusBadRequest, code)
+	_, code = createToken(models.AddApiToken{Scopes: []string{"write:devices"}}, "read:devices write:users")
+	assert.Equal(http.StatusBadRequest, code)
+	_, code = createToken(models.AddApiToken{}, "")
+	assert.Equal(http.StatusBadRequest, code)
+
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, code := createToken(models.AddApiToken{Scopes: []string{"read:users"}, ExpiresAt: &expiresAt}, "read:users write:users")
+	require.Equal(http.StatusCreated, code)
+	_, _, err = suite.api.ValidateApiToken(context.Background(), expiring.Token)
+	require.NoError(err)
+	require.NoError(suite.api.db.Model(&models.ApiToken{}).Where("id = ?", expiring.ID).Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, _, err = suite.api.ValidateApiToken(context.Background(), expiring.Token)
+	assert.ErrorIs(err, ErrInvalidApiToken)
+
+	_, res, err := suite.ServeRequest(http.MethodGet, "/tokens", "/tokens", suite.api.ListApiTokens, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var tokens []models.ApiToken
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &tokens))
+	require.Len(tokens, 2)
+	for _, t := range tokens {
+		assert.Empty(t.Token)
+	}
+
+	// the tokens of other users can not be revoked
+	_, res, err = suite.ServeRequest(http.MethodDelete, "/tokens/:id", fmt.Sprintf("/tokens/%s", token.ID), func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, TestUser2ID)
+		suite.api.DeleteApiToken(c)
+	}, nil)
+	require.NoError(err)
+	assert.Equal(http.StatusNotFound, res.Code)
+
+	_, res, err = suite.ServeRequest(http.MethodDelete, "/tokens/:id", fmt.Sprintf("/tokens/%s", token.ID), suite.api.DeleteApiToken, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	_, _, err = suite.api.ValidateApiToken(context.Background(), token.Token)
+	assert.ErrorIs(err, ErrInvalidApiToken)
+}
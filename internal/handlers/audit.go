@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	auditActionCreate    = "create"
+	auditActionUpdate    = "update"
+	auditActionDelete    = "delete"
+	auditActionAccept    = "accept"
+	auditActionTransfer  = "transfer"
+	auditActionReaddress = "readdress"
+	auditActionApprove   = "approve"
+	auditActionRotateKey = "rotate_key"
+)
+
+const (
+	// defaultListLimit and maxListLimit bound the number of items of the lists paginated with a limit
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// recordAuditEvent stores the change of a resource made by the current user, before is nil when
+// the resource is created and after is nil when it is deleted. Record the event in the transaction
+// of the change so that both are stored or neither is. Updates that change nothing are not recorded.
+// c is nil for the changes the apiserver makes on its own, they are recorded without an actor.
+func (api *API) recordAuditEvent(ctx context.Context, c *gin.Context, db *gorm.DB, orgId uuid.UUID, resourceType string, resourceId string, action string, before, after interface{}) error {
+	changes, err := auditChanges(before, after)
+	if err != nil {
+		return err
+	}
+	if action == auditActionUpdate && len(changes) == 0 {
+		return nil
+	}
+	actorId := ""
+	if c != nil {
+		actorId = c.GetString(gin.AuthUserKey)
+	}
+	event := models.AuditEvent{
+		ActorID:        actorId,
+		OrganizationID: orgId,
+		ResourceType:   resourceType,
+		ResourceID:     resourceId,
+		Action:         action,
+		Changes:        changes,
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		event.TraceID = sc.TraceID().String()
+	}
+	if res := db.WithContext(ctx).Create(&event); res.Error != nil {
+		return res.Error
+	}
+	return api.queueWebhookDeliveries(ctx, db, event)
+}
+
+// auditChanges returns the json fields that differ between two versions of a resource, the
+// revision is left out as it changes on every update
+func auditChanges(before, after interface{}) (map[string]models.AuditChange, error) {
+	b, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+	delete(b, "revision")
+	delete(a, "revision")
+	changes := map[string]models.AuditChange{}
+	for k, v := range b {
+		if w, ok := a[k]; !ok || !reflect.DeepEqual(v, w) {
+			changes[k] = models.AuditChange{Before: v, After: a[k]}
+		}
+	}
+	for k, w := range a {
+		if _, ok := b[k]; !ok {
+			changes[k] = models.AuditChange{After: w}
+		}
+	}
+	return changes, nil
+}
+
+func auditFields(v interface{}) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Pointer && reflect.ValueOf(v).IsNil()) {
+		return fields, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// ListOrganizationEvents lists the audit events of an organization
+// @Summary      List Organization Events
+// @Description  Lists the changes made to the organization and its resources, newest first. Only the organization admins can list the events.
+// @Id           ListOrganizationEvents
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization_id path   string true "Organization ID"
+// @Param		 resource_type   query  string false "only the events of this resource type"
+// @Param		 resource_id     query  string false "only the events of this resource"
+// @Param		 action          query  string false "only the events of this action"
+// @Param		 actor_id        query  string false "only the events of this user"
+// @Param		 since           query  string false "only the events after this RFC3339 time"
+// @Param		 limit           query  int    false "maximum number of events, 100 by default"
+// @Param		 offset          query  int    false "number of events to skip"
+// @Success      200  {object}  []models.AuditEvent
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/events [get]
+func (api *API) ListOrganizationEvents(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizationEvents",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var query Query
+	if err := c.BindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewApiInternalError(err))
+		return
+	}
+	limit := defaultListLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("limit", "must be a number between 1 and 1000"))
+			return
+		}
+	}
+	offset := 0
+	if v := c.Query("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("offset", "must be a number that is not negative"))
+			return
+		}
+	}
+	var since time.Time
+	if v := c.Query("since"); v != "" {
+		since, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("since", "must be a RFC3339 time"))
+			return
+		}
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		err := res.Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			err = api.organizationRoleError(api.db.WithContext(ctx), c, orgId)
+		}
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError("only the organization admins can list the events"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	db := api.db.WithContext(ctx).Where("organization_id = ?", org.ID)
+	for _, param := range []string{"resource_type", "resource_id", "action", "actor_id"} {
+		if v := c.Query(param); v != "" {
+			db = db.Where(param+" = ?", v)
+		}
+	}
+	if !since.IsZero() {
+		db = db.Where("created_at > ?", since)
+	}
+	db = db.Scopes(FilterAndPaginateWithQuery(&models.AuditEvent{}, c, query, "created_at DESC"))
+	if _, _, err := query.GetRange(); err != nil {
+		// no react-admin range, paginate with the limit and offset
+		var totalCount int64
+		if res := db.Session(&gorm.Session{Initialized: true}).Model(&models.AuditEvent{}).Count(&totalCount); res.Error != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+			return
+		}
+		c.Header("Access-Control-Expose-Headers", TotalCountHeader)
+		c.Header(TotalCountHeader, strconv.Itoa(int(totalCount)))
+		db = db.Offset(offset).Limit(limit)
+	}
+
+	events := []models.AuditEvent{}
+	if res := db.Find(&events); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
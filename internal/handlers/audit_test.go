@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestListOrganizationEvents() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	reqBody, err := json.Marshal(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "auditpubkey",
+		Hostname:       "before",
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+
+	reqBody, err = json.Marshal(models.UpdateDevice{Hostname: "after"})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.UpdateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	// an update that changes nothing is not recorded
+	_, res, err = suite.ServeRequest(http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.UpdateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	listEvents := func(userId, query string) []models.AuditEvent {
+		_, res, err := suite.ServeRequest(http.MethodGet,
+			"/organizations/:organization/events", fmt.Sprintf("/organizations/%s/events%s", suite.testOrganizationID, query),
+			func(c *gin.Context) {
+				c.Set(gin.AuthUserKey, userId)
+				suite.api.ListOrganizationEvents(c)
+			}, nil)
+		require.NoError(err)
+		if res.Code != http.StatusOK {
+			return nil
+		}
+		var events []models.AuditEvent
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &events))
+		assert.Equal(fmt.Sprint(len(events)), res.Header().Get(TotalCountHeader))
+		return events
+	}
+
+	events := listEvents(TestUserID, "?resource_type=device")
+	require.Len(events, 2)
+	byAction := map[string]models.AuditEvent{}
+	for _, event := range events {
+		assert.Equal(TestUserID, event.ActorID)
+		assert.Equal(suite.testOrganizationID, event.OrganizationID)
+		assert.Equal(device.ID.String(), event.ResourceID)
+		byAction[event.Action] = event
+	}
+	assert.Equal("auditpubkey", byAction[auditActionCreate].Changes["public_key"].After)
+	assert.Nil(byAction[auditActionCreate].Changes["public_key"].Before)
+	assert.Equal(map[string]models.AuditChange{
+		"hostname": {Before: "before", After: "after"},
+	}, byAction[auditActionUpdate].Changes)
+
+	events = listEvents(TestUserID, "?action=update")
+	require.Len(events, 1)
+	assert.Equal(auditActionUpdate, events[0].Action)
+
+	// only the admins of the organization can list its events
+	assert.Nil(listEvents(TestUser2ID, ""))
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// GetOrganizationConnectivity combines the status reports of the devices of an organization
+// @Summary      Get Organization Connectivity
+// @Description  Combines the status reported by every device of the organization into the connectivity between every pair of devices
+// @Id           GetOrganizationConnectivity
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param		 organization_id path   string true "Organization ID"
+// @Success      200  {object}  models.ConnectivityMatrix
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/connectivity [get]
+func (api *API) GetOrganizationConnectivity(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetOrganizationConnectivity",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		}
+		return
+	}
+
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).
+		Select("id", "hostname", "relay").
+		Where("organization_id = ?", org.ID).
+		Order("hostname").
+		Find(&devices); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	ids := make([]uuid.UUID, 0, len(devices))
+	for _, device := range devices {
+		ids = append(ids, device.ID)
+	}
+	var statuses []models.DeviceStatus
+	if len(ids) > 0 {
+		if res := api.db.WithContext(ctx).
+			Where("device_id IN ?", ids).
+			Find(&statuses); res.Error != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, connectivityMatrix(devices, statuses, time.Now()))
+}
+
+// connectivityMatrix builds the paths between every pair of devices from the peers reported
+// by the source devices, the reports of the offline devices are ignored
+func connectivityMatrix(devices []models.Device, statuses []models.DeviceStatus, now time.Time) models.ConnectivityMatrix {
+	reports := make(map[uuid.UUID]map[uuid.UUID]models.PeerStatus, len(statuses))
+	lastSeen := make(map[uuid.UUID]time.Time, len(statuses))
+	for _, status := range statuses {
+		lastSeen[status.DeviceID] = status.LastSeen
+		if now.Sub(status.LastSeen) >= deviceOnlineTimeout {
+			continue
+		}
+		peers := make(map[uuid.UUID]models.PeerStatus, len(status.Peers))
+		for _, peer := range status.Peers {
+			peers[peer.DeviceID] = peer
+		}
+		reports[status.DeviceID] = peers
+	}
+
+	matrix := models.ConnectivityMatrix{
+		Devices: make([]models.ConnectivityDevice, 0, len(devices)),
+		Paths:   make([]models.ConnectivityPath, 0, len(devices)*len(devices)),
+	}
+	for _, device := range devices {
+		d := models.ConnectivityDevice{
+			ID:       device.ID,
+			Hostname: device.Hostname,
+			Relay:    device.Relay,
+		}
+		if t, ok := lastSeen[device.ID]; ok {
+			d.LastSeen = &t
+			d.Online = now.Sub(t) < deviceOnlineTimeout
+		}
+		matrix.Devices = append(matrix.Devices, d)
+	}
+
+	for _, src := range devices {
+		for _, dst := range devices {
+			if src.ID == dst.ID {
+				continue
+			}
+			path := models.ConnectivityPath{
+				Source:      src.ID,
+				Destination: dst.ID,
+				Status:      models.ConnectivityUnknown,
+			}
+			if peer, ok := reports[src.ID][dst.ID]; ok {
+				path.Method = peer.Method
+				path.LatencyMs = peer.LatencyMs
+				if peer.Reachable {
+					path.Status = models.ConnectivityReachable
+				} else {
+					path.Status = models.ConnectivityUnreachable
+				}
+			}
+			matrix.Paths = append(matrix.Paths, path)
+		}
+	}
+	return matrix
+}
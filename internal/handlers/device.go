@@ -1,16 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/database"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/nexodus-io/nexodus/internal/util"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -22,6 +25,19 @@ var (
 	errDeviceNotFound        = errors.New("device not found")
 	errInvitationNotFound    = errors.New("invitation not found")
 	errSecurityGroupNotFound = errors.New("security group not found")
+	errFlagNotFound          = errors.New("feature flag not found")
+	errFlagExists            = errors.New("feature flag already exists")
+	errOrgRoleNotAllowed     = errors.New("the role of the user in the organization does not allow this operation")
+	errOrgOwnerNotAllowed    = errors.New("the owner of the organization is always one of its admins")
+	errOrgOwnerMustTransfer  = errors.New("the ownership of the organization must be transferred first")
+	errOrgAlreadyOwner       = errors.New("is already the owner of the organization")
+	errRevisionConflict      = errors.New("the resource has been modified since the revision of the update")
+	errFlagOverrideNotFound  = errors.New("feature flag override not found")
+	errWebhookNotFound       = errors.New("webhook not found")
+	errApiTokenNotFound      = errors.New("api token not found")
+	errRegKeyNotFound        = errors.New("registration key not found")
+	errRegKeyNotAllowed      = errors.New("the registration key does not allow this registration")
+	errDeviceNotOwned        = errors.New("only the owner of the device can change it, the organization admins can change its security group")
 )
 
 type errDuplicateDevice struct {
@@ -39,17 +55,26 @@ func (e errDuplicateDevice) Error() string {
 // @Tags         Devices
 // @Accept       json
 // @Produce      json
+// @Param		 labelSelector   query  string false "label selector, for example env=prod,role!=db"
 // @Success      200  {object}  []models.Device
+// @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Router       /api/devices [get]
 func (api *API) ListDevices(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListDevices")
 	defer span.End()
-	devices := make([]models.Device, 0)
+	devices := make([]*models.Device, 0)
+
+	selector, err := labelSelectorFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("labelSelector", err.Error()))
+		return
+	}
 
 	result := api.db.WithContext(ctx).Scopes(
 		api.DeviceIsOwnedByCurrentUser(c),
+		api.DeviceMatchesLabelSelector(selector),
 		FilterAndPaginate(&models.Device{}, c, "hostname"),
 	).Find(&devices)
 
@@ -57,6 +82,10 @@ func (api *API) ListDevices(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "error fetching keys from db"})
 		return
 	}
+	if err := api.populateDeviceStatus(ctx, devices...); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
 	c.JSON(http.StatusOK, devices)
 }
 
@@ -75,6 +104,36 @@ func (api *API) DeviceIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.DB) *go
 	}
 }
 
+// DeviceIsReadableByCurrentUser matches the devices of the current user and the devices of
+// the organizations the current user belongs to
+func (api *API) DeviceIsReadableByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		userId := c.Value(gin.AuthUserKey).(string)
+		if api.dialect == database.DialectSqlLite {
+			return db.Where("user_id = ? OR organization_id in (SELECT id FROM organizations where owner_id=?) OR organization_id in (SELECT organization_id FROM user_organizations where user_id=?)",
+				userId, userId, userId)
+		} else {
+			return db.Where("user_id = ? OR organization_id::text in (SELECT id::text FROM organizations where owner_id=?) OR organization_id::text in (SELECT organization_id::text FROM user_organizations where user_id=?)",
+				userId, userId, userId)
+		}
+	}
+}
+
+// DeviceIsAdministeredByCurrentUser matches the devices of the current user and the devices of
+// the organizations where the current user is an admin
+func (api *API) DeviceIsAdministeredByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		userId := c.Value(gin.AuthUserKey).(string)
+		if api.dialect == database.DialectSqlLite {
+			return db.Where("user_id = ? OR organization_id in (SELECT id FROM organizations where owner_id=?) OR organization_id in (SELECT organization_id FROM user_organizations where user_id=? AND role=?)",
+				userId, userId, userId, models.OrganizationRoleAdmin)
+		} else {
+			return db.Where("user_id = ? OR organization_id::text in (SELECT id::text FROM organizations where owner_id=?) OR organization_id::text in (SELECT organization_id::text FROM user_organizations where user_id=? AND role=?)",
+				userId, userId, userId, models.OrganizationRoleAdmin)
+		}
+	}
+}
+
 // GetDevice gets a device by ID
 // @Summary      Get Devices
 // @Description  Gets a device by ID
@@ -107,6 +166,10 @@ func (api *API) GetDevice(c *gin.Context) {
 		c.Status(http.StatusNotFound)
 		return
 	}
+	if err := api.populateDeviceStatus(ctx, &device); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
 	c.JSON(http.StatusOK, device)
 }
 
@@ -122,6 +185,7 @@ func (api *API) GetDevice(c *gin.Context) {
 // @Success      200  {object}  models.Device
 // @Failure		 401  {object}  models.BaseError
 // @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
 // @Failure      404  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Router       /api/devices/{id} [patch]
@@ -135,21 +199,47 @@ func (api *API) UpdateDevice(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
 		return
 	}
+	if err := checkRegKeyDeviceAccess(c, k); err != nil {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		return
+	}
 	var request models.UpdateDevice
 
 	if err := c.BindJSON(&request); err != nil {
 		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
 		return
 	}
+	if err := validateLabels(request.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("labels", err.Error()))
+		return
+	}
 
 	var device models.Device
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
 		result := tx.
-			Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			Scopes(api.DeviceIsAdministeredByCurrentUser(c)).
 			First(&device, "id = ?", k)
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return errDeviceNotFound
 		}
+		before := device
+
+		// the devices registered with a registration key stay in its organization and security group
+		if _, ok := currentRegKey(c); ok {
+			if request.OrganizationID != uuid.Nil && request.OrganizationID != device.OrganizationID {
+				return fmt.Errorf("%w: the organization of the device can not be changed with a registration key", errRegKeyNotAllowed)
+			}
+			if request.SecurityGroupId != uuid.Nil && request.SecurityGroupId != device.SecurityGroupId {
+				return fmt.Errorf("%w: the security group of the device can not be changed with a registration key", errRegKeyNotAllowed)
+			}
+		}
+
+		// the admins of the organization can move the devices of its members to another security group,
+		// the other fields can only be changed by the owner of the device
+		isOwner := device.UserID == c.Value(gin.AuthUserKey).(string)
+		if !isOwner && updatesOwnerFields(request) {
+			return errDeviceNotOwned
+		}
 
 		if request.EndpointLocalAddressIPv4 != "" {
 			device.EndpointLocalAddressIPv4 = request.EndpointLocalAddressIPv4
@@ -163,17 +253,27 @@ func (api *API) UpdateDevice(c *gin.Context) {
 			device.Endpoints = request.Endpoints
 		}
 
-		if request.OrganizationID != uuid.Nil && request.OrganizationID != device.OrganizationID {
-			userId := c.GetString(gin.AuthUserKey)
+		if request.Labels != nil {
+			device.Labels = request.Labels
+		}
 
+		if request.OrganizationID != uuid.Nil && request.OrganizationID != device.OrganizationID {
 			var org models.Organization
-			if res := tx.Model(&org).
-				Joins("inner join user_organizations on user_organizations.organization_id=organizations.id").
-				Where("user_organizations.user_id=? AND organizations.id=?", userId, request.OrganizationID).
-				First(&org); res.Error != nil {
+			if res := tx.
+				Scopes(api.OrganizationIsWritableByCurrentUser(c)).
+				First(&org, "id = ?", request.OrganizationID); res.Error != nil {
+				if err := api.organizationRoleError(tx, c, request.OrganizationID); !errors.Is(err, errOrgNotFound) {
+					return err
+				}
 				return errUserOrOrgNotFound
 			}
 
+			if err := api.releasePreviousTunnelIPs(ctx, tx, device); err != nil {
+				return err
+			}
+			device.PreviousTunnelIP = ""
+			device.PreviousTunnelIpV6 = ""
+
 			if err := api.ipam.ReleaseToPool(c.Request.Context(), device.OrganizationID, device.TunnelIP, device.OrganizationPrefix); err != nil {
 				c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v4 address to pool: %w", err)))
 				return err
@@ -202,9 +302,39 @@ func (api *API) UpdateDevice(c *gin.Context) {
 			}
 
 			device.OrganizationID = request.OrganizationID
+			// the device leaves the security group of the old organization
+			device.SecurityGroupId = org.SecurityGroupId
+			// the device waits for the approval of the admins of an organization that requires it
+			device.Pending = org.ApprovalRequired
+		}
+
+		if request.SecurityGroupId != uuid.Nil && request.SecurityGroupId != device.SecurityGroupId {
+			// only the organization admins can move a device to another security group
+			var org models.Organization
+			if res := tx.
+				Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
+				First(&org, "id = ?", device.OrganizationID); res.Error != nil {
+				if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+					return errOrgRoleNotAllowed
+				}
+				return res.Error
+			}
+			// the security group must belong to the organization of the device
+			var secGroup models.SecurityGroup
+			if res := tx.Select("id").
+				Where("organization_id = ?", device.OrganizationID).
+				First(&secGroup, "id = ?", request.SecurityGroupId); res.Error != nil {
+				if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+					return errSecurityGroupNotFound
+				}
+				return res.Error
+			}
+			device.SecurityGroupId = secGroup.ID
 		}
 
-		device.SymmetricNat = request.SymmetricNat
+		if isOwner {
+			device.SymmetricNat = request.SymmetricNat
+		}
 
 		// check if the updated device child prefix matches the existing device prefix
 		if request.ChildPrefix != nil && !childPrefixEquals(device.ChildPrefix, request.ChildPrefix) {
@@ -243,12 +373,18 @@ func (api *API) UpdateDevice(c *gin.Context) {
 			return res.Error
 		}
 
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, device.OrganizationID, "device", device.ID.String(), auditActionUpdate, before, device)
 	})
 
 	if err != nil {
 		if errors.Is(err, errDeviceNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else if errors.Is(err, errUserOrOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errSecurityGroupNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("security group"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) || errors.Is(err, errDeviceNotOwned) || errors.Is(err, errRegKeyNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -259,6 +395,17 @@ func (api *API) UpdateDevice(c *gin.Context) {
 	c.JSON(http.StatusOK, device)
 }
 
+// updatesOwnerFields returns true when the update changes more than the security group of the device
+func updatesOwnerFields(request models.UpdateDevice) bool {
+	return request.OrganizationID != uuid.Nil ||
+		request.ChildPrefix != nil ||
+		request.EndpointLocalAddressIPv4 != "" ||
+		request.SymmetricNat ||
+		request.Hostname != "" ||
+		len(request.Endpoints) > 0 ||
+		request.Labels != nil
+}
+
 func getAllowedIPs(ip string, ip6 string, relay bool) ([]string, error) {
 	var err error
 
@@ -299,6 +446,7 @@ func getAllowedIPs(ip string, ip6 string, relay bool) ([]string, error) {
 // @Success      201  {object}  models.Device
 // @Failure      400  {object}  models.BaseError
 // @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
 // @Failure      409  {object}  models.ConflictsError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
@@ -317,17 +465,24 @@ func (api *API) CreateDevice(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("public_key"))
 		return
 	}
+	if err := validateLabels(request.Labels); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("labels", err.Error()))
+		return
+	}
 
 	userId := c.GetString(gin.AuthUserKey)
 	var device models.Device
+	var deviceToken string
 
 	err := api.transaction(ctx, func(tx *gorm.DB) error {
 
 		var org models.Organization
-		if res := tx.Model(&org).
-			Joins("inner join user_organizations on user_organizations.organization_id=organizations.id").
-			Where("user_organizations.user_id=? AND organizations.id=?", userId, request.OrganizationID).
-			First(&org); res.Error != nil {
+		if res := tx.
+			Scopes(api.OrganizationIsWritableByCurrentUser(c)).
+			First(&org, "id = ?", request.OrganizationID); res.Error != nil {
+			if err := api.organizationRoleError(tx, c, request.OrganizationID); !errors.Is(err, errOrgNotFound) {
+				return err
+			}
 			return errUserOrOrgNotFound
 		}
 
@@ -339,6 +494,11 @@ func (api *API) CreateDevice(c *gin.Context) {
 			return res.Error
 		}
 
+		regKey, err := api.checkRegKeyRegistration(tx, c, org.ID, request.Relay)
+		if err != nil {
+			return err
+		}
+
 		var relay bool
 		// determine if the node joining is a relay node
 		if request.Relay {
@@ -347,7 +507,6 @@ func (api *API) CreateDevice(c *gin.Context) {
 
 		var ipamIP string
 		var ipamIPv6 string
-		var err error
 		// If this was a static address request
 		// TODO: handle a user requesting an IP not in the IPAM prefix
 		if request.TunnelIP != "" {
@@ -403,6 +562,22 @@ func (api *API) CreateDevice(c *gin.Context) {
 			Hostname:                 request.Hostname,
 			Os:                       request.Os,
 			SecurityGroupId:          org.SecurityGroupId,
+			Pending:                  org.ApprovalRequired,
+			Ephemeral:                request.Ephemeral,
+			Labels:                   request.Labels,
+		}
+		if regKey != nil {
+			device.RegKeyID = &regKey.ID
+			device.Discovery = regKey.Discovery
+			if regKey.SecurityGroupID != nil {
+				device.SecurityGroupId = *regKey.SecurityGroupID
+			}
+			// the device authenticates with its own token from now on, the key may be single use or expire
+			deviceToken, err = newSecretToken(DeviceTokenPrefix)
+			if err != nil {
+				return err
+			}
+			device.DeviceTokenHash = hashApiToken(deviceToken)
 		}
 
 		if res := tx.
@@ -414,15 +589,19 @@ func (api *API) CreateDevice(c *gin.Context) {
 			attribute.String("id", device.ID.String()),
 		)
 
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, org.ID, "device", device.ID.String(), auditActionCreate, nil, device)
 	})
 
 	if err != nil {
 		var duplicate errDuplicateDevice
 		if errors.Is(err, errUserOrOrgNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotAllowedError("user or organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError("read-only members can not register devices"))
 		} else if errors.As(err, &duplicate) {
 			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else if errors.Is(err, errRegKeyNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -430,12 +609,13 @@ func (api *API) CreateDevice(c *gin.Context) {
 	}
 
 	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+	device.DeviceToken = deviceToken
 	c.JSON(http.StatusCreated, device)
 }
 
 // DeleteDevice handles deleting an existing device and associated ipam lease
 // @Summary      Delete Device
-// @Description  Deletes an existing device and associated IPAM lease
+// @Description  Deletes an existing device and associated IPAM lease, the organization admins can delete any device of the organization
 // @Id 			 DeleteDevice
 // @Tags         Devices
 // @Accept       json
@@ -457,7 +637,7 @@ func (api *API) DeleteDevice(c *gin.Context) {
 
 	device := models.Device{}
 	if res := api.db.
-		Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+		Scopes(api.DeviceIsAdministeredByCurrentUser(c)).
 		First(&device, "id = ?", deviceID); res.Error != nil {
 
 		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
@@ -468,31 +648,61 @@ func (api *API) DeleteDevice(c *gin.Context) {
 		return
 	}
 
+	if err := api.deleteDevice(ctx, c, device); err != nil {
+		if errors.Is(err, errDeviceNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, device)
+}
+
+// deleteDevice deletes a device, notifies the watchers of its organization and releases its ipam
+// leases. c is nil when the apiserver deletes the device on its own.
+func (api *API) deleteDevice(ctx context.Context, c *gin.Context, device models.Device) error {
+	if err := api.releasePreviousTunnelIPs(ctx, api.db.WithContext(ctx), device); err != nil {
+		return err
+	}
+
 	ipamAddress := device.TunnelIP
 	orgID := device.OrganizationID
 	orgPrefix := device.OrganizationPrefix
 	childPrefix := device.ChildPrefix
 
-	if res := api.db.WithContext(ctx).
-		Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
-		Delete(&device, "id = ?", device.Base.ID); res.Error != nil {
-		c.JSON(http.StatusBadRequest, models.NewApiInternalError(res.Error))
-		return
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		res := tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Delete(&device, "id = ?", device.Base.ID)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			// somebody else deleted it first
+			return errDeviceNotFound
+		}
+		if res := tx.Delete(&models.DeviceStatus{}, "device_id = ?", device.ID); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, device.OrganizationID, "device", device.ID.String(), auditActionDelete, device, nil)
+	})
+	if err != nil {
+		return err
 	}
 
 	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
 
 	if ipamAddress != "" && orgPrefix != "" {
-		if err := api.ipam.ReleaseToPool(c.Request.Context(), orgID, ipamAddress, orgPrefix); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v4 address to pool: %w", err)))
-			return
+		if err := api.ipam.ReleaseToPool(ctx, orgID, ipamAddress, orgPrefix); err != nil {
+			return fmt.Errorf("failed to release the v4 address to pool: %w", err)
 		}
 	}
 
 	for _, prefix := range childPrefix {
-		if err := api.ipam.ReleasePrefix(c.Request.Context(), orgID, prefix); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release child prefix: %w", err)))
-			return
+		if err := api.ipam.ReleasePrefix(ctx, orgID, prefix); err != nil {
+			return fmt.Errorf("failed to release child prefix: %w", err)
 		}
 	}
 
@@ -500,12 +710,81 @@ func (api *API) DeleteDevice(c *gin.Context) {
 	orgPrefixV6 := device.OrganizationPrefixV6
 
 	if ipamAddressV6 != "" && orgPrefixV6 != "" {
-		if err := api.ipam.ReleaseToPool(c.Request.Context(), orgID, ipamAddressV6, orgPrefixV6); err != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the v6 address to pool: %w", err)))
-			return
+		if err := api.ipam.ReleaseToPool(ctx, orgID, ipamAddressV6, orgPrefixV6); err != nil {
+			return fmt.Errorf("failed to release the v6 address to pool: %w", err)
+		}
+	}
+	return nil
+}
+
+// ApproveDevice approves a device waiting for approval
+// @Summary      Approve Device
+// @Description  Approves a device registered in an organization that requires approval, the device then joins the mesh. Approving a device that is not pending does nothing. Requires the admin role in the organization of the device.
+// @Id 			 ApproveDevice
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Success      200  {object}  models.Device
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/devices/{id}/approve [post]
+func (api *API) ApproveDevice(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ApproveDevice", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	if _, ok := currentRegKey(c); ok {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError("devices can not be approved with a registration key"))
+		return
+	}
+
+	var device models.Device
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Scopes(api.DeviceIsReadableByCurrentUser(c)).
+			First(&device, "id = ?", deviceID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errDeviceNotFound
+			}
+			return res.Error
+		}
+		if err := api.administeredOrganization(tx, c, device.OrganizationID); err != nil {
+			return err
 		}
+		if !device.Pending {
+			// already approved
+			return nil
+		}
+		before := device
+		device.Pending = false
+		if res := tx.Model(&device).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Update("pending", false); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, device.OrganizationID, "device", device.ID.String(), auditActionApprove, before, device)
+	})
+	if err != nil {
+		if errors.Is(err, errDeviceNotFound) || errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError("only the organization admins can approve devices"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
 	}
 
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
 	c.JSON(http.StatusOK, device)
 }
 
@@ -525,3 +804,97 @@ func childPrefixEquals(existingPrefix, newPrefix []string) bool {
 	}
 	return true
 }
+
+// RotateDeviceKey replaces the WireGuard public key of a device
+// @Summary      Rotate Device Key
+// @Description  Replaces the WireGuard public key of a device, the device keeps its ID and tunnel addresses and its peers pick up the new key from the device watch
+// @Id 			 RotateDeviceKey
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Param		 key  body      models.RotateDeviceKey true "New Public Key"
+// @Success      200  {object}  models.Device
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/devices/{id}/rotate_key [post]
+func (api *API) RotateDeviceKey(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "RotateDeviceKey", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	if err := checkRegKeyDeviceAccess(c, deviceID); err != nil {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		return
+	}
+	var request models.RotateDeviceKey
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.PublicKey == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("public_key"))
+		return
+	}
+	if _, err := wgtypes.ParseKey(request.PublicKey); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("public_key", "not a valid WireGuard public key"))
+		return
+	}
+
+	var device models.Device
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+			First(&device, "id = ?", deviceID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errDeviceNotFound
+			}
+			return res.Error
+		}
+		if device.PublicKey == request.PublicKey {
+			// already rotated
+			return nil
+		}
+
+		var existing models.Device
+		res := tx.Where("public_key = ?", request.PublicKey).First(&existing)
+		if res.Error == nil {
+			return errDuplicateDevice{ID: existing.ID.String()}
+		}
+		if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return res.Error
+		}
+
+		before := device
+		device.PublicKey = request.PublicKey
+		if res := tx.Model(&device).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Update("public_key", request.PublicKey); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, device.OrganizationID, "device", device.ID.String(), auditActionRotateKey, before, device)
+	})
+	if err != nil {
+		var duplicate errDuplicateDevice
+		if errors.Is(err, errDeviceNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else if errors.As(err, &duplicate) {
+			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", device.OrganizationID.String()))
+	c.JSON(http.StatusOK, device)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// deviceOnlineTimeout is how long a device is considered online after its last status report
+const deviceOnlineTimeout = 2 * time.Minute
+
+// ReportDeviceStatus stores the status reported by a device
+// @Summary      Report Device Status
+// @Description  Stores the health reported by a device, the time of the report is its last seen time
+// @Id  		 ReportDeviceStatus
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Param		 status body models.ReportDeviceStatus true "Device Status"
+// @Success      200  {object}  models.DeviceStatus
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/devices/{id}/status [put]
+func (api *API) ReportDeviceStatus(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ReportDeviceStatus", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	k, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	if err := checkRegKeyDeviceAccess(c, k); err != nil {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		return
+	}
+	var request models.ReportDeviceStatus
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+
+	var device models.Device
+	if res := api.db.WithContext(ctx).
+		Scopes(api.DeviceIsOwnedByCurrentUser(c)).
+		Select("id").
+		First(&device, "id = ?", k); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		}
+		return
+	}
+
+	status := models.DeviceStatus{
+		DeviceID:    device.ID,
+		LastSeen:    time.Now(),
+		NexdVersion: request.NexdVersion,
+		Peers:       request.Peers,
+	}
+	// the status is kept apart from the device so the reports do not bump its revision
+	if res := api.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "device_id"}},
+			UpdateAll: true,
+		}).
+		Create(&status); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// GetDeviceStatus gets the status last reported by a device
+// @Summary      Get Device Status
+// @Description  Gets the health last reported by a device
+// @Id  		 GetDeviceStatus
+// @Tags         Devices
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true "Device ID"
+// @Success      200  {object}  models.DeviceStatus
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/devices/{id}/status [get]
+func (api *API) GetDeviceStatus(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetDeviceStatus", trace.WithAttributes(
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	k, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var device models.Device
+	if res := api.db.WithContext(ctx).
+		Scopes(api.DeviceIsReadableByCurrentUser(c)).
+		Select("id").
+		First(&device, "id = ?", k); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		}
+		return
+	}
+
+	var status models.DeviceStatus
+	if res := api.db.WithContext(ctx).First(&status, "device_id = ?", device.ID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("device status"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// populateDeviceStatus fills the online and last seen fields of the devices from their last status report
+func (api *API) populateDeviceStatus(ctx context.Context, devices ...*models.Device) error {
+	if len(devices) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(devices))
+	for _, device := range devices {
+		ids = append(ids, device.ID)
+	}
+	var statuses []models.DeviceStatus
+	if res := api.db.WithContext(ctx).
+		Select("device_id", "last_seen").
+		Where("device_id IN ?", ids).
+		Find(&statuses); res.Error != nil {
+		return res.Error
+	}
+	lastSeen := make(map[uuid.UUID]time.Time, len(statuses))
+	for _, status := range statuses {
+		lastSeen[status.DeviceID] = status.LastSeen
+	}
+	for _, device := range devices {
+		if t, ok := lastSeen[device.ID]; ok {
+			device.LastSeen = &t
+			device.Online = time.Since(t) < deviceOnlineTimeout
+		}
+	}
+	return nil
+}
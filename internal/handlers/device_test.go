@@ -2,14 +2,20 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/models"
 	"github.com/stretchr/testify/assert"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
 func (suite *HandlerTestSuite) TestCreateGetDevice() {
@@ -60,6 +66,120 @@ func (suite *HandlerTestSuite) TestCreateGetDevice() {
 	assert.Equal(actual, device)
 }
 
+func (suite *HandlerTestSuite) TestUpdateDeviceSecurityGroup() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	reqBody, err := json.Marshal(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "securitygrouppubkey",
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/", "/",
+		suite.api.CreateDevice, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var device models.Device
+	require.NoError(json.Unmarshal(body, &device))
+
+	sameOrgGroup := models.SecurityGroup{GroupName: "database", OrganizationId: suite.testOrganizationID}
+	require.NoError(suite.api.db.Create(&sameOrgGroup).Error)
+	otherOrgGroup := models.SecurityGroup{GroupName: "other", OrganizationId: suite.testUser2OrgID}
+	require.NoError(suite.api.db.Create(&otherOrgGroup).Error)
+
+	// a security group of the device's organization can be assigned
+	reqBody, err = json.Marshal(models.UpdateDevice{SecurityGroupId: sameOrgGroup.ID})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID),
+		suite.api.UpdateDevice, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", string(body))
+
+	var updated models.Device
+	require.NoError(json.Unmarshal(body, &updated))
+	assert.Equal(sameOrgGroup.ID, updated.SecurityGroupId)
+
+	// a security group of another organization is rejected
+	reqBody, err = json.Marshal(models.UpdateDevice{SecurityGroupId: otherOrgGroup.ID})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID),
+		suite.api.UpdateDevice, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusNotFound, res.Code, "HTTP error: %s", string(body))
+
+	var stored models.Device
+	require.NoError(suite.api.db.First(&stored, "id = ?", device.ID).Error)
+	assert.Equal(sameOrgGroup.ID, stored.SecurityGroupId)
+}
+
+func (suite *HandlerTestSuite) TestUpdateDeviceSecurityGroupAsAdmin() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	serve := func(userId string, method, path, uri string, handler func(*gin.Context), body interface{}) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(body)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(method, path, uri, func(c *gin.Context) {
+			c.Set(gin.AuthUserKey, userId)
+			handler(c)
+		}, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		return res
+	}
+	update := func(userId string, deviceId uuid.UUID, request models.UpdateDevice) *httptest.ResponseRecorder {
+		return serve(userId, http.MethodPatch, "/:id", fmt.Sprintf("/%s", deviceId), suite.api.UpdateDevice, request)
+	}
+
+	// TestUser2ID owns and administers the organization, TestUserID is one of its members
+	require.NoError(suite.api.db.Create(&models.UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+		Role:           models.OrganizationRoleMember,
+	}).Error)
+	res := serve(TestUserID, http.MethodPost, "/", "/", suite.api.CreateDevice, models.AddDevice{
+		OrganizationID: suite.testUser2OrgID,
+		PublicKey:      "memberdevicepubkey",
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+
+	group := models.SecurityGroup{GroupName: "members", OrganizationId: suite.testUser2OrgID}
+	require.NoError(suite.api.db.Create(&group).Error)
+
+	// the member can not move their own device to another security group
+	res = update(TestUserID, device.ID, models.UpdateDevice{SecurityGroupId: group.ID})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	// the admin can move the device of a member
+	res = update(TestUser2ID, device.ID, models.UpdateDevice{SecurityGroupId: group.ID})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var updated models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &updated))
+	assert.Equal(group.ID, updated.SecurityGroupId)
+	assert.Equal(TestUserID, updated.UserID)
+
+	// but the other fields of the device stay with its owner
+	res = update(TestUser2ID, device.ID, models.UpdateDevice{Hostname: "renamed"})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+	res = update(TestUserID, device.ID, models.UpdateDevice{Hostname: "renamed"})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+}
+
 func TestChildPrefixEquals(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -100,3 +220,283 @@ func TestChildPrefixEquals(t *testing.T) {
 		})
 	}
 }
+
+func (suite *HandlerTestSuite) TestReportDeviceStatus() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	reqBody, err := json.Marshal(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "statuspubkey",
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+	assert.False(device.Online)
+	assert.Nil(device.LastSeen)
+
+	report := func(request models.ReportDeviceStatus) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPut, "/:id/status", fmt.Sprintf("/%s/status", device.ID),
+			suite.api.ReportDeviceStatus, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		return res
+	}
+
+	peerID := uuid.New()
+	res = report(models.ReportDeviceStatus{NexdVersion: "0.0.1"})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	// a second report replaces the first one
+	res = report(models.ReportDeviceStatus{
+		NexdVersion: "0.0.2",
+		Peers:       []models.PeerStatus{{DeviceID: peerID, Endpoint: "10.1.1.1:51820", Reachable: true}},
+	})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	_, res, err = suite.ServeRequest(http.MethodGet, "/:id/status", fmt.Sprintf("/%s/status", device.ID),
+		suite.api.GetDeviceStatus, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var status models.DeviceStatus
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &status))
+	assert.Equal("0.0.2", status.NexdVersion)
+	require.Len(status.Peers, 1)
+	assert.Equal(peerID, status.Peers[0].DeviceID)
+	assert.True(status.Peers[0].Reachable)
+
+	_, res, err = suite.ServeRequest(http.MethodGet, "/organizations/:organization/devices",
+		fmt.Sprintf("/organizations/%s/devices", suite.testOrganizationID),
+		suite.api.ListDevicesInOrganization, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var devices []models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &devices))
+	require.Len(devices, 1)
+	assert.True(devices[0].Online)
+	require.NotNil(devices[0].LastSeen)
+
+	// only the owner of the device can report its status
+	_, res, err = suite.ServeRequest(http.MethodPut, "/:id/status", fmt.Sprintf("/%s/status", device.ID),
+		func(c *gin.Context) {
+			c.Set(gin.AuthUserKey, TestUser2ID)
+			suite.api.ReportDeviceStatus(c)
+		}, bytes.NewBufferString("{}"))
+	require.NoError(err)
+	require.Equal(http.StatusNotFound, res.Code, "HTTP error: %s", res.Body.String())
+}
+
+func (suite *HandlerTestSuite) TestApproveDevice() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	// serve runs the handler as the given user
+	serve := func(userId, method, path, uri string, handler func(*gin.Context), body interface{}) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			reqBody, err := json.Marshal(body)
+			require.NoError(err)
+			reader = bytes.NewBuffer(reqBody)
+		}
+		_, res, err := suite.ServeRequest(method, path, uri, func(c *gin.Context) {
+			c.Set(gin.AuthUserKey, userId)
+			handler(c)
+		}, reader)
+		require.NoError(err)
+		return res
+	}
+
+	require.NoError(suite.api.db.Create(&models.UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+		Role:           models.OrganizationRoleMember,
+	}).Error)
+	approvalRequired := true
+	res := serve(TestUser2ID, http.MethodPatch,
+		"/organizations/:organization", fmt.Sprintf("/organizations/%s", suite.testUser2OrgID),
+		suite.api.UpdateOrganization, models.UpdateOrganization{ApprovalRequired: &approvalRequired})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var org models.Organization
+	require.NoError(suite.api.db.First(&org, "id = ?", suite.testUser2OrgID).Error)
+	assert.True(org.ApprovalRequired)
+
+	// the new devices of the organization wait for approval, they still get their addresses
+	res = serve(TestUserID, http.MethodPost, "/devices", "/devices", suite.api.CreateDevice, models.AddDevice{
+		OrganizationID: suite.testUser2OrgID,
+		PublicKey:      "pendingpubkey",
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+	assert.True(device.Pending)
+	assert.NotEmpty(device.TunnelIP)
+
+	// only the admins of the organization approve devices
+	res = serve(TestUserID, http.MethodPost, "/:id/approve", fmt.Sprintf("/%s/approve", device.ID), suite.api.ApproveDevice, nil)
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = serve(TestUser2ID, http.MethodPost, "/:id/approve", fmt.Sprintf("/%s/approve", device.ID), suite.api.ApproveDevice, nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var approved models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &approved))
+	assert.False(approved.Pending)
+
+	var events int64
+	require.NoError(suite.api.db.Model(&models.AuditEvent{}).
+		Where("resource_id = ? AND action = ?", device.ID.String(), auditActionApprove).Count(&events).Error)
+	assert.Equal(int64(1), events)
+
+	// approving an approved device does nothing
+	res = serve(TestUser2ID, http.MethodPost, "/:id/approve", fmt.Sprintf("/%s/approve", device.ID), suite.api.ApproveDevice, nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	// the devices of organizations that do not require approval join right away
+	res = serve(TestUserID, http.MethodPost, "/devices", "/devices", suite.api.CreateDevice, models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "approvedpubkey",
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+	assert.False(device.Pending)
+
+	// a device moved to an organization that requires approval waits for approval again
+	res = serve(TestUserID, http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.UpdateDevice,
+		models.UpdateDevice{OrganizationID: suite.testUser2OrgID})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+	assert.True(device.Pending)
+
+	res = serve(TestUserID, http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.UpdateDevice,
+		models.UpdateDevice{OrganizationID: suite.testOrganizationID})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+	assert.False(device.Pending)
+}
+
+func (suite *HandlerTestSuite) TestReapEphemeralDevices() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	createDevice := func(publicKey string, ephemeral bool) models.Device {
+		reqBody, err := json.Marshal(models.AddDevice{
+			OrganizationID: suite.testOrganizationID,
+			PublicKey:      publicKey,
+			Ephemeral:      ephemeral,
+		})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+		var device models.Device
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+		return device
+	}
+
+	// the addresses of this device can not be released, it does not keep the other devices from being deleted
+	broken := models.Device{
+		OrganizationID:   uuid.New(),
+		PublicKey:        "ephemeralbrokenpubkey",
+		PreviousTunnelIP: "100.64.0.9",
+		Ephemeral:        true,
+	}
+	require.NoError(suite.api.db.Create(&broken).Error)
+	require.NoError(suite.api.db.Model(&broken).UpdateColumn("updated_at", time.Now().Add(-3*DefaultEphemeralDeviceTTL)).Error)
+
+	gone := createDevice("ephemeralgonepubkey", true)
+	assert.True(gone.Ephemeral)
+	reporting := createDevice("ephemeralreportingpubkey", true)
+	permanent := createDevice("permanentpubkey", false)
+
+	// the devices were last updated before the TTL, only one of them still reports its status
+	lastUpdate := time.Now().Add(-2 * DefaultEphemeralDeviceTTL)
+	require.NoError(suite.api.db.Model(&models.Device{}).
+		Where("id IN ?", []uuid.UUID{gone.ID, reporting.ID, permanent.ID}).
+		UpdateColumn("updated_at", lastUpdate).Error)
+	require.NoError(suite.api.db.Create(&models.DeviceStatus{DeviceID: reporting.ID, LastSeen: time.Now()}).Error)
+
+	require.NoError(suite.api.reapEphemeralDevices(context.Background(), time.Now()))
+
+	var remaining []models.Device
+	require.NoError(suite.api.db.Find(&remaining, "id IN ?", []uuid.UUID{broken.ID, gone.ID, reporting.ID, permanent.ID}).Error)
+	ids := []uuid.UUID{}
+	for _, device := range remaining {
+		ids = append(ids, device.ID)
+	}
+	assert.ElementsMatch([]uuid.UUID{broken.ID, reporting.ID, permanent.ID}, ids)
+
+	// the deletion is recorded without an actor
+	var event models.AuditEvent
+	require.NoError(suite.api.db.First(&event, "resource_id = ? AND action = ?", gone.ID.String(), auditActionDelete).Error)
+	assert.Empty(event.ActorID)
+
+	// a device that went away since is deleted by the next run
+	require.NoError(suite.api.reapEphemeralDevices(context.Background(), time.Now().Add(2*DefaultEphemeralDeviceTTL)))
+	require.NoError(suite.api.db.Find(&remaining, "id IN ?", []uuid.UUID{reporting.ID, permanent.ID}).Error)
+	require.Len(remaining, 1)
+	assert.Equal(permanent.ID, remaining[0].ID)
+}
+
+func (suite *HandlerTestSuite) TestRotateDeviceKey() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	newKey := func() string {
+		privateKey, err := wgtypes.GeneratePrivateKey()
+		require.NoError(err)
+		return privateKey.PublicKey().String()
+	}
+	rotate := func(deviceID uuid.UUID, publicKey string) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(models.RotateDeviceKey{PublicKey: publicKey})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/:id/rotate_key", fmt.Sprintf("/%s/rotate_key", deviceID),
+			suite.api.RotateDeviceKey, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		return res
+	}
+
+	createDevice := func(publicKey string) models.Device {
+		reqBody, err := json.Marshal(models.AddDevice{
+			OrganizationID: suite.testOrganizationID,
+			PublicKey:      publicKey,
+		})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+		var device models.Device
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+		return device
+	}
+	device := createDevice(newKey())
+	other := createDevice(newKey())
+
+	// the device keeps its ID and addresses
+	rotatedKey := newKey()
+	res := rotate(device.ID, rotatedKey)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var rotated models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &rotated))
+	assert.Equal(device.ID, rotated.ID)
+	assert.Equal(rotatedKey, rotated.PublicKey)
+	assert.Equal(device.TunnelIP, rotated.TunnelIP)
+	assert.Equal(device.TunnelIpV6, rotated.TunnelIpV6)
+
+	var events int64
+	require.NoError(suite.api.db.Model(&models.AuditEvent{}).
+		Where("resource_id = ? AND action = ?", device.ID.String(), auditActionRotateKey).Count(&events).Error)
+	assert.Equal(int64(1), events)
+
+	// rotating to the current key again does nothing
+	res = rotate(device.ID, rotatedKey)
+	assert.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = rotate(device.ID, other.PublicKey)
+	assert.Equal(http.StatusConflict, res.Code, "HTTP error: %s", res.Body.String())
+	res = rotate(device.ID, "notakey")
+	assert.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+	res = rotate(uuid.New(), newKey())
+	assert.Equal(http.StatusNotFound, res.Code, "HTTP error: %s", res.Body.String())
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+const (
+	// DefaultEphemeralDeviceTTL is how long an ephemeral device is kept after its last update or status report
+	DefaultEphemeralDeviceTTL = 10 * time.Minute
+	// ephemeralDeviceReapInterval is how often the expired ephemeral devices are looked up
+	ephemeralDeviceReapInterval = time.Minute
+)
+
+// SetEphemeralDeviceTTL sets how long an ephemeral device is kept after its last update or status report
+func (api *API) SetEphemeralDeviceTTL(ttl time.Duration) {
+	api.ephemeralDeviceTTL = ttl
+}
+
+// reapEphemeralDevices deletes the ephemeral devices that were neither updated nor reported their
+// status during the TTL before now, nexd reports the status of a pending device while it waits for
+// its approval
+func (api *API) reapEphemeralDevices(ctx context.Context, now time.Time) error {
+	ctx, span := tracer.Start(ctx, "reapEphemeralDevices")
+	defer span.End()
+
+	cutoff := now.Add(-api.ephemeralDeviceTTL)
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).
+		Where("ephemeral = ? AND updated_at < ?", true, cutoff).
+		Order("updated_at").
+		Find(&devices); res.Error != nil {
+		return res.Error
+	}
+	if len(devices) == 0 {
+		return nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(devices))
+	for _, device := range devices {
+		ids = append(ids, device.ID)
+	}
+	var statuses []models.DeviceStatus
+	if res := api.db.WithContext(ctx).
+		Select("device_id", "last_seen").
+		Where("device_id IN ? AND last_seen >= ?", ids, cutoff).
+		Find(&statuses); res.Error != nil {
+		return res.Error
+	}
+	alive := make(map[uuid.UUID]bool, len(statuses))
+	for _, status := range statuses {
+		alive[status.DeviceID] = true
+	}
+
+	for _, device := range devices {
+		if alive[device.ID] {
+			continue
+		}
+		if err := api.deleteDevice(ctx, nil, device); err != nil {
+			// the other expired devices are still deleted, this one is tried again by the next run
+			if !errors.Is(err, errDeviceNotFound) {
+				api.Logger(ctx).Errorw("failed to delete the ephemeral device", "device", device.ID, "error", err)
+			}
+			continue
+		}
+		api.Logger(ctx).Infow("ephemeral device deleted",
+			"device", device.ID,
+			"organization", device.OrganizationID,
+			"hostname", device.Hostname,
+		)
+	}
+	return nil
+}
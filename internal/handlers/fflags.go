@@ -1,29 +1,58 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"regexp"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/fflags"
 	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var flagNameRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// flagSubject returns who the feature flags are evaluated for: the calling user and,
+// when the request is scoped to an organization, that organization.
+func (api *API) flagSubject(c *gin.Context) fflags.Subject {
+	subject := fflags.Subject{UserId: c.GetString(gin.AuthUserKey)}
+	if orgId, err := uuid.Parse(c.Param("organization")); err == nil {
+		subject.OrganizationIds = []uuid.UUID{orgId}
+	}
+	return subject
+}
+
 // ListFeatureFlags lists all feature flags
 // @Summary      List Feature Flags
-// @Description  Lists all feature flags
+// @Description  Lists all feature flags and whether they are enabled for the current user
 // @Id           ListFeatureFlags
 // @Tags         FFlag
 // @Accept       json
 // @Produce      json
 // @Success      200  {object} map[string]bool
 // @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
 // @Router       /api/fflags [get]
 func (api *API) ListFeatureFlags(c *gin.Context) {
-	c.JSON(http.StatusOK, api.fflags.ListFlags())
+	ctx, span := tracer.Start(c.Request.Context(), "ListFeatureFlags")
+	defer span.End()
+
+	flags, err := api.fflags.ListFlags(ctx, api.flagSubject(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, flags)
 }
 
 // GetFeatureFlag gets a feature flag by name
 // @Summary      Get Feature Flag
-// @Description  Gets a Feature Flag by name
+// @Description  Gets whether a Feature Flag is enabled for the current user
 // @Id           GetFeatureFlag
 // @Tags         FFlag
 // @Accept       json
@@ -33,19 +62,394 @@ func (api *API) ListFeatureFlags(c *gin.Context) {
 // @Failure      400  {object}  models.BaseError
 // @Failure      404  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
 // @Router       /api/fflags/{name} [get]
 func (api *API) GetFeatureFlag(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetFeatureFlag", trace.WithAttributes(
+		attribute.String("name", c.Param("name")),
+	))
+	defer span.End()
+
 	flagName := c.Param("name")
 	if flagName == "" {
 		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("name"))
 		return
 	}
 
-	enabled, err := api.fflags.GetFlag(flagName)
-	if err != nil {
+	enabled, err := api.fflags.GetFlag(ctx, api.flagSubject(c), flagName)
+	if errors.Is(err, fflags.ErrFlagNotFound) {
 		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
 		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
 	}
 
 	c.JSON(http.StatusOK, map[string]bool{flagName: enabled})
 }
+
+// GetFeatureFlagDefinition gets the global default and the overrides of a feature flag
+// @Summary      Get Feature Flag Definition
+// @Description  Gets the global default and the organization and user overrides of a Feature Flag, requires the admin role
+// @Id           GetFeatureFlagDefinition
+// @Tags         FFlag
+// @Accept       json
+// @Produce      json
+// @Param		 name path      string true  "feature flag name"
+// @Success      200  {object}  models.FeatureFlag
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/fflags/{name}/definition [get]
+func (api *API) GetFeatureFlagDefinition(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetFeatureFlagDefinition", trace.WithAttributes(
+		attribute.String("name", c.Param("name")),
+	))
+	defer span.End()
+
+	var flag models.FeatureFlag
+	res := api.db.WithContext(ctx).
+		Preload("Overrides").
+		First(&flag, "name = ?", c.Param("name"))
+	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
+		return
+	} else if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// CreateFeatureFlag creates a feature flag
+// @Summary      Create Feature Flag
+// @Description  Creates a Feature Flag with its global default, requires the admin role
+// @Id           CreateFeatureFlag
+// @Tags         FFlag
+// @Accept       json
+// @Produce      json
+// @Param        FeatureFlag  body   models.AddFeatureFlag  true "Add Feature Flag"
+// @Success      201  {object}  models.FeatureFlag
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/fflags [post]
+func (api *API) CreateFeatureFlag(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateFeatureFlag")
+	defer span.End()
+
+	var request models.AddFeatureFlag
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Name == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("name"))
+		return
+	}
+	if !flagNameRegex.MatchString(request.Name) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("name", "must only contain lowercase letters, digits and dashes"))
+		return
+	}
+
+	flag := models.FeatureFlag{
+		Name:        request.Name,
+		Description: request.Description,
+		Enabled:     request.Enabled,
+	}
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		res := tx.
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&flag)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errFlagExists
+		}
+		// feature flags do not belong to an organization
+		return api.recordAuditEvent(ctx, c, tx, uuid.Nil, "feature_flag", flag.Name, auditActionCreate, nil, flag)
+	})
+	if errors.Is(err, errFlagExists) {
+		c.JSON(http.StatusConflict, models.NewConflictsError(request.Name))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusCreated, flag)
+}
+
+// UpdateFeatureFlag updates the global default of a feature flag
+// @Summary      Update Feature Flag
+// @Description  Updates the global default and the description of a Feature Flag, requires the admin role
+// @Id           UpdateFeatureFlag
+// @Tags         FFlag
+// @Accept       json
+// @Produce      json
+// @Param		 name path      string true  "feature flag name"
+// @Param        update  body   models.UpdateFeatureFlag  true "Feature Flag Update"
+// @Success      200  {object}  models.FeatureFlag
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/fflags/{name} [patch]
+func (api *API) UpdateFeatureFlag(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "UpdateFeatureFlag", trace.WithAttributes(
+		attribute.String("name", c.Param("name")),
+	))
+	defer span.End()
+
+	var request models.UpdateFeatureFlag
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+
+	var flag models.FeatureFlag
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.First(&flag, "name = ?", c.Param("name")); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errFlagNotFound
+			}
+			return res.Error
+		}
+		before := flag
+		if request.Description != nil {
+			flag.Description = *request.Description
+		}
+		if request.Enabled != nil {
+			flag.Enabled = *request.Enabled
+		}
+		if res := tx.Save(&flag); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, uuid.Nil, "feature_flag", flag.Name, auditActionUpdate, before, flag)
+	})
+	if errors.Is(err, errFlagNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// DeleteFeatureFlag deletes a feature flag
+// @Summary      Delete Feature Flag
+// @Description  Deletes a Feature Flag and its overrides, requires the admin role. Built-in flags can not be deleted.
+// @Id           DeleteFeatureFlag
+// @Tags         FFlag
+// @Accept       json
+// @Produce      json
+// @Param		 name path      string true  "feature flag name"
+// @Success      200  {object}  models.FeatureFlag
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      405  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/fflags/{name} [delete]
+func (api *API) DeleteFeatureFlag(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteFeatureFlag", trace.WithAttributes(
+		attribute.String("name", c.Param("name")),
+	))
+	defer span.End()
+
+	name := c.Param("name")
+	if fflags.IsBuiltIn(name) {
+		c.JSON(http.StatusMethodNotAllowed, models.NewNotAllowedError("built-in feature flags can not be deleted"))
+		return
+	}
+
+	var flag models.FeatureFlag
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.First(&flag, "name = ?", name); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errFlagNotFound
+			}
+			return res.Error
+		}
+		if res := tx.Unscoped().Where("flag_name = ?", name).Delete(&models.FeatureFlagOverride{}); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Delete(&flag); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, uuid.Nil, "feature_flag", flag.Name, auditActionDelete, flag, nil)
+	})
+	if errors.Is(err, errFlagNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// SetFeatureFlagOverride enables or disables a feature flag for an organization or a user
+// @Summary      Set Feature Flag Override
+// @Description  Enables or disables a Feature Flag for an organization or a user, replacing any previous override of it, requires the admin role
+// @Id           SetFeatureFlagOverride
+// @Tags         FFlag
+// @Accept       json
+// @Produce      json
+// @Param		 name path      string true  "feature flag name"
+// @Param        override  body   models.SetFeatureFlagOverride  true "Feature Flag Override"
+// @Success      200  {object}  models.FeatureFlagOverride
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/fflags/{name}/overrides [put]
+func (api *API) SetFeatureFlagOverride(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "SetFeatureFlagOverride", trace.WithAttributes(
+		attribute.String("name", c.Param("name")),
+	))
+	defer span.End()
+
+	var request models.SetFeatureFlagOverride
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if (request.OrganizationId == nil) == (request.UserId == "") {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("organization_id", "exactly one of organization_id or user_id must be set"))
+		return
+	}
+
+	name := c.Param("name")
+	var override models.FeatureFlagOverride
+	err := api.transaction(ctx, func(tx *gorm.DB) error {
+		var flag models.FeatureFlag
+		if res := tx.First(&flag, "name = ?", name); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errFlagNotFound
+			}
+			return res.Error
+		}
+
+		db := tx.Where("flag_name = ?", name)
+		if request.OrganizationId != nil {
+			var org models.Organization
+			if res := tx.First(&org, "id = ?", *request.OrganizationId); res.Error != nil {
+				if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+					return errOrgNotFound
+				}
+				return res.Error
+			}
+			db = db.Where("organization_id = ?", *request.OrganizationId)
+		} else {
+			var user models.User
+			if res := tx.First(&user, "id = ?", request.UserId); res.Error != nil {
+				if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+					return errUserNotFound
+				}
+				return res.Error
+			}
+			db = db.Where("user_id = ?", request.UserId)
+		}
+
+		action := auditActionUpdate
+		var before interface{}
+		res := db.First(&override)
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			action = auditActionCreate
+		} else if res.Error != nil {
+			return res.Error
+		} else {
+			before = override
+		}
+		override.FlagName = name
+		override.OrganizationId = request.OrganizationId
+		override.UserId = request.UserId
+		override.Enabled = request.Enabled
+		if res := tx.Save(&override); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, overrideOrganization(override), "feature_flag_override", override.ID.String(), action, before, override)
+	})
+	if errors.Is(err, errFlagNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("flag"))
+		return
+	} else if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	} else if errors.Is(err, errUserNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, override)
+}
+
+// DeleteFeatureFlagOverride deletes an override of a feature flag
+// @Summary      Delete Feature Flag Override
+// @Description  Deletes an organization or user override of a Feature Flag, requires the admin role
+// @Id           DeleteFeatureFlagOverride
+// @Tags         FFlag
+// @Accept       json
+// @Produce      json
+// @Param		 name path      string true  "feature flag name"
+// @Param		 id   path      string true  "Override ID"
+// @Success      200  {object}  models.FeatureFlagOverride
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/fflags/{name}/overrides/{id} [delete]
+func (api *API) DeleteFeatureFlagOverride(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteFeatureFlagOverride", trace.WithAttributes(
+		attribute.String("name", c.Param("name")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var override models.FeatureFlagOverride
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.First(&override, "id = ? AND flag_name = ?", id, c.Param("name")); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errFlagOverrideNotFound
+			}
+			return res.Error
+		}
+		if res := tx.Unscoped().Delete(&override); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, overrideOrganization(override), "feature_flag_override", override.ID.String(), auditActionDelete, override, nil)
+	})
+	if errors.Is(err, errFlagOverrideNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("override"))
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	c.JSON(http.StatusOK, override)
+}
+
+// overrideOrganization returns the organization of an override, user overrides do not belong to one
+func overrideOrganization(override models.FeatureFlagOverride) uuid.UUID {
+	if override.OrganizationId == nil {
+		return uuid.Nil
+	}
+	return *override.OrganizationId
+}
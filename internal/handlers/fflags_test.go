@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/nexodus-io/nexodus/internal/fflags"
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) getFlag(name string) bool {
+	require := suite.Require()
+	_, res, err := suite.ServeRequest(
+		http.MethodGet, "/fflags/:name", "/fflags/"+name,
+		suite.api.GetFeatureFlag, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var flags map[string]bool
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &flags))
+	return flags[name]
+}
+
+func (suite *HandlerTestSuite) setFlagOverride(name string, override models.SetFeatureFlagOverride) models.FeatureFlagOverride {
+	require := suite.Require()
+	reqBody, err := json.Marshal(override)
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPut, "/fflags/:name/overrides", "/fflags/"+name+"/overrides",
+		suite.api.SetFeatureFlagOverride, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var result models.FeatureFlagOverride
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &result))
+	return result
+}
+
+func (suite *HandlerTestSuite) TestFeatureFlagOverrides() {
+	require := suite.Require()
+	suite.api.db.Exec("DELETE FROM feature_flag_overrides")
+
+	reqBody, err := json.Marshal(models.AddFeatureFlag{Name: "pilot-feature", Description: "a feature in pilot"})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPost, "/fflags", "/fflags",
+		suite.api.CreateFeatureFlag, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+
+	_, res, err = suite.ServeRequest(
+		http.MethodPost, "/fflags", "/fflags",
+		suite.api.CreateFeatureFlag, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusConflict, res.Code, "HTTP error: %s", res.Body.String())
+
+	require.False(suite.getFlag("pilot-feature"))
+
+	// the organization of the user is in the pilot
+	orgOverride := suite.setFlagOverride("pilot-feature", models.SetFeatureFlagOverride{OrganizationId: &suite.testOrganizationID, Enabled: true})
+	require.True(suite.getFlag("pilot-feature"))
+
+	// setting the override again replaces it
+	again := suite.setFlagOverride("pilot-feature", models.SetFeatureFlagOverride{OrganizationId: &suite.testOrganizationID, Enabled: true})
+	require.Equal(orgOverride.ID, again.ID)
+
+	// the organization of the other user is not
+	enabled, err := suite.api.fflags.GetFlag(context.Background(), fflags.Subject{UserId: TestUser2ID}, "pilot-feature")
+	require.NoError(err)
+	require.False(enabled)
+
+	// a user override wins over the organization override
+	suite.setFlagOverride("pilot-feature", models.SetFeatureFlagOverride{UserId: TestUserID, Enabled: false})
+	require.False(suite.getFlag("pilot-feature"))
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/fflags/:name/definition", "/fflags/pilot-feature/definition",
+		suite.api.GetFeatureFlagDefinition, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var definition models.FeatureFlag
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &definition))
+	require.False(definition.Enabled)
+	require.Len(definition.Overrides, 2)
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete, "/fflags/:name/overrides/:id", "/fflags/pilot-feature/overrides/"+orgOverride.ID.String(),
+		suite.api.DeleteFeatureFlagOverride, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	reqBody, err = json.Marshal(models.SetFeatureFlagOverride{OrganizationId: &suite.testOrganizationID, UserId: TestUserID})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPut, "/fflags/:name/overrides", "/fflags/pilot-feature/overrides",
+		suite.api.SetFeatureFlagOverride, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete, "/fflags/:name", "/fflags/pilot-feature",
+		suite.api.DeleteFeatureFlag, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	_, res, err = suite.ServeRequest(
+		http.MethodGet, "/fflags/:name", "/fflags/pilot-feature",
+		suite.api.GetFeatureFlag, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusNotFound, res.Code, "HTTP error: %s", res.Body.String())
+}
+
+func (suite *HandlerTestSuite) TestUpdateFeatureFlag() {
+	require := suite.Require()
+
+	enabled := true
+	reqBody, err := json.Marshal(models.UpdateFeatureFlag{Enabled: &enabled})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(
+		http.MethodPatch, "/fflags/:name", "/fflags/security-groups",
+		suite.api.UpdateFeatureFlag, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.True(suite.getFlag("security-groups"))
+
+	enabled = false
+	reqBody, err = json.Marshal(models.UpdateFeatureFlag{Enabled: &enabled})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(
+		http.MethodPatch, "/fflags/:name", "/fflags/security-groups",
+		suite.api.UpdateFeatureFlag, bytes.NewBuffer(reqBody),
+	)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.False(suite.getFlag("security-groups"))
+
+	_, res, err = suite.ServeRequest(
+		http.MethodDelete, "/fflags/:name", "/fflags/security-groups",
+		suite.api.DeleteFeatureFlag, nil,
+	)
+	require.NoError(err)
+	require.Equal(http.StatusMethodNotAllowed, res.Code, "HTTP error: %s", res.Body.String())
+}
@@ -7,6 +7,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"sync"
 	"testing"
 
@@ -62,12 +63,14 @@ func (suite *HandlerTestSuite) SetupSuite() {
 
 	ipamClient := ipam.NewIPAM(suite.logger, ipamClientAddr)
 
-	fflags := fflags.NewFFlags(suite.logger)
+	fflags := fflags.NewFFlags(suite.logger, db)
 	store := inmem.New()
 	suite.api, err = NewAPI(context.Background(), suite.logger, db, ipamClient, fflags, store, signalbus.NewSignalBus())
 	if err != nil {
 		suite.T().Fatal(err)
 	}
+	// the test webhooks listen on the loopback address
+	suite.api.webhookClient = newWebhookClient(func(netip.Addr) bool { return true })
 }
 
 func (suite *HandlerTestSuite) BeforeTest(_, _ string) {
@@ -75,6 +78,11 @@ func (suite *HandlerTestSuite) BeforeTest(_, _ string) {
 	suite.api.db.Exec("DELETE FROM organizations")
 	suite.api.db.Exec("DELETE FROM user_organizations")
 	suite.api.db.Exec("DELETE FROM devices")
+	suite.api.db.Exec("DELETE FROM audit_events")
+	suite.api.db.Exec("DELETE FROM webhooks")
+	suite.api.db.Exec("DELETE FROM webhook_deliveries")
+	suite.api.db.Exec("DELETE FROM api_tokens")
+	suite.api.db.Exec("DELETE FROM registration_keys")
 	var err error
 	suite.testOrganizationID, err = suite.api.createUserIfNotExists(context.Background(), TestUserID, "testuser")
 	suite.Require().NoError(err)
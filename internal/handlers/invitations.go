@@ -24,13 +24,14 @@ import (
 // @Param        Invitation  body     models.AddInvitation  true  "Add Invitation"
 // @Success      201  {object}  models.Invitation
 // @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
 // @Failure      404  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Router       /api/invitations [post]
 func (api *API) CreateInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "InviteUserToOrganization")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
+	multiOrganizationEnabled, err := api.fflags.GetFlag(ctx, api.flagSubject(c), "multi-organization")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
@@ -46,11 +47,15 @@ func (api *API) CreateInvitation(c *gin.Context) {
 		return
 	}
 
-	// Only allow org owners to create invites...
+	// Only allow org admins to create invites...
 	var org models.Organization
 	if res := api.db.WithContext(ctx).
-		Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+		Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
 		First(&org, "id = ?", request.OrganizationID); res.Error != nil {
+		if err := api.organizationRoleError(api.db.WithContext(ctx), c, request.OrganizationID); errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+			return
+		}
 		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
 		return
 	}
@@ -91,7 +96,13 @@ func (api *API) CreateInvitation(c *gin.Context) {
 	}
 
 	invite := models.NewInvitation(user.ID, request.OrganizationID)
-	if res := api.db.WithContext(ctx).Create(&invite); res.Error != nil {
+	err = api.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if res := tx.Create(&invite); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, invite.OrganizationID, "invitation", invite.ID.String(), auditActionCreate, nil, invite)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
 	}
@@ -114,7 +125,7 @@ func (api *API) ListInvitations(c *gin.Context) {
 	defer span.End()
 	invitations := make([]*models.Invitation, 0)
 	result := api.db.WithContext(ctx).
-		Scopes(api.InvitationIsForCurrentUserOrOrgOwner(c)).
+		Scopes(api.InvitationIsForCurrentUserOrOrgAdmin(c)).
 		Scopes(FilterAndPaginate(&models.Invitation{}, c, "id")).
 		Find(&invitations)
 	if result.Error != nil {
@@ -151,7 +162,7 @@ func (api *API) GetInvitation(c *gin.Context) {
 	}
 	var org models.Invitation
 	result := api.db.WithContext(ctx).
-		Scopes(api.InvitationIsForCurrentUserOrOrgOwner(c)).
+		Scopes(api.InvitationIsForCurrentUserOrOrgAdmin(c)).
 		First(&org, "id = ?", k.String())
 
 	if result.Error != nil {
@@ -175,15 +186,17 @@ func (api *API) InvitationIsForCurrentUser(c *gin.Context) func(db *gorm.DB) *go
 	}
 }
 
-func (api *API) InvitationIsForCurrentUserOrOrgOwner(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+func (api *API) InvitationIsForCurrentUserOrOrgAdmin(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
 
 		// this could potentially be driven by rego output
 		if api.dialect == database.DialectSqlLite {
-			return db.Where("user_id = ? OR organization_id in (SELECT id FROM organizations where owner_id=?)", userId, userId)
+			return db.Where("user_id = ? OR organization_id in (SELECT id FROM organizations where owner_id=?) OR organization_id in (SELECT organization_id FROM user_organizations where user_id=? AND role=?)",
+				userId, userId, userId, models.OrganizationRoleAdmin)
 		} else {
-			return db.Where("user_id = ? OR organization_id::text in (SELECT id::text FROM organizations where owner_id=?)", userId, userId)
+			return db.Where("user_id = ? OR organization_id::text in (SELECT id::text FROM organizations where owner_id=?) OR organization_id::text in (SELECT organization_id::text FROM user_organizations where user_id=? AND role=?)",
+				userId, userId, userId, models.OrganizationRoleAdmin)
 		}
 	}
 }
@@ -204,7 +217,7 @@ func (api *API) InvitationIsForCurrentUserOrOrgOwner(c *gin.Context) func(db *go
 func (api *API) AcceptInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "InviteUserToOrganization")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
+	multiOrganizationEnabled, err := api.fflags.GetFlag(ctx, api.flagSubject(c), "multi-organization")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
@@ -243,7 +256,7 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 		if res := tx.Delete(&invitation); res.Error != nil {
 			return res.Error
 		}
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, invitation.OrganizationID, "invitation", invitation.ID.String(), auditActionAccept, invitation, nil)
 	})
 
 	if err != nil {
@@ -279,7 +292,7 @@ func (api *API) AcceptInvitation(c *gin.Context) {
 func (api *API) DeleteInvitation(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "DeleteInvitation")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
+	multiOrganizationEnabled, err := api.fflags.GetFlag(ctx, api.flagSubject(c), "multi-organization")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
@@ -297,14 +310,20 @@ func (api *API) DeleteInvitation(c *gin.Context) {
 
 	var invitation models.Invitation
 	if res := api.db.WithContext(ctx).
-		Scopes(api.InvitationIsForCurrentUserOrOrgOwner(c)).
+		Scopes(api.InvitationIsForCurrentUserOrOrgAdmin(c)).
 		First(&invitation, "id = ?", k); res.Error != nil {
 		c.JSON(http.StatusNotFound, models.NewNotFoundError("invitation"))
 		return
 	}
 
-	if res := api.db.WithContext(ctx).Delete(&models.Invitation{}, k); res.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+	err = api.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if res := tx.Delete(&models.Invitation{}, k); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, invitation.OrganizationID, "invitation", invitation.ID.String(), auditActionDelete, invitation, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
 	}
 	c.Status(http.StatusNoContent)
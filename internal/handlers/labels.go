@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/database"
+	"gorm.io/gorm"
+)
+
+const (
+	labelOperatorEquals    = "="
+	labelOperatorNotEquals = "!="
+	labelOperatorIn        = "in"
+	labelOperatorNotIn     = "notin"
+	labelOperatorExists    = "exists"
+	labelOperatorNotExists = "!"
+
+	// maxLabels is the maximum number of labels of a device
+	maxLabels = 64
+)
+
+var (
+	// labelNameRegex matches the names of the labels and their values, the same way as Kubernetes
+	labelNameRegex = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?$`)
+	// labelPrefixRegex matches the optional DNS subdomain prefix of the label keys
+	labelPrefixRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+	// labelSetRegex matches the requirements on a set of values: <key> in (<values>) or <key> notin (<values>)
+	labelSetRegex = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+)
+
+// labelRequirement is one of the comma separated requirements of a label selector
+type labelRequirement struct {
+	key      string
+	operator string
+	values   []string
+}
+
+// validateLabelKey checks a label key: an optional DNS subdomain prefix and a / followed by a name
+func validateLabelKey(key string) error {
+	name := key
+	if prefix, n, found := strings.Cut(key, "/"); found {
+		if len(prefix) > 253 || !labelPrefixRegex.MatchString(prefix) {
+			return fmt.Errorf("the prefix of the label key %q must be a DNS subdomain", key)
+		}
+		name = n
+	}
+	if len(name) > 63 || !labelNameRegex.MatchString(name) {
+		return fmt.Errorf("the label key %q must be at most 63 alphanumeric characters, '-', '_' or '.'", key)
+	}
+	return nil
+}
+
+// validateLabelValue checks a label value, it may be empty
+func validateLabelValue(value string) error {
+	if value == "" {
+		return nil
+	}
+	if len(value) > 63 || !labelNameRegex.MatchString(value) {
+		return fmt.Errorf("the label value %q must be at most 63 alphanumeric characters, '-', '_' or '.'", value)
+	}
+	return nil
+}
+
+// validateLabels checks the labels of a device
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("a device has at most %d labels", maxLabels)
+	}
+	for key, value := range labels {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if err := validateLabelValue(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitLabelSelector splits a label selector at the commas that are not part of a set of values
+func splitLabelSelector(selector string) []string {
+	var terms []string
+	depth := 0
+	start := 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+// parseLabelSelector parses a Kubernetes style label selector such as env=prod,role!=db,tier in (web,api),!canary
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+	if strings.TrimSpace(selector) == "" {
+		return nil, nil
+	}
+	var requirements []labelRequirement
+	for _, term := range splitLabelSelector(selector) {
+		term = strings.TrimSpace(term)
+		var r labelRequirement
+		if match := labelSetRegex.FindStringSubmatch(term); match != nil {
+			r = labelRequirement{key: match[1], operator: match[2]}
+			for _, value := range strings.Split(match[3], ",") {
+				r.values = append(r.values, strings.TrimSpace(value))
+			}
+		} else if key, value, found := strings.Cut(term, "!="); found {
+			r = labelRequirement{key: key, operator: labelOperatorNotEquals, values: []string{value}}
+		} else if key, value, found := strings.Cut(term, "=="); found {
+			r = labelRequirement{key: key, operator: labelOperatorEquals, values: []string{value}}
+		} else if key, value, found := strings.Cut(term, "="); found {
+			r = labelRequirement{key: key, operator: labelOperatorEquals, values: []string{value}}
+		} else if key, found := strings.CutPrefix(term, "!"); found {
+			r = labelRequirement{key: key, operator: labelOperatorNotExists}
+		} else {
+			r = labelRequirement{key: term, operator: labelOperatorExists}
+		}
+		r.key = strings.TrimSpace(r.key)
+		if err := validateLabelKey(r.key); err != nil {
+			return nil, err
+		}
+		for i := range r.values {
+			r.values[i] = strings.TrimSpace(r.values[i])
+			if err := validateLabelValue(r.values[i]); err != nil {
+				return nil, err
+			}
+		}
+		requirements = append(requirements, r)
+	}
+	return requirements, nil
+}
+
+// labelSelectorFromQuery parses the labelSelector query parameter of the request
+func labelSelectorFromQuery(c *gin.Context) ([]labelRequirement, error) {
+	return parseLabelSelector(c.Query("labelSelector"))
+}
+
+// labelsMatchSelector returns true when the labels meet every requirement of the selector, it matches
+// the same devices as DeviceMatchesLabelSelector
+func labelsMatchSelector(labels map[string]string, requirements []labelRequirement) bool {
+	for _, r := range requirements {
+		value, ok := labels[r.key]
+		switch r.operator {
+		case labelOperatorEquals:
+			if !ok || value != r.values[0] {
+				return false
+			}
+		case labelOperatorNotEquals:
+			if ok && value == r.values[0] {
+				return false
+			}
+		case labelOperatorIn:
+			if !ok || !labelValueIn(value, r.values) {
+				return false
+			}
+		case labelOperatorNotIn:
+			if ok && labelValueIn(value, r.values) {
+				return false
+			}
+		case labelOperatorExists:
+			if !ok {
+				return false
+			}
+		case labelOperatorNotExists:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func labelValueIn(value string, values []string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DeviceMatchesLabelSelector matches the devices whose labels meet every requirement of the selector
+func (api *API) DeviceMatchesLabelSelector(requirements []labelRequirement) func(db *gorm.DB) *gorm.DB {
+	if api.dialect == database.DialectSqlLite {
+		return deviceMatchesLabelSelectorSqlLite(requirements)
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		for _, r := range requirements {
+			switch r.operator {
+			case labelOperatorEquals:
+				db = db.Where("labels->>? = ?", r.key, r.values[0])
+			case labelOperatorNotEquals:
+				db = db.Where("(labels->>? IS NULL OR labels->>? <> ?)", r.key, r.key, r.values[0])
+			case labelOperatorIn:
+				db = db.Where("labels->>? IN ?", r.key, r.values)
+			case labelOperatorNotIn:
+				db = db.Where("(labels->>? IS NULL OR labels->>? NOT IN ?)", r.key, r.key, r.values)
+			case labelOperatorExists:
+				db = db.Where("labels->>? IS NOT NULL", r.key)
+			case labelOperatorNotExists:
+				db = db.Where("labels->>? IS NULL", r.key)
+			}
+		}
+		return db
+	}
+}
+
+// deviceMatchesLabelSelectorSqlLite matches the labels against their JSON encoding since the sqlite
+// driver has no JSON functions. The keys and values are validated, so they contain no quotes
+func deviceMatchesLabelSelectorSqlLite(requirements []labelRequirement) func(db *gorm.DB) *gorm.DB {
+	escape := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	keyPattern := func(key string) string {
+		return fmt.Sprintf(`%%"%s":%%`, escape.Replace(key))
+	}
+	labelPattern := func(key, value string) string {
+		return fmt.Sprintf(`%%"%s":"%s"%%`, escape.Replace(key), escape.Replace(value))
+	}
+	return func(db *gorm.DB) *gorm.DB {
+		for _, r := range requirements {
+			switch r.operator {
+			case labelOperatorEquals, labelOperatorIn:
+				var conditions []string
+				var args []interface{}
+				for _, value := range r.values {
+					conditions = append(conditions, `labels LIKE ? ESCAPE '\'`)
+					args = append(args, labelPattern(r.key, value))
+				}
+				db = db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+			case labelOperatorNotEquals, labelOperatorNotIn:
+				for _, value := range r.values {
+					db = db.Where(`(labels IS NULL OR labels NOT LIKE ? ESCAPE '\')`, labelPattern(r.key, value))
+				}
+			case labelOperatorExists:
+				db = db.Where(`labels LIKE ? ESCAPE '\'`, keyPattern(r.key))
+			case labelOperatorNotExists:
+				db = db.Where(`(labels IS NULL OR labels NOT LIKE ? ESCAPE '\')`, keyPattern(r.key))
+			}
+		}
+		return db
+	}
+}
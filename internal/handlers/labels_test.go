@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     []labelRequirement
+		wantErr  bool
+	}{
+		{
+			name:     "empty",
+			selector: " ",
+		},
+		{
+			name:     "equality",
+			selector: "env=prod, role!=db,tier==web",
+			want: []labelRequirement{
+				{key: "env", operator: labelOperatorEquals, values: []string{"prod"}},
+				{key: "role", operator: labelOperatorNotEquals, values: []string{"db"}},
+				{key: "tier", operator: labelOperatorEquals, values: []string{"web"}},
+			},
+		},
+		{
+			name:     "sets",
+			selector: "env in (prod, staging),example.com/role notin (db)",
+			want: []labelRequirement{
+				{key: "env", operator: labelOperatorIn, values: []string{"prod", "staging"}},
+				{key: "example.com/role", operator: labelOperatorNotIn, values: []string{"db"}},
+			},
+		},
+		{
+			name:     "existence",
+			selector: "gpu,!canary",
+			want: []labelRequirement{
+				{key: "gpu", operator: labelOperatorExists},
+				{key: "canary", operator: labelOperatorNotExists},
+			},
+		},
+		{
+			name:     "invalid key",
+			selector: "env prod=x",
+			wantErr:  true,
+		},
+		{
+			name:     "invalid value",
+			selector: "env=prod;drop",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLabelSelector(tt.selector)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLabelsMatchSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "role": "web"}
+	tests := map[string]bool{
+		"":                         true,
+		"env=prod,role!=db":        true,
+		"env=dev":                  false,
+		"role!=web":                false,
+		"env in (prod,staging)":    true,
+		"env notin (prod,staging)": false,
+		"tier notin (db)":          true,
+		"tier in (db)":             false,
+		"role":                     true,
+		"!canary":                  true,
+		"!env":                     false,
+	}
+	for selector, want := range tests {
+		requirements, err := parseLabelSelector(selector)
+		require.NoError(t, err)
+		assert.Equal(t, want, labelsMatchSelector(labels, requirements), selector)
+	}
+}
+
+func (suite *HandlerTestSuite) TestDeviceLabels() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	createDevice := func(publicKey string, labels map[string]string) models.Device {
+		reqBody, err := json.Marshal(models.AddDevice{
+			OrganizationID: suite.testOrganizationID,
+			PublicKey:      publicKey,
+			Labels:         labels,
+		})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+		var device models.Device
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+		return device
+	}
+	listDevices := func(selector string) ([]models.Device, int) {
+		uri := fmt.Sprintf("/organizations/%s/devices?labelSelector=%s", suite.testOrganizationID, url.QueryEscape(selector))
+		_, res, err := suite.ServeRequest(http.MethodGet, "/organizations/:organization/devices", uri,
+			suite.api.ListDevicesInOrganization, nil)
+		require.NoError(err)
+		var devices []models.Device
+		if res.Code == http.StatusOK {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &devices))
+		}
+		return devices, res.Code
+	}
+	publicKeys := func(devices []models.Device) []string {
+		var names []string
+		for _, d := range devices {
+			names = append(names, d.PublicKey)
+		}
+		return names
+	}
+
+	web := createDevice("labelsweb", map[string]string{"env": "prod", "role": "web"})
+	assert.Equal(map[string]string{"env": "prod", "role": "web"}, web.Labels)
+	createDevice("labelsdb", map[string]string{"env": "prod", "role": "db"})
+	createDevice("labelsdev", map[string]string{"env": "dev"})
+	createDevice("labelsnone", nil)
+
+	devices, code := listDevices("env=prod,role!=db")
+	require.Equal(http.StatusOK, code)
+	assert.ElementsMatch([]string{"labelsweb"}, publicKeys(devices))
+
+	devices, code = listDevices("env in (prod,dev),role notin (web)")
+	require.Equal(http.StatusOK, code)
+	assert.ElementsMatch([]string{"labelsdb", "labelsdev"}, publicKeys(devices))
+
+	devices, code = listDevices("!env")
+	require.Equal(http.StatusOK, code)
+	assert.ElementsMatch([]string{"labelsnone"}, publicKeys(devices))
+
+	_, code = listDevices("env in prod")
+	assert.Equal(http.StatusBadRequest, code)
+
+	// invalid labels are rejected
+	reqBody, err := json.Marshal(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "labelsinvalid",
+		Labels:         map[string]string{"bad key": "x"},
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	// updating the labels replaces them, leaving them out keeps them
+	update := func(request models.UpdateDevice) models.Device {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPatch, "/:id", fmt.Sprintf("/%s", web.ID),
+			suite.api.UpdateDevice, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+		var device models.Device
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+		return device
+	}
+	updated := update(models.UpdateDevice{Labels: map[string]string{"env": "dev"}})
+	assert.Equal(map[string]string{"env": "dev"}, updated.Labels)
+	updated = update(models.UpdateDevice{Hostname: "web"})
+	assert.Equal(map[string]string{"env": "dev"}, updated.Labels)
+
+	devices, code = listDevices("env=dev")
+	require.Equal(http.StatusOK, code)
+	assert.ElementsMatch([]string{"labelsweb", "labelsdev"}, publicKeys(devices))
+}
+
+func (suite *HandlerTestSuite) TestWatchDevicesLabelSelector() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, TestUserID)
+		c.Next()
+	})
+	r.GET("/organizations/:organization/devices", suite.api.ListDevicesInOrganization)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// the revisions are set by the test, sqlite does not generate them
+	web := models.Device{OrganizationID: suite.testOrganizationID, PublicKey: "watchweb", Labels: map[string]string{"env": "prod"}, Revision: 1}
+	require.NoError(suite.api.db.Create(&web).Error)
+	dev := models.Device{OrganizationID: suite.testOrganizationID, PublicKey: "watchdev", Labels: map[string]string{"env": "dev"}, Revision: 2}
+	require.NoError(suite.api.db.Create(&dev).Error)
+	relabel := func(device models.Device, env string, revision uint64) {
+		require.NoError(suite.api.db.Model(&device).Select("labels", "revision").
+			Updates(models.Device{Labels: map[string]string{"env": env}, Revision: revision}).Error)
+		suite.api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", suite.testOrganizationID))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/organizations/%s/devices?watch=true&labelSelector=%s",
+		server.URL, suite.testOrganizationID, url.QueryEscape("env=prod")), nil)
+	require.NoError(err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(err)
+	defer res.Body.Close()
+	decoder := json.NewDecoder(res.Body)
+	next := func() (string, string) {
+		var event struct {
+			Type  string        `json:"type"`
+			Value models.Device `json:"value"`
+		}
+		require.NoError(decoder.Decode(&event))
+		return event.Type, event.Value.PublicKey
+	}
+
+	eventType, publicKey := next()
+	assert.Equal("change", eventType)
+	assert.Equal(web.PublicKey, publicKey)
+	eventType, _ = next()
+	assert.Equal("bookmark", eventType)
+
+	// the device that stops matching the selector is deleted from the watch, the one that starts matching is added
+	relabel(web, "dev", 3)
+	eventType, publicKey = next()
+	assert.Equal("delete", eventType)
+	assert.Equal(web.PublicKey, publicKey)
+
+	relabel(dev, "prod", 4)
+	eventType, publicKey = next()
+	assert.Equal("change", eventType)
+	assert.Equal(dev.PublicKey, publicKey)
+
+	// the devices that did not match before are not sent when they change
+	relabel(web, "staging", 5)
+	relabel(dev, "prod", 6)
+	eventType, publicKey = next()
+	assert.Equal("change", eventType)
+	assert.Equal(dev.PublicKey, publicKey)
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -49,7 +51,7 @@ func (e errDuplicateOrganization) Error() string {
 func (api *API) CreateOrganization(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "CreateOrganization")
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
+	multiOrganizationEnabled, err := api.fflags.GetFlag(ctx, api.flagSubject(c), "multi-organization")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
@@ -94,7 +96,6 @@ func (api *API) CreateOrganization(c *gin.Context) {
 			IpCidr:      request.IpCidr,
 			IpCidrV6:    request.IpCidrV6,
 			HubZone:     request.HubZone,
-			Users:       []*models.User{&user},
 		}
 
 		if res := tx.Create(&org); res.Error != nil {
@@ -104,6 +105,14 @@ func (api *API) CreateOrganization(c *gin.Context) {
 			return res.Error
 		}
 
+		if res := tx.Create(&models.UserOrganization{
+			UserID:         user.ID,
+			OrganizationID: org.ID,
+			Role:           models.OrganizationRoleAdmin,
+		}); res.Error != nil {
+			return res.Error
+		}
+
 		// Create the organization in IPAM
 		if err := api.ipam.CreateNamespace(ctx, org.ID); err != nil {
 			return err
@@ -119,7 +128,7 @@ func (api *API) CreateOrganization(c *gin.Context) {
 
 		span.SetAttributes(attribute.String("id", org.ID.String()))
 		api.logger.Infof("New organization request [ %s ] ipam v4 [ %s ] ipam v6 [ %s ] request", org.Name, org.IpCidr, org.IpCidrV6)
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, org.ID, "organization", org.ID.String(), auditActionCreate, nil, org)
 	})
 
 	if err != nil {
@@ -153,12 +162,53 @@ func (api *API) CreateOrganization(c *gin.Context) {
 		return
 	}
 
+	api.notifyOrganizationWatchers(org.OwnerID)
 	c.JSON(http.StatusCreated, org)
 }
 
+// organizationsSignal is the signal that wakes up the organization watches of a user
+func organizationsSignal(userId string) string {
+	return fmt.Sprintf("/organizations/user=%s", userId)
+}
+
+// organizationUserIds returns the owner and the members of the organization
+func organizationUserIds(db *gorm.DB, org models.Organization) ([]string, error) {
+	var userIds []string
+	if res := db.Model(&models.UserOrganization{}).Where("organization_id = ?", org.ID).Pluck("user_id", &userIds); res.Error != nil {
+		return nil, res.Error
+	}
+	return append(userIds, org.OwnerID), nil
+}
+
+// notifyOrganizationWatchers wakes up the organization watches of the given users
+func (api *API) notifyOrganizationWatchers(userIds ...string) {
+	notified := map[string]struct{}{}
+	for _, userId := range userIds {
+		if _, ok := notified[userId]; ok || userId == "" {
+			continue
+		}
+		notified[userId] = struct{}{}
+		api.signalBus.Notify(organizationsSignal(userId))
+	}
+}
+
+// notifyOrganizationChanged wakes up the organization watches of the owner and the members of the
+// organization, and of the given users that were part of it before the change
+func (api *API) notifyOrganizationChanged(ctx context.Context, org models.Organization, formerUserIds ...string) {
+	userIds, err := organizationUserIds(api.db.WithContext(ctx), org)
+	if err != nil {
+		// rather wake up every watch than miss the change
+		api.Logger(ctx).Warnw("failed to look up the users of the organization", "organization", org.ID, "error", err)
+		api.signalBus.NotifyAll()
+		return
+	}
+	api.notifyOrganizationWatchers(append(userIds, formerUserIds...)...)
+}
+
 func (api *API) OrganizationIsReadableByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
+		db = regKeyOrganizationScope(c, db)
 
 		// this could potentially be driven by rego output
 		if api.dialect == database.DialectSqlLite {
@@ -169,6 +219,30 @@ func (api *API) OrganizationIsReadableByCurrentUser(c *gin.Context) func(db *gor
 	}
 }
 
+// OrganizationIsWritableByCurrentUser matches the organizations where the current user can register devices
+func (api *API) OrganizationIsWritableByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return api.organizationHasCurrentUserRole(c, models.OrganizationRoleAdmin, models.OrganizationRoleMember)
+}
+
+// OrganizationIsAdministeredByCurrentUser matches the organizations where the current user is an admin
+func (api *API) OrganizationIsAdministeredByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
+	return api.organizationHasCurrentUserRole(c, models.OrganizationRoleAdmin)
+}
+
+func (api *API) organizationHasCurrentUserRole(c *gin.Context, roles ...string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		userId := c.Value(gin.AuthUserKey).(string)
+		db = regKeyOrganizationScope(c, db)
+
+		// the owner is always an admin of the organization
+		if api.dialect == database.DialectSqlLite {
+			return db.Where("owner_id = ? OR id in (SELECT organization_id FROM user_organizations where user_id=? AND role in ?)", userId, userId, roles)
+		} else {
+			return db.Where("owner_id = ? OR id::text in (SELECT organization_id::text FROM user_organizations where user_id=? AND role in ?)", userId, userId, roles)
+		}
+	}
+}
+
 func (api *API) OrganizationIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		userId := c.Value(gin.AuthUserKey).(string)
@@ -177,6 +251,21 @@ func (api *API) OrganizationIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.D
 	}
 }
 
+// organizationRoleError tells why an organization did not match one of the role scopes:
+// errOrgNotFound when the current user can not read it, errOrgRoleNotAllowed otherwise.
+func (api *API) organizationRoleError(tx *gorm.DB, c *gin.Context, orgId uuid.UUID) error {
+	var org models.Organization
+	if res := tx.Select("id").
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return errOrgNotFound
+		}
+		return res.Error
+	}
+	return errOrgRoleNotAllowed
+}
+
 // ListOrganizations lists all Organizations
 // @Summary      List Organizations
 // @Description  Lists all Organizations
@@ -184,6 +273,7 @@ func (api *API) OrganizationIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.D
 // @Tags         Organizations
 // @Accept       json
 // @Produce      json
+// @Param		 gt_revision     query  uint64 false "greater than revision"
 // @Success      200  {object}  []models.Organization
 // @Failure		 401  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
@@ -192,22 +282,116 @@ func (api *API) OrganizationIsOwnedByCurrentUser(c *gin.Context) func(db *gorm.D
 func (api *API) ListOrganizations(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizations")
 	defer span.End()
-	var orgs []models.Organization
-	result := api.db.WithContext(ctx).
-		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
-		Scopes(FilterAndPaginate(&models.Organization{}, c, "name")).
-		Find(&orgs)
 
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
-		} else {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+	gtRevision := uint64(0)
+	if v := c.Query("gt_revision"); v != "" {
+		gtRevision, _ = strconv.ParseUint(v, 10, 0)
+	}
+
+	includeDeleted := false
+
+	getList := func(scope func(db *gorm.DB) *gorm.DB) ([]*models.Organization, error) {
+		orgs := make([]*models.Organization, 0)
+
+		db := api.db.WithContext(ctx)
+		if includeDeleted {
+			db = db.Unscoped()
 		}
-		return
+		db = db.Scopes(api.OrganizationIsReadableByCurrentUser(c)).Scopes(scope)
+		if gtRevision != 0 {
+			db = db.Where("revision > ?", gtRevision)
+		}
+
+		result := db.Find(&orgs)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, result.Error
+		}
+		return orgs, nil
 	}
 
-	c.JSON(http.StatusOK, orgs)
+	if v := c.Query("watch"); v == "true" {
+		includeDeleted = true
+		byRevision := func(db *gorm.DB) *gorm.DB {
+			return db.Order("revision")
+		}
+		sub := api.signalBus.Subscribe(organizationsSignal(c.Value(gin.AuthUserKey).(string)))
+		defer sub.Close()
+
+		idx := 0
+		var list []*models.Organization
+		var err error
+		bookmarkSent := false
+
+		c.Header("Content-Type", "application/json;stream=watch")
+		c.Status(http.StatusOK)
+		stream(c, func() models.WatchEvent {
+			// This function blocks until there is an event to return...
+			for {
+				if err != nil {
+					return models.WatchEvent{
+						Type:  "error",
+						Value: err.Error(),
+					}
+				}
+				if idx < len(list) {
+					result := list[idx]
+					gtRevision = result.Revision
+					idx += 1
+
+					if result.DeletedAt.Valid {
+						return models.WatchEvent{
+							Type:  "delete",
+							Value: result,
+						}
+					} else {
+						return models.WatchEvent{
+							Type:  "change",
+							Value: result,
+						}
+					}
+				} else {
+
+					// get the next list...
+					list, err = getList(byRevision)
+					if err != nil {
+						return models.WatchEvent{
+							Type:  "error",
+							Value: err.Error(),
+						}
+					}
+					idx = 0
+
+					// did we run out of items to send?
+					if len(list) == 0 {
+
+						if !bookmarkSent {
+							bookmarkSent = true
+							return models.WatchEvent{
+								Type: "bookmark",
+							}
+						}
+
+						// Wait for some items to come into the list
+						if waitForCancelOrTimeoutOrNotification(ctx, 30*time.Second, sub) {
+							// ctx was canceled... likely due to the http connection being closed by
+							// the client.  Signal the event stream is done.
+							return models.WatchEvent{
+								Type: "close",
+							}
+						}
+					}
+				}
+			}
+		})
+
+	} else {
+		orgs, err := getList(FilterAndPaginate(&models.Organization{}, c, "name"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
+		c.JSON(http.StatusOK, orgs)
+	}
 }
 
 // GetOrganizations gets a specific Organization
@@ -252,6 +436,114 @@ func (api *API) GetOrganizations(c *gin.Context) {
 	c.JSON(http.StatusOK, org)
 }
 
+// UpdateOrganization updates an Organization
+// @Summary      Update Organization
+// @Description  Updates the name, description or hub zone of an organization, requires the admin role
+// @Id 			 UpdateOrganization
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        update  body   models.UpdateOrganization  true "Organization Update"
+// @Success      200  {object}  models.Organization
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure      409  {object}  models.ConflictsError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id} [patch]
+func (api *API) UpdateOrganization(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "UpdateOrganization",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.UpdateOrganization
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.Name != nil && *request.Name == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("name", "must not be empty"))
+		return
+	}
+
+	var org models.Organization
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return api.organizationRoleError(tx, c, orgId)
+			}
+			return res.Error
+		}
+		if request.Revision != nil && *request.Revision != org.Revision {
+			return errRevisionConflict
+		}
+
+		updates := map[string]interface{}{}
+		if request.Name != nil {
+			updates["name"] = *request.Name
+		}
+		if request.Description != nil {
+			updates["description"] = *request.Description
+		}
+		if request.HubZone != nil {
+			updates["hub_zone"] = *request.HubZone
+		}
+		if request.ApprovalRequired != nil {
+			updates["approval_required"] = *request.ApprovalRequired
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		before := org
+
+		// only update the organization if nobody else did since it was read
+		res := tx.Model(&org).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Where("revision = ?", org.Revision).
+			Updates(updates)
+		if res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrDuplicatedKey) {
+				return errDuplicateOrganization{ID: org.ID.String()}
+			}
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errRevisionConflict
+		}
+		return api.recordAuditEvent(ctx, c, tx, org.ID, "organization", org.ID.String(), auditActionUpdate, before, org)
+	})
+
+	if err != nil {
+		var duplicate errDuplicateOrganization
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		} else if errors.Is(err, errRevisionConflict) {
+			c.JSON(http.StatusConflict, models.NewRevisionConflictError(orgId.String()))
+		} else if errors.As(err, &duplicate) {
+			c.JSON(http.StatusConflict, models.NewConflictsError(duplicate.ID))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	api.notifyOrganizationChanged(ctx, org)
+	c.JSON(http.StatusOK, org)
+}
+
 // ListDevicesInOrganization lists all devices in an Organization
 // @Summary      List Devices
 // @Description  Lists all devices for this Organization
@@ -260,6 +552,7 @@ func (api *API) GetOrganizations(c *gin.Context) {
 // @Accept       json
 // @Produce      json
 // @Param		 gt_revision     query  uint64 false "greater than revision"
+// @Param		 labelSelector   query  string false "label selector, for example env=prod,role!=db"
 // @Param		 organization_id path   string true "Organization ID"
 // @Success      200  {object}  []models.Device
 // @Failure      400  {object}  models.BaseError
@@ -298,12 +591,21 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 	}
 	defaultOrderBy := "hostname"
 
+	selector, err := labelSelectorFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("labelSelector", err.Error()))
+		return
+	}
+
 	gtRevision := uint64(0)
 	if v := c.Query("gt_revision"); v != "" {
 		gtRevision, _ = strconv.ParseUint(v, 10, 0)
 	}
 
 	includeDeleted := false
+	watchPeers := false
+	// the watches match the labels once they get the devices, to tell the devices that stop matching
+	matchLabels := true
 
 	getList := func() ([]*models.Device, error) {
 		var devices []*models.Device
@@ -314,10 +616,17 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 		}
 		db = db.Scopes(FilterAndPaginateWithQuery(&models.Device{}, c, query, defaultOrderBy)).
 			Where("organization_id = ?", k.String())
+		if matchLabels {
+			db = db.Scopes(api.DeviceMatchesLabelSelector(selector))
+		}
 
 		if gtRevision != 0 {
 			db = db.Where("revision > ?", gtRevision)
 		}
+		if watchPeers {
+			// the devices waiting for approval do not join the mesh yet
+			db = db.Where("pending = ?", false)
+		}
 
 		result = db.Find(&devices)
 
@@ -331,12 +640,16 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 		query.Sort = ""
 		defaultOrderBy = "revision"
 		includeDeleted = true
+		watchPeers = true
+		matchLabels = false
 		sub := api.signalBus.Subscribe(fmt.Sprintf("/devices/org=%s", k.String()))
 		defer sub.Close()
 
 		idx := 1
 		var list []*models.Device
 		bookmarkSent := false
+		// the devices sent to the watch that match the label selector
+		sent := map[uuid.UUID]bool{}
 
 		c.Header("Content-Type", "application/json;stream=watch")
 		c.Status(http.StatusOK)
@@ -355,11 +668,23 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 					idx += 1
 
 					if result.DeletedAt.Valid {
+						delete(sent, result.ID)
+						return models.WatchEvent{
+							Type:  "delete",
+							Value: result,
+						}
+					} else if !labelsMatchSelector(result.Labels, selector) {
+						// the device no longer matches the label selector, the watch drops it
+						if !sent[result.ID] {
+							continue
+						}
+						delete(sent, result.ID)
 						return models.WatchEvent{
 							Type:  "delete",
 							Value: result,
 						}
 					} else {
+						sent[result.ID] = true
 						return models.WatchEvent{
 							Type:  "change",
 							Value: result,
@@ -407,6 +732,11 @@ func (api *API) ListDevicesInOrganization(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 			return
 		}
+		// the watch events leave out the status so that the status reports do not change the devices
+		if err := api.populateDeviceStatus(ctx, devices...); err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
 
 		// For pagination
 		c.Header("Access-Control-Expose-Headers", TotalCountHeader)
@@ -476,6 +806,10 @@ func (api *API) GetDeviceInOrganization(c *gin.Context) {
 		}
 		return
 	}
+	if err := api.populateDeviceStatus(ctx, &device); err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
 	c.JSON(http.StatusOK, device)
 }
 
@@ -533,9 +867,286 @@ func (api *API) ListUsersInOrganization(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
+func isOrganizationRole(role string) bool {
+	for _, r := range models.OrganizationRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ListOrganizationRoles lists the roles of the users in an organization
+// @Summary      List Organization Roles
+// @Description  Lists the role of every user in the organization
+// @Id 			 ListOrganizationRoles
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Success      200  {object}  []models.UserOrganization
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/roles [get]
+func (api *API) ListOrganizationRoles(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListOrganizationRoles",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	k, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	var org models.Organization
+	result := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", k.String())
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+		}
+		return
+	}
+
+	roles := make([]models.UserOrganization, 0)
+	result = api.db.WithContext(ctx).
+		Where("organization_id = ?", org.ID).
+		Order("user_id").
+		Find(&roles)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(result.Error))
+		return
+	}
+	c.JSON(http.StatusOK, roles)
+}
+
+// UpdateOrganizationRole changes the role of a user in an organization
+// @Summary      Update Organization Role
+// @Description  Changes the role of a user in the organization, requires the admin role
+// @Id 			 UpdateOrganizationRole
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        user_id           path      string  true "User ID"
+// @Param        update  body   models.UpdateUserOrganization  true "Role Update"
+// @Success      200  {object}  models.UserOrganization
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/roles/{user_id} [patch]
+func (api *API) UpdateOrganizationRole(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "UpdateOrganizationRole",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+			attribute.String("id", c.Param("id")),
+		))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	userId := c.Param("id")
+
+	var request models.UpdateUserOrganization
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if !isOrganizationRole(request.Role) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("role", fmt.Sprintf("must be one of %s", strings.Join(models.OrganizationRoles, ", "))))
+		return
+	}
+
+	var membership models.UserOrganization
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		var org models.Organization
+		if res := tx.Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return api.organizationRoleError(tx, c, orgId)
+			}
+			return res.Error
+		}
+		if org.OwnerID == userId {
+			return errOrgOwnerNotAllowed
+		}
+		if res := tx.First(&membership, "organization_id = ? AND user_id = ?", orgId, userId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errUserNotFound
+			}
+			return res.Error
+		}
+		before := membership
+		membership.Role = request.Role
+		if res := tx.Model(&membership).
+			Where("organization_id = ? AND user_id = ?", orgId, userId).
+			Update("role", request.Role); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, orgId, "organization_role", userId, auditActionUpdate, before, membership)
+	})
+
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
+		} else if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) || errors.Is(err, errOrgOwnerNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+	c.JSON(http.StatusOK, membership)
+}
+
+// TransferOrganization transfers the ownership of an organization to one of its members
+// @Summary      Transfer Organization
+// @Description  Transfers the ownership of an organization to one of its members, only the owner can transfer an organization
+// @Id 			 TransferOrganization
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        transfer  body   models.TransferOrganization  true "Ownership Transfer"
+// @Success      200  {object}  models.Organization
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/transfer [post]
+func (api *API) TransferOrganization(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "TransferOrganization",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.TransferOrganization
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.NewOwnerId == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("new_owner_id"))
+		return
+	}
+
+	var org models.Organization
+	var previousOwner string
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		// the admins can not take over the organization, only its owner can hand it over
+		if res := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return api.organizationRoleError(tx, c, orgId)
+			}
+			return res.Error
+		}
+		if org.OwnerID == request.NewOwnerId {
+			return errOrgAlreadyOwner
+		}
+		var membership models.UserOrganization
+		if res := tx.First(&membership, "organization_id = ? AND user_id = ?", orgId, request.NewOwnerId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errUserNotFound
+			}
+			return res.Error
+		}
+
+		previousOwner = org.OwnerID
+		before := org
+		if res := tx.Model(&org).Update("owner_id", request.NewOwnerId); res.Error != nil {
+			return res.Error
+		}
+		org.OwnerID = request.NewOwnerId
+		if err := api.recordAuditEvent(ctx, c, tx, org.ID, "organization", org.ID.String(), auditActionTransfer, before, org); err != nil {
+			return err
+		}
+
+		// the owner is always an admin of the organization
+		membershipBefore := membership
+		membership.Role = models.OrganizationRoleAdmin
+		if res := tx.Model(&membership).
+			Where("organization_id = ? AND user_id = ?", orgId, request.NewOwnerId).
+			Update("role", models.OrganizationRoleAdmin); res.Error != nil {
+			return res.Error
+		}
+		if err := api.recordAuditEvent(ctx, c, tx, orgId, "organization_role", request.NewOwnerId, auditActionUpdate, membershipBefore, membership); err != nil {
+			return err
+		}
+
+		var previous models.UserOrganization
+		if res := tx.First(&previous, "organization_id = ? AND user_id = ?", orgId, previousOwner); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return res.Error
+		}
+		previousBefore := previous
+		scope := tx.Where("organization_id = ? AND user_id = ?", orgId, previousOwner)
+		if request.RemovePreviousOwner {
+			if err := scope.Delete(&models.UserOrganization{}).Error; err != nil {
+				return err
+			}
+			return api.recordAuditEvent(ctx, c, tx, orgId, "organization_role", previousOwner, auditActionDelete, previousBefore, nil)
+		}
+		previous.Role = models.OrganizationRoleMember
+		if err := scope.Model(&models.UserOrganization{}).Update("role", models.OrganizationRoleMember).Error; err != nil {
+			return err
+		}
+		return api.recordAuditEvent(ctx, c, tx, orgId, "organization_role", previousOwner, auditActionUpdate, previousBefore, previous)
+	})
+
+	if err != nil {
+		if errors.Is(err, errUserNotFound) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("new_owner_id", "must be a member of the organization"))
+		} else if errors.Is(err, errOrgAlreadyOwner) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("new_owner_id", err.Error()))
+		} else if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	api.Logger(ctx).Infow("organization ownership transferred",
+		"organization", org.ID,
+		"previous_owner", previousOwner,
+		"new_owner", request.NewOwnerId,
+		"previous_owner_removed", request.RemovePreviousOwner,
+		"transferred_by", c.GetString(gin.AuthUserKey),
+	)
+	api.notifyOrganizationChanged(ctx, org, previousOwner)
+	c.JSON(http.StatusOK, org)
+}
+
 // DeleteOrganization handles deleting an existing organization and associated ipam prefix
 // @Summary      Delete Organization
-// @Description  Deletes an existing organization and associated IPAM prefix
+// @Description  Deletes an existing organization and associated IPAM prefix, only the owner can delete an organization
 // @Id 			 DeleteOrganization
 // @Tags         Organizations
 // @Accept       json
@@ -554,7 +1165,7 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 			attribute.String("id", c.Param("id")),
 		))
 	defer span.End()
-	multiOrganizationEnabled, err := api.fflags.GetFlag("multi-organization")
+	multiOrganizationEnabled, err := api.fflags.GetFlag(ctx, api.flagSubject(c), "multi-organization")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
@@ -572,7 +1183,7 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 
 	var org models.Organization
 	result := api.db.WithContext(ctx).
-		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
 		First(&org, "id = ?", orgID)
 
 	if result.Error != nil {
@@ -594,8 +1205,21 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 		return
 	}
 
-	if res := api.db.Select(clause.Associations).Delete(&org); res.Error != nil {
-		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to delete the organization: %w", err)))
+	// the memberships are deleted with the organization
+	userIds, err := organizationUserIds(api.db.WithContext(ctx), org)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Select(clause.Associations).Delete(&org); res.Error != nil {
+			return fmt.Errorf("failed to delete the organization: %w", res.Error)
+		}
+		return api.recordAuditEvent(ctx, c, tx, org.ID, "organization", org.ID.String(), auditActionDelete, org, nil)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return
 	}
 
@@ -607,5 +1231,14 @@ func (api *API) DeleteOrganization(c *gin.Context) {
 			return
 		}
 	}
+
+	// the organization may be deleted during the grace period of a re-addressing
+	if org.PreviousIpCidr != "" {
+		if err := api.ipam.ReleasePrefix(c.Request.Context(), org.ID, org.PreviousIpCidr); err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to release the previous ipam organization prefix: %w", err)))
+			return
+		}
+	}
+	api.notifyOrganizationWatchers(userIds...)
 	c.JSON(http.StatusOK, org)
 }
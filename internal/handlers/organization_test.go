@@ -2,9 +2,14 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nexodus-io/nexodus/internal/models"
@@ -163,3 +168,445 @@ func (suite *HandlerTestSuite) TestListOrganizations() {
 	}
 
 }
+
+func (suite *HandlerTestSuite) TestOrganizationRoles() {
+	require := suite.Require()
+
+	// serve runs the handler as the given user
+	serve := func(userId, method, path, uri string, handler func(*gin.Context), body interface{}) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			reqBody, err := json.Marshal(body)
+			require.NoError(err)
+			reader = bytes.NewBuffer(reqBody)
+		}
+		_, res, err := suite.ServeRequest(method, path, uri, func(c *gin.Context) {
+			c.Set(gin.AuthUserKey, userId)
+			c.Set("_apex.testCreateOrganization", "true")
+			handler(c)
+		}, reader)
+		require.NoError(err)
+		return res
+	}
+	setRole := func(userId, role string) *httptest.ResponseRecorder {
+		return serve(TestUser2ID, http.MethodPatch,
+			"/organizations/:organization/roles/:id", fmt.Sprintf("/organizations/%s/roles/%s", suite.testUser2OrgID, userId),
+			suite.api.UpdateOrganizationRole, models.UpdateUserOrganization{Role: role})
+	}
+
+	require.NoError(suite.api.db.Create(&models.UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: suite.testUser2OrgID,
+	}).Error)
+
+	res := serve(TestUserID, http.MethodGet,
+		"/organizations/:organization/roles", fmt.Sprintf("/organizations/%s/roles", suite.testUser2OrgID),
+		suite.api.ListOrganizationRoles, nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var roles []models.UserOrganization
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &roles))
+	require.ElementsMatch([]models.UserOrganization{
+		{UserID: TestUser2ID, OrganizationID: suite.testUser2OrgID, Role: models.OrganizationRoleAdmin},
+		{UserID: TestUserID, OrganizationID: suite.testUser2OrgID, Role: models.OrganizationRoleMember},
+	}, roles)
+
+	// members can not change roles
+	res = serve(TestUserID, http.MethodPatch,
+		"/organizations/:organization/roles/:id", fmt.Sprintf("/organizations/%s/roles/%s", suite.testUser2OrgID, TestUserID),
+		suite.api.UpdateOrganizationRole, models.UpdateUserOrganization{Role: models.OrganizationRoleAdmin})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = setRole(TestUserID, "owner")
+	require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+	res = setRole(TestUser2ID, models.OrganizationRoleMember)
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = setRole(TestUserID, models.OrganizationRoleReadOnly)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	// read-only members can not register devices or invite users
+	res = serve(TestUserID, http.MethodPost, "/devices", "/devices", suite.api.CreateDevice, models.AddDevice{
+		OrganizationID: suite.testUser2OrgID,
+		PublicKey:      "readonlypubkey",
+	})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+	res = serve(TestUserID, http.MethodPost, "/invitations", "/invitations", suite.api.CreateInvitation, models.AddInvitation{
+		UserID:         TestUser2ID,
+		OrganizationID: suite.testUser2OrgID,
+	})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	// only admins can delete the devices of other users
+	res = serve(TestUser2ID, http.MethodPost, "/devices", "/devices", suite.api.CreateDevice, models.AddDevice{
+		OrganizationID: suite.testUser2OrgID,
+		PublicKey:      "user2pubkey",
+	})
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+
+	res = serve(TestUserID, http.MethodDelete, "/devices/:id", "/devices/"+device.ID.String(), suite.api.DeleteDevice, nil)
+	require.Equal(http.StatusNotFound, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = setRole(TestUserID, models.OrganizationRoleAdmin)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = serve(TestUserID, http.MethodDelete, "/devices/:id", "/devices/"+device.ID.String(), suite.api.DeleteDevice, nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	// the owner can not be removed, the other users can leave
+	res = serve(TestUserID, http.MethodDelete,
+		"/users/:id/organizations/:organization", fmt.Sprintf("/users/%s/organizations/%s", TestUser2ID, suite.testUser2OrgID),
+		suite.api.DeleteUserFromOrganization, nil)
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = serve(TestUserID, http.MethodDelete,
+		"/users/:id/organizations/:organization", fmt.Sprintf("/users/%s/organizations/%s", TestUserID, suite.testUser2OrgID),
+		suite.api.DeleteUserFromOrganization, nil)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = serve(TestUserID, http.MethodGet,
+		"/organizations/:organization/roles", fmt.Sprintf("/organizations/%s/roles", suite.testUser2OrgID),
+		suite.api.ListOrganizationRoles, nil)
+	require.Equal(http.StatusNotFound, res.Code, "HTTP error: %s", res.Body.String())
+}
+
+func (suite *HandlerTestSuite) TestTransferOrganization() {
+	require := suite.Require()
+
+	serve := func(userId, method, path, uri string, handler func(*gin.Context), body interface{}) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != nil {
+			reqBody, err := json.Marshal(body)
+			require.NoError(err)
+			reader = bytes.NewBuffer(reqBody)
+		}
+		_, res, err := suite.ServeRequest(method, path, uri, func(c *gin.Context) {
+			c.Set(gin.AuthUserKey, userId)
+			handler(c)
+		}, reader)
+		require.NoError(err)
+		return res
+	}
+
+	org := models.Organization{
+		OwnerID:  TestUser2ID,
+		Name:     "transfer-org",
+		IpCidr:   "10.42.0.0/24",
+		IpCidrV6: "0300::/64",
+	}
+	require.NoError(suite.api.db.Create(&org).Error)
+	require.NoError(suite.api.db.Create(&models.UserOrganization{
+		UserID:         TestUser2ID,
+		OrganizationID: org.ID,
+		Role:           models.OrganizationRoleAdmin,
+	}).Error)
+	require.NoError(suite.api.db.Create(&models.UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: org.ID,
+		Role:           models.OrganizationRoleMember,
+	}).Error)
+
+	transfer := func(userId string, request models.TransferOrganization) *httptest.ResponseRecorder {
+		return serve(userId, http.MethodPost,
+			"/organizations/:organization/transfer", fmt.Sprintf("/organizations/%s/transfer", org.ID),
+			suite.api.TransferOrganization, request)
+	}
+
+	// members can not transfer the organization
+	res := transfer(TestUserID, models.TransferOrganization{NewOwnerId: TestUserID})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	// the new owner must be a member
+	res = transfer(TestUser2ID, models.TransferOrganization{NewOwnerId: "not-a-member"})
+	require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+
+	// the owner can not leave before transferring the organization
+	res = serve(TestUser2ID, http.MethodDelete,
+		"/users/:id/organizations/:organization", fmt.Sprintf("/users/%s/organizations/%s", TestUser2ID, org.ID),
+		suite.api.DeleteUserFromOrganization, nil)
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = transfer(TestUser2ID, models.TransferOrganization{NewOwnerId: TestUserID})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var actual models.Organization
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &actual))
+	require.Equal(TestUserID, actual.OwnerID)
+
+	var roles []models.UserOrganization
+	require.NoError(suite.api.db.Order("user_id").Find(&roles, "organization_id = ?", org.ID).Error)
+	require.ElementsMatch([]models.UserOrganization{
+		{UserID: TestUserID, OrganizationID: org.ID, Role: models.OrganizationRoleAdmin},
+		{UserID: TestUser2ID, OrganizationID: org.ID, Role: models.OrganizationRoleMember},
+	}, roles)
+
+	// the new owner can not be deleted while other users are in the organization
+	res = serve(TestUserID, http.MethodDelete, "/users/:id", "/users/"+TestUserID, suite.api.DeleteUser, nil)
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	// an admin that is not the owner can not take the organization over and remove its owner
+	require.NoError(suite.api.db.Model(&models.UserOrganization{}).
+		Where("organization_id = ? AND user_id = ?", org.ID, TestUser2ID).
+		Update("role", models.OrganizationRoleAdmin).Error)
+	res = transfer(TestUser2ID, models.TransferOrganization{NewOwnerId: TestUser2ID, RemovePreviousOwner: true})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	res = transfer(TestUserID, models.TransferOrganization{NewOwnerId: TestUser2ID, RemovePreviousOwner: true})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	roles = nil
+	require.NoError(suite.api.db.Find(&roles, "organization_id = ?", org.ID).Error)
+	require.Equal([]models.UserOrganization{
+		{UserID: TestUser2ID, OrganizationID: org.ID, Role: models.OrganizationRoleAdmin},
+	}, roles)
+
+	// the removal of the previous owner is audited with the transfer
+	var events []models.AuditEvent
+	require.NoError(suite.api.db.Find(&events, "organization_id = ? AND resource_type = ? AND resource_id = ? AND action = ?",
+		org.ID, "organization_role", TestUserID, auditActionDelete).Error)
+	require.Len(events, 1)
+	require.Equal(TestUserID, events[0].ActorID)
+}
+
+func (suite *HandlerTestSuite) TestUpdateOrganization() {
+	require := suite.Require()
+
+	org := models.Organization{
+		OwnerID:  TestUser2ID,
+		Name:     "update-org",
+		IpCidr:   "10.43.0.0/24",
+		IpCidrV6: "0301::/64",
+	}
+	require.NoError(suite.api.db.Create(&org).Error)
+	require.NoError(suite.api.db.Create(&models.UserOrganization{
+		UserID:         TestUserID,
+		OrganizationID: org.ID,
+		Role:           models.OrganizationRoleMember,
+	}).Error)
+
+	update := func(userId string, request models.UpdateOrganization) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPatch,
+			"/organizations/:organization", fmt.Sprintf("/organizations/%s", org.ID),
+			func(c *gin.Context) {
+				c.Set(gin.AuthUserKey, userId)
+				suite.api.UpdateOrganization(c)
+			}, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		return res
+	}
+
+	description := "The Blue Zone"
+	hubZone := true
+	res := update(TestUserID, models.UpdateOrganization{Description: &description})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	empty := ""
+	res = update(TestUser2ID, models.UpdateOrganization{Name: &empty})
+	require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+
+	stale := org.Revision + 1
+	res = update(TestUser2ID, models.UpdateOrganization{Description: &description, Revision: &stale})
+	require.Equal(http.StatusConflict, res.Code, "HTTP error: %s", res.Body.String())
+
+	// only the watches of the users of the organization wake up
+	memberSub := suite.api.signalBus.Subscribe(organizationsSignal(TestUserID))
+	defer memberSub.Close()
+	outsiderSub := suite.api.signalBus.Subscribe(organizationsSignal("outsider"))
+	defer outsiderSub.Close()
+
+	res = update(TestUser2ID, models.UpdateOrganization{Description: &description, HubZone: &hubZone, Revision: &org.Revision})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.True(memberSub.IsSignaled())
+	require.False(outsiderSub.IsSignaled())
+	var actual models.Organization
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &actual))
+	require.Equal("update-org", actual.Name)
+	require.Equal(description, actual.Description)
+	require.True(actual.HubZone)
+
+	var stored models.Organization
+	require.NoError(suite.api.db.First(&stored, "id = ?", org.ID).Error)
+	require.Equal(description, stored.Description)
+	require.True(stored.HubZone)
+}
+
+func (suite *HandlerTestSuite) TestReaddressOrganization() {
+	require := suite.Require()
+
+	reqBody, err := json.Marshal(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "readdresspubkey",
+	})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+
+	readdress := func(request models.ReaddressOrganization) *httptest.ResponseRecorder {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost,
+			"/organizations/:organization/readdress", fmt.Sprintf("/organizations/%s/readdress", suite.testOrganizationID),
+			suite.api.ReaddressOrganization, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		return res
+	}
+
+	res = readdress(models.ReaddressOrganization{IpCidr: defaultOrganizationPrefixIPv4})
+	require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+	res = readdress(models.ReaddressOrganization{IpCidr: "10.44.0.0/24", GracePeriod: "-1h"})
+	require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+
+	// the new prefix can not overlap the child prefix of a device
+	router := models.Device{
+		OrganizationID: suite.testOrganizationID,
+		UserID:         TestUserID,
+		PublicKey:      "readdressrouterkey",
+		ChildPrefix:    []string{"10.47.1.0/24"},
+	}
+	require.NoError(suite.api.db.Create(&router).Error)
+	res = readdress(models.ReaddressOrganization{IpCidr: "10.47.0.0/16"})
+	require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(suite.api.db.Delete(&router).Error)
+
+	// a prefix too small for the devices fails, the addresses assigned until then are released
+	for i := 0; i < 3; i++ {
+		require.NoError(suite.api.db.Create(&models.Device{
+			OrganizationID: suite.testOrganizationID,
+			UserID:         TestUserID,
+			PublicKey:      fmt.Sprintf("readdressfillerkey%d", i),
+		}).Error)
+	}
+	res = readdress(models.ReaddressOrganization{IpCidr: "10.46.0.0/30"})
+	require.Equal(http.StatusInternalServerError, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(suite.api.ipam.AssignPrefix(context.Background(), suite.testOrganizationID, "10.46.0.0/30"))
+	for i := 0; i < 2; i++ {
+		_, err := suite.api.ipam.AssignFromPool(context.Background(), suite.testOrganizationID, "10.46.0.0/30")
+		require.NoError(err)
+	}
+	require.NoError(suite.api.db.Where("public_key LIKE ?", "readdressfillerkey%").Delete(&models.Device{}).Error)
+
+	res = readdress(models.ReaddressOrganization{IpCidr: "10.44.0.0/24", GracePeriod: "30m"})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var org models.Organization
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &org))
+	require.Equal("10.44.0.0/24", org.IpCidr)
+	require.Equal(defaultOrganizationPrefixIPv4, org.PreviousIpCidr)
+	require.Equal(defaultOrganizationPrefixIPv6, org.IpCidrV6)
+	require.Empty(org.PreviousIpCidrV6)
+	require.NotNil(org.ReaddressDeadline)
+
+	// the device keeps its previous address during the grace period
+	var readdressed models.Device
+	require.NoError(suite.api.db.First(&readdressed, "id = ?", device.ID).Error)
+	require.True(netip.MustParsePrefix("10.44.0.0/24").Contains(netip.MustParseAddr(readdressed.TunnelIP)))
+	require.Equal(device.TunnelIP, readdressed.PreviousTunnelIP)
+	require.Equal(device.TunnelIpV6, readdressed.TunnelIpV6)
+	require.Empty(readdressed.PreviousTunnelIpV6)
+	require.ElementsMatch([]string{readdressed.TunnelIP + "/32", readdressed.TunnelIpV6 + "/128", device.TunnelIP + "/32"}, readdressed.AllowedIPs)
+
+	res = readdress(models.ReaddressOrganization{IpCidr: "10.45.0.0/24"})
+	require.Equal(http.StatusForbidden, res.Code, "HTTP error: %s", res.Body.String())
+
+	// nothing happens before the end of the grace period
+	require.NoError(suite.api.completeReaddressing(context.Background(), time.Now()))
+	require.NoError(suite.api.db.First(&readdressed, "id = ?", device.ID).Error)
+	require.Equal(device.TunnelIP, readdressed.PreviousTunnelIP)
+
+	require.NoError(suite.api.completeReaddressing(context.Background(), time.Now().Add(time.Hour)))
+	require.NoError(suite.api.db.First(&readdressed, "id = ?", device.ID).Error)
+	require.Empty(readdressed.PreviousTunnelIP)
+	require.ElementsMatch([]string{readdressed.TunnelIP + "/32", readdressed.TunnelIpV6 + "/128"}, readdressed.AllowedIPs)
+	var stored models.Organization
+	require.NoError(suite.api.db.First(&stored, "id = ?", suite.testOrganizationID).Error)
+	require.Equal("10.44.0.0/24", stored.IpCidr)
+	require.Empty(stored.PreviousIpCidr)
+	require.Nil(stored.ReaddressDeadline)
+
+	// an IPv6 only re-addressing keeps the IPv4 addresses
+	device = readdressed
+	res = readdress(models.ReaddressOrganization{IpCidrV6: "300::/64"})
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &org))
+	require.Equal("10.44.0.0/24", org.IpCidr)
+	require.Empty(org.PreviousIpCidr)
+	require.Equal("300::/64", org.IpCidrV6)
+	require.Equal(defaultOrganizationPrefixIPv6, org.PreviousIpCidrV6)
+	require.NoError(suite.api.db.First(&readdressed, "id = ?", device.ID).Error)
+	require.Equal(device.TunnelIP, readdressed.TunnelIP)
+	require.Empty(readdressed.PreviousTunnelIP)
+	require.True(netip.MustParsePrefix("300::/64").Contains(netip.MustParseAddr(readdressed.TunnelIpV6)))
+	require.Equal(device.TunnelIpV6, readdressed.PreviousTunnelIpV6)
+	require.ElementsMatch([]string{readdressed.TunnelIP + "/32", readdressed.TunnelIpV6 + "/128", device.TunnelIpV6 + "/128"}, readdressed.AllowedIPs)
+
+	require.NoError(suite.api.completeReaddressing(context.Background(), time.Now().Add(time.Hour)))
+	require.NoError(suite.api.db.First(&readdressed, "id = ?", device.ID).Error)
+	require.Empty(readdressed.PreviousTunnelIpV6)
+	require.ElementsMatch([]string{readdressed.TunnelIP + "/32", readdressed.TunnelIpV6 + "/128"}, readdressed.AllowedIPs)
+	require.NoError(suite.api.db.First(&stored, "id = ?", suite.testOrganizationID).Error)
+	require.Equal("300::/64", stored.IpCidrV6)
+	require.Empty(stored.PreviousIpCidrV6)
+	require.Nil(stored.ReaddressDeadline)
+	// the previous prefix was given back to ipam, it can be assigned again
+	require.NoError(suite.api.ipam.AssignPrefix(context.Background(), suite.testOrganizationID, defaultOrganizationPrefixIPv6))
+	require.NoError(suite.api.ipam.ReleasePrefix(context.Background(), suite.testOrganizationID, defaultOrganizationPrefixIPv6))
+}
+
+func (suite *HandlerTestSuite) TestGetOrganizationConnectivity() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	node1 := models.Device{OrganizationID: suite.testOrganizationID, UserID: TestUserID, Hostname: "node1", PublicKey: "node1pubkey"}
+	node2 := models.Device{OrganizationID: suite.testOrganizationID, UserID: TestUserID, Hostname: "node2", PublicKey: "node2pubkey"}
+	node3 := models.Device{OrganizationID: suite.testOrganizationID, UserID: TestUserID, Hostname: "node3", PublicKey: "node3pubkey"}
+	for _, device := range []*models.Device{&node1, &node2, &node3} {
+		require.NoError(suite.api.db.Create(device).Error)
+	}
+	require.NoError(suite.api.db.Create(&models.DeviceStatus{
+		DeviceID: node1.ID,
+		LastSeen: time.Now(),
+		Peers: []models.PeerStatus{
+			{DeviceID: node2.ID, Reachable: true, Method: "local", LatencyMs: 0.5},
+			{DeviceID: node3.ID, Reachable: false, Method: "relay"},
+		},
+	}).Error)
+	// the report of an offline device is ignored
+	require.NoError(suite.api.db.Create(&models.DeviceStatus{
+		DeviceID: node2.ID,
+		LastSeen: time.Now().Add(-time.Hour),
+		Peers: []models.PeerStatus{
+			{DeviceID: node1.ID, Reachable: true, Method: "local"},
+		},
+	}).Error)
+
+	_, res, err := suite.ServeRequest(http.MethodGet,
+		"/organizations/:organization/connectivity", fmt.Sprintf("/organizations/%s/connectivity", suite.testOrganizationID),
+		suite.api.GetOrganizationConnectivity, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var matrix models.ConnectivityMatrix
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &matrix))
+
+	require.Len(matrix.Devices, 3)
+	assert.True(matrix.Devices[0].Online)
+	assert.False(matrix.Devices[1].Online)
+	assert.NotNil(matrix.Devices[1].LastSeen)
+	assert.Nil(matrix.Devices[2].LastSeen)
+
+	require.Len(matrix.Paths, 6)
+	paths := map[string]models.ConnectivityPath{}
+	for _, path := range matrix.Paths {
+		paths[path.Source.String()+">"+path.Destination.String()] = path
+	}
+	assert.Equal(models.ConnectivityPath{
+		Source: node1.ID, Destination: node2.ID, Method: "local", Status: models.ConnectivityReachable, LatencyMs: 0.5,
+	}, paths[node1.ID.String()+">"+node2.ID.String()])
+	assert.Equal(models.ConnectivityUnreachable, paths[node1.ID.String()+">"+node3.ID.String()].Status)
+	assert.Equal("relay", paths[node1.ID.String()+">"+node3.ID.String()].Method)
+	assert.Equal(models.ConnectivityUnknown, paths[node2.ID.String()+">"+node1.ID.String()].Status)
+	assert.Equal(models.ConnectivityUnknown, paths[node3.ID.String()+">"+node1.ID.String()].Status)
+}
@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultReaddressGracePeriod is how long the previous addresses of the devices stay valid
+	// when the grace period of a re-addressing is not specified
+	defaultReaddressGracePeriod = time.Hour
+	// readdressReapInterval is how often the organizations with an expired grace period are looked up
+	readdressReapInterval = time.Minute
+)
+
+var errOrgReaddressInProgress = errors.New("the organization is already being re-addressed")
+
+type errReaddressSamePrefix struct {
+	Field string
+}
+
+func (e errReaddressSamePrefix) Error() string {
+	return "must differ from the current prefix of the organization"
+}
+
+type errReaddressChildPrefix struct {
+	Field    string
+	Prefix   string
+	DeviceId uuid.UUID
+}
+
+func (e errReaddressChildPrefix) Error() string {
+	return fmt.Sprintf("must not overlap the child prefix %s of device %s", e.Prefix, e.DeviceId)
+}
+
+// ipamLease is an address assigned from a prefix of the organization
+type ipamLease struct {
+	address string
+	prefix  string
+}
+
+// ReaddressOrganization moves an organization to new prefixes
+// @Summary      Re-address Organization
+// @Description  Assigns new tunnel addresses to every device of the organization, the previous addresses stay valid during the grace period, requires the admin role
+// @Id 			 ReaddressOrganization
+// @Tags         Organizations
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        readdress  body   models.ReaddressOrganization  true "Organization Re-addressing"
+// @Success      200  {object}  models.Organization
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/readdress [post]
+func (api *API) ReaddressOrganization(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ReaddressOrganization",
+		trace.WithAttributes(
+			attribute.String("organization", c.Param("organization")),
+		))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.ReaddressOrganization
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.IpCidr == "" && request.IpCidrV6 == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("cidr"))
+		return
+	}
+	if request.IpCidr != "" && !util.IsIPv4Prefix(request.IpCidr) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("cidr", "must be a valid IPv4 prefix"))
+		return
+	}
+	if request.IpCidrV6 != "" && !util.IsIPv6Prefix(request.IpCidrV6) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("cidr_v6", "must be a valid IPv6 prefix"))
+		return
+	}
+	gracePeriod := defaultReaddressGracePeriod
+	if request.GracePeriod != "" {
+		gracePeriod, err = time.ParseDuration(request.GracePeriod)
+		if err != nil || gracePeriod < 0 {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("grace_period", "must be a valid duration that is not negative"))
+			return
+		}
+	}
+
+	var org models.Organization
+	// the ipam assignments are not rolled back with the transaction, they are released when it fails
+	var assignedPrefixes []string
+	var assignedLeases []ipamLease
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if res := tx.Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
+			First(&org, "id = ?", orgId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return api.organizationRoleError(tx, c, orgId)
+			}
+			return res.Error
+		}
+		if org.ReaddressDeadline != nil {
+			return errOrgReaddressInProgress
+		}
+		if request.IpCidr == org.IpCidr {
+			return errReaddressSamePrefix{Field: "cidr"}
+		}
+		if request.IpCidrV6 == org.IpCidrV6 {
+			return errReaddressSamePrefix{Field: "cidr_v6"}
+		}
+
+		var devices []models.Device
+		if res := tx.Where("organization_id = ?", org.ID).Find(&devices); res.Error != nil {
+			return res.Error
+		}
+		// the child prefixes of the devices share the ipam namespace of the organization
+		if err := checkReaddressChildPrefixes(devices, "cidr", request.IpCidr); err != nil {
+			return err
+		}
+		if err := checkReaddressChildPrefixes(devices, "cidr_v6", request.IpCidrV6); err != nil {
+			return err
+		}
+
+		newCidr, newCidrV6 := org.IpCidr, org.IpCidrV6
+		if request.IpCidr != "" {
+			if err := api.ipam.AssignPrefix(ctx, org.ID, request.IpCidr); err != nil {
+				return err
+			}
+			assignedPrefixes = append(assignedPrefixes, request.IpCidr)
+			newCidr = request.IpCidr
+		}
+		if request.IpCidrV6 != "" {
+			if err := api.ipam.AssignPrefix(ctx, org.ID, request.IpCidrV6); err != nil {
+				return err
+			}
+			assignedPrefixes = append(assignedPrefixes, request.IpCidrV6)
+			newCidrV6 = request.IpCidrV6
+		}
+
+		for _, device := range devices {
+			var err error
+			if newCidr != org.IpCidr {
+				device.PreviousTunnelIP = device.TunnelIP
+				device.TunnelIP, err = api.ipam.AssignFromPool(ctx, org.ID, newCidr)
+				if err != nil {
+					return fmt.Errorf("failed to request ipam address: %w", err)
+				}
+				assignedLeases = append(assignedLeases, ipamLease{address: device.TunnelIP, prefix: newCidr})
+				device.OrganizationPrefix = newCidr
+			}
+			if newCidrV6 != org.IpCidrV6 {
+				device.PreviousTunnelIpV6 = device.TunnelIpV6
+				device.TunnelIpV6, err = api.ipam.AssignFromPool(ctx, org.ID, newCidrV6)
+				if err != nil {
+					return fmt.Errorf("failed to request ipam v6 address: %w", err)
+				}
+				assignedLeases = append(assignedLeases, ipamLease{address: device.TunnelIpV6, prefix: newCidrV6})
+				device.OrganizationPrefixV6 = newCidrV6
+			}
+			device.AllowedIPs, err = deviceAllowedIPs(device)
+			if err != nil {
+				return err
+			}
+			if res := tx.
+				Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+				Save(&device); res.Error != nil {
+				return res.Error
+			}
+		}
+
+		before := org
+		deadline := time.Now().Add(gracePeriod)
+		if newCidr != org.IpCidr {
+			org.PreviousIpCidr = org.IpCidr
+			org.IpCidr = newCidr
+		}
+		if newCidrV6 != org.IpCidrV6 {
+			org.PreviousIpCidrV6 = org.IpCidrV6
+			org.IpCidrV6 = newCidrV6
+		}
+		org.ReaddressDeadline = &deadline
+		if res := tx.Model(&org).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Select("ip_cidr", "ip_cidr_v6", "previous_ip_cidr", "previous_ip_cidr_v6", "readdress_deadline").
+			Updates(&org); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, org.ID, "organization", org.ID.String(), auditActionReaddress, before, org)
+	})
+
+	if err != nil {
+		api.releaseIpamAssignments(ctx, orgId, assignedPrefixes, assignedLeases)
+		var samePrefix errReaddressSamePrefix
+		var childPrefix errReaddressChildPrefix
+		if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) || errors.Is(err, errOrgReaddressInProgress) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		} else if errors.As(err, &samePrefix) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(samePrefix.Field, samePrefix.Error()))
+		} else if errors.As(err, &childPrefix) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(childPrefix.Field, childPrefix.Error()))
+		} else {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		}
+		return
+	}
+
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", org.ID.String()))
+	api.notifyOrganizationChanged(ctx, org)
+	api.Logger(ctx).Infow("organization re-addressing started",
+		"organization", org.ID, "cidr", org.IpCidr, "cidr_v6", org.IpCidrV6, "deadline", org.ReaddressDeadline)
+	c.JSON(http.StatusOK, org)
+}
+
+// checkReaddressChildPrefixes fails when the new prefix of the organization overlaps a child prefix of one of its devices
+func checkReaddressChildPrefixes(devices []models.Device, field, cidr string) error {
+	if cidr == "" {
+		return nil
+	}
+	newPrefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return err
+	}
+	for _, device := range devices {
+		for _, childPrefix := range device.ChildPrefix {
+			if util.IsDefaultIPRoute(childPrefix) {
+				continue
+			}
+			prefix, err := netip.ParsePrefix(childPrefix)
+			if err != nil {
+				continue
+			}
+			if prefix.Overlaps(newPrefix) {
+				return errReaddressChildPrefix{Field: field, Prefix: childPrefix, DeviceId: device.ID}
+			}
+		}
+	}
+	return nil
+}
+
+// releaseIpamAssignments gives back the addresses and prefixes assigned by a re-addressing that failed,
+// or the previous ones of a re-addressing that completed
+func (api *API) releaseIpamAssignments(ctx context.Context, orgId uuid.UUID, prefixes []string, leases []ipamLease) {
+	for _, lease := range leases {
+		if err := api.ipam.ReleaseToPool(ctx, orgId, lease.address, lease.prefix); err != nil {
+			api.Logger(ctx).Warnw("failed to release an address of a re-addressing",
+				"organization", orgId, "address", lease.address, "error", err)
+		}
+	}
+	for _, prefix := range prefixes {
+		if err := api.ipam.ReleasePrefix(ctx, orgId, prefix); err != nil {
+			api.Logger(ctx).Warnw("failed to release a prefix of a re-addressing",
+				"organization", orgId, "cidr", prefix, "error", err)
+		}
+	}
+}
+
+// deviceAllowedIPs returns the allowed ips of a device, including the addresses it had before the
+// re-addressing of its organization until the end of the grace period
+func deviceAllowedIPs(device models.Device) ([]string, error) {
+	allowedIPs, err := getAllowedIPs(device.TunnelIP, device.TunnelIpV6, device.Relay)
+	if err != nil {
+		return nil, err
+	}
+	// relays already advertise the whole organization prefixes
+	if device.Relay {
+		return allowedIPs, nil
+	}
+	if device.PreviousTunnelIP != "" {
+		prefix, err := util.AppendPrefixMask(device.PreviousTunnelIP, 32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to append a v4 prefix length to the previous IPAM address: %w", err)
+		}
+		allowedIPs = append(allowedIPs, prefix)
+	}
+	if device.PreviousTunnelIpV6 != "" {
+		prefix, err := util.AppendPrefixMask(device.PreviousTunnelIpV6, 128)
+		if err != nil {
+			return nil, fmt.Errorf("failed to append a v6 prefix length to the previous IPAM address: %w", err)
+		}
+		allowedIPs = append(allowedIPs, prefix)
+	}
+	return allowedIPs, nil
+}
+
+// releasePreviousTunnelIPs releases the addresses a device had before the re-addressing of its organization
+func (api *API) releasePreviousTunnelIPs(ctx context.Context, db *gorm.DB, device models.Device) error {
+	if device.PreviousTunnelIP == "" && device.PreviousTunnelIpV6 == "" {
+		return nil
+	}
+	var org models.Organization
+	if res := db.Select("id", "previous_ip_cidr", "previous_ip_cidr_v6").
+		First(&org, "id = ?", device.OrganizationID); res.Error != nil {
+		return res.Error
+	}
+	if device.PreviousTunnelIP != "" && org.PreviousIpCidr != "" {
+		if err := api.ipam.ReleaseToPool(ctx, org.ID, device.PreviousTunnelIP, org.PreviousIpCidr); err != nil {
+			return fmt.Errorf("failed to release the previous v4 address to pool: %w", err)
+		}
+	}
+	if device.PreviousTunnelIpV6 != "" && org.PreviousIpCidrV6 != "" {
+		if err := api.ipam.ReleaseToPool(ctx, org.ID, device.PreviousTunnelIpV6, org.PreviousIpCidrV6); err != nil {
+			return fmt.Errorf("failed to release the previous v6 address to pool: %w", err)
+		}
+	}
+	return nil
+}
+
+// completeReaddressing releases the previous addresses of the organizations whose grace period ended before now,
+// the organizations that fail are logged and completed on a later call
+func (api *API) completeReaddressing(ctx context.Context, now time.Time) error {
+	ctx, span := tracer.Start(ctx, "completeReaddressing")
+	defer span.End()
+
+	var orgs []models.Organization
+	if res := api.db.WithContext(ctx).
+		Where("readdress_deadline IS NOT NULL AND readdress_deadline < ?", now).
+		Find(&orgs); res.Error != nil {
+		return res.Error
+	}
+
+	for _, org := range orgs {
+		// the ipam releases can not be rolled back, they happen once the transaction committed so
+		// that a failed transaction does not release the same addresses again on the next attempt
+		var releasedPrefixes []string
+		var releasedLeases []ipamLease
+		completed := false
+		err := api.transaction(ctx, func(tx *gorm.DB) error {
+			releasedPrefixes, releasedLeases = nil, nil
+			// another apiserver may be completing the same re-addressing
+			res := tx.Model(&models.Organization{}).
+				Where("id = ? AND revision = ? AND readdress_deadline IS NOT NULL", org.ID, org.Revision).
+				Updates(map[string]interface{}{
+					"previous_ip_cidr":    "",
+					"previous_ip_cidr_v6": "",
+					"readdress_deadline":  nil,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return nil
+			}
+
+			var devices []models.Device
+			if res := tx.Where("organization_id = ?", org.ID).Find(&devices); res.Error != nil {
+				return res.Error
+			}
+			for _, device := range devices {
+				if device.PreviousTunnelIP == "" && device.PreviousTunnelIpV6 == "" {
+					continue
+				}
+				if device.PreviousTunnelIP != "" {
+					releasedLeases = append(releasedLeases, ipamLease{address: device.PreviousTunnelIP, prefix: org.PreviousIpCidr})
+				}
+				if device.PreviousTunnelIpV6 != "" {
+					releasedLeases = append(releasedLeases, ipamLease{address: device.PreviousTunnelIpV6, prefix: org.PreviousIpCidrV6})
+				}
+				device.PreviousTunnelIP = ""
+				device.PreviousTunnelIpV6 = ""
+				var err error
+				device.AllowedIPs, err = deviceAllowedIPs(device)
+				if err != nil {
+					return err
+				}
+				if res := tx.
+					Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+					Save(&device); res.Error != nil {
+					return res.Error
+				}
+			}
+
+			if org.PreviousIpCidr != "" {
+				releasedPrefixes = append(releasedPrefixes, org.PreviousIpCidr)
+			}
+			if org.PreviousIpCidrV6 != "" {
+				releasedPrefixes = append(releasedPrefixes, org.PreviousIpCidrV6)
+			}
+			completed = true
+			return nil
+		})
+		if err != nil {
+			// the other organizations are still completed, this one is retried on the next tick
+			api.Logger(ctx).Errorw("failed to complete the organization re-addressing", "organization", org.ID, "error", err)
+			continue
+		}
+		if !completed {
+			continue
+		}
+		api.releaseIpamAssignments(ctx, org.ID, releasedPrefixes, releasedLeases)
+		api.Logger(ctx).Infow("organization re-addressing completed",
+			"organization", org.ID, "previous_cidr", org.PreviousIpCidr, "previous_cidr_v6", org.PreviousIpCidrV6)
+		api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", org.ID.String()))
+		api.notifyOrganizationChanged(ctx, org)
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// RegKeyPrefix starts every registration key, it tells them apart from the other bearer tokens
+const RegKeyPrefix = "nexreg_"
+
+// DeviceTokenPrefix starts every device token, the credential of a device registered with a registration key
+const DeviceTokenPrefix = "nexdev_"
+
+// key for the registration key the device authenticated with in gin.Context
+const AuthRegKey string = "_nexodus.RegKey"
+
+// key for the id of the device that authenticated with its device token in gin.Context
+const AuthDeviceToken string = "_nexodus.DeviceToken"
+
+// ErrInvalidRegKey is returned when a registration key is unknown or deleted, when it expired or when
+// it is a single use key that already registered its device
+var ErrInvalidRegKey = errors.New("invalid registration key")
+
+// ErrInvalidDeviceToken is returned when a device token is unknown, or when its device or the
+// registration key of its device was deleted
+var ErrInvalidDeviceToken = errors.New("invalid device token")
+
+// currentRegKey returns the registration key of the current request, when the device authenticated with
+// one or with the device token of a device registered with one
+func currentRegKey(c *gin.Context) (models.RegKey, bool) {
+	if v, ok := c.Get(AuthRegKey); ok {
+		regKey, ok := v.(models.RegKey)
+		return regKey, ok
+	}
+	return models.RegKey{}, false
+}
+
+// currentDeviceToken returns the id of the device of the current request, when the device authenticated
+// with its device token
+func currentDeviceToken(c *gin.Context) (uuid.UUID, bool) {
+	if v, ok := c.Get(AuthDeviceToken); ok {
+		deviceId, ok := v.(uuid.UUID)
+		return deviceId, ok
+	}
+	return uuid.Nil, false
+}
+
+// checkRegKeyDeviceAccess rejects the requests for a registered device authenticated with a registration key,
+// the key only registers devices and each device authenticates with its own device token from then on
+func checkRegKeyDeviceAccess(c *gin.Context, deviceId uuid.UUID) error {
+	if _, ok := currentRegKey(c); !ok {
+		return nil
+	}
+	if tokenDeviceId, ok := currentDeviceToken(c); ok && tokenDeviceId == deviceId {
+		return nil
+	}
+	return fmt.Errorf("%w: only the device token of the device gives access to a registered device", errRegKeyNotAllowed)
+}
+
+// regKeyOrganizationScope limits the organizations of a request authenticated with a registration key
+// to the organization of the key
+func regKeyOrganizationScope(c *gin.Context, db *gorm.DB) *gorm.DB {
+	if regKey, ok := currentRegKey(c); ok {
+		return db.Where("id = ?", regKey.OrganizationID)
+	}
+	return db
+}
+
+// ValidateRegKey returns the registration key and its owner. It fails with ErrInvalidRegKey when the key is
+// unknown or deleted, when its owner was deleted, when the key expired or when it is a single use key that
+// already registered its device. The devices registered with a key authenticate with their device token instead.
+func (api *API) ValidateRegKey(ctx context.Context, token string) (models.RegKey, models.User, error) {
+	ctx, span := tracer.Start(ctx, "ValidateRegKey")
+	defer span.End()
+
+	var regKey models.RegKey
+	var user models.User
+	db := api.db.WithContext(ctx)
+	if res := db.First(&regKey, "token_hash = ?", hashApiToken(token)); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return regKey, user, ErrInvalidRegKey
+		}
+		return regKey, user, res.Error
+	}
+	if regKey.ExpiresAt != nil && !time.Now().Before(*regKey.ExpiresAt) {
+		return regKey, user, ErrInvalidRegKey
+	}
+	if regKey.SingleUse {
+		// the devices deleted since do not give the key back
+		var registered int64
+		if res := db.Unscoped().Model(&models.Device{}).Where("reg_key_id = ?", regKey.ID).Count(&registered); res.Error != nil {
+			return regKey, user, res.Error
+		}
+		if registered > 0 {
+			return regKey, user, ErrInvalidRegKey
+		}
+	}
+	if res := db.First(&user, "id = ?", regKey.OwnerID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return regKey, user, ErrInvalidRegKey
+		}
+		return regKey, user, res.Error
+	}
+	span.SetAttributes(attribute.String("id", regKey.ID.String()))
+	return regKey, user, nil
+}
+
+// ValidateDeviceToken returns the device of a device token, the registration key the device was registered
+// with and the owner of the key. It fails with ErrInvalidDeviceToken when the token is unknown, or when the
+// device, the registration key or its owner was deleted: deleting the device or the registration key
+// revokes the token.
+func (api *API) ValidateDeviceToken(ctx context.Context, token string) (models.RegKey, models.User, models.Device, error) {
+	ctx, span := tracer.Start(ctx, "ValidateDeviceToken")
+	defer span.End()
+
+	var regKey models.RegKey
+	var user models.User
+	var device models.Device
+	db := api.db.WithContext(ctx)
+	if res := db.First(&device, "device_token_hash = ?", hashApiToken(token)); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return regKey, user, device, ErrInvalidDeviceToken
+		}
+		return regKey, user, device, res.Error
+	}
+	if device.RegKeyID == nil {
+		return regKey, user, device, ErrInvalidDeviceToken
+	}
+	if res := db.First(&regKey, "id = ?", *device.RegKeyID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return regKey, user, device, ErrInvalidDeviceToken
+		}
+		return regKey, user, device, res.Error
+	}
+	if res := db.First(&user, "id = ?", regKey.OwnerID); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return regKey, user, device, ErrInvalidDeviceToken
+		}
+		return regKey, user, device, res.Error
+	}
+	span.SetAttributes(attribute.String("id", device.ID.String()))
+	return regKey, user, device, nil
+}
+
+// checkRegKeyRegistration checks that the registration key of the current request, if any, can register
+// a new device in the organization. It returns nil when the request has no registration key.
+func (api *API) checkRegKeyRegistration(tx *gorm.DB, c *gin.Context, orgId uuid.UUID, relay bool) (*models.RegKey, error) {
+	regKey, ok := currentRegKey(c)
+	if !ok {
+		return nil, nil
+	}
+	if _, ok := currentDeviceToken(c); ok {
+		return nil, fmt.Errorf("%w: a device token can not register other devices", errRegKeyNotAllowed)
+	}
+	if orgId != regKey.OrganizationID {
+		return nil, fmt.Errorf("%w: the key is for organization %s", errRegKeyNotAllowed, regKey.OrganizationID)
+	}
+	if regKey.ExpiresAt != nil && !time.Now().Before(*regKey.ExpiresAt) {
+		return nil, fmt.Errorf("%w: the key expired", errRegKeyNotAllowed)
+	}
+	if relay != regKey.Relay {
+		return nil, fmt.Errorf("%w: the relay setting of the device must be %t", errRegKeyNotAllowed, regKey.Relay)
+	}
+	if regKey.SingleUse {
+		// the devices deleted since do not give the key back
+		var registered int64
+		if res := tx.Unscoped().Model(&models.Device{}).Where("reg_key_id = ?", regKey.ID).Count(&registered); res.Error != nil {
+			return nil, res.Error
+		}
+		if registered > 0 {
+			return nil, fmt.Errorf("%w: the key was already used", errRegKeyNotAllowed)
+		}
+	}
+	if regKey.MaxDevices > 0 {
+		var registered int64
+		if res := tx.Model(&models.Device{}).Where("reg_key_id = ?", regKey.ID).Count(&registered); res.Error != nil {
+			return nil, res.Error
+		}
+		if registered >= int64(regKey.MaxDevices) {
+			return nil, fmt.Errorf("%w: the key registered its maximum of %d devices", errRegKeyNotAllowed, regKey.MaxDevices)
+		}
+	}
+	return &regKey, nil
+}
+
+// regKeyErrorResponse writes the response of a failed registration key request
+func regKeyErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errRegKeyNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("registration key"))
+	} else if errors.Is(err, errSecurityGroupNotFound) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("security_group_id", "must be a security group of the organization"))
+	} else if errors.Is(err, errOrgRoleNotAllowed) {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError("only the organization admins can manage its registration keys"))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
+
+// CreateRegKey creates a registration key for an organization
+// @Summary      Create Registration Key
+// @Description  Creates a key that registers devices into the organization without the OIDC session of a user, requires the admin role. The key is only returned by this call.
+// @Id           CreateRegKey
+// @Tags         RegKeys
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        reg_key  body   models.AddRegKey  true "Add Registration Key"
+// @Success      201  {object}  models.RegKey
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/registration_keys [post]
+func (api *API) CreateRegKey(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateRegKey", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddRegKey
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("expires_at", "must be in the future"))
+		return
+	}
+	if request.MaxDevices < 0 {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("max_devices", "must not be negative"))
+		return
+	}
+
+	token, err := newSecretToken(RegKeyPrefix)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	regKey := models.RegKey{
+		OrganizationID:  orgId,
+		OwnerID:         c.GetString(gin.AuthUserKey),
+		Description:     request.Description,
+		Prefix:          token[:len(RegKeyPrefix)+6],
+		TokenHash:       hashApiToken(token),
+		ExpiresAt:       request.ExpiresAt,
+		SingleUse:       request.SingleUse,
+		MaxDevices:      request.MaxDevices,
+		SecurityGroupID: request.SecurityGroupID,
+		Relay:           request.Relay,
+		Discovery:       request.Discovery,
+	}
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if err := api.administeredOrganization(tx, c, orgId); err != nil {
+			return err
+		}
+		if regKey.SecurityGroupID != nil {
+			var sg models.SecurityGroup
+			if res := tx.Select("id").First(&sg, "id = ? AND organization_id = ?", *regKey.SecurityGroupID, orgId); res.Error != nil {
+				if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+					return errSecurityGroupNotFound
+				}
+				return res.Error
+			}
+		}
+		if res := tx.Create(&regKey); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, orgId, "registration_key", regKey.ID.String(), auditActionCreate, nil, regKey)
+	})
+	if err != nil {
+		regKeyErrorResponse(c, err)
+		return
+	}
+	regKey.Token = token
+	c.JSON(http.StatusCreated, regKey)
+}
+
+// ListRegKeys lists the registration keys of an organization
+// @Summary      List Registration Keys
+// @Description  Lists the registration keys of the organization, requires the admin role
+// @Id           ListRegKeys
+// @Tags         RegKeys
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Success      200  {object}  []models.RegKey
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/registration_keys [get]
+func (api *API) ListRegKeys(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListRegKeys", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	if err := api.administeredOrganization(api.db.WithContext(ctx), c, orgId); err != nil {
+		regKeyErrorResponse(c, err)
+		return
+	}
+
+	regKeys := []models.RegKey{}
+	if res := api.db.WithContext(ctx).
+		Scopes(FilterAndPaginate(&models.RegKey{}, c, "created_at")).
+		Where("organization_id = ?", orgId).
+		Find(&regKeys); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+
+	var counts []struct {
+		RegKeyID uuid.UUID
+		Devices  int
+	}
+	if res := api.db.WithContext(ctx).Model(&models.Device{}).
+		Select("reg_key_id, count(*) AS devices").
+		Where("organization_id = ? AND reg_key_id IS NOT NULL", orgId).
+		Group("reg_key_id").
+		Scan(&counts); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	devices := map[uuid.UUID]int{}
+	for _, count := range counts {
+		devices[count.RegKeyID] = count.Devices
+	}
+	for i := range regKeys {
+		regKeys[i].Devices = devices[regKeys[i].ID]
+	}
+	c.JSON(http.StatusOK, regKeys)
+}
+
+// DeleteRegKey deletes a registration key of an organization
+// @Summary      Delete Registration Key
+// @Description  Deletes a registration key, requires the admin role. The device tokens of the devices registered with the key are revoked.
+// @Id           DeleteRegKey
+// @Tags         RegKeys
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        id   path      string  true "Registration Key ID"
+// @Success      200  {object}  models.RegKey
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/registration_keys/{id} [delete]
+func (api *API) DeleteRegKey(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteRegKey", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var regKey models.RegKey
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if err := api.administeredOrganization(tx, c, orgId); err != nil {
+			return err
+		}
+		if res := tx.First(&regKey, "id = ? AND organization_id = ?", id, orgId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return errRegKeyNotFound
+			}
+			return res.Error
+		}
+		if res := tx.Delete(&regKey); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, orgId, "registration_key", regKey.ID.String(), auditActionDelete, regKey, nil)
+	})
+	if err != nil {
+		regKeyErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, regKey)
+}
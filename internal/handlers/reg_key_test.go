@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+func (suite *HandlerTestSuite) TestRegKeys() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	createRegKey := func(add models.AddRegKey) (models.RegKey, int) {
+		reqBody, err := json.Marshal(add)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost,
+			"/organizations/:organization/registration_keys", fmt.Sprintf("/organizations/%s/registration_keys", suite.testOrganizationID),
+			suite.api.CreateRegKey, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		var regKey models.RegKey
+		if res.Code == http.StatusCreated {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &regKey))
+		}
+		return regKey, res.Code
+	}
+	registerDevice := func(regKey models.RegKey, add models.AddDevice) (models.Device, int) {
+		validated, _, err := suite.api.ValidateRegKey(context.Background(), regKey.Token)
+		require.NoError(err)
+		reqBody, err := json.Marshal(add)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", func(c *gin.Context) {
+			c.Set(AuthRegKey, validated)
+			suite.api.CreateDevice(c)
+		}, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		var device models.Device
+		if res.Code == http.StatusCreated {
+			require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+		}
+		return device, res.Code
+	}
+
+	group := models.SecurityGroup{GroupName: "edge", OrganizationId: suite.testOrganizationID}
+	require.NoError(suite.api.db.Create(&group).Error)
+	otherOrgGroup := models.SecurityGroup{GroupName: "other", OrganizationId: suite.testUser2OrgID}
+	require.NoError(suite.api.db.Create(&otherOrgGroup).Error)
+
+	// the security group must belong to the organization
+	_, code := createRegKey(models.AddRegKey{SecurityGroupID: &otherOrgGroup.ID})
+	assert.Equal(http.StatusBadRequest, code)
+	_, code = createRegKey(models.AddRegKey{MaxDevices: -1})
+	assert.Equal(http.StatusBadRequest, code)
+
+	regKey, code := createRegKey(models.AddRegKey{Description: "edge", SecurityGroupID: &group.ID, MaxDevices: 2, Discovery: true})
+	require.Equal(http.StatusCreated, code)
+	assert.True(strings.HasPrefix(regKey.Token, RegKeyPrefix))
+	assert.True(strings.HasPrefix(regKey.Token, regKey.Prefix))
+	assert.Equal(TestUserID, regKey.OwnerID)
+
+	validated, user, err := suite.api.ValidateRegKey(context.Background(), regKey.Token)
+	require.NoError(err)
+	assert.Equal(regKey.ID, validated.ID)
+	assert.Equal(TestUserID, user.ID)
+	_, _, err = suite.api.ValidateRegKey(context.Background(), RegKeyPrefix+"unknown")
+	assert.ErrorIs(err, ErrInvalidRegKey)
+
+	// the devices get the settings of the key
+	device, code := registerDevice(regKey, models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: "regkeypubkey1"})
+	require.Equal(http.StatusCreated, code)
+	require.NotNil(device.RegKeyID)
+	assert.Equal(regKey.ID, *device.RegKeyID)
+	assert.Equal(group.ID, device.SecurityGroupId)
+	assert.True(device.Discovery)
+
+	// the relay setting must match and the devices are limited to the organization of the key
+	_, code = registerDevice(regKey, models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: "regkeyrelay", Relay: true})
+	assert.Equal(http.StatusForbidden, code)
+	_, code = registerDevice(regKey, models.AddDevice{OrganizationID: suite.testUser2OrgID, PublicKey: "regkeyotherorg"})
+	assert.NotEqual(http.StatusCreated, code)
+
+	_, code = registerDevice(regKey, models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: "regkeypubkey2"})
+	require.Equal(http.StatusCreated, code)
+	_, code = registerDevice(regKey, models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: "regkeypubkey3"})
+	assert.Equal(http.StatusForbidden, code)
+
+	// a single use key only registers one device
+	singleUse, code := createRegKey(models.AddRegKey{SingleUse: true})
+	require.Equal(http.StatusCreated, code)
+	singleUseDevice, code := registerDevice(singleUse, models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: "singleusepubkey1"})
+	require.Equal(http.StatusCreated, code)
+	_, _, err = suite.api.ValidateRegKey(context.Background(), singleUse.Token)
+	assert.ErrorIs(err, ErrInvalidRegKey)
+
+	// the device authenticates with its own token once the key is used
+	require.True(strings.HasPrefix(singleUseDevice.DeviceToken, DeviceTokenPrefix))
+	tokenKey, user, tokenDevice, err := suite.api.ValidateDeviceToken(context.Background(), singleUseDevice.DeviceToken)
+	require.NoError(err)
+	assert.Equal(singleUse.ID, tokenKey.ID)
+	assert.Equal(TestUserID, user.ID)
+	assert.Equal(singleUseDevice.ID, tokenDevice.ID)
+	_, _, _, err = suite.api.ValidateDeviceToken(context.Background(), DeviceTokenPrefix+"unknown")
+	assert.ErrorIs(err, ErrInvalidDeviceToken)
+	withDeviceToken := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(AuthRegKey, tokenKey)
+			c.Set(AuthDeviceToken, tokenDevice.ID)
+			handler(c)
+		}
+	}
+
+	// the device token can not register other devices, nexd gets the id of its device to update it instead
+	for publicKey, expected := range map[string]int{"singleusepubkey2": http.StatusForbidden, "singleusepubkey1": http.StatusConflict} {
+		reqBody, err := json.Marshal(models.AddDevice{OrganizationID: suite.testOrganizationID, PublicKey: publicKey})
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost, "/", "/", withDeviceToken(suite.api.CreateDevice), bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		assert.Equal(expected, res.Code, "HTTP error: %s", res.Body.String())
+	}
+
+	// the device can update its endpoints but not leave the organization or the security group of the key
+	for update, expected := range map[*models.UpdateDevice]int{
+		{Hostname: "edge", OrganizationID: suite.testOrganizationID}: http.StatusOK,
+		{OrganizationID: suite.testUser2OrgID}:                       http.StatusForbidden,
+		{SecurityGroupId: group.ID}:                                  http.StatusForbidden,
+	} {
+		reqBody, err := json.Marshal(update)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPatch, "/:id", fmt.Sprintf("/%s", singleUseDevice.ID),
+			withDeviceToken(suite.api.UpdateDevice), bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		assert.Equal(expected, res.Code, "HTTP error: %s", res.Body.String())
+	}
+
+	// a reusable key can not act on the devices it registered, each device is only reachable with its own token
+	withRegKey := func(handler gin.HandlerFunc) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(AuthRegKey, regKey)
+			handler(c)
+		}
+	}
+	newPublicKey, err := wgtypes.GeneratePrivateKey()
+	require.NoError(err)
+	for _, call := range []struct {
+		method  string
+		path    string
+		handler gin.HandlerFunc
+		body    interface{}
+	}{
+		{http.MethodPatch, "/%s", suite.api.UpdateDevice, models.UpdateDevice{Hostname: "taken-over"}},
+		{http.MethodPost, "/%s/rotate_key", suite.api.RotateDeviceKey, models.RotateDeviceKey{PublicKey: newPublicKey.PublicKey().String()}},
+		{http.MethodPut, "/%s/status", suite.api.ReportDeviceStatus, models.ReportDeviceStatus{NexdVersion: "test"}},
+	} {
+		route := strings.Replace(call.path, "%s", ":id", 1)
+		for _, auth := range []struct {
+			handler  gin.HandlerFunc
+			deviceId uuid.UUID
+			expected int
+		}{
+			{withRegKey(call.handler), device.ID, http.StatusForbidden},
+			{withDeviceToken(call.handler), device.ID, http.StatusForbidden},
+			{withDeviceToken(call.handler), singleUseDevice.ID, http.StatusOK},
+		} {
+			reqBody, err := json.Marshal(call.body)
+			require.NoError(err)
+			_, res, err := suite.ServeRequest(call.method, route, fmt.Sprintf(call.path, auth.deviceId), auth.handler, bytes.NewBuffer(reqBody))
+			require.NoError(err)
+			assert.Equal(auth.expected, res.Code, "%s %s: %s", call.method, call.path, res.Body.String())
+		}
+	}
+
+	// an expired key is no longer accepted
+	expiresAt := time.Now().Add(time.Hour)
+	expiring, code := createRegKey(models.AddRegKey{ExpiresAt: &expiresAt})
+	require.Equal(http.StatusCreated, code)
+	require.NoError(suite.api.db.Model(&models.RegKey{}).Where("id = ?", expiring.ID).
+		Update("expires_at", time.Now().Add(-time.Minute)).Error)
+	_, _, err = suite.api.ValidateRegKey(context.Background(), expiring.Token)
+	assert.ErrorIs(err, ErrInvalidRegKey)
+
+	_, res, err := suite.ServeRequest(http.MethodGet,
+		"/organizations/:organization/registration_keys", fmt.Sprintf("/organizations/%s/registration_keys", suite.testOrganizationID),
+		suite.api.ListRegKeys, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	var regKeys []models.RegKey
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &regKeys))
+	require.Len(regKeys, 3)
+	devices := map[string]int{}
+	for _, k := range regKeys {
+		assert.Empty(k.Token)
+		devices[k.ID.String()] = k.Devices
+	}
+	assert.Equal(2, devices[regKey.ID.String()])
+	assert.Equal(1, devices[singleUse.ID.String()])
+
+	// deleting the key revokes the devices registered with it
+	_, res, err = suite.ServeRequest(http.MethodDelete,
+		"/organizations/:organization/registration_keys/:id", fmt.Sprintf("/organizations/%s/registration_keys/%s", suite.testOrganizationID, regKey.ID),
+		suite.api.DeleteRegKey, nil)
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+	_, _, err = suite.api.ValidateRegKey(context.Background(), regKey.Token)
+	assert.ErrorIs(err, ErrInvalidRegKey)
+	_, _, _, err = suite.api.ValidateDeviceToken(context.Background(), device.DeviceToken)
+	assert.ErrorIs(err, ErrInvalidDeviceToken)
+}
@@ -5,13 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/secgroup"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // ListSecurityGroups lists all Security Groups
@@ -21,10 +27,12 @@ import (
 // @Tags         SecurityGroup
 // @Accepts		 json
 // @Produce      json
+// @Param		 gt_revision     query  uint64 false "greater than revision"
 // @Param        organization_id   path      string  true "Organization ID"
 // @Success      200  {object}  []models.SecurityGroup
 // @Failure		 401  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
+// @Failure		 500  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/security_groups [get]
 func (api *API) ListSecurityGroups(c *gin.Context) {
 	ctx, span := tracer.Start(c.Request.Context(), "ListSecurityGroups")
@@ -44,14 +52,116 @@ func (api *API) ListSecurityGroups(c *gin.Context) {
 		return
 	}
 
-	securityGroups := make([]models.SecurityGroup, 0)
-	result := api.db.WithContext(ctx).Where("organization_id = ?", orgId).Find(&securityGroups)
+	gtRevision := uint64(0)
+	if v := c.Query("gt_revision"); v != "" {
+		gtRevision, _ = strconv.ParseUint(v, 10, 0)
+	}
 
-	if result.Error != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"message": "error fetching security groups from db"})
-		return
+	includeDeleted := false
+	orderBy := ""
+
+	getList := func() ([]*models.SecurityGroup, error) {
+		securityGroups := make([]*models.SecurityGroup, 0)
+
+		db := api.db.WithContext(ctx)
+		if includeDeleted {
+			db = db.Unscoped()
+		}
+		if orderBy != "" {
+			db = db.Order(orderBy)
+		}
+		db = db.Where("organization_id = ?", orgId)
+		if gtRevision != 0 {
+			db = db.Where("revision > ?", gtRevision)
+		}
+
+		result := db.Find(&securityGroups)
+		if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, result.Error
+		}
+		return securityGroups, nil
+	}
+
+	if v := c.Query("watch"); v == "true" {
+		orderBy = "revision"
+		includeDeleted = true
+		sub := api.signalBus.Subscribe(fmt.Sprintf("/security-groups/org=%s", orgId.String()))
+		defer sub.Close()
+
+		idx := 0
+		var list []*models.SecurityGroup
+		bookmarkSent := false
+
+		c.Header("Content-Type", "application/json;stream=watch")
+		c.Status(http.StatusOK)
+		stream(c, func() models.WatchEvent {
+			// This function blocks until there is an event to return...
+			for {
+				if err != nil {
+					return models.WatchEvent{
+						Type:  "error",
+						Value: err.Error(),
+					}
+				}
+				if idx < len(list) {
+					result := list[idx]
+					gtRevision = result.Revision
+					idx += 1
+
+					if result.DeletedAt.Valid {
+						return models.WatchEvent{
+							Type:  "delete",
+							Value: result,
+						}
+					} else {
+						return models.WatchEvent{
+							Type:  "change",
+							Value: result,
+						}
+					}
+				} else {
+
+					// get the next list...
+					list, err = getList()
+					if err != nil {
+						return models.WatchEvent{
+							Type:  "error",
+							Value: err.Error(),
+						}
+					}
+					idx = 0
+
+					// did we run out of items to send?
+					if len(list) == 0 {
+
+						if !bookmarkSent {
+							bookmarkSent = true
+							return models.WatchEvent{
+								Type: "bookmark",
+							}
+						}
+
+						// Wait for some items to come into the list
+						if waitForCancelOrTimeoutOrNotification(ctx, 30*time.Second, sub) {
+							// ctx was canceled... likely due to the http connection being closed by
+							// the client.  Signal the event stream is done.
+							return models.WatchEvent{
+								Type: "close",
+							}
+						}
+					}
+				}
+			}
+		})
+
+	} else {
+		securityGroups, err := getList()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "error fetching security groups from db"})
+			return
+		}
+		c.JSON(http.StatusOK, securityGroups)
 	}
-	c.JSON(http.StatusOK, securityGroups)
 }
 
 // GetSecurityGroup gets a Security Group by ID
@@ -106,7 +216,7 @@ func (api *API) GetSecurityGroup(c *gin.Context) {
 }
 
 func (api *API) secGroupsEnabled(c *gin.Context) bool {
-	secGroupsEnabled, err := api.fflags.GetFlag("security-groups")
+	secGroupsEnabled, err := api.fflags.GetFlag(c.Request.Context(), api.flagSubject(c), "security-groups")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		return false
@@ -129,8 +239,9 @@ func (api *API) secGroupsEnabled(c *gin.Context) bool {
 // @Param        organization_id   path      string  true "Organization ID"
 // @Param        SecurityGroup   body   models.AddSecurityGroup  true "Add SecurityGroup"
 // @Success      201  {object}  models.SecurityGroup
-// @Failure      400  {object}  models.BaseError
+// @Failure      400  {object}  models.ValidationError
 // @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
 // @Failure      409  {object}  models.ConflictsError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
@@ -163,23 +274,40 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 		return
 	}
 
+	inboundRules, outboundRules, ok := normalizeSecurityGroupRules(c, request.InboundRules, request.OutboundRules)
+	if !ok {
+		return
+	}
+
 	var sg models.SecurityGroup
 	err := api.transaction(ctx, func(tx *gorm.DB) error {
 		var org models.Organization
 		if res := api.db.WithContext(ctx).
-			Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
 			First(&org, "id = ?", request.OrganizationId); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return api.organizationRoleError(tx, c, request.OrganizationId)
+			}
 			return res.Error
 		}
 
+		if err := checkSecurityRuleReferences(tx, org.ID, "inbound_rules", inboundRules); err != nil {
+			return err
+		}
+		if err := checkSecurityRuleReferences(tx, org.ID, "outbound_rules", outboundRules); err != nil {
+			return err
+		}
+
 		sg = models.SecurityGroup{
 			GroupName:        request.GroupName,
 			OrganizationId:   request.OrganizationId,
-			InboundRules:     request.InboundRules,
-			OutboundRules:    request.OutboundRules,
+			InboundRules:     inboundRules,
+			OutboundRules:    outboundRules,
 			GroupDescription: request.GroupDescription,
 		}
-		if res := tx.Create(&sg); res.Error != nil {
+		if res := tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Create(&sg); res.Error != nil {
 			return res.Error
 		}
 
@@ -191,18 +319,26 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 
 		span.SetAttributes(attribute.String("id", sg.ID.String()))
 		api.logger.Infof("New security group created [ %s ] in organization [ %s ]", sg.GroupName, org.ID)
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, org.ID, "security_group", sg.ID.String(), auditActionCreate, nil, sg)
 	})
 
 	if err != nil {
+		var invalid errInvalidSecurityRule
 		if errors.Is(err, errUserNotFound) {
 			c.JSON(http.StatusNotFound, models.NewApiInternalError(err))
+		} else if errors.Is(err, errOrgNotFound) {
+			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		} else if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.Field, invalid.Reason))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
 		return
 	}
 
+	api.signalBus.Notify(fmt.Sprintf("/security-groups/org=%s", sg.OrganizationId.String()))
 	c.JSON(http.StatusCreated, sg)
 }
 
@@ -217,6 +353,7 @@ func (api *API) CreateSecurityGroup(c *gin.Context) {
 // @Param        security_group_id   path      string  true "Security Group ID"
 // @Success      204  {object}  models.SecurityGroup
 // @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/security_groups/{security_group_id} [delete]
@@ -247,9 +384,14 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 
 	err = api.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var organization models.Organization
-		result := tx.Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+		result := tx.Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
 			First(&organization, "id = ?", orgId.String())
 		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				if err := api.organizationRoleError(tx, c, orgId); errors.Is(err, errOrgRoleNotAllowed) {
+					return err
+				}
+			}
 			return result.Error
 		}
 
@@ -259,7 +401,9 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 			}
 		}
 
-		if res := tx.Delete(&sg, "id = ?", sg.ID); res.Error != nil {
+		if res := tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Delete(&sg, "id = ?", sg.ID); res.Error != nil {
 			return result.Error
 		}
 
@@ -269,26 +413,33 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 			return result.Error
 		}
 
+		// devices assigned to the group fall back to the security group of the organization
+		var fallback interface{}
+		if organization.SecurityGroupId != sg.ID {
+			fallback = organization.SecurityGroupId
+		}
 		if res := tx.Model(&models.Device{}).
 			Where("organization_id = ? AND security_group_id = ?", sg.OrganizationId, sg.ID).
-			Update("security_group_id", nil); res.Error != nil {
-			return result.Error
+			Update("security_group_id", fallback); res.Error != nil {
+			return res.Error
 		}
 
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, organization.ID, "security_group", sg.ID.String(), auditActionDelete, sg, nil)
 	})
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			c.JSON(http.StatusNotFound, err)
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
 		} else {
 			c.JSON(http.StatusInternalServerError, err)
 		}
 		return
 	}
 
-	// TODO: signal bus notify of SecGroup deletes
-	//api.signalBus.Notify
+	api.signalBus.Notify(fmt.Sprintf("/security-groups/org=%s", sg.OrganizationId.String()))
+	api.signalBus.Notify(fmt.Sprintf("/devices/org=%s", sg.OrganizationId.String()))
 
 	c.JSON(http.StatusOK, sg)
 }
@@ -305,7 +456,8 @@ func (api *API) DeleteSecurityGroup(c *gin.Context) {
 // @Param		 update body models.UpdateSecurityGroup true "Security Group Update"
 // @Success      200  {object}  models.SecurityGroup
 // @Failure		 401  {object}  models.BaseError
-// @Failure      400  {object}  models.BaseError
+// @Failure      400  {object}  models.ValidationError
+// @Failure      403  {object}  models.NotAllowedError
 // @Failure      404  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Router       /api/organizations/{organization_id}/security_groups/{security_group_id} [patch]
@@ -336,14 +488,25 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
 		return
 	}
+
+	inboundRules, outboundRules, ok := normalizeSecurityGroupRules(c, request.InboundRules, request.OutboundRules)
+	if !ok {
+		return
+	}
+
 	var securityGroup models.SecurityGroup
 
 	err = api.transaction(ctx, func(tx *gorm.DB) error {
 		var org models.Organization
 		if res := tx.WithContext(ctx).
-			Scopes(api.OrganizationIsOwnedByCurrentUser(c)).
+			Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
 			First(&org, "id = ?", orgId); res.Error != nil {
-			c.JSON(http.StatusNotFound, models.NewNotFoundError("security_group"))
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				if err := api.organizationRoleError(tx, c, orgId); errors.Is(err, errOrgRoleNotAllowed) {
+					return err
+				}
+				return errSecurityGroupNotFound
+			}
 			return res.Error
 		}
 
@@ -352,30 +515,45 @@ func (api *API) UpdateSecurityGroup(c *gin.Context) {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return errSecurityGroupNotFound
 		}
+		before := securityGroup
 
 		securityGroup.GroupName = request.GroupName
 		securityGroup.GroupDescription = request.GroupDescription
-		securityGroup.InboundRules = request.InboundRules
-		securityGroup.OutboundRules = request.OutboundRules
+		if err := checkSecurityRuleReferences(tx, org.ID, "inbound_rules", inboundRules); err != nil {
+			return err
+		}
+		if err := checkSecurityRuleReferences(tx, org.ID, "outbound_rules", outboundRules); err != nil {
+			return err
+		}
+		securityGroup.InboundRules = inboundRules
+		securityGroup.OutboundRules = outboundRules
 
-		if res := tx.Save(&securityGroup); res.Error != nil {
+		if res := tx.
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "revision"}}}).
+			Save(&securityGroup); res.Error != nil {
 			return res.Error
 		}
 
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, org.ID, "security_group", securityGroup.ID.String(), auditActionUpdate, before, securityGroup)
 	})
 
 	if err != nil {
+		var invalid errInvalidSecurityRule
 		if errors.Is(err, errSecurityGroupNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("security_group"))
 		} else if errors.Is(err, errOrgNotFound) {
 			c.JSON(http.StatusNotFound, err)
+		} else if errors.Is(err, errOrgRoleNotAllowed) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
+		} else if errors.As(err, &invalid) {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.Field, invalid.Reason))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
 		return
 	}
 
+	api.signalBus.Notify(fmt.Sprintf("/security-groups/org=%s", securityGroup.OrganizationId.String()))
 	c.JSON(http.StatusOK, securityGroup)
 }
 
@@ -417,3 +595,242 @@ func (api *API) updateOrganizationSecGroupId(ctx context.Context, sgId, orgId uu
 
 	return api.db.WithContext(ctx).Model(&org).Update("SecurityGroupId", sgId).Error
 }
+
+// SimulateSecurityGroup evaluates a flow between devices against their security groups
+// @Summary      Simulate Security Groups
+// @Description  Evaluates a flow from a device to another device or IP against the security groups of the devices, without changing them
+// @Id           SimulateSecurityGroup
+// @Tags         SecurityGroup
+// @Accepts		 json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        simulation  body   models.SimulateSecurityGroup  true "Flow to simulate"
+// @Success      200  {object}  models.SecurityGroupSimulation
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      404  {object}  models.BaseError
+// @Failure      405  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/security_groups/simulate [post]
+func (api *API) SimulateSecurityGroup(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "SimulateSecurityGroup", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+
+	if !api.secGroupsEnabled(c) {
+		return
+	}
+
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.SimulateSecurityGroup
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.SourceDeviceId == uuid.Nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("source_device_id"))
+		return
+	}
+	if request.DestinationDeviceId == uuid.Nil && request.DestinationIp == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("destination_device_id"))
+		return
+	}
+	var dstAddr netip.Addr
+	if request.DestinationIp != "" {
+		dstAddr, err = netip.ParseAddr(request.DestinationIp)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("destination_ip", "must be an IPv4 or IPv6 address"))
+			return
+		}
+	}
+	if request.Port < 0 || request.Port > 65535 {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("port", "must be between 0 and 65535"))
+		return
+	}
+
+	var org models.Organization
+	if res := api.db.WithContext(ctx).
+		Scopes(api.OrganizationIsReadableByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		return
+	}
+
+	var devices []models.Device
+	if res := api.db.WithContext(ctx).Where("organization_id = ?", orgId).Find(&devices); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+
+	var src, dst *models.Device
+	for i := range devices {
+		device := &devices[i]
+		if device.ID == request.SourceDeviceId {
+			src = device
+		}
+		if request.DestinationDeviceId != uuid.Nil {
+			if device.ID == request.DestinationDeviceId {
+				dst = device
+			}
+		} else if device.TunnelIP == dstAddr.String() || device.TunnelIpV6 == dstAddr.String() {
+			dst = device
+		}
+	}
+	if src == nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("source device"))
+		return
+	}
+	if request.DestinationDeviceId != uuid.Nil && dst == nil {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("destination device"))
+		return
+	}
+	if !dstAddr.IsValid() {
+		dstAddr, err = netip.ParseAddr(dst.TunnelIP)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("destination device has an invalid tunnel ip: %w", err)))
+			return
+		}
+	}
+
+	srcIp := src.TunnelIP
+	if dstAddr.Is6() {
+		srcIp = src.TunnelIpV6
+	}
+	srcAddr, err := netip.ParseAddr(srcIp)
+	if err != nil {
+		// the device may not have a tunnel address of the family of the destination
+		family := "IPv4"
+		if dstAddr.Is6() {
+			family = "IPv6"
+		}
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("source_device_id", fmt.Sprintf("the source device has no valid %s tunnel address", family)))
+		return
+	}
+
+	protocol, err := secgroup.ProtocolNumber(request.IpProtocol, dstAddr.Is6())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("ip_protocol", "must be one of tcp, udp, icmp, icmpv4 or icmpv6"))
+		return
+	}
+	pkt := secgroup.Packet{
+		Src:      srcAddr,
+		Dst:      dstAddr,
+		Protocol: protocol,
+	}
+	if ipProtocol := strings.ToLower(request.IpProtocol); ipProtocol == secgroup.ProtoTCP || ipProtocol == secgroup.ProtoUDP {
+		pkt.DstPort = uint16(request.Port)
+	}
+
+	orgDevices := toSecGroupDevices(devices)
+	var result models.SecurityGroupSimulation
+	result.Outbound, err = api.securityGroupVerdict(ctx, *src, secgroup.Outbound, pkt, orgDevices)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+		return
+	}
+	result.Allowed = result.Outbound.Allowed
+	if dst != nil {
+		inbound, err := api.securityGroupVerdict(ctx, *dst, secgroup.Inbound, pkt, orgDevices)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
+		result.Inbound = &inbound
+		result.Allowed = result.Allowed && inbound.Allowed
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// securityGroupVerdict evaluates the packet against the rules of one direction of the device's security group,
+// using the same evaluator nexd enforces the rules with.
+func (api *API) securityGroupVerdict(ctx context.Context, device models.Device, dir secgroup.Direction, pkt secgroup.Packet, orgDevices []secgroup.Device) (models.SecurityGroupVerdict, error) {
+	verdict := models.SecurityGroupVerdict{
+		DeviceId:        device.ID,
+		SecurityGroupId: device.SecurityGroupId,
+		RuleIndex:       -1,
+	}
+
+	var sg models.SecurityGroup
+	if device.SecurityGroupId != uuid.Nil {
+		res := api.db.WithContext(ctx).First(&sg, "id = ? AND organization_id = ?", device.SecurityGroupId, device.OrganizationID)
+		if res.Error != nil && !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return verdict, res.Error
+		}
+	}
+	if sg.ID == uuid.Nil {
+		verdict.Allowed = true
+		verdict.Reason = "the device has no security group, all traffic is permitted"
+		return verdict, nil
+	}
+
+	rules := sg.InboundRules
+	if dir == secgroup.Outbound {
+		rules = sg.OutboundRules
+	}
+	policy, err := secgroup.Compile(
+		secgroup.Resolve(toSecGroupRules(sg.InboundRules), orgDevices),
+		secgroup.Resolve(toSecGroupRules(sg.OutboundRules), orgDevices),
+	)
+	if err != nil {
+		return verdict, fmt.Errorf("invalid security group %s: %w", sg.ID, err)
+	}
+
+	if !policy.HasRules(dir) {
+		verdict.Allowed = true
+		verdict.Reason = fmt.Sprintf("the security group has no %s rules, all %s traffic is permitted", dir, dir)
+		return verdict, nil
+	}
+	if index, ok := policy.Match(dir, pkt); ok {
+		verdict.Allowed = true
+		verdict.RuleIndex = index
+		verdict.Rule = &rules[index]
+		verdict.Reason = fmt.Sprintf("permitted by %s rule %d", dir, index)
+		return verdict, nil
+	}
+	verdict.Reason = fmt.Sprintf("no %s rule permits the flow", dir)
+	return verdict, nil
+}
+
+// toSecGroupRules converts the security rules for the secgroup policy compiler
+func toSecGroupRules(rules []models.SecurityRule) []secgroup.Rule {
+	result := make([]secgroup.Rule, 0, len(rules))
+	for _, rule := range rules {
+		r := secgroup.Rule{
+			IpProtocol: rule.IpProtocol,
+			FromPort:   rule.FromPort,
+			ToPort:     rule.ToPort,
+			IpRanges:   rule.IpRanges,
+			AllDevices: rule.AllDevices,
+		}
+		for _, id := range rule.SecurityGroupIds {
+			r.SecurityGroupIds = append(r.SecurityGroupIds, id.String())
+		}
+		for _, id := range rule.DeviceIds {
+			r.DeviceIds = append(r.DeviceIds, id.String())
+		}
+		result = append(result, r)
+	}
+	return result
+}
+
+// toSecGroupDevices converts the devices of an organization for resolving the rules referring to them
+func toSecGroupDevices(devices []models.Device) []secgroup.Device {
+	result := make([]secgroup.Device, 0, len(devices))
+	for _, device := range devices {
+		result = append(result, secgroup.Device{
+			Id:              device.ID.String(),
+			SecurityGroupId: device.SecurityGroupId.String(),
+			TunnelIp:        device.TunnelIP,
+			TunnelIpV6:      device.TunnelIpV6,
+		})
+	}
+	return result
+}
@@ -2,12 +2,16 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 
 	"github.com/nexodus-io/nexodus/internal/models"
 )
@@ -26,7 +30,7 @@ func (suite *HandlerTestSuite) TestCreateGetSecurityGroups() {
 				{IpProtocol: "udp", FromPort: 5000, ToPort: 5001, IpRanges: []string{"10.0.0.0/8"}},
 			},
 			OutboundRules: []models.SecurityRule{
-				{IpProtocol: "tcp", FromPort: 22, ToPort: 22, IpRanges: []string{"2001:db8::/32", "::/0"}},
+				{IpProtocol: "tcp", FromPort: 22, ToPort: 22, IpRanges: []string{"2001:db8::/32", "fd00::/8"}},
 				{IpProtocol: "udp", FromPort: 5000, ToPort: 5001, IpRanges: []string{"10.0.0.0/8", "100.101.0.0/24"}},
 			},
 		},
@@ -35,12 +39,12 @@ func (suite *HandlerTestSuite) TestCreateGetSecurityGroups() {
 			GroupDescription: "This is test group 2",
 			OrganizationId:   suite.testOrganizationID,
 			InboundRules: []models.SecurityRule{
-				{IpProtocol: "udp", FromPort: 99, ToPort: 100, IpRanges: []string{"2001:db8:a0b:12f0::/64", "2001:db8::/32"}},
+				{IpProtocol: "udp", FromPort: 99, ToPort: 100, IpRanges: []string{"2001:db8:a0b:12f0::/64", "2001:db9::/32"}},
 				{IpProtocol: "icmp", FromPort: 0, ToPort: 0, IpRanges: []string{"192.168.1.0/24", "10.0.0.0/8"}},
 			},
 			OutboundRules: []models.SecurityRule{
 				{IpProtocol: "udp", FromPort: 53, ToPort: 53, IpRanges: []string{"10.0.0.0/8", "192.168.1.0/24"}},
-				{IpProtocol: "icmpv6", FromPort: 0, ToPort: 0, IpRanges: []string{"2001:db8::/32", "2001:db8:a0b:12f0::/64", "200::/64"}},
+				{IpProtocol: "icmpv6", FromPort: 0, ToPort: 0, IpRanges: []string{"2001:db9::/32", "2001:db8:a0b:12f0::/64", "200::/64"}},
 			},
 		},
 		{
@@ -174,7 +178,7 @@ func (suite *HandlerTestSuite) TestListSecurityGroups() {
 			GroupName:        "testGroup1",
 			GroupDescription: "This is test group 1",
 			OrganizationId:   suite.testOrganizationID,
-			InboundRules:     []models.SecurityRule{{IpProtocol: "tcp", FromPort: 80, ToPort: 80, IpRanges: []string{"100.100.0/16"}}},
+			InboundRules:     []models.SecurityRule{{IpProtocol: "tcp", FromPort: 80, ToPort: 80, IpRanges: []string{"100.100.0.0/16"}}},
 			OutboundRules:    []models.SecurityRule{{IpProtocol: "tcp", FromPort: 80, ToPort: 80, IpRanges: []string{}}},
 		},
 		{
@@ -295,3 +299,344 @@ func (suite *HandlerTestSuite) TestUpdateSecurityGroup() {
 	assert.Equal(updateGroup.InboundRules, updatedGroup.InboundRules)
 	assert.Equal(updateGroup.OutboundRules, updatedGroup.OutboundRules)
 }
+
+func (suite *HandlerTestSuite) TestSecurityGroupRuleValidation() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	tests := []struct {
+		name     string
+		inbound  []models.SecurityRule
+		outbound []models.SecurityRule
+		field    string
+	}{
+		{
+			name:    "missing protocol",
+			inbound: []models.SecurityRule{{FromPort: 22, ToPort: 22}},
+			field:   "inbound_rules[0].ip_protocol",
+		},
+		{
+			name:    "unknown protocol",
+			inbound: []models.SecurityRule{{IpProtocol: "tcp"}, {IpProtocol: "sctp"}},
+			field:   "inbound_rules[1].ip_protocol",
+		},
+		{
+			name: "to port out of bounds",
+			inbound: []models.SecurityRule{
+				{IpProtocol: "tcp", FromPort: 22, ToPort: 22},
+				{IpProtocol: "udp", FromPort: 53, ToPort: 53},
+				{IpProtocol: "tcp", FromPort: 80, ToPort: 70000},
+			},
+			field: "inbound_rules[2].to_port",
+		},
+		{
+			name:     "inverted port range",
+			outbound: []models.SecurityRule{{IpProtocol: "tcp", FromPort: 443, ToPort: 80}},
+			field:    "outbound_rules[0].to_port",
+		},
+		{
+			name:    "icmp with ports",
+			inbound: []models.SecurityRule{{IpProtocol: "icmp", FromPort: 8}},
+			field:   "inbound_rules[0].from_port",
+		},
+		{
+			name:    "invalid ip range",
+			inbound: []models.SecurityRule{{IpProtocol: "tcp", IpRanges: []string{"10.0.0.0/8", "100.100.0/16"}}},
+			field:   "inbound_rules[0].ip_ranges[1]",
+		},
+		{
+			name:     "ipv6 range in an ipv4 rule",
+			outbound: []models.SecurityRule{{IpProtocol: "ipv4", IpRanges: []string{"200::1"}}},
+			field:    "outbound_rules[0].ip_ranges[0]",
+		},
+		{
+			name:    "overlapping ip ranges",
+			inbound: []models.SecurityRule{{IpProtocol: "tcp", IpRanges: []string{"100.100.0.0/16", "200::/64", "100.100.1.1-100.100.1.9"}}},
+			field:   "inbound_rules[0].ip_ranges[2]",
+		},
+		{
+			name: "duplicate rules",
+			outbound: []models.SecurityRule{
+				{IpProtocol: "udp", FromPort: 53, ToPort: 53},
+				{IpProtocol: "UDP", FromPort: 53, ToPort: 53},
+			},
+			field: "outbound_rules[1]",
+		},
+		{
+			name:    "duplicate device ids",
+			inbound: []models.SecurityRule{{IpProtocol: "tcp", DeviceIds: []uuid.UUID{suite.testOrganizationID, suite.testOrganizationID}}},
+			field:   "inbound_rules[0].device_ids[1]",
+		},
+		{
+			name:    "unknown security group",
+			inbound: []models.SecurityRule{{IpProtocol: "tcp", SecurityGroupIds: []uuid.UUID{uuid.New()}}},
+			field:   "inbound_rules[0].security_group_ids[0]",
+		},
+	}
+	for _, test := range tests {
+		suite.Run(test.name, func() {
+			resBody, err := json.Marshal(models.AddSecurityGroup{
+				GroupName:      "invalidGroup",
+				OrganizationId: suite.testOrganizationID,
+				InboundRules:   test.inbound,
+				OutboundRules:  test.outbound,
+			})
+			require.NoError(err)
+
+			_, res, err := suite.ServeRequest(
+				http.MethodPost,
+				"/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups", suite.testOrganizationID.String()),
+				func(c *gin.Context) {
+					c.Set("nexodus.secGroupsEnabled", "true")
+					suite.api.CreateSecurityGroup(c)
+				},
+				bytes.NewBuffer(resBody),
+			)
+			require.NoError(err)
+
+			body, err := io.ReadAll(res.Body)
+			require.NoError(err)
+			require.Equal(http.StatusBadRequest, res.Code, "HTTP error: %s", string(body))
+
+			var validationErr models.ValidationError
+			err = json.Unmarshal(body, &validationErr)
+			require.NoError(err)
+			assert.Equal(test.field, validationErr.Field)
+		})
+	}
+
+	// protocol names are normalized
+	resBody, err := json.Marshal(models.AddSecurityGroup{
+		GroupName:      "normalizedGroup",
+		OrganizationId: suite.testOrganizationID,
+		InboundRules:   []models.SecurityRule{{IpProtocol: " TCP", FromPort: 22, ToPort: 22}},
+		OutboundRules:  []models.SecurityRule{{IpProtocol: "icmp6", IpRanges: []string{"200::/64"}}},
+	})
+	require.NoError(err)
+
+	_, res, err := suite.ServeRequest(
+		http.MethodPost,
+		"/organizations/:organization/security_groups", fmt.Sprintf("/organizations/%s/security_groups", suite.testOrganizationID.String()),
+		func(c *gin.Context) {
+			c.Set("nexodus.secGroupsEnabled", "true")
+			suite.api.CreateSecurityGroup(c)
+		},
+		bytes.NewBuffer(resBody),
+	)
+	require.NoError(err)
+
+	body, err := io.ReadAll(res.Body)
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", string(body))
+
+	var group models.SecurityGroup
+	err = json.Unmarshal(body, &group)
+	require.NoError(err)
+	assert.Equal("tcp", group.InboundRules[0].IpProtocol)
+	assert.Equal("icmpv6", group.OutboundRules[0].IpProtocol)
+}
+
+func (suite *HandlerTestSuite) TestSimulateSecurityGroup() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	webGroup := models.SecurityGroup{
+		GroupName:      "web",
+		OrganizationId: suite.testOrganizationID,
+		InboundRules: []models.SecurityRule{
+			{IpProtocol: "icmp"},
+			{IpProtocol: "tcp", FromPort: 443, ToPort: 443, AllDevices: true},
+		},
+	}
+	require.NoError(suite.api.db.Create(&webGroup).Error)
+
+	client := models.Device{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "simulateclientkey",
+		TunnelIP:       "100.100.0.1",
+		TunnelIpV6:     "200::1",
+	}
+	require.NoError(suite.api.db.Create(&client).Error)
+	server := models.Device{
+		OrganizationID:  suite.testOrganizationID,
+		PublicKey:       "simulateserverkey",
+		TunnelIP:        "100.100.0.2",
+		TunnelIpV6:      "200::2",
+		SecurityGroupId: webGroup.ID,
+	}
+	require.NoError(suite.api.db.Create(&server).Error)
+
+	simulate := func(request models.SimulateSecurityGroup) (int, models.SecurityGroupSimulation) {
+		reqBody, err := json.Marshal(request)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(
+			http.MethodPost,
+			"/organizations/:organization/security_groups/simulate", fmt.Sprintf("/organizations/%s/security_groups/simulate", suite.testOrganizationID.String()),
+			func(c *gin.Context) {
+				c.Set("nexodus.secGroupsEnabled", "true")
+				suite.api.SimulateSecurityGroup(c)
+			},
+			bytes.NewBuffer(reqBody),
+		)
+		require.NoError(err)
+		body, err := io.ReadAll(res.Body)
+		require.NoError(err)
+
+		var result models.SecurityGroupSimulation
+		if res.Code == http.StatusOK {
+			require.NoError(json.Unmarshal(body, &result))
+		}
+		return res.Code, result
+	}
+
+	code, result := simulate(models.SimulateSecurityGroup{
+		SourceDeviceId:      client.ID,
+		DestinationDeviceId: server.ID,
+		IpProtocol:          "tcp",
+		Port:                443,
+	})
+	require.Equal(http.StatusOK, code)
+	assert.True(result.Allowed)
+	assert.True(result.Outbound.Allowed)
+	assert.Equal(-1, result.Outbound.RuleIndex)
+	require.NotNil(result.Inbound)
+	assert.Equal(server.ID, result.Inbound.DeviceId)
+	assert.Equal(webGroup.ID, result.Inbound.SecurityGroupId)
+	assert.Equal(1, result.Inbound.RuleIndex)
+	assert.Equal(&webGroup.InboundRules[1], result.Inbound.Rule)
+
+	// the destination device is looked up by its tunnel address
+	code, result = simulate(models.SimulateSecurityGroup{
+		SourceDeviceId: client.ID,
+		DestinationIp:  "200::2",
+		IpProtocol:     "tcp",
+		Port:           22,
+	})
+	require.Equal(http.StatusOK, code)
+	assert.False(result.Allowed)
+	require.NotNil(result.Inbound)
+	assert.False(result.Inbound.Allowed)
+	assert.Nil(result.Inbound.Rule)
+
+	code, result = simulate(models.SimulateSecurityGroup{
+		SourceDeviceId: client.ID,
+		DestinationIp:  "100.100.0.2",
+		IpProtocol:     "icmp",
+	})
+	require.Equal(http.StatusOK, code)
+	assert.True(result.Allowed)
+	assert.Equal(0, result.Inbound.RuleIndex)
+
+	// a destination outside the organization only has an outbound verdict
+	code, result = simulate(models.SimulateSecurityGroup{
+		SourceDeviceId: server.ID,
+		DestinationIp:  "10.0.0.1",
+		IpProtocol:     "udp",
+		Port:           53,
+	})
+	require.Equal(http.StatusOK, code)
+	assert.True(result.Allowed)
+	assert.Nil(result.Inbound)
+
+	code, _ = simulate(models.SimulateSecurityGroup{
+		SourceDeviceId:      client.ID,
+		DestinationDeviceId: server.ID,
+		IpProtocol:          "gre",
+	})
+	assert.Equal(http.StatusBadRequest, code)
+
+	// a source device without an IPv6 tunnel address cannot reach an IPv6 destination
+	v4Only := models.Device{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "simulatev4onlykey",
+		TunnelIP:       "100.100.0.3",
+	}
+	require.NoError(suite.api.db.Create(&v4Only).Error)
+	code, _ = simulate(models.SimulateSecurityGroup{
+		SourceDeviceId: v4Only.ID,
+		DestinationIp:  "200::2",
+		IpProtocol:     "tcp",
+		Port:           443,
+	})
+	assert.Equal(http.StatusBadRequest, code)
+}
+
+func (suite *HandlerTestSuite) TestWatchSecurityGroups() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(gin.AuthUserKey, TestUserID)
+		c.Set("nexodus.secGroupsEnabled", "true")
+		c.Next()
+	})
+	r.GET("/organizations/:organization/security_groups", suite.api.ListSecurityGroups)
+	r.PATCH("/organizations/:organization/security_groups/:id", suite.api.UpdateSecurityGroup)
+	r.DELETE("/organizations/:organization/security_groups/:id", suite.api.DeleteSecurityGroup)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// the revisions are set by the test, sqlite does not generate them, so the watch is signaled again
+	// once the revision of a change is set
+	var revision uint64
+	require.NoError(suite.api.db.Unscoped().Model(&models.SecurityGroup{}).
+		Select("COALESCE(MAX(revision), 0)").Scan(&revision).Error)
+	revision++
+	group := models.SecurityGroup{GroupName: "watched", OrganizationId: suite.testOrganizationID, Revision: revision + 1}
+	require.NoError(suite.api.db.Create(&group).Error)
+	setRevision := func(revision uint64) {
+		require.NoError(suite.api.db.Unscoped().Model(&models.SecurityGroup{}).Where("id = ?", group.ID).
+			Update("revision", revision).Error)
+		suite.api.signalBus.Notify(fmt.Sprintf("/security-groups/org=%s", suite.testOrganizationID))
+	}
+	send := func(method string, body interface{}) {
+		reqBody, err := json.Marshal(body)
+		require.NoError(err)
+		req, err := http.NewRequest(method, fmt.Sprintf("%s/organizations/%s/security_groups/%s",
+			server.URL, suite.testOrganizationID, group.ID), bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(err)
+		defer res.Body.Close()
+		require.Equal(http.StatusOK, res.StatusCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/organizations/%s/security_groups?watch=true&gt_revision=%d",
+		server.URL, suite.testOrganizationID, revision), nil)
+	require.NoError(err)
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(err)
+	defer res.Body.Close()
+	decoder := json.NewDecoder(res.Body)
+	next := func() (string, models.SecurityGroup) {
+		var event struct {
+			Type  string               `json:"type"`
+			Value models.SecurityGroup `json:"value"`
+		}
+		require.NoError(decoder.Decode(&event))
+		return event.Type, event.Value
+	}
+
+	eventType, value := next()
+	assert.Equal("change", eventType)
+	assert.Equal(group.ID, value.ID)
+	eventType, _ = next()
+	assert.Equal("bookmark", eventType)
+
+	send(http.MethodPatch, models.UpdateSecurityGroup{GroupDescription: "updated"})
+	setRevision(revision + 2)
+	eventType, value = next()
+	assert.Equal("change", eventType)
+	assert.Equal(group.ID, value.ID)
+	assert.Equal("updated", value.GroupDescription)
+
+	send(http.MethodDelete, nil)
+	setRevision(revision + 3)
+	eventType, value = next()
+	assert.Equal("delete", eventType)
+	assert.Equal(group.ID, value.ID)
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"github.com/nexodus-io/nexodus/internal/secgroup"
+	"gorm.io/gorm"
+)
+
+// errInvalidSecurityRule reports the field of a security rule that failed validation,
+// Field is the path of the offending field in the request, for example inbound_rules[2].to_port
+type errInvalidSecurityRule struct {
+	Field  string
+	Reason string
+}
+
+func (e errInvalidSecurityRule) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Reason)
+}
+
+// normalizeSecurityGroupRules validates and normalizes the inbound and outbound rules of a
+// security group request, it writes an HTTP 400 response and returns false if a rule is invalid.
+func normalizeSecurityGroupRules(c *gin.Context, inbound, outbound []models.SecurityRule) ([]models.SecurityRule, []models.SecurityRule, bool) {
+	var invalid errInvalidSecurityRule
+	inbound, err := normalizeSecurityRules("inbound_rules", inbound)
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.Field, invalid.Reason))
+		return nil, nil, false
+	}
+	outbound, err = normalizeSecurityRules("outbound_rules", outbound)
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError(invalid.Field, invalid.Reason))
+		return nil, nil, false
+	}
+	return inbound, outbound, true
+}
+
+// normalizeSecurityRules validates the rules of a security group and returns them in their
+// canonical form with lower case protocol names and aliases such as icmp6 replaced. The field
+// name is used as the prefix of the field reported in errInvalidSecurityRule.
+func normalizeSecurityRules(field string, rules []models.SecurityRule) ([]models.SecurityRule, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	result := make([]models.SecurityRule, 0, len(rules))
+	for i, rule := range rules {
+		prefix := fmt.Sprintf("%s[%d]", field, i)
+		normalized, err := normalizeSecurityRule(prefix, rule)
+		if err != nil {
+			return nil, err
+		}
+		for j, previous := range result {
+			if reflect.DeepEqual(previous, normalized) {
+				return nil, errInvalidSecurityRule{
+					Field:  prefix,
+					Reason: fmt.Sprintf("duplicates %s[%d]", field, j),
+				}
+			}
+		}
+		result = append(result, normalized)
+	}
+	return result, nil
+}
+
+func normalizeSecurityRule(prefix string, rule models.SecurityRule) (models.SecurityRule, error) {
+	if rule.IpProtocol == "" {
+		return rule, errInvalidSecurityRule{Field: prefix + ".ip_protocol", Reason: "field not present"}
+	}
+	protocol, err := secgroup.NormalizeProtocol(rule.IpProtocol)
+	if err != nil {
+		return rule, errInvalidSecurityRule{
+			Field:  prefix + ".ip_protocol",
+			Reason: "must be one of ipv4, ipv6, icmp, icmpv4, icmpv6, tcp or udp",
+		}
+	}
+	rule.IpProtocol = protocol
+
+	if rule.FromPort < 0 || rule.FromPort > 65535 {
+		return rule, errInvalidSecurityRule{Field: prefix + ".from_port", Reason: "must be between 0 and 65535"}
+	}
+	if rule.ToPort < 0 || rule.ToPort > 65535 {
+		return rule, errInvalidSecurityRule{Field: prefix + ".to_port", Reason: "must be between 0 and 65535"}
+	}
+	switch protocol {
+	case secgroup.ProtoICMP, secgroup.ProtoICMPv4, secgroup.ProtoICMPv6:
+		if rule.FromPort != 0 {
+			return rule, errInvalidSecurityRule{Field: prefix + ".from_port", Reason: "must be 0 for icmp rules"}
+		}
+		if rule.ToPort != 0 {
+			return rule, errInvalidSecurityRule{Field: prefix + ".to_port", Reason: "must be 0 for icmp rules"}
+		}
+	}
+	if rule.FromPort > rule.ToPort {
+		return rule, errInvalidSecurityRule{Field: prefix + ".to_port", Reason: "must not be less than from_port"}
+	}
+
+	var parsed []secgroup.AddrRange
+	var indexes []int
+	for j, ipRange := range rule.IpRanges {
+		if strings.TrimSpace(ipRange) == "" {
+			continue
+		}
+		field := fmt.Sprintf("%s.ip_ranges[%d]", prefix, j)
+		r, err := secgroup.ParseAddrRange(ipRange)
+		if err != nil {
+			return rule, errInvalidSecurityRule{Field: field, Reason: "must be an ip address, a CIDR or a dash-separated range"}
+		}
+		switch protocol {
+		case secgroup.ProtoIPv4, secgroup.ProtoICMPv4:
+			if !r.From.Is4() {
+				return rule, errInvalidSecurityRule{Field: field, Reason: fmt.Sprintf("must be an IPv4 range for %s rules", protocol)}
+			}
+		case secgroup.ProtoIPv6, secgroup.ProtoICMPv6:
+			if r.From.Is4() {
+				return rule, errInvalidSecurityRule{Field: field, Reason: fmt.Sprintf("must be an IPv6 range for %s rules", protocol)}
+			}
+		}
+		for k, other := range parsed {
+			if r.Overlaps(other) {
+				return rule, errInvalidSecurityRule{
+					Field:  field,
+					Reason: fmt.Sprintf("overlaps %s.ip_ranges[%d]", prefix, indexes[k]),
+				}
+			}
+		}
+		parsed = append(parsed, r)
+		indexes = append(indexes, j)
+	}
+
+	if err := checkUniqueIds(prefix+".security_group_ids", rule.SecurityGroupIds); err != nil {
+		return rule, err
+	}
+	if err := checkUniqueIds(prefix+".device_ids", rule.DeviceIds); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+func checkUniqueIds(field string, ids []uuid.UUID) error {
+	seen := map[uuid.UUID]int{}
+	for i, id := range ids {
+		if id == uuid.Nil {
+			return errInvalidSecurityRule{Field: fmt.Sprintf("%s[%d]", field, i), Reason: "must not be empty"}
+		}
+		if j, ok := seen[id]; ok {
+			return errInvalidSecurityRule{Field: fmt.Sprintf("%s[%d]", field, i), Reason: fmt.Sprintf("duplicates %s[%d]", field, j)}
+		}
+		seen[id] = i
+	}
+	return nil
+}
+
+// checkSecurityRuleReferences verifies that the security groups and devices referred to by
+// the rules belong to the organization.
+func checkSecurityRuleReferences(tx *gorm.DB, orgId uuid.UUID, field string, rules []models.SecurityRule) error {
+	for i, rule := range rules {
+		for j, id := range rule.SecurityGroupIds {
+			var count int64
+			if res := tx.Model(&models.SecurityGroup{}).
+				Where("id = ? AND organization_id = ?", id, orgId).
+				Count(&count); res.Error != nil {
+				return res.Error
+			}
+			if count == 0 {
+				return errInvalidSecurityRule{
+					Field:  fmt.Sprintf("%s[%d].security_group_ids[%d]", field, i, j),
+					Reason: "security group not found in the organization",
+				}
+			}
+		}
+		for j, id := range rule.DeviceIds {
+			var count int64
+			if res := tx.Model(&models.Device{}).
+				Where("id = ? AND organization_id = ?", id, orgId).
+				Count(&count); res.Error != nil {
+				return res.Error
+			}
+			if count == 0 {
+				return errInvalidSecurityRule{
+					Field:  fmt.Sprintf("%s[%d].device_ids[%d]", field, i, j),
+					Reason: "device not found in the organization",
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -17,6 +17,9 @@ import (
 // key for username in gin.Context
 const AuthUserName string = "_nexodus.UserName"
 
+// key for the space separated scopes of the credentials in gin.Context
+const AuthUserScope string = "_nexodus.UserScope"
+
 func (api *API) CreateUserIfNotExists() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.GetString(gin.AuthUserKey)
@@ -114,12 +117,17 @@ func (api *API) createUserOrgIfNotExists(ctx context.Context, userId string, use
 		IpCidr:      defaultOrganizationPrefixIPv4,
 		IpCidrV6:    defaultOrganizationPrefixIPv6,
 		HubZone:     true,
-		Users: []*models.User{&models.User{
-			ID: userId,
-		}},
 	}
 	if res = api.db.Create(&org); res.Error == nil {
 
+		if res := api.db.Create(&models.UserOrganization{
+			UserID:         userId,
+			OrganizationID: org.ID,
+			Role:           models.OrganizationRoleAdmin,
+		}); res.Error != nil {
+			return noUUID, fmt.Errorf("can't add the user to the organization: %w", res.Error)
+		}
+
 		if err := api.ipam.CreateNamespace(ctx, org.ID); err != nil {
 			return noUUID, fmt.Errorf("failed to create ipam namespace: %w", err)
 		}
@@ -235,7 +243,8 @@ func (api *API) ListUsers(c *gin.Context) {
 // @Param        id  path       string  true  "User ID"
 // @Success      200  {object}  models.User
 // @Failure		 400  {object}  models.BaseError
-// @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
 // @Failure		 429  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/users/{id} [delete]
@@ -250,13 +259,36 @@ func (api *API) DeleteUser(c *gin.Context) {
 
 	var user models.User
 	err := api.transaction(ctx, func(tx *gorm.DB) error {
-		if res := api.db.
+		if res := tx.
 			Scopes(api.UserIsCurrentUser(c)).
 			First(&user, "id = ?", userID); res.Error != nil {
 			return errUserNotFound
 		}
-		if res := api.db.Select(clause.Associations).Delete(&user); res.Error != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to delete user: %w", res.Error)))
+		// an owner can not leave the other users of an organization without an owner
+		var shared int64
+		if res := tx.Model(&models.UserOrganization{}).
+			Where("user_id <> ?", userID).
+			Where("organization_id IN (?)", tx.Model(&models.Organization{}).Select("id").Where("owner_id = ?", userID)).
+			Count(&shared); res.Error != nil {
+			return res.Error
+		}
+		if shared > 0 {
+			return errOrgOwnerMustTransfer
+		}
+		var memberships []models.UserOrganization
+		if res := tx.Where("user_id = ?", userID).Find(&memberships); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Select(clause.Associations).Delete(&user); res.Error != nil {
+			return fmt.Errorf("failed to delete user: %w", res.Error)
+		}
+		if res := tx.Where("user_id = ?", userID).Delete(&models.ApiToken{}); res.Error != nil {
+			return res.Error
+		}
+		for _, membership := range memberships {
+			if err := api.recordAuditEvent(ctx, c, tx, membership.OrganizationID, "organization_role", userID, auditActionDelete, membership, nil); err != nil {
+				return err
+			}
 		}
 
 		return nil
@@ -265,6 +297,8 @@ func (api *API) DeleteUser(c *gin.Context) {
 	if err != nil {
 		if errors.Is(err, errUserNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
+		} else if errors.Is(err, errOrgOwnerMustTransfer) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError("the ownership of the organizations shared with other users must be transferred first"))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -273,11 +307,6 @@ func (api *API) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
-type UserOrganization struct {
-	UserID         string    `json:"user_id"`
-	OrganizationID uuid.UUID `json:"organization_id"`
-}
-
 // DeleteUserFromOrganization removes a user from an organization
 // @Summary      Remove a User from an Organization
 // @Description  Deletes an existing organization associated to a user
@@ -289,7 +318,8 @@ type UserOrganization struct {
 // @Param        organization   path      string  true "Organization ID"
 // @Success      204  {object}  models.User
 // @Failure      400  {object}  models.BaseError
-// @Failure      400  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
 // @Failure      500  {object}  models.BaseError
 // @Router       /api/users/{id}/organizations/{organization} [delete]
 func (api *API) DeleteUserFromOrganization(c *gin.Context) {
@@ -302,37 +332,59 @@ func (api *API) DeleteUserFromOrganization(c *gin.Context) {
 		return
 	}
 
-	orgID := c.Param("organization")
-	if userID == "" {
+	orgID, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
 		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
 		return
 	}
 
 	var user models.User
 	var organization models.Organization
-	err := api.transaction(ctx, func(tx *gorm.DB) error {
-		if res := api.db.First(&user, "id = ?", userID); res.Error != nil {
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		// users can leave an organization, only its admins can remove other users
+		scope := api.OrganizationIsAdministeredByCurrentUser(c)
+		if userID == c.GetString(gin.AuthUserKey) {
+			scope = api.OrganizationIsReadableByCurrentUser(c)
+		}
+		if res := tx.Scopes(scope).First(&organization, "id = ?", orgID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				return api.organizationRoleError(tx, c, orgID)
+			}
+			return res.Error
+		}
+		if organization.OwnerID == userID {
+			if userID == c.GetString(gin.AuthUserKey) {
+				return errOrgOwnerMustTransfer
+			}
+			return errOrgOwnerNotAllowed
+		}
+		if res := tx.First(&user, "id = ?", userID); res.Error != nil {
 			return errUserNotFound
 		}
-		if res := api.db.First(&organization, "id = ?", orgID); res.Error != nil {
-			return errOrgNotFound
+		var membership models.UserOrganization
+		if res := tx.First(&membership, "organization_id = ? AND user_id = ?", orgID, userID); res.Error != nil {
+			if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+				// the user is not a member, there is nothing to remove
+				return nil
+			}
+			return res.Error
 		}
-		if res := api.db.
-			Select(clause.Associations).
+		if res := tx.
 			Where("user_id = ?", userID).
 			Where("organization_id = ?", orgID).
-			Delete(&UserOrganization{}); res.Error != nil {
-			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(fmt.Errorf("failed to remove the association from the user_organizations table: %w", res.Error)))
+			Delete(&models.UserOrganization{}); res.Error != nil {
+			return fmt.Errorf("failed to remove the association from the user_organizations table: %w", res.Error)
 		}
-		return nil
+		return api.recordAuditEvent(ctx, c, tx, orgID, "organization_role", userID, auditActionDelete, membership, nil)
 	})
 
 	if err != nil {
 		if errors.Is(err, errUserNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("user"))
-		}
-		if errors.Is(err, errOrgNotFound) {
+		} else if errors.Is(err, errOrgNotFound) {
 			c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+		} else if errors.Is(err, errOrgRoleNotAllowed) || errors.Is(err, errOrgOwnerNotAllowed) || errors.Is(err, errOrgOwnerMustTransfer) {
+			c.JSON(http.StatusForbidden, models.NewNotAllowedError(err.Error()))
 		} else {
 			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
 		}
@@ -0,0 +1,740 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nexodus-io/nexodus/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+const (
+	// webhookAttempts is how many times the delivery of an event is attempted before giving up
+	webhookAttempts = 5
+	// webhookRetryBackoff is the delay before the second attempt, it doubles after every failed attempt
+	webhookRetryBackoff = 30 * time.Second
+	// webhookTimeout is how long a webhook has to answer a delivery
+	webhookTimeout = 10 * time.Second
+	// webhookDeliveryInterval is how often the pending deliveries are sent
+	webhookDeliveryInterval = 5 * time.Second
+	// webhookDeliveryBatch is the maximum number of deliveries sent every interval
+	webhookDeliveryBatch = 100
+	// webhookConcurrency is the maximum number of deliveries sent at the same time to a webhook
+	webhookConcurrency = 4
+	// webhookLease is how long the deliveries of a batch are held while they are sent, long enough for a
+	// webhook that times out on every delivery of the batch
+	webhookLease = (webhookDeliveryBatch/webhookConcurrency + 1) * webhookTimeout
+
+	WebhookSignatureHeader = "X-Nexodus-Signature"
+	WebhookEventHeader     = "X-Nexodus-Event"
+	WebhookDeliveryHeader  = "X-Nexodus-Delivery"
+)
+
+// webhookEventTypeRegex matches the event types of the webhooks: <resource type>.<action>, <resource type>.* or *
+var webhookEventTypeRegex = regexp.MustCompile(`^(\*|[a-z_]+\.(\*|[a-z_]+))$`)
+
+// webhookEventType returns the type of the webhook event of an audit event
+func webhookEventType(event models.AuditEvent) string {
+	return event.ResourceType + "." + event.Action
+}
+
+// webhookMatches returns true when the event types of a webhook include eventType
+func webhookMatches(eventTypes []string, eventType string) bool {
+	if len(eventTypes) == 0 {
+		return true
+	}
+	resourceType, _, _ := strings.Cut(eventType, ".")
+	for _, t := range eventTypes {
+		if t == "*" || t == eventType || t == resourceType+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which is also used by the organization prefixes
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// isPublicAddr returns false for the loopback, private, link-local, multicast and unspecified addresses,
+// the webhooks can not be used to reach the apiserver itself or the services of its network
+func isPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// newWebhookClient returns the client that sends the webhook deliveries, it only connects to the
+// addresses allowed by allowed, after resolving the host of the webhook, and it does not follow redirects
+func newWebhookClient(allowed func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !allowed(addrPort.Addr()) {
+				return fmt.Errorf("the webhook address %s is not allowed", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be dialed instead of the webhook
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// SignWebhookPayload returns the value of the signature header of a webhook payload
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func validateWebhookURL(u string) error {
+	parsed, err := url.Parse(u)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errors.New("must be an http or https URL")
+	}
+	return nil
+}
+
+func validateWebhookEventTypes(eventTypes []string) error {
+	for _, t := range eventTypes {
+		if !webhookEventTypeRegex.MatchString(t) {
+			return fmt.Errorf("%q must be <resource type>.<action>, <resource type>.* or *", t)
+		}
+	}
+	return nil
+}
+
+func newWebhookSecret() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// withoutSecret returns the webhook without its secret, so it can be returned or recorded
+func withoutSecret(webhook models.Webhook) models.Webhook {
+	webhook.Secret = ""
+	return webhook
+}
+
+// queueWebhookDeliveries queues the delivery of an audit event to the matching webhooks of its organization
+func (api *API) queueWebhookDeliveries(ctx context.Context, db *gorm.DB, event models.AuditEvent) error {
+	if event.OrganizationID == uuid.Nil {
+		return nil
+	}
+	var webhooks []models.Webhook
+	if res := db.WithContext(ctx).
+		Where("organization_id = ? AND enabled = ?", event.OrganizationID, true).
+		Find(&webhooks); res.Error != nil {
+		return res.Error
+	}
+	eventType := webhookEventType(event)
+	var payload []byte
+	for _, webhook := range webhooks {
+		if !webhookMatches(webhook.EventTypes, eventType) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			payload, err = json.Marshal(models.WebhookPayload{
+				ID:             event.ID,
+				Type:           eventType,
+				OrganizationID: event.OrganizationID,
+				CreatedAt:      event.CreatedAt,
+				Event:          event,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		nextAttempt := event.CreatedAt
+		if res := db.WithContext(ctx).Create(&models.WebhookDelivery{
+			WebhookID:   webhook.ID,
+			EventID:     event.ID,
+			EventType:   eventType,
+			Payload:     string(payload),
+			Status:      models.WebhookDeliveryPending,
+			NextAttempt: &nextAttempt,
+		}); res.Error != nil {
+			return res.Error
+		}
+	}
+	return nil
+}
+
+// webhookResult is the outcome of sending a delivery
+type webhookResult struct {
+	delivery models.WebhookDelivery
+	webhook  models.Webhook
+	code     int
+	err      error
+}
+
+// deliverWebhooks sends the pending webhook deliveries that are due before now, the deliveries of
+// different webhooks are sent in parallel and at most webhookConcurrency at a time to the same webhook
+func (api *API) deliverWebhooks(ctx context.Context, now time.Time) error {
+	ctx, span := tracer.Start(ctx, "deliverWebhooks")
+	defer span.End()
+
+	var deliveries []models.WebhookDelivery
+	if res := api.db.WithContext(ctx).
+		Where("status = ? AND next_attempt <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt").
+		Limit(webhookDeliveryBatch).
+		Find(&deliveries); res.Error != nil {
+		return res.Error
+	}
+
+	webhooks := map[uuid.UUID]*models.Webhook{}
+	pending := map[uuid.UUID][]models.WebhookDelivery{}
+	for _, delivery := range deliveries {
+		// another apiserver may be sending the same delivery, hold it while it is being sent
+		res := api.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+			Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.WebhookDeliveryPending, delivery.Attempts).
+			Updates(map[string]interface{}{
+				"attempts":     delivery.Attempts + 1,
+				"next_attempt": now.Add(webhookLease),
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			continue
+		}
+		delivery.Attempts++
+
+		webhook, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			webhook = &models.Webhook{}
+			if res := api.db.WithContext(ctx).First(webhook, "id = ?", delivery.WebhookID); res.Error != nil {
+				if !errors.Is(res.Error, gorm.ErrRecordNotFound) {
+					return res.Error
+				}
+				// the deliveries of a deleted webhook are deleted along with it
+				webhook = nil
+			}
+			webhooks[delivery.WebhookID] = webhook
+		}
+		if webhook == nil {
+			continue
+		}
+		pending[webhook.ID] = append(pending[webhook.ID], delivery)
+	}
+
+	results := make(chan webhookResult, len(deliveries))
+	wg := sync.WaitGroup{}
+	for webhookId, queued := range pending {
+		webhook := *webhooks[webhookId]
+		queue := make(chan models.WebhookDelivery, len(queued))
+		for _, delivery := range queued {
+			queue <- delivery
+		}
+		close(queue)
+		for i := 0; i < webhookConcurrency && i < len(queued); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for delivery := range queue {
+					result := webhookResult{delivery: delivery, webhook: webhook, err: errors.New("the webhook is disabled")}
+					if webhook.Enabled {
+						result.code, result.err = api.sendWebhook(ctx, webhook, delivery)
+					}
+					results <- result
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	close(results)
+
+	for result := range results {
+		delivery, webhook, err := result.delivery, result.webhook, result.err
+		updates := map[string]interface{}{
+			"last_attempt":  now,
+			"response_code": result.code,
+			"error":         "",
+		}
+		if err == nil {
+			updates["status"] = models.WebhookDeliverySucceeded
+			updates["next_attempt"] = nil
+		} else if delivery.Attempts >= webhookAttempts || !webhook.Enabled {
+			updates["status"] = models.WebhookDeliveryFailed
+			updates["next_attempt"] = nil
+			updates["error"] = err.Error()
+		} else {
+			updates["next_attempt"] = now.Add(webhookRetryBackoff << (delivery.Attempts - 1))
+			updates["error"] = err.Error()
+		}
+		if res := api.db.WithContext(ctx).Model(&delivery).Updates(updates); res.Error != nil {
+			return res.Error
+		}
+		if err != nil {
+			api.Logger(ctx).Debugw("webhook delivery failed",
+				"webhook", webhook.ID, "delivery", delivery.ID, "attempts", delivery.Attempts, "error", err)
+		}
+	}
+	return nil
+}
+
+// sendWebhook posts a delivery to its webhook, it returns the status code of the response
+func (api *API) sendWebhook(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.String())
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, payload))
+	res, err := api.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected response status %d", res.StatusCode)
+	}
+	return res.StatusCode, nil
+}
+
+// administeredOrganization checks that the current user is an admin of the organization
+func (api *API) administeredOrganization(db *gorm.DB, c *gin.Context, orgId uuid.UUID) error {
+	var org models.Organization
+	if res := db.Select("id").
+		Scopes(api.OrganizationIsAdministeredByCurrentUser(c)).
+		First(&org, "id = ?", orgId); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return api.organizationRoleError(db, c, orgId)
+		}
+		return res.Error
+	}
+	return nil
+}
+
+// webhookErrorResponse writes the response of a failed webhook request
+func webhookErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, errOrgNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("organization"))
+	} else if errors.Is(err, errWebhookNotFound) {
+		c.JSON(http.StatusNotFound, models.NewNotFoundError("webhook"))
+	} else if errors.Is(err, errOrgRoleNotAllowed) {
+		c.JSON(http.StatusForbidden, models.NewNotAllowedError("only the organization admins can manage its webhooks"))
+	} else {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+	}
+}
+
+// CreateWebhook creates a webhook for the events of an organization
+// @Summary      Create Webhook
+// @Description  Creates a webhook that receives the events of the organization, requires the admin role. The secret of the webhook is only returned by this call.
+// @Id           CreateWebhook
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        webhook  body   models.AddWebhook  true "Add Webhook"
+// @Success      201  {object}  models.Webhook
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks [post]
+func (api *API) CreateWebhook(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "CreateWebhook", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+
+	var request models.AddWebhook
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.URL == "" {
+		c.JSON(http.StatusBadRequest, models.NewFieldNotPresentError("url"))
+		return
+	}
+	if err := validateWebhookURL(request.URL); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("url", err.Error()))
+		return
+	}
+	if err := validateWebhookEventTypes(request.EventTypes); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("event_types", err.Error()))
+		return
+	}
+	if request.Secret == "" {
+		request.Secret, err = newWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, models.NewApiInternalError(err))
+			return
+		}
+	}
+
+	webhook := models.Webhook{
+		OrganizationID: orgId,
+		URL:            request.URL,
+		Description:    request.Description,
+		EventTypes:     request.EventTypes,
+		Enabled:        true,
+		Secret:         request.Secret,
+	}
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		if err := api.administeredOrganization(tx, c, orgId); err != nil {
+			return err
+		}
+		if res := tx.Create(&webhook); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, orgId, "webhook", webhook.ID.String(), auditActionCreate, nil, withoutSecret(webhook))
+	})
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks lists the webhooks of an organization
+// @Summary      List Webhooks
+// @Description  Lists the webhooks of the organization, requires the admin role
+// @Id           ListWebhooks
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Success      200  {object}  []models.Webhook
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks [get]
+func (api *API) ListWebhooks(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListWebhooks", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	if err := api.administeredOrganization(api.db.WithContext(ctx), c, orgId); err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+
+	webhooks := []models.Webhook{}
+	if res := api.db.WithContext(ctx).
+		Scopes(FilterAndPaginate(&models.Webhook{}, c, "created_at")).
+		Where("organization_id = ?", orgId).
+		Find(&webhooks); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	for i := range webhooks {
+		webhooks[i] = withoutSecret(webhooks[i])
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook gets a webhook of an organization
+// @Summary      Get Webhook
+// @Description  Gets a webhook of the organization by ID, requires the admin role
+// @Id           GetWebhook
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        id   path      string  true "Webhook ID"
+// @Success      200  {object}  models.Webhook
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks/{id} [get]
+func (api *API) GetWebhook(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "GetWebhook", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	webhook, err := api.getWebhook(api.db.WithContext(ctx), c, orgId, id)
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, withoutSecret(webhook))
+}
+
+// getWebhook gets a webhook of an organization administered by the current user
+func (api *API) getWebhook(db *gorm.DB, c *gin.Context, orgId, id uuid.UUID) (models.Webhook, error) {
+	var webhook models.Webhook
+	if err := api.administeredOrganization(db, c, orgId); err != nil {
+		return webhook, err
+	}
+	if res := db.First(&webhook, "id = ? AND organization_id = ?", id, orgId); res.Error != nil {
+		if errors.Is(res.Error, gorm.ErrRecordNotFound) {
+			return webhook, errWebhookNotFound
+		}
+		return webhook, res.Error
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook updates a webhook of an organization
+// @Summary      Update Webhook
+// @Description  Updates the URL, description, event types or state of a webhook, requires the admin role
+// @Id           UpdateWebhook
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        id   path      string  true "Webhook ID"
+// @Param        update  body   models.UpdateWebhook  true "Webhook Update"
+// @Success      200  {object}  models.Webhook
+// @Failure      400  {object}  models.ValidationError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks/{id} [patch]
+func (api *API) UpdateWebhook(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "UpdateWebhook", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var request models.UpdateWebhook
+	if err := c.BindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPayloadError())
+		return
+	}
+	if request.URL != nil {
+		if err := validateWebhookURL(*request.URL); err != nil {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("url", err.Error()))
+			return
+		}
+	}
+	if err := validateWebhookEventTypes(request.EventTypes); err != nil {
+		c.JSON(http.StatusBadRequest, models.NewFieldValidationError("event_types", err.Error()))
+		return
+	}
+
+	var webhook models.Webhook
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		webhook, err = api.getWebhook(tx, c, orgId, id)
+		if err != nil {
+			return err
+		}
+		before := withoutSecret(webhook)
+		if request.URL != nil {
+			webhook.URL = *request.URL
+		}
+		if request.Description != nil {
+			webhook.Description = *request.Description
+		}
+		if request.EventTypes != nil {
+			webhook.EventTypes = request.EventTypes
+		}
+		if request.Enabled != nil {
+			webhook.Enabled = *request.Enabled
+		}
+		if res := tx.Save(&webhook); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, orgId, "webhook", webhook.ID.String(), auditActionUpdate, before, withoutSecret(webhook))
+	})
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, withoutSecret(webhook))
+}
+
+// DeleteWebhook deletes a webhook of an organization
+// @Summary      Delete Webhook
+// @Description  Deletes a webhook and its delivery history, requires the admin role
+// @Id           DeleteWebhook
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        id   path      string  true "Webhook ID"
+// @Success      200  {object}  models.Webhook
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks/{id} [delete]
+func (api *API) DeleteWebhook(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "DeleteWebhook", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+
+	var webhook models.Webhook
+	err = api.transaction(ctx, func(tx *gorm.DB) error {
+		webhook, err = api.getWebhook(tx, c, orgId, id)
+		if err != nil {
+			return err
+		}
+		if res := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}); res.Error != nil {
+			return res.Error
+		}
+		if res := tx.Delete(&webhook); res.Error != nil {
+			return res.Error
+		}
+		return api.recordAuditEvent(ctx, c, tx, orgId, "webhook", webhook.ID.String(), auditActionDelete, withoutSecret(webhook), nil)
+	})
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, withoutSecret(webhook))
+}
+
+// ListWebhookDeliveries lists the deliveries of a webhook
+// @Summary      List Webhook Deliveries
+// @Description  Lists the deliveries of the events to a webhook, newest first, requires the admin role
+// @Id           ListWebhookDeliveries
+// @Tags         Webhooks
+// @Accept       json
+// @Produce      json
+// @Param        organization_id   path      string  true "Organization ID"
+// @Param        id   path      string  true "Webhook ID"
+// @Param		 status          query  string false "only the deliveries with this status: pending, succeeded or failed"
+// @Param		 limit           query  int    false "maximum number of deliveries, 100 by default"
+// @Success      200  {object}  []models.WebhookDelivery
+// @Failure      400  {object}  models.BaseError
+// @Failure		 401  {object}  models.BaseError
+// @Failure      403  {object}  models.NotAllowedError
+// @Failure      404  {object}  models.BaseError
+// @Failure		 429  {object}  models.BaseError
+// @Failure      500  {object}  models.BaseError
+// @Router       /api/organizations/{organization_id}/webhooks/{id}/deliveries [get]
+func (api *API) ListWebhookDeliveries(c *gin.Context) {
+	ctx, span := tracer.Start(c.Request.Context(), "ListWebhookDeliveries", trace.WithAttributes(
+		attribute.String("organization", c.Param("organization")),
+		attribute.String("id", c.Param("id")),
+	))
+	defer span.End()
+	orgId, err := uuid.Parse(c.Param("organization"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("organization"))
+		return
+	}
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.NewBadPathParameterError("id"))
+		return
+	}
+	limit := defaultListLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxListLimit {
+			c.JSON(http.StatusBadRequest, models.NewFieldValidationError("limit", "must be a number between 1 and 1000"))
+			return
+		}
+	}
+
+	webhook, err := api.getWebhook(api.db.WithContext(ctx), c, orgId, id)
+	if err != nil {
+		webhookErrorResponse(c, err)
+		return
+	}
+
+	db := api.db.WithContext(ctx).Where("webhook_id = ?", webhook.ID)
+	if v := c.Query("status"); v != "" {
+		db = db.Where("status = ?", v)
+	}
+	deliveries := []models.WebhookDelivery{}
+	if res := db.Order("created_at DESC").Limit(limit).Find(&deliveries); res.Error != nil {
+		c.JSON(http.StatusInternalServerError, models.NewApiInternalError(res.Error))
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
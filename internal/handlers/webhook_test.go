@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nexodus-io/nexodus/internal/models"
+)
+
+func (suite *HandlerTestSuite) TestWebhookDeliveries() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	type received struct {
+		header http.Header
+		body   []byte
+	}
+	requests := make(chan received, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{header: r.Header, body: body}
+	}))
+	defer receiver.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	createWebhook := func(add models.AddWebhook) models.Webhook {
+		reqBody, err := json.Marshal(add)
+		require.NoError(err)
+		_, res, err := suite.ServeRequest(http.MethodPost,
+			"/organizations/:organization/webhooks", fmt.Sprintf("/organizations/%s/webhooks", suite.testOrganizationID),
+			suite.api.CreateWebhook, bytes.NewBuffer(reqBody))
+		require.NoError(err)
+		require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+		var webhook models.Webhook
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &webhook))
+		return webhook
+	}
+	listDeliveries := func(webhook models.Webhook) []models.WebhookDelivery {
+		_, res, err := suite.ServeRequest(http.MethodGet,
+			"/organizations/:organization/webhooks/:id/deliveries", fmt.Sprintf("/organizations/%s/webhooks/%s/deliveries", suite.testOrganizationID, webhook.ID),
+			suite.api.ListWebhookDeliveries, nil)
+		require.NoError(err)
+		require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+		var deliveries []models.WebhookDelivery
+		require.NoError(json.Unmarshal(res.Body.Bytes(), &deliveries))
+		return deliveries
+	}
+
+	webhook := createWebhook(models.AddWebhook{
+		URL:        receiver.URL,
+		EventTypes: []string{"device.create"},
+		Secret:     "s3cr3t",
+	})
+	assert.Equal("s3cr3t", webhook.Secret)
+	assert.True(webhook.Enabled)
+	broken := createWebhook(models.AddWebhook{URL: failing.URL, EventTypes: []string{"device.*"}})
+	assert.NotEmpty(broken.Secret)
+
+	// invalid event types are rejected
+	reqBody, err := json.Marshal(models.AddWebhook{URL: receiver.URL, EventTypes: []string{"device"}})
+	require.NoError(err)
+	_, res, err := suite.ServeRequest(http.MethodPost,
+		"/organizations/:organization/webhooks", fmt.Sprintf("/organizations/%s/webhooks", suite.testOrganizationID),
+		suite.api.CreateWebhook, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	assert.Equal(http.StatusBadRequest, res.Code)
+
+	reqBody, err = json.Marshal(models.AddDevice{
+		OrganizationID: suite.testOrganizationID,
+		PublicKey:      "webhookpubkey",
+		Hostname:       "webhook",
+	})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(http.MethodPost, "/", "/", suite.api.CreateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusCreated, res.Code, "HTTP error: %s", res.Body.String())
+	var device models.Device
+	require.NoError(json.Unmarshal(res.Body.Bytes(), &device))
+
+	// the update does not match the event types of the first webhook
+	reqBody, err = json.Marshal(models.UpdateDevice{Hostname: "renamed"})
+	require.NoError(err)
+	_, res, err = suite.ServeRequest(http.MethodPatch, "/:id", fmt.Sprintf("/%s", device.ID), suite.api.UpdateDevice, bytes.NewBuffer(reqBody))
+	require.NoError(err)
+	require.Equal(http.StatusOK, res.Code, "HTTP error: %s", res.Body.String())
+
+	now := time.Now()
+	require.NoError(suite.api.deliverWebhooks(context.Background(), now))
+
+	require.Len(requests, 1)
+	req := <-requests
+	assert.Equal("device.create", req.header.Get(WebhookEventHeader))
+	assert.Equal(SignWebhookPayload("s3cr3t", req.body), req.header.Get(WebhookSignatureHeader))
+	var payload models.WebhookPayload
+	require.NoError(json.Unmarshal(req.body, &payload))
+	assert.Equal("device.create", payload.Type)
+	assert.Equal(suite.testOrganizationID, payload.OrganizationID)
+	assert.Equal(device.ID.String(), payload.Event.ResourceID)
+
+	deliveries := listDeliveries(webhook)
+	require.Len(deliveries, 1)
+	assert.Equal(models.WebhookDeliverySucceeded, deliveries[0].Status)
+	assert.Equal(req.header.Get(WebhookDeliveryHeader), deliveries[0].ID.String())
+	assert.Equal(http.StatusOK, deliveries[0].ResponseCode)
+
+	// the deliveries to the failing webhook are retried with a backoff until they run out of attempts
+	for i := 1; i < webhookAttempts; i++ {
+		for _, delivery := range listDeliveries(broken) {
+			assert.Equal(models.WebhookDeliveryPending, delivery.Status)
+			assert.Equal(i, delivery.Attempts)
+			assert.Equal(http.StatusServiceUnavailable, delivery.ResponseCode)
+		}
+		now = now.Add(webhookRetryBackoff << (i - 1))
+		require.NoError(suite.api.deliverWebhooks(context.Background(), now))
+	}
+	deliveries = listDeliveries(broken)
+	require.Len(deliveries, 2)
+	for _, delivery := range deliveries {
+		assert.Equal(models.WebhookDeliveryFailed, delivery.Status)
+		assert.Equal(webhookAttempts, delivery.Attempts)
+		assert.Nil(delivery.NextAttempt)
+	}
+	assert.Len(requests, 0)
+}
+
+func (suite *HandlerTestSuite) TestWebhookClient() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	received := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received++
+	}))
+	defer receiver.Close()
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, receiver.URL, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "100.100.0.1", "0.0.0.0", "::ffff:127.0.0.1"} {
+		assert.False(isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		assert.True(isPublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+
+	webhook := models.Webhook{URL: receiver.URL, Secret: "s3cr3t"}
+	delivery := models.WebhookDelivery{ID: uuid.New(), EventType: "device.create", Payload: "{}"}
+
+	// the webhooks can not reach the loopback addresses of the apiserver
+	api := *suite.api
+	api.webhookClient = newWebhookClient(isPublicAddr)
+	_, err := api.sendWebhook(context.Background(), webhook, delivery)
+	require.Error(err)
+	assert.Contains(err.Error(), "is not allowed")
+	assert.Equal(0, received)
+
+	// the redirects are not followed
+	webhook.URL = redirect.URL
+	code, err := suite.api.sendWebhook(context.Background(), webhook, delivery)
+	require.Error(err)
+	assert.Equal(http.StatusFound, code)
+	assert.Equal(0, received)
+}
+
+func (suite *HandlerTestSuite) TestWebhookConcurrency() {
+	require := suite.Require()
+	assert := suite.Assert()
+
+	var inFlight, maxInFlight, received int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		atomic.AddInt32(&received, 1)
+	}))
+	defer receiver.Close()
+
+	webhook := models.Webhook{
+		OrganizationID: suite.testOrganizationID,
+		URL:            receiver.URL,
+		Enabled:        true,
+		Secret:         "s3cr3t",
+	}
+	require.NoError(suite.api.db.Create(&webhook).Error)
+	now := time.Now()
+	deliveries := 3 * webhookConcurrency
+	for i := 0; i < deliveries; i++ {
+		require.NoError(suite.api.db.Create(&models.WebhookDelivery{
+			WebhookID:   webhook.ID,
+			EventID:     uuid.New(),
+			EventType:   "device.create",
+			Payload:     "{}",
+			Status:      models.WebhookDeliveryPending,
+			NextAttempt: &now,
+		}).Error)
+	}
+
+	require.NoError(suite.api.deliverWebhooks(context.Background(), now))
+	assert.Equal(int32(deliveries), atomic.LoadInt32(&received))
+	assert.LessOrEqual(atomic.LoadInt32(&maxInFlight), int32(webhookConcurrency))
+	assert.Greater(atomic.LoadInt32(&maxInFlight), int32(1))
+
+	var succeeded int64
+	require.NoError(suite.api.db.Model(&models.WebhookDelivery{}).
+		Where("webhook_id = ? AND status = ?", webhook.ID, models.WebhookDeliverySucceeded).
+		Count(&succeeded).Error)
+	assert.Equal(int64(deliveries), succeeded)
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ApiToken is a personal access token, it authenticates the API calls of automation as the user that created it
+type ApiToken struct {
+	Base
+	UserID      string `json:"user_id" gorm:"index"`
+	Description string `json:"description" example:"terraform"`
+	// Scopes limit the API calls that can be made with the token, like the scopes of an access token
+	Scopes pq.StringArray `json:"scopes" gorm:"type:text[]" swaggertype:"array,string" example:"read:devices,write:devices"`
+	// Prefix is the start of the token, it identifies the token without revealing it
+	Prefix     string     `json:"prefix" example:"nexapi_3fa85f"`
+	TokenHash  string     `json:"-" gorm:"uniqueIndex"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	// Token is only returned when the token is created, only its hash is stored
+	Token string `json:"token,omitempty" gorm:"-"`
+}
+
+// AddApiToken is the information needed to add a new API token.
+type AddApiToken struct {
+	Description string   `json:"description" example:"terraform"`
+	Scopes      []string `json:"scopes" example:"read:devices,write:devices"`
+	// ExpiresAt is when the token stops being valid, it does not expire when not set
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
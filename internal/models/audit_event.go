@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditEvent records a change made through the api
+type AuditEvent struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	// ActorID is the id of the user that made the change, it is empty for the changes the apiserver makes on its own
+	ActorID string `json:"actor_id"`
+	// OrganizationID is nil for the resources outside of the organizations, such as the api tokens and the
+	// feature flags, their events are not listed by any endpoint
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	ResourceType   string    `json:"resource_type" example:"device"`
+	ResourceID     string    `json:"resource_id"`
+	Action         string    `json:"action" example:"update"`
+	// Changes holds the fields of the resource that were changed by the action
+	Changes map[string]AuditChange `json:"changes" gorm:"type:JSONB; serializer:json"`
+	TraceID string                 `json:"trace_id"`
+}
+
+// AuditChange is the value of a field before and after a change
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
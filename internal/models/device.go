@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/lib/pq"
 )
@@ -27,6 +29,29 @@ type Device struct {
 	Endpoints                []Endpoint     `json:"endpoints" gorm:"type:JSONB; serializer:json"`
 	Revision                 uint64         `json:"revision" gorm:"type:bigserial;index:"`
 	SecurityGroupId          uuid.UUID      `json:"security_group_id"`
+	// PreviousTunnelIP and PreviousTunnelIpV6 are the addresses the device had before its
+	// organization was re-addressed, they stay valid until the end of the grace period
+	PreviousTunnelIP   string `json:"previous_tunnel_ip"`
+	PreviousTunnelIpV6 string `json:"previous_tunnel_ip_v6"`
+	// RegKeyID is the registration key the device was registered with
+	RegKeyID *uuid.UUID `json:"reg_key_id,omitempty" gorm:"type:uuid"`
+	// DeviceTokenHash is the hash of the credential of a device registered with a registration key
+	DeviceTokenHash string `json:"-"`
+	// DeviceToken is the credential nexd authenticates with once the device is registered with a
+	// registration key, it is only returned when the device is created, only its hash is stored
+	DeviceToken string `json:"device_token,omitempty" gorm:"-"`
+	// Pending devices were registered in an organization that requires approval, they
+	// are not sent to the other devices until an admin approves them
+	Pending bool `json:"pending"`
+	// Ephemeral devices are deleted once they have not been updated or reported their status for
+	// the ephemeral device TTL of the apiserver
+	Ephemeral bool `json:"ephemeral"`
+	// Labels are free-form key/value pairs that can be used to select the devices
+	Labels map[string]string `json:"labels" gorm:"type:JSONB; serializer:json"`
+	// Online and LastSeen are computed from the last status reported by the device, they
+	// are not included in the watch events
+	Online   bool       `json:"online" gorm:"-"`
+	LastSeen *time.Time `json:"last_seen,omitempty" gorm:"-"`
 }
 
 // AddDevice is the information needed to add a new Device.
@@ -45,6 +70,9 @@ type AddDevice struct {
 	Endpoints                []Endpoint `json:"endpoints" gorm:"type:JSONB; serializer:json"`
 	Os                       string     `json:"os"`
 	SecurityGroupId          uuid.UUID  `json:"security_group_id"`
+	// Ephemeral devices are deleted automatically once they go away
+	Ephemeral bool              `json:"ephemeral"`
+	Labels    map[string]string `json:"labels" example:"env:prod"`
 }
 
 // UpdateDevice is the information needed to update a Device.
@@ -56,4 +84,12 @@ type UpdateDevice struct {
 	Hostname                 string     `json:"hostname" example:"myhost"`
 	Endpoints                []Endpoint `json:"endpoints" gorm:"type:JSONB; serializer:json"`
 	Revision                 *uint64    `json:"revision"`
+	SecurityGroupId          uuid.UUID  `json:"security_group_id" example:"cb2e0192-5eb9-41ee-a732-7484602ac883"`
+	// Labels replace the labels of the device when they are set
+	Labels map[string]string `json:"labels" example:"env:prod"`
+}
+
+// RotateDeviceKey is the new WireGuard public key of a Device.
+type RotateDeviceKey struct {
+	PublicKey string `json:"public_key" example:"kjTkCfSmcLmE4lZ9dQvN6FzBo8SNDAtyWaKUoQhGfHg="`
 }
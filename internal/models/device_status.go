@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeviceStatus is the health a device last reported about itself
+type DeviceStatus struct {
+	DeviceID    uuid.UUID    `json:"device_id" gorm:"type:uuid;primary_key"`
+	LastSeen    time.Time    `json:"last_seen"`
+	NexdVersion string       `json:"nexd_version" example:"0.0.1"`
+	Peers       []PeerStatus `json:"peers" gorm:"type:JSONB; serializer:json"`
+}
+
+// PeerStatus is the connectivity of a device to one of its peers
+type PeerStatus struct {
+	DeviceID        uuid.UUID `json:"device_id"`
+	Endpoint        string    `json:"endpoint" example:"10.1.1.1:51820"`
+	Reachable       bool      `json:"reachable"`
+	LatestHandshake string    `json:"latest_handshake"`
+	// Method is how the device reaches the peer: local, reflexive or relay
+	Method string `json:"method" example:"reflexive"`
+	// LatencyMs is the round trip time to the peer, 0 when it is not reachable
+	LatencyMs float64 `json:"latency_ms" example:"12.5"`
+}
+
+const (
+	ConnectivityReachable   = "reachable"
+	ConnectivityUnreachable = "unreachable"
+	// ConnectivityUnknown is used when the source device did not report the destination recently
+	ConnectivityUnknown = "unknown"
+)
+
+// ConnectivityMatrix is the connectivity between every pair of devices of an organization
+type ConnectivityMatrix struct {
+	Devices []ConnectivityDevice `json:"devices"`
+	Paths   []ConnectivityPath   `json:"paths"`
+}
+
+// ConnectivityDevice is a device of a ConnectivityMatrix
+type ConnectivityDevice struct {
+	ID       uuid.UUID  `json:"id"`
+	Hostname string     `json:"hostname"`
+	Relay    bool       `json:"relay"`
+	Online   bool       `json:"online"`
+	LastSeen *time.Time `json:"last_seen,omitempty"`
+}
+
+// ConnectivityPath is the connectivity from a source device to a destination device, as reported by the source
+type ConnectivityPath struct {
+	Source      uuid.UUID `json:"source"`
+	Destination uuid.UUID `json:"destination"`
+	Method      string    `json:"method" example:"reflexive"`
+	Status      string    `json:"status" example:"reachable"`
+	LatencyMs   float64   `json:"latency_ms" example:"12.5"`
+}
+
+// ReportDeviceStatus is the information a device reports about its health.
+type ReportDeviceStatus struct {
+	NexdVersion string       `json:"nexd_version" example:"0.0.1"`
+	Peers       []PeerStatus `json:"peers"`
+}
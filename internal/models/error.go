@@ -77,6 +77,17 @@ func NewConflictsError(id string) ConflictsError {
 	}
 }
 
+// NewRevisionConflictError is returned in the body of an HTTP 409 when the resource
+// was modified after the revision an update is based on
+func NewRevisionConflictError(id string) ConflictsError {
+	return ConflictsError{
+		ID: id,
+		BaseError: BaseError{
+			Error: "resource has been modified, retry with its latest revision",
+		},
+	}
+}
+
 // NotFoundError is returned in the body of an HTTP 404
 type NotFoundError struct {
 	BaseError
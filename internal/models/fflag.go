@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FeatureFlag is a feature flag with its global default and per organization or user overrides
+type FeatureFlag struct {
+	Name        string                `gorm:"primary_key;" json:"name" example:"security-groups"`
+	CreatedAt   time.Time             `json:"-"`
+	UpdatedAt   time.Time             `json:"-"`
+	Description string                `json:"description" example:"Enables the security groups api"`
+	Enabled     bool                  `json:"enabled"`
+	Overrides   []FeatureFlagOverride `gorm:"foreignKey:FlagName" json:"overrides,omitempty"`
+}
+
+// FeatureFlagOverride enables or disables a feature flag for a single organization or user
+type FeatureFlagOverride struct {
+	Base
+	FlagName       string     `gorm:"index" json:"flag_name" example:"security-groups"`
+	OrganizationId *uuid.UUID `gorm:"type:uuid;index" json:"organization_id,omitempty"`
+	UserId         string     `gorm:"index" json:"user_id,omitempty"`
+	Enabled        bool       `json:"enabled"`
+}
+
+// AddFeatureFlag is the information needed to add a new feature flag.
+type AddFeatureFlag struct {
+	Name        string `json:"name" example:"my-feature"`
+	Description string `json:"description" example:"Enables my feature"`
+	Enabled     bool   `json:"enabled"`
+}
+
+// UpdateFeatureFlag is the information needed to update the global default of a feature flag.
+type UpdateFeatureFlag struct {
+	Description *string `json:"description,omitempty"`
+	Enabled     *bool   `json:"enabled,omitempty"`
+}
+
+// SetFeatureFlagOverride is the information needed to override a feature flag for an organization or a user.
+// Exactly one of OrganizationId or UserId must be set.
+type SetFeatureFlagOverride struct {
+	OrganizationId *uuid.UUID `json:"organization_id,omitempty" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	UserId         string     `json:"user_id,omitempty" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	Enabled        bool       `json:"enabled"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -20,30 +21,48 @@ type Organization struct {
 	HubZone         bool      `json:"hub_zone"`
 	Invitations     []*Invitation
 	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	Revision        uint64    `json:"revision" gorm:"type:bigserial;index:"`
+	// PreviousIpCidr and PreviousIpCidrV6 are the prefixes the organization is being re-addressed
+	// from, they stay valid until the ReaddressDeadline
+	PreviousIpCidr    string     `json:"previous_cidr"`
+	PreviousIpCidrV6  string     `json:"previous_cidr_v6"`
+	ReaddressDeadline *time.Time `json:"readdress_deadline,omitempty"`
+	// ApprovalRequired keeps the new devices pending until an admin approves them
+	ApprovalRequired bool `json:"approval_required"`
 }
 
 // Organization contains Users and their Devices
 type OrganizationJSON struct {
-	ID              uuid.UUID `json:"id"`
-	OwnerID         string    `json:"owner_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
-	Name            string    `json:"name" example:"zone-red"`
-	Description     string    `json:"description" example:"The Red Zone"`
-	IpCidr          string    `json:"cidr" example:"172.16.42.0/24"`
-	IpCidrV6        string    `json:"cidr_v6" example:"200::/8"`
-	HubZone         bool      `json:"hub_zone"`
-	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	ID                uuid.UUID  `json:"id"`
+	OwnerID           string     `json:"owner_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	Name              string     `json:"name" example:"zone-red"`
+	Description       string     `json:"description" example:"The Red Zone"`
+	IpCidr            string     `json:"cidr" example:"172.16.42.0/24"`
+	IpCidrV6          string     `json:"cidr_v6" example:"200::/8"`
+	HubZone           bool       `json:"hub_zone"`
+	SecurityGroupId   uuid.UUID  `json:"security_group_id"`
+	Revision          uint64     `json:"revision"`
+	PreviousIpCidr    string     `json:"previous_cidr" example:"172.16.41.0/24"`
+	PreviousIpCidrV6  string     `json:"previous_cidr_v6" example:"100::/8"`
+	ReaddressDeadline *time.Time `json:"readdress_deadline,omitempty"`
+	ApprovalRequired  bool       `json:"approval_required"`
 }
 
 func (o Organization) MarshalJSON() ([]byte, error) {
 	org := OrganizationJSON{
-		ID:              o.ID,
-		OwnerID:         o.OwnerID,
-		Name:            o.Name,
-		Description:     o.Description,
-		IpCidr:          o.IpCidr,
-		IpCidrV6:        o.IpCidrV6,
-		HubZone:         o.HubZone,
-		SecurityGroupId: o.SecurityGroupId,
+		ID:                o.ID,
+		OwnerID:           o.OwnerID,
+		Name:              o.Name,
+		Description:       o.Description,
+		IpCidr:            o.IpCidr,
+		IpCidrV6:          o.IpCidrV6,
+		HubZone:           o.HubZone,
+		SecurityGroupId:   o.SecurityGroupId,
+		Revision:          o.Revision,
+		PreviousIpCidr:    o.PreviousIpCidr,
+		PreviousIpCidrV6:  o.PreviousIpCidrV6,
+		ReaddressDeadline: o.ReaddressDeadline,
+		ApprovalRequired:  o.ApprovalRequired,
 	}
 	return json.Marshal(org)
 }
@@ -66,3 +85,58 @@ type AddOrganization struct {
 	HubZone         bool      `json:"hub_zone"`
 	SecurityGroupId uuid.UUID `json:"security_group_id"`
 }
+
+// UpdateOrganization is the information needed to update an Organization.
+type UpdateOrganization struct {
+	Name        *string `json:"name,omitempty" example:"zone-red"`
+	Description *string `json:"description,omitempty" example:"The Red Zone" extensions:"x-nullable"`
+	HubZone     *bool   `json:"hub_zone,omitempty" extensions:"x-nullable"`
+	// ApprovalRequired keeps the new devices pending until an admin approves them
+	ApprovalRequired *bool `json:"approval_required,omitempty" extensions:"x-nullable"`
+	// Revision is the revision of the organization the update is based on, the update
+	// fails when the organization has been modified since then
+	Revision *uint64 `json:"revision,omitempty"`
+}
+
+// ReaddressOrganization is the information needed to move an organization to new prefixes.
+type ReaddressOrganization struct {
+	// IpCidr is the new IPv4 prefix, the current one is kept when empty
+	IpCidr string `json:"cidr" example:"172.16.43.0/24"`
+	// IpCidrV6 is the new IPv6 prefix, the current one is kept when empty
+	IpCidrV6 string `json:"cidr_v6" example:"0300::/64"`
+	// GracePeriod is how long the previous addresses of the devices stay valid, defaults to 1h
+	GracePeriod string `json:"grace_period" example:"30m"`
+}
+
+const (
+	// OrganizationRoleAdmin can manage the organization: invite users, change their roles,
+	// delete any device and edit the security groups
+	OrganizationRoleAdmin = "admin"
+	// OrganizationRoleMember can register and manage their own devices
+	OrganizationRoleMember = "member"
+	// OrganizationRoleReadOnly can list the organization resources but not change them
+	OrganizationRoleReadOnly = "read-only"
+)
+
+// OrganizationRoles are the valid roles of a user in an organization
+var OrganizationRoles = []string{OrganizationRoleAdmin, OrganizationRoleMember, OrganizationRoleReadOnly}
+
+// UserOrganization is the membership of a user in an organization
+type UserOrganization struct {
+	UserID         string    `json:"user_id" gorm:"primaryKey" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;primaryKey" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	Role           string    `json:"role" gorm:"not null;default:member" example:"member"`
+}
+
+// UpdateUserOrganization is the information needed to change the role of a user in an organization.
+type UpdateUserOrganization struct {
+	Role string `json:"role" example:"read-only"`
+}
+
+// TransferOrganization is the information needed to transfer the ownership of an organization.
+type TransferOrganization struct {
+	// NewOwnerId is the user that becomes the owner, they must already be a member of the organization
+	NewOwnerId string `json:"new_owner_id" example:"aa22666c-0f57-45cb-a449-16efecc04f2e"`
+	// RemovePreviousOwner removes the previous owner from the organization, otherwise they stay as a member
+	RemovePreviousOwner bool `json:"remove_previous_owner"`
+}
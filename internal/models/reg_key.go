@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RegKey is a registration key, it enrolls devices into an organization without the OIDC session of a user.
+// The devices registered with the key authenticate with their own device token, deleting the key revokes their tokens.
+type RegKey struct {
+	Base
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	// OwnerID is the user that created the key, the devices registered with the key belong to this user
+	OwnerID     string `json:"owner_id"`
+	Description string `json:"description" example:"cloud-init"`
+	// Prefix is the start of the key, it identifies the key without revealing it
+	Prefix    string `json:"prefix" example:"nexreg_3fa85f"`
+	TokenHash string `json:"-" gorm:"uniqueIndex"`
+	// ExpiresAt is when the key stops being accepted, the devices already registered keep working with their device token
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// SingleUse keys register one device only, they are no longer accepted once the device is registered
+	SingleUse bool `json:"single_use"`
+	// MaxDevices is the maximum number of devices registered with the key at a time, unlimited when 0
+	MaxDevices int `json:"max_devices"`
+	// SecurityGroupID is the security group of the devices registered with the key, the default security group of the organization when not set
+	SecurityGroupID *uuid.UUID `json:"security_group_id,omitempty" gorm:"type:uuid"`
+	// Relay devices are the only ones registered with the key when set, nexd must be started as a relay accordingly
+	Relay bool `json:"relay"`
+	// Discovery is the discovery setting of the devices registered with the key
+	Discovery bool `json:"discovery"`
+	// Devices is the number of devices registered with the key
+	Devices int `json:"devices" gorm:"-"`
+	// Token is only returned when the key is created, only its hash is stored
+	Token string `json:"token,omitempty" gorm:"-"`
+}
+
+// AddRegKey is the information needed to add a new registration key.
+type AddRegKey struct {
+	Description string `json:"description" example:"cloud-init"`
+	// ExpiresAt is when the key stops registering new devices, it does not expire when not set
+	ExpiresAt       *time.Time `json:"expires_at,omitempty"`
+	SingleUse       bool       `json:"single_use"`
+	MaxDevices      int        `json:"max_devices"`
+	SecurityGroupID *uuid.UUID `json:"security_group_id,omitempty"`
+	Relay           bool       `json:"relay"`
+	Discovery       bool       `json:"discovery"`
+}
@@ -12,6 +12,7 @@ type SecurityGroup struct {
 	OrganizationId   uuid.UUID      `json:"org_id"`
 	InboundRules     []SecurityRule `json:"inbound_rules,omitempty" gorm:"type:JSONB; serializer:json"`
 	OutboundRules    []SecurityRule `json:"outbound_rules,omitempty" gorm:"type:JSONB; serializer:json"`
+	Revision         uint64         `json:"revision" gorm:"type:bigserial;index:"`
 }
 
 // AddSecurityGroup is the information needed to add a new Security Group.
@@ -37,4 +38,40 @@ type SecurityRule struct {
 	FromPort   int64    `json:"from_port"`
 	ToPort     int64    `json:"to_port"`
 	IpRanges   []string `json:"ip_ranges,omitempty"`
+	// SecurityGroupIds matches the tunnel addresses of the devices in the listed security groups
+	SecurityGroupIds []uuid.UUID `json:"security_group_ids,omitempty"`
+	// DeviceIds matches the tunnel addresses of the listed devices
+	DeviceIds []uuid.UUID `json:"device_ids,omitempty"`
+	// AllDevices matches the tunnel addresses of all the devices in the organization
+	AllDevices bool `json:"all_devices,omitempty"`
+}
+
+// SimulateSecurityGroup describes a flow between two devices of an organization to evaluate against their security groups.
+type SimulateSecurityGroup struct {
+	SourceDeviceId uuid.UUID `json:"source_device_id" example:"694aa002-5d19-495e-980b-3d8fd508ea10"`
+	// DestinationDeviceId or DestinationIp is required, the destination device is looked up by its tunnel address when only DestinationIp is set
+	DestinationDeviceId uuid.UUID `json:"destination_device_id" example:"cb2e0192-5eb9-41ee-a732-7484602ac883"`
+	DestinationIp       string    `json:"destination_ip" example:"100.100.0.2"`
+	IpProtocol          string    `json:"ip_protocol" example:"tcp"`
+	Port                int64     `json:"port" example:"22"`
+}
+
+// SecurityGroupSimulation is the result of evaluating a flow against the security groups of the source and destination devices.
+type SecurityGroupSimulation struct {
+	Allowed bool `json:"allowed"`
+	// Outbound is the verdict of the outbound rules of the source device
+	Outbound SecurityGroupVerdict `json:"outbound"`
+	// Inbound is the verdict of the inbound rules of the destination device, it is not set when the destination is not a device of the organization
+	Inbound *SecurityGroupVerdict `json:"inbound,omitempty"`
+}
+
+// SecurityGroupVerdict is the verdict of the rules of one direction of a device's security group.
+type SecurityGroupVerdict struct {
+	DeviceId        uuid.UUID `json:"device_id"`
+	SecurityGroupId uuid.UUID `json:"security_group_id"`
+	Allowed         bool      `json:"allowed"`
+	// RuleIndex is the index of the matched rule, -1 when no rule matched
+	RuleIndex int           `json:"rule_index"`
+	Rule      *SecurityRule `json:"rule,omitempty"`
+	Reason    string        `json:"reason"`
 }
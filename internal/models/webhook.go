@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+// Webhook delivers the events of an organization to an HTTP endpoint
+type Webhook struct {
+	Base
+	OrganizationID uuid.UUID `json:"organization_id" gorm:"type:uuid;index"`
+	URL            string    `json:"url" example:"https://example.com/hooks/nexodus"`
+	Description    string    `json:"description"`
+	// EventTypes are the types of the events delivered to the webhook, every event is delivered when empty
+	EventTypes pq.StringArray `json:"event_types" gorm:"type:text[]" swaggertype:"array,string" example:"device.create,device.delete,security_group.*"`
+	Enabled    bool           `json:"enabled"`
+	// Secret is the key of the HMAC-SHA256 signature of the payloads, it is only returned when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+// AddWebhook is the information needed to add a new webhook.
+type AddWebhook struct {
+	URL         string   `json:"url" example:"https://example.com/hooks/nexodus"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"event_types" example:"device.create,device.delete,security_group.*"`
+	// Secret is the key of the signature of the payloads, a random one is generated when empty
+	Secret string `json:"secret"`
+}
+
+// UpdateWebhook is the information needed to update a webhook, the fields that are not set are not changed.
+type UpdateWebhook struct {
+	URL         *string  `json:"url,omitempty" example:"https://example.com/hooks/nexodus"`
+	Description *string  `json:"description,omitempty"`
+	EventTypes  []string `json:"event_types,omitempty" example:"device.create,device.delete"`
+	Enabled     *bool    `json:"enabled,omitempty"`
+}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is the delivery of an event to a webhook
+type WebhookDelivery struct {
+	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key"`
+	CreatedAt time.Time `json:"created_at"`
+	WebhookID uuid.UUID `json:"webhook_id" gorm:"type:uuid;index"`
+	EventID   uuid.UUID `json:"event_id" gorm:"type:uuid"`
+	EventType string    `json:"event_type" example:"device.create"`
+	// Payload is the body sent to the webhook
+	Payload string `json:"payload"`
+	// Status is pending until the delivery succeeds or runs out of attempts
+	Status   string `json:"status" example:"succeeded"`
+	Attempts int    `json:"attempts"`
+	// NextAttempt is when a pending delivery is attempted again
+	NextAttempt  *time.Time `json:"next_attempt,omitempty" gorm:"index"`
+	LastAttempt  *time.Time `json:"last_attempt,omitempty"`
+	ResponseCode int        `json:"response_code,omitempty" example:"200"`
+	Error        string     `json:"error,omitempty"`
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+// WebhookPayload is the body of the requests sent to the webhooks
+type WebhookPayload struct {
+	// ID is the id of the event, it is the same for every attempt to deliver it
+	ID             uuid.UUID  `json:"id"`
+	Type           string     `json:"type" example:"device.create"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Event          AuditEvent `json:"event"`
+}
@@ -0,0 +1,121 @@
+package nexodus
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/secgroup"
+)
+
+// SecurityGroupStats is the result of the SecurityGroupStats ctl method
+type SecurityGroupStats struct {
+	SecurityGroupId string
+	Rules           []SecurityRuleStats
+}
+
+// SecurityRuleStats holds the counters of one rule of the security group. The implicit rules
+// that permit the return traffic of established flows and deny everything else have a Rule
+// index of -1.
+type SecurityRuleStats struct {
+	Direction string
+	Rule      int
+	Action    string
+	Match     string
+	Packets   uint64
+	Bytes     uint64
+}
+
+func (ac *NexdCtl) SecurityGroupStats(_ string, result *string) error {
+	// the group and its counters must come from the same ruleset
+	ac.nx.securityGroupLock.RLock()
+	group := ac.nx.securityGroup
+	stats, err := ac.nx.securityGroupStats()
+	ac.nx.securityGroupLock.RUnlock()
+	if err != nil {
+		return fmt.Errorf("error getting the security group stats: %w", err)
+	}
+
+	res := SecurityGroupStats{Rules: []SecurityRuleStats{}}
+	if group != nil {
+		res.SecurityGroupId = group.Id
+		res.Rules = append(res.Rules, securityRuleStats(secgroup.Inbound, group.InboundRules, stats.Inbound)...)
+		res.Rules = append(res.Rules, securityRuleStats(secgroup.Outbound, group.OutboundRules, stats.Outbound)...)
+	}
+
+	statsJSON, err := json.Marshal(res)
+	if err != nil {
+		return fmt.Errorf("error marshalling the security group stats: %w", err)
+	}
+	*result = string(statsJSON)
+	return nil
+}
+
+func securityRuleStats(dir secgroup.Direction, rules []public.ModelsSecurityRule, stats secgroup.DirectionStats) []SecurityRuleStats {
+	if len(rules) == 0 {
+		// without rules all traffic is permitted and nothing is counted
+		return nil
+	}
+	result := []SecurityRuleStats{{
+		Direction: dir.String(),
+		Rule:      -1,
+		Action:    "accept",
+		Match:     "established,related",
+		Packets:   stats.Established.Packets,
+		Bytes:     stats.Established.Bytes,
+	}}
+	for i, rule := range rules {
+		var counter secgroup.Counter
+		if i < len(stats.Rules) {
+			counter = stats.Rules[i]
+		}
+		result = append(result, SecurityRuleStats{
+			Direction: dir.String(),
+			Rule:      i,
+			Action:    "accept",
+			Match:     securityRuleMatch(rule),
+			Packets:   counter.Packets,
+			Bytes:     counter.Bytes,
+		})
+	}
+	return append(result, SecurityRuleStats{
+		Direction: dir.String(),
+		Rule:      -1,
+		Action:    "drop",
+		Match:     "any",
+		Packets:   stats.Denied.Packets,
+		Bytes:     stats.Denied.Bytes,
+	})
+}
+
+// securityRuleMatch describes the traffic a rule matches, for example: tcp 22 100.100.0.0/16
+func securityRuleMatch(rule public.ModelsSecurityRule) string {
+	desc := []string{rule.IpProtocol}
+	if rule.FromPort != 0 || rule.ToPort != 0 {
+		if rule.FromPort == rule.ToPort {
+			desc = append(desc, fmt.Sprintf("%d", rule.FromPort))
+		} else {
+			desc = append(desc, fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort))
+		}
+	}
+	var from []string
+	for _, ipRange := range rule.IpRanges {
+		if strings.TrimSpace(ipRange) != "" {
+			from = append(from, strings.TrimSpace(ipRange))
+		}
+	}
+	for _, id := range rule.SecurityGroupIds {
+		from = append(from, "security-group:"+id)
+	}
+	for _, id := range rule.DeviceIds {
+		from = append(from, "device:"+id)
+	}
+	if rule.AllDevices {
+		from = append(from, "all-devices")
+	}
+	if len(from) > 0 {
+		desc = append(desc, strings.Join(from, ","))
+	}
+	return strings.Join(desc, " ")
+}
@@ -21,6 +21,8 @@ func (ac *NexdCtl) Status(_ string, result *string) error {
 		statusStr = "WaitingForAuth"
 	case NexdStatusRunning:
 		statusStr = "Running"
+	case NexdStatusWaitingForApproval:
+		statusStr = "WaitingForApproval"
 	default:
 		statusStr = "Unknown"
 	}
@@ -47,6 +49,23 @@ func (ac *NexdCtl) GetTunnelIPv6(_ string, result *string) error {
 	return nil
 }
 
+func (ac *NexdCtl) RotateKeys(_ string, result *string) error {
+	publicKey, err := ac.nx.RotateKeys()
+	if err != nil {
+		return err
+	}
+	*result = fmt.Sprintf("Rotated the WireGuard keys, the new public key is %s\n", publicKey)
+	return nil
+}
+
+func (ac *NexdCtl) Leave(_ string, result *string) error {
+	if err := ac.nx.Leave(); err != nil {
+		return err
+	}
+	*result = "Deregistered the device, nexd is stopping\n"
+	return nil
+}
+
 func (ac *NexdCtl) ProxyList(_ string, result *string) error {
 	*result = ""
 	ac.nx.proxyLock.RLock()
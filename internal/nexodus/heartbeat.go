@@ -0,0 +1,128 @@
+package nexodus
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/util"
+)
+
+const (
+	// statusReportInterval is how often the device reports its health to the api-server, the
+	// api-server considers the device offline when it misses a few reports
+	statusReportInterval = 30 * time.Second
+)
+
+// the methods of the peer status reports
+const (
+	// peerMethodLocal is a direct connection to the local address of the peer
+	peerMethodLocal = "local"
+	// peerMethodReflexive is a direct connection to the address of the peer seen by the stun servers
+	peerMethodReflexive = "reflexive"
+	// peerMethodRelay is a connection through the relay of the organization
+	peerMethodRelay = "relay"
+)
+
+// statusPeer is a peer of the status report along with the address used to measure its latency
+type statusPeer struct {
+	public.ModelsPeerStatus
+	tunnelIP string
+}
+
+// reportStatus sends the health of the local device and of its peer connections to the api-server.
+// The peers are read synchronously, the latency probes and the report run in the background.
+func (nx *Nexodus) reportStatus(ctx context.Context, wg *sync.WaitGroup, deviceID string) {
+	if !nx.statusReportRunning.CompareAndSwap(false, true) {
+		nx.logger.Debug("previous device status report still running, skipping")
+		return
+	}
+
+	peers := []statusPeer{}
+	nx.deviceCacheIterRead(func(d deviceCacheEntry) {
+		// skip ourselves
+		if d.device.PublicKey == nx.wireguardPubKey {
+			return
+		}
+		latestHandshake := ""
+		if !d.lastHandshakeTime.IsZero() {
+			latestHandshake = d.lastHandshakeTime.UTC().Format(time.RFC3339)
+		}
+		peers = append(peers, statusPeer{
+			ModelsPeerStatus: public.ModelsPeerStatus{
+				DeviceId:        d.device.Id,
+				Endpoint:        d.endpoint,
+				Reachable:       d.peerHealthy,
+				LatestHandshake: latestHandshake,
+				Method:          nx.peerMethod(d),
+			},
+			tunnelIP: d.device.TunnelIp,
+		})
+	})
+
+	util.GoWithWaitGroup(wg, func() {
+		defer nx.statusReportRunning.Store(false)
+
+		status := public.ModelsReportDeviceStatus{
+			NexdVersion: nx.version,
+			Peers:       nx.probeLatency(peers),
+		}
+		ctx, cancel := context.WithTimeout(ctx, statusReportInterval)
+		defer cancel()
+		if _, _, err := nx.client.DevicesApi.ReportDeviceStatus(ctx, deviceID).Status(status).Execute(); err != nil {
+			nx.logger.Debugf("failed to report the device status: %v", err)
+		}
+	})
+}
+
+// peerMethod returns how the traffic to a peer flows, assumes the peer configuration is not being updated
+func (nx *Nexodus) peerMethod(d deviceCacheEntry) string {
+	peer, ok := nx.wgConfig.Peers[d.device.PublicKey]
+	if !ok {
+		// without a peer configuration the traffic goes through the relay, if there is one
+		if nx.relayWgIP != "" && !nx.relay {
+			return peerMethodRelay
+		}
+		return ""
+	}
+	localIP, _ := nx.extractLocalAndReflexiveIP(d.device)
+	if peer.Endpoint == localIP {
+		return peerMethodLocal
+	}
+	return peerMethodReflexive
+}
+
+// probeLatency measures the round trip time to the reachable peers, in batches to limit the traffic
+func (nx *Nexodus) probeLatency(peers []statusPeer) []public.ModelsPeerStatus {
+	waitFor := time.Duration(timeWait) * time.Millisecond
+	for i := 0; i < len(peers); i += batchSize {
+		end := i + batchSize
+		if end > len(peers) {
+			end = len(peers)
+		}
+		var batch sync.WaitGroup
+		for j := i; j < end; j++ {
+			if !peers[j].Reachable || peers[j].tunnelIP == "" {
+				continue
+			}
+			batch.Add(1)
+			go func(p *statusPeer) {
+				defer batch.Done()
+				start := time.Now()
+				if _, err := nx.doPing(p.tunnelIP, 1, waitFor); err != nil {
+					nx.logger.Debugf("latency probe [ %s ] failed: %v", p.tunnelIP, err)
+					return
+				}
+				p.LatencyMs = float32(time.Since(start).Microseconds()) / 1000
+			}(&peers[j])
+		}
+		batch.Wait()
+	}
+
+	result := make([]public.ModelsPeerStatus, 0, len(peers))
+	for _, p := range peers {
+		result = append(result, p.ModelsPeerStatus)
+	}
+	return result
+}
@@ -5,11 +5,47 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
 
+	atomicFile "github.com/natefinch/atomic"
 	"github.com/nexodus-io/nexodus/internal/api/public"
 )
 
+// deviceTokenFile is the file of the state directory that holds the token of a device registered with a registration key
+const deviceTokenFile = "devicetoken"
+
+// readDeviceToken returns the device token saved when the device registered with a registration key, if any
+func (nx *Nexodus) readDeviceToken() string {
+	if nx.stateDir == "" {
+		return ""
+	}
+	buf, err := os.ReadFile(filepath.Join(nx.stateDir, deviceTokenFile))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			nx.logger.Warnf("unable to read the device token: %v", err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(buf))
+}
+
+// saveDeviceToken saves the device token returned when the device registered with a registration key,
+// nexd authenticates with it instead of the key from then on
+func (nx *Nexodus) saveDeviceToken(token string) error {
+	if nx.stateDir == "" {
+		nx.logger.Warn("No state directory to save the device token, nexd needs a valid registration key to start again")
+		return nil
+	}
+	if err := os.MkdirAll(nx.stateDir, 0700); err != nil {
+		return err
+	}
+	return atomicFile.WriteFile(filepath.Join(nx.stateDir, deviceTokenFile), strings.NewReader(token))
+}
+
 func (ax *Nexodus) createOrUpdateDeviceOperation(userID string, endpoints []public.ModelsEndpoint) (public.ModelsDevice, error) {
 	d, _, err := ax.client.DevicesApi.CreateDevice(context.Background()).Device(public.ModelsAddDevice{
 		UserId:                  userID,
@@ -23,6 +59,8 @@ func (ax *Nexodus) createOrUpdateDeviceOperation(userID string, endpoints []publ
 		Relay:                   ax.relay,
 		Os:                      ax.os,
 		Endpoints:               endpoints,
+		Ephemeral:               ax.ephemeral,
+		Labels:                  ax.labels,
 	}).Execute()
 
 	if err != nil {
@@ -38,6 +76,7 @@ func (ax *Nexodus) createOrUpdateDeviceOperation(userID string, endpoints []publ
 					Hostname:                ax.hostname,
 					Endpoints:               endpoints,
 					OrganizationId:          ax.org.Id,
+					Labels:                  ax.labels,
 				}).Execute()
 				if err != nil {
 					respText := ""
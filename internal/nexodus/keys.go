@@ -1,10 +1,14 @@
 package nexodus
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/nexodus-io/nexodus/internal/api/public"
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 
 	"go.uber.org/zap"
@@ -22,8 +26,20 @@ const (
 	windowsPrivateKeyFile = "C:/nexd/private.key"
 	publicKeyPermissions  = 0644
 	privateKeyPermissions = 0600
+	// stagedKeySuffix marks the key files of a rotation that the api-server has not confirmed yet
+	stagedKeySuffix = ".new"
+	// keyRotationRequestTimeout bounds the wait for the reconcile loop to pick up a key rotation request
+	keyRotationRequestTimeout = 30 * time.Second
+	// keyRotationRetryInterval is the delay before retrying a failed automatic key rotation
+	keyRotationRetryInterval = time.Minute
 )
 
+// keyRotation is the result of a key rotation request
+type keyRotation struct {
+	publicKey string
+	err       error
+}
+
 // generateKeyPair a key pair and write them to disk
 func (nx *Nexodus) generateKeyPair(publicKeyFile, privateKeyFile string) error {
 
@@ -38,9 +54,23 @@ func (nx *Nexodus) generateKeyPair(publicKeyFile, privateKeyFile string) error {
 	// TODO remove this debug statement at some point
 	nx.logger.Debugf("Public Key [ %s ] Private Key [ %s ]", nx.wireguardPubKey, nx.wireguardPvtKey)
 	// write the new keys to disk
-	WriteToFile(nx.logger, nx.wireguardPubKey, publicKeyFile, publicKeyPermissions)
-	WriteToFile(nx.logger, nx.wireguardPvtKey, privateKeyFile, privateKeyPermissions)
+	if err := writeKeyFile(nx.wireguardPubKey, publicKeyFile, publicKeyPermissions); err != nil {
+		return err
+	}
+	return writeKeyFile(nx.wireguardPvtKey, privateKeyFile, privateKeyPermissions)
+}
 
+// writeKeyFile overwrites a key file, the key is written to a temporary file first so that a
+// failed write never leaves a truncated key behind
+func writeKeyFile(key, keyFile string, permissions os.FileMode) error {
+	tmpFile := keyFile + ".tmp"
+	if err := os.WriteFile(tmpFile, []byte(key), permissions); err != nil {
+		return fmt.Errorf("unable to write the key file %s: %w", keyFile, err)
+	}
+	if err := os.Rename(tmpFile, keyFile); err != nil {
+		_ = os.Remove(tmpFile)
+		return fmt.Errorf("unable to write the key file %s: %w", keyFile, err)
+	}
 	return nil
 }
 
@@ -67,3 +97,166 @@ func readKeyFileToString(s string) (string, error) {
 	rawStr := string(buf)
 	return strings.Replace(rawStr, "\n", "", -1), nil
 }
+
+// RotateKeys asks the reconcile loop to rotate the WireGuard keys of the device and returns the new public key
+func (nx *Nexodus) RotateKeys() (string, error) {
+	result := make(chan keyRotation, 1)
+	select {
+	case nx.keyRotationRequests <- result:
+	case <-time.After(keyRotationRequestTimeout):
+		return "", errors.New("nexd has not joined the mesh yet, the keys can be rotated once it is running")
+	}
+	rotation := <-result
+	return rotation.publicKey, rotation.err
+}
+
+// nextKeyRotation returns the time left until the keys are due for rotation, the age of the keys
+// is taken from the private key file so that restarting nexd does not postpone the rotation
+func (nx *Nexodus) nextKeyRotation() time.Duration {
+	info, err := os.Stat(nx.privateKeyFile)
+	if err != nil {
+		return nx.keyRotationInterval
+	}
+	next := nx.keyRotationInterval - time.Since(info.ModTime())
+	if next < 0 {
+		return 0
+	}
+	return next
+}
+
+// rotateKeys generates a new key pair and registers its public key for the device. The device keeps
+// its ID and tunnel addresses, its peers swap the key once they get the device update.
+func (nx *Nexodus) rotateKeys(ctx context.Context, deviceID string) (string, error) {
+	privateKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate private key: %w", err)
+	}
+	publicKey := privateKey.PublicKey().String()
+
+	// the new keys are staged on disk before the api-server learns about them, the current keys
+	// are only replaced once it has, see recoverStagedKeys for a restart in between
+	if err := nx.stageKeys(publicKey, privateKey.String()); err != nil {
+		nx.removeStagedKeys()
+		return "", err
+	}
+
+	device, resp, err := nx.client.DevicesApi.RotateDeviceKey(ctx, deviceID).Key(public.ModelsRotateDeviceKey{
+		PublicKey: publicKey,
+	}).Execute()
+	if err != nil {
+		// without a response the api-server may have registered the key anyway, the staged keys
+		// are kept so that the next start can tell which key pair the device has
+		if resp != nil {
+			nx.removeStagedKeys()
+		}
+		return "", fmt.Errorf("failed to register the new public key: %w", err)
+	}
+
+	nx.deviceCacheLock.Lock()
+	defer nx.deviceCacheLock.Unlock()
+
+	previousPublicKey := nx.wireguardPubKey
+	nx.wireguardPubKey = publicKey
+	nx.wireguardPvtKey = privateKey.String()
+	if err := nx.commitStagedKeys(); err != nil {
+		// the api-server already has the new public key, keep running with it and let the next
+		// start finish the rotation from the staged keys
+		nx.logger.Errorf("Failed to replace the key files with the rotated keys: %v", err)
+	}
+
+	// the local device is cached by its key, it must not show up as a peer with the previous one
+	delete(nx.deviceCache, previousPublicKey)
+	nx.addToDeviceCache(*device)
+	nx.wgConfig.Interface.PrivateKey = nx.wireguardPvtKey
+	if err := nx.applyPrivateKey(); err != nil {
+		return "", fmt.Errorf("failed to apply the new private key to %s: %w", nx.tunnelIface, err)
+	}
+
+	nx.logger.Infof("Rotated the WireGuard keys of the device, the new public key is [ %s ]", publicKey)
+	return publicKey, nil
+}
+
+// stageKeys writes a key pair next to the current key files without replacing them
+func (nx *Nexodus) stageKeys(publicKey, privateKey string) error {
+	if err := writeKeyFile(publicKey, nx.publicKeyFile+stagedKeySuffix, publicKeyPermissions); err != nil {
+		return err
+	}
+	return writeKeyFile(privateKey, nx.privateKeyFile+stagedKeySuffix, privateKeyPermissions)
+}
+
+// commitStagedKeys replaces the current key files with the staged ones, the private key goes
+// last since its presence is what marks a rotation as unfinished
+func (nx *Nexodus) commitStagedKeys() error {
+	if err := os.Rename(nx.publicKeyFile+stagedKeySuffix, nx.publicKeyFile); err != nil {
+		return fmt.Errorf("unable to replace the public key file: %w", err)
+	}
+	if err := os.Rename(nx.privateKeyFile+stagedKeySuffix, nx.privateKeyFile); err != nil {
+		return fmt.Errorf("unable to replace the private key file: %w", err)
+	}
+	return nil
+}
+
+// removeStagedKeys drops the staged key files of a failed rotation
+func (nx *Nexodus) removeStagedKeys() {
+	for _, file := range []string{nx.publicKeyFile + stagedKeySuffix, nx.privateKeyFile + stagedKeySuffix} {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			nx.logger.Warnf("Unable to remove the staged key file %s: %v", file, err)
+		}
+	}
+}
+
+// recoverStagedKeys finishes a key rotation that was interrupted by a restart. The staged keys
+// replace the current ones if the api-server registered their public key for a device of the
+// organization, they are dropped otherwise.
+func (nx *Nexodus) recoverStagedKeys() error {
+	stagedPrivateKey := readKeyFile(nx.logger, nx.privateKeyFile+stagedKeySuffix)
+	if stagedPrivateKey == "" {
+		// the private key is staged last and committed last, without it there is nothing to recover
+		nx.removeStagedKeys()
+		return nil
+	}
+	privateKey, err := wgtypes.ParseKey(stagedPrivateKey)
+	if err != nil {
+		nx.logger.Warnf("Dropping the unreadable staged private key: %v", err)
+		nx.removeStagedKeys()
+		return nil
+	}
+	stagedPublicKey := privateKey.PublicKey().String()
+
+	devices, _, err := nx.informer.Execute()
+	if err != nil {
+		return err
+	}
+	registered := false
+	for _, device := range devices {
+		if device.PublicKey == stagedPublicKey {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		nx.logger.Infof("Dropping the keys of an interrupted key rotation, the api-server has not registered them")
+		nx.removeStagedKeys()
+		return nil
+	}
+
+	// the public key file may already have been replaced, or be missing, write it again from the private key
+	if err := writeKeyFile(stagedPublicKey, nx.publicKeyFile+stagedKeySuffix, publicKeyPermissions); err != nil {
+		return err
+	}
+	if err := nx.commitStagedKeys(); err != nil {
+		return err
+	}
+	nx.wireguardPubKey = stagedPublicKey
+	nx.wireguardPvtKey = privateKey.String()
+	nx.logger.Infof("Finished an interrupted key rotation, the public key is [ %s ]", stagedPublicKey)
+	return nil
+}
+
+// applyPrivateKey replaces the private key of the running tunnel interface
+func (nx *Nexodus) applyPrivateKey() error {
+	if nx.userspaceMode {
+		return nx.applyPrivateKeyUS()
+	}
+	return nx.applyPrivateKeyOS()
+}
@@ -16,6 +16,9 @@ func (nx *Nexodus) handleKeys() error {
 		pubKeyFile = darwinPublicKeyFile
 		privKeyFile = darwinPrivateKeyFile
 	}
+	// the rotated keys are written to the same files
+	nx.publicKeyFile = pubKeyFile
+	nx.privateKeyFile = privKeyFile
 	publicKey := readKeyFile(nx.logger, pubKeyFile)
 	privateKey := readKeyFile(nx.logger, privKeyFile)
 	if publicKey != "" && privateKey != "" {
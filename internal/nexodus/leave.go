@@ -0,0 +1,81 @@
+package nexodus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// leaveRequestTimeout bounds the wait for the reconcile loop to pick up a leave request
+	leaveRequestTimeout = 30 * time.Second
+	// deregisterTimeout bounds the deregistration when nexd exits, the nexd context is done by then
+	deregisterTimeout = 30 * time.Second
+)
+
+// Leave asks the reconcile loop, or the wait for the approval of a pending device, to deregister
+// the device, nexd stops once the device is deleted
+func (nx *Nexodus) Leave() error {
+	result := make(chan error, 1)
+	select {
+	case nx.leaveRequests <- result:
+	case <-time.After(leaveRequestTimeout):
+		return errors.New("nexd has not joined the mesh yet, it can leave once it is running")
+	}
+	return <-result
+}
+
+// Done is closed once nexd stops, either because the context passed to Start is done or
+// because the device left the organization
+func (nx *Nexodus) Done() <-chan struct{} {
+	return nx.nexCtx.Done()
+}
+
+// deregister deletes the device from the api-server, which releases its IPAM leases and drops it from
+// the peers of every other device, then it removes the local network configuration of the device
+func (nx *Nexodus) deregister(ctx context.Context) error {
+	if nx.deviceId == "" {
+		return nil
+	}
+	_, resp, err := nx.client.DevicesApi.DeleteDevice(ctx, nx.deviceId).Execute()
+	// the device may have been deleted already, for example by an admin of the organization
+	if err != nil && (resp == nil || resp.StatusCode != http.StatusNotFound) {
+		return fmt.Errorf("failed to delete device %s: %w", nx.deviceId, err)
+	}
+	nx.logger.Infof("Deregistered device %s from organization: [ %s (%s) ]", nx.deviceId, nx.org.Name, nx.org.Id)
+	nx.deviceId = ""
+	nx.teardown()
+	return nil
+}
+
+// teardown removes the routes to the peers, the security group rules and the tunnel interface
+func (nx *Nexodus) teardown() {
+	nx.deviceCacheLock.Lock()
+	for publicKey, d := range nx.deviceCache {
+		if publicKey != nx.wireguardPubKey {
+			nx.handlePeerRouteDelete(nx.tunnelIface, d.device)
+		}
+	}
+	nx.deviceCache = make(map[string]deviceCacheEntry)
+	nx.wgConfig.Peers = make(map[string]wgPeerConfig)
+	nx.deviceCacheLock.Unlock()
+
+	// without a security group the nexodus nftables table is dropped
+	nx.securityGroupLock.Lock()
+	nx.securityGroup = nil
+	if err := nx.applySecurityGroupRules(); err != nil {
+		nx.logger.Error(err)
+	}
+	nx.securityGroupLock.Unlock()
+
+	if nx.userspaceMode {
+		if nx.userspaceDev != nil {
+			nx.userspaceDev.Close()
+			nx.userspaceDev = nil
+		}
+	} else {
+		nx.removeExistingInterface()
+	}
+}
@@ -15,12 +15,14 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/nexodus-io/nexodus/internal/api/public"
 	"github.com/nexodus-io/nexodus/internal/client"
+	"github.com/nexodus-io/nexodus/internal/secgroup"
 	"github.com/nexodus-io/nexodus/internal/stun"
 	"github.com/nexodus-io/nexodus/internal/util"
 	"go.uber.org/zap"
@@ -52,6 +54,8 @@ const (
 	NexdStatusAuth
 	// nexd is up and running normally
 	NexdStatusRunning
+	// the device is registered and waits for an admin of the organization to approve it
+	NexdStatusWaitingForApproval
 )
 
 const (
@@ -72,6 +76,8 @@ type userspaceWG struct {
 	userspaceTun  tun.Device
 	userspaceNet  *netstack.Net
 	userspaceDev  *device.Device
+	// filters the userspace packets with the security group rules
+	userspaceFilter *secgroup.FilterTun
 	// the last address configured on the userspace wireguard interface
 	userspaceLastAddress string
 	proxyLock            sync.RWMutex
@@ -112,21 +118,33 @@ type deviceCacheEntry struct {
 }
 
 type Nexodus struct {
-	wireguardPubKey          string
-	wireguardPvtKey          string
-	wireguardPubKeyInConfig  bool
-	tunnelIface              string
-	controllerIP             string
-	listenPort               int
-	orgId                    string
-	org                      *public.ModelsOrganization
-	requestedIP              string
-	userProvidedLocalIP      string
-	TunnelIP                 string
-	TunnelIpV6               string
+	wireguardPubKey         string
+	wireguardPvtKey         string
+	wireguardPubKeyInConfig bool
+	tunnelIface             string
+	controllerIP            string
+	listenPort              int
+	orgId                   string
+	org                     *public.ModelsOrganization
+	requestedIP             string
+	userProvidedLocalIP     string
+	TunnelIP                string
+	TunnelIpV6              string
+	// the addresses kept on the tunnel interface during the grace period of a re-addressing
+	previousTunnelIP         string
+	previousTunnelIpV6       string
 	childPrefix              []string
 	stun                     bool
 	relay                    bool
+	ephemeral                bool
+	labels                   map[string]string
+	publicKeyFile            string
+	privateKeyFile           string
+	keyRotationInterval      time.Duration
+	keyRotationRequests      chan chan keyRotation
+	deregisterOnExit         bool
+	leaveRequests            chan chan error
+	deviceId                 string
 	relayWgIP                string
 	wgConfig                 wgConfig
 	client                   *client.APIClient
@@ -136,7 +154,10 @@ type Nexodus struct {
 	endpointLocalAddress     string
 	nodeReflexiveAddressIPv4 netip.AddrPort
 	hostname                 string
+	securityGroupLock        sync.RWMutex
 	securityGroup            *public.ModelsSecurityGroup
+	securityGroupRefDevices  []secgroup.Device
+	securityGroupLog         SecurityGroupLog
 	symmetricNat             bool
 	ipv6Supported            bool
 	os                       string
@@ -148,13 +169,19 @@ type Nexodus struct {
 	version       string
 	username      string
 	password      string
+	regKey        string
 	skipTlsVerify bool
 	stateDir      string
 	userspaceWG
-	informer     *public.ApiListDevicesInOrganizationInformer
-	informerStop context.CancelFunc
-	nexCtx       context.Context
-	nexWg        *sync.WaitGroup
+	informer         *public.ApiListDevicesInOrganizationInformer
+	secGroupInformer *public.ApiListSecurityGroupsInformer
+	orgInformer      *public.ApiListOrganizationsInformer
+	informerStop     context.CancelFunc
+	nexCtx           context.Context
+	nexCancel        context.CancelFunc
+	nexWg            *sync.WaitGroup
+	// set while a device status report is being sent
+	statusReportRunning atomic.Bool
 }
 
 type wgConfig struct {
@@ -180,6 +207,7 @@ func NewNexodus(
 	controller string,
 	username string,
 	password string,
+	regKey string,
 	wgListenPort int,
 	wireguardPubKey string,
 	wireguardPvtKey string,
@@ -189,12 +217,17 @@ func NewNexodus(
 	stun bool,
 	relay bool,
 	relayOnly bool,
+	ephemeral bool,
+	labels map[string]string,
+	keyRotationInterval time.Duration,
+	deregisterOnExit bool,
 	insecureSkipTlsVerify bool,
 	version string,
 	userspaceMode bool,
 	stateDir string,
 	ctx context.Context,
 	orgId string,
+	securityGroupLog string,
 ) (*Nexodus, error) {
 
 	if err := binaryChecks(); err != nil {
@@ -219,6 +252,11 @@ func NewNexodus(
 		return nil, err
 	}
 
+	sgLog, err := ParseSecurityGroupLog(securityGroupLog)
+	if err != nil {
+		return nil, err
+	}
+
 	if wgListenPort == 0 {
 		wgListenPort, err = getWgListenPort()
 		if err != nil {
@@ -236,6 +274,12 @@ func NewNexodus(
 		childPrefix:         childPrefix,
 		stun:                stun,
 		relay:               relay,
+		ephemeral:           ephemeral,
+		labels:              labels,
+		keyRotationInterval: keyRotationInterval,
+		keyRotationRequests: make(chan chan keyRotation),
+		deregisterOnExit:    deregisterOnExit,
+		leaveRequests:       make(chan chan error),
 		deviceCache:         make(map[string]deviceCacheEntry),
 		controllerURL:       controllerURL,
 		hostname:            hostname,
@@ -246,9 +290,11 @@ func NewNexodus(
 		version:             version,
 		username:            username,
 		password:            password,
+		regKey:              regKey,
 		skipTlsVerify:       insecureSkipTlsVerify,
 		stateDir:            stateDir,
 		orgId:               orgId,
+		securityGroupLog:    sgLog,
 		userspaceWG: userspaceWG{
 			proxies: map[ProxyKey]*UsProxy{},
 		},
@@ -284,6 +330,8 @@ func (nx *Nexodus) SetStatus(status int, msg string) {
 }
 
 func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
+	// nexd cancels its own context when the device leaves the organization
+	ctx, nx.nexCancel = context.WithCancel(ctx)
 	nx.nexCtx = ctx
 	nx.nexWg = wg
 
@@ -297,17 +345,24 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		return fmt.Errorf("CtlServerStart(): %w", err)
 	}
 
-	if runtime.GOOS != Linux.String() {
-		nx.logger.Info("Security Groups are currently only supported on Linux")
-	} else if nx.userspaceMode {
-		nx.logger.Info("Security Groups are not supported in userspace proxy mode")
+	if runtime.GOOS != Linux.String() && !nx.userspaceMode {
+		nx.logger.Info("Security Groups are currently only supported on Linux or in userspace proxy mode")
 	}
 
 	var options []client.Option
-	if nx.stateDir != "" {
+	if nx.stateDir != "" && nx.regKey == "" {
 		options = append(options, client.WithTokenFile(filepath.Join(nx.stateDir, apiToken)))
 	}
-	if nx.username == "" {
+	if nx.regKey != "" {
+		// the registration key authenticates the device, there is no user session to store. Once the device
+		// is registered it authenticates with its own token, the key may be single use or expire.
+		bearerToken := nx.regKey
+		if deviceToken := nx.readDeviceToken(); deviceToken != "" {
+			nx.logger.Infof("Authenticating with the device token stored in %s", filepath.Join(nx.stateDir, deviceTokenFile))
+			bearerToken = deviceToken
+		}
+		options = append(options, client.WithBearerToken(bearerToken))
+	} else if nx.username == "" {
 		options = append(options, client.WithDeviceFlow())
 	} else if nx.username != "" && nx.password == "" {
 		fmt.Print("Enter nexodus account password: ")
@@ -397,6 +452,12 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	informerCtx, informerCancel := context.WithCancel(ctx)
 	nx.informerStop = informerCancel
 	nx.informer = nx.client.DevicesApi.ListDevicesInOrganization(informerCtx, nx.org.Id).Informer()
+	nx.secGroupInformer = nx.client.SecurityGroupApi.ListSecurityGroups(informerCtx, nx.org.Id).Informer()
+	nx.orgInformer = nx.client.OrganizationsApi.ListOrganizations(informerCtx).Informer()
+
+	if err := nx.recoverStagedKeys(); err != nil {
+		return fmt.Errorf("failed to recover the keys of an interrupted key rotation: %w", err)
+	}
 
 	var localIP string
 	var localEndpointPort int
@@ -472,6 +533,26 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 		return fmt.Errorf("join error %w", err)
 	}
 
+	if modelsDevice.DeviceToken != "" {
+		if err := nx.saveDeviceToken(modelsDevice.DeviceToken); err != nil {
+			return fmt.Errorf("failed to save the device token: %w", err)
+		}
+		options = append(options, client.WithBearerToken(modelsDevice.DeviceToken))
+		nx.client, err = client.NewAPIClient(ctx, nx.controllerURL.String(), func(msg string) {
+			nx.SetStatus(NexdStatusAuth, msg)
+		}, options...)
+		if err != nil {
+			return fmt.Errorf("client api error: %w", err)
+		}
+		informerCancel()
+		informerCtx, informerCancel = context.WithCancel(ctx)
+		nx.informerStop = informerCancel
+		nx.informer = nx.client.DevicesApi.ListDevicesInOrganization(informerCtx, nx.org.Id).Informer()
+		nx.secGroupInformer = nx.client.SecurityGroupApi.ListSecurityGroups(informerCtx, nx.org.Id).Informer()
+		nx.orgInformer = nx.client.OrganizationsApi.ListOrganizations(informerCtx).Informer()
+	}
+
+	nx.deviceId = modelsDevice.Id
 	nx.logger.Debug(fmt.Sprintf("Device: %+v", modelsDevice))
 	nx.logger.Infof("Successfully registered device with UUID: [ %+v ] into organization: [ %s (%s) ]",
 		modelsDevice.Id, nx.org.Name, nx.org.Id)
@@ -484,34 +565,70 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	}
 
 	util.GoWithWaitGroup(wg, func() {
+		// the device only joins the mesh once it is approved
+		if modelsDevice.Pending && !nx.waitForApproval(ctx, wg, modelsDevice.Id) {
+			return
+		}
 		// kick it off with an immediate reconcile
 		nx.reconcileDevices(ctx, options)
 		nx.reconcileSecurityGroups(ctx)
+		nx.reconcileOrganization()
 		for _, proxy := range nx.proxies {
 			proxy.Start(ctx, wg, nx.userspaceNet)
 		}
 		stunTicker := time.NewTicker(time.Second * 20)
-		secGroupTicker := time.NewTicker(time.Second * 20)
 		defer stunTicker.Stop()
 		pollTicker := time.NewTicker(pollInterval)
 		defer pollTicker.Stop()
+		statusTicker := time.NewTicker(statusReportInterval)
+		defer statusTicker.Stop()
+		var keyRotationTimer <-chan time.Time
+		if nx.keyRotationInterval > 0 {
+			keyRotationTimer = time.After(nx.nextKeyRotation())
+		}
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case <-keyRotationTimer:
+				if _, err := nx.rotateKeys(ctx, modelsDevice.Id); err != nil {
+					nx.logger.Errorf("Failed to rotate the WireGuard keys, retrying in %v: %v", keyRotationRetryInterval, err)
+					keyRotationTimer = time.After(keyRotationRetryInterval)
+				} else {
+					keyRotationTimer = time.After(nx.keyRotationInterval)
+				}
+			case result := <-nx.keyRotationRequests:
+				publicKey, err := nx.rotateKeys(ctx, modelsDevice.Id)
+				result <- keyRotation{publicKey: publicKey, err: err}
+			case result := <-nx.leaveRequests:
+				err := nx.deregister(ctx)
+				result <- err
+				if err == nil {
+					// there is nothing left to run once the device is deleted
+					nx.nexCancel()
+					return
+				}
 			case <-stunTicker.C:
 				if err := nx.reconcileStun(modelsDevice.Id); err != nil {
 					nx.logger.Debug(err)
 				}
+			case <-statusTicker.C:
+				nx.reportStatus(ctx, wg, modelsDevice.Id)
 			case <-nx.informer.Changed():
 				nx.reconcileDevices(ctx, options)
+				// the local device may have been assigned to a different security group
+				nx.reconcileSecurityGroups(ctx)
 			case <-pollTicker.C:
 				// This does not actually poll the API for changes. Peer configuration changes will only
 				// be processed when they come in on the informer. This periodic check is needed to
 				// re-establish our connection to the API if it is lost.
 				nx.reconcileDevices(ctx, options)
-			case <-secGroupTicker.C:
 				nx.reconcileSecurityGroups(ctx)
+				nx.reconcileOrganization()
+			case <-nx.secGroupInformer.Changed():
+				nx.reconcileSecurityGroups(ctx)
+			case <-nx.orgInformer.Changed():
+				nx.reconcileOrganization()
 			}
 		}
 	})
@@ -519,19 +636,102 @@ func (nx *Nexodus) Start(ctx context.Context, wg *sync.WaitGroup) error {
 	return nil
 }
 
+// waitForApproval polls the device until an admin of the organization approves it, it returns
+// false if the context is done first or if the device left the organization while it waited
+func (nx *Nexodus) waitForApproval(ctx context.Context, wg *sync.WaitGroup, deviceID string) bool {
+	nx.logger.Infof("Device %s is waiting for an admin of the organization to approve it", deviceID)
+	nx.SetStatus(NexdStatusWaitingForApproval, fmt.Sprintf("An admin of the organization must approve the device with: nexctl device approve --device-id %s\n", deviceID))
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	// the status reports keep a pending ephemeral device from being deleted while it waits
+	statusTicker := time.NewTicker(statusReportInterval)
+	defer statusTicker.Stop()
+	nx.reportStatus(ctx, wg, deviceID)
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-statusTicker.C:
+			nx.reportStatus(ctx, wg, deviceID)
+		case result := <-nx.leaveRequests:
+			// the pending device is registered, so it can leave before the reconcile loop runs
+			err := nx.deregister(ctx)
+			result <- err
+			if err == nil {
+				nx.nexCancel()
+				return false
+			}
+		case <-ticker.C:
+			device, _, err := nx.client.DevicesApi.GetDevice(ctx, deviceID).Execute()
+			if err != nil {
+				nx.logger.Debugf("failed to get the approval of the device: %v", err)
+				continue
+			}
+			if !device.Pending {
+				nx.logger.Infof("Device %s was approved", deviceID)
+				nx.SetStatus(NexdStatusRunning, "")
+				return true
+			}
+		}
+	}
+}
+
 func (nx *Nexodus) Stop() {
 	nx.logger.Info("Stopping nexd")
 	for _, proxy := range nx.proxies {
 		proxy.Stop()
 	}
+	if !nx.deregisterOnExit {
+		return
+	}
+	// wait for the reconcile loop to exit so that it does not set the tunnel up again
+	if nx.nexWg != nil {
+		nx.nexWg.Wait()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), deregisterTimeout)
+	defer cancel()
+	if err := nx.deregister(ctx); err != nil {
+		nx.logger.Errorf("Failed to deregister the device: %v", err)
+	}
+}
+
+// reconcileOrganization refreshes the organization of the device when it is updated.
+func (nx *Nexodus) reconcileOrganization() {
+	orgs, _, err := nx.orgInformer.Execute()
+	if err != nil {
+		nx.logger.Errorf("Error retrieving the organizations: %v", err)
+		return
+	}
+	org, ok := orgs[nx.org.Id]
+	if !ok || org.Revision == nx.org.Revision {
+		return
+	}
+
+	if org.Name != nx.org.Name {
+		nx.logger.Infof("Organization %s renamed from %s to %s", org.Id, nx.org.Name, org.Name)
+	}
+	if org.Cidr != nx.org.Cidr || org.CidrV6 != nx.org.CidrV6 {
+		nx.logger.Infof("Organization %s re-addressed to IPv4 [ %s ] IPv6 [ %s ]", org.Id, org.Cidr, org.CidrV6)
+	}
+	if org.HubZone != nx.org.HubZone {
+		nx.logger.Infof("Organization %s hub zone changed to %t", org.Id, org.HubZone)
+		if nx.relay && !org.HubZone {
+			nx.logger.Warnf("This device is a relay but organization %s is no longer a hub zone", org.Id)
+		}
+	}
+	nx.org = &org
 }
 
 // reconcileSecurityGroups will check the security group and update it if necessary.
 func (nx *Nexodus) reconcileSecurityGroups(ctx context.Context) {
-	if runtime.GOOS != Linux.String() || nx.userspaceMode {
+	if runtime.GOOS != Linux.String() && !nx.userspaceMode {
 		return
 	}
 
+	// the ctl server reads the group and the rule counters under this lock
+	nx.securityGroupLock.Lock()
+	defer nx.securityGroupLock.Unlock()
+
 	existing, ok := nx.deviceCacheLookup(nx.wireguardPubKey)
 	if !ok {
 		// local device not in the cache, so we don't have our config yet.
@@ -546,49 +746,77 @@ func (nx *Nexodus) reconcileSecurityGroups(ctx context.Context) {
 		}
 		// drop local security group configuration
 		nx.securityGroup = nil
-		if err := nx.processSecurityGroupRules(); err != nil {
+		if err := nx.applySecurityGroupRules(); err != nil {
 			nx.logger.Error(err)
 		}
 		return
 	}
 
 	// if the security group ID is not nil, lookup the ID and check for any changes
-	responseSecGroup, httpResp, err := nx.client.SecurityGroupApi.GetSecurityGroup(ctx, nx.org.Id, existing.device.SecurityGroupId).Execute()
+	secGroups, _, err := nx.secGroupInformer.Execute()
 	if err != nil {
-		// if the group ID returns a 404, clear the current rules
-		if httpResp != nil && httpResp.StatusCode == http.StatusNotFound {
+		nx.logger.Errorf("Error retrieving the security groups: %v", err)
+		return
+	}
+	secGroup, ok := secGroups[existing.device.SecurityGroupId]
+	if !ok {
+		// the group is gone, clear the current rules
+		if nx.securityGroup != nil {
 			nx.securityGroup = nil
-			if err := nx.processSecurityGroupRules(); err != nil {
+			if err := nx.applySecurityGroupRules(); err != nil {
 				nx.logger.Error(err)
 			}
-			return
 		}
-		nx.logger.Errorf("Error retrieving the security group: %v", err)
 		return
 	}
+	responseSecGroup := &secGroup
 
-	if nx.securityGroup != nil && reflect.DeepEqual(responseSecGroup, nx.securityGroup) {
+	// rules referring to devices are re-resolved whenever the devices of the organization change
+	devices := nx.securityGroupDevices(responseSecGroup)
+
+	if nx.securityGroup != nil && reflect.DeepEqual(responseSecGroup, nx.securityGroup) &&
+		reflect.DeepEqual(devices, nx.securityGroupRefDevices) {
 		// no changes to previously applied security group
 		return
 	}
 
 	nx.logger.Debugf("Security Group change detected: %+v", responseSecGroup)
 	oldSecGroup := nx.securityGroup
+	oldDevices := nx.securityGroupRefDevices
 	nx.securityGroup = responseSecGroup
+	nx.securityGroupRefDevices = devices
 
 	if oldSecGroup != nil && responseSecGroup.Id == oldSecGroup.Id &&
 		reflect.DeepEqual(responseSecGroup.InboundRules, oldSecGroup.InboundRules) &&
-		reflect.DeepEqual(responseSecGroup.OutboundRules, oldSecGroup.OutboundRules) {
+		reflect.DeepEqual(responseSecGroup.OutboundRules, oldSecGroup.OutboundRules) &&
+		reflect.DeepEqual(devices, oldDevices) {
 		// the group changed, but not in a way that matters for applying the rules locally
 		return
 	}
 
 	// apply the new security group rules
-	if err := nx.processSecurityGroupRules(); err != nil {
+	if err := nx.applySecurityGroupRules(); err != nil {
 		nx.logger.Error(err)
 	}
 }
 
+// applySecurityGroupRules enforces the current security group with nftables, or in the
+// netstack packet path when running in userspace mode.
+func (nx *Nexodus) applySecurityGroupRules() error {
+	if nx.userspaceMode {
+		return nx.processSecurityGroupRulesUS()
+	}
+	return nx.processSecurityGroupRules()
+}
+
+// securityGroupStats returns the counters of the rules of the current security group.
+func (nx *Nexodus) securityGroupStats() (secgroup.Stats, error) {
+	if nx.userspaceMode {
+		return nx.securityGroupRuleStatsUS()
+	}
+	return nx.securityGroupRuleStats()
+}
+
 func (nx *Nexodus) reconcileDevices(ctx context.Context, options []client.Option) {
 	var err error
 	if err = nx.reconcileDeviceCache(); err == nil {
@@ -635,6 +863,8 @@ func (nx *Nexodus) reconcileDevices(ctx context.Context, options []client.Option
 	informerCtx, informerCancel := context.WithCancel(ctx)
 	nx.informerStop = informerCancel
 	nx.informer = nx.client.DevicesApi.ListDevicesInOrganization(informerCtx, nx.org.Id).Informer()
+	nx.secGroupInformer = nx.client.SecurityGroupApi.ListSecurityGroups(informerCtx, nx.org.Id).Informer()
+	nx.orgInformer = nx.client.OrganizationsApi.ListOrganizations(informerCtx).Informer()
 
 	nx.SetStatus(NexdStatusRunning, "")
 	nx.logger.Infoln("Nexodus agent has re-established a connection to the api-server")
@@ -837,6 +1067,9 @@ func (nx *Nexodus) reconcileDeviceCache() error {
 	nx.deviceCacheLock.Lock()
 	defer nx.deviceCacheLock.Unlock()
 
+	// the devices that rotated their keys are still cached with their previous keys
+	nx.handlePeerKeyRotation(peerMap)
+
 	// Get our device cache up to date
 	newLocalConfig := false
 	for _, p := range peerMap {
@@ -848,6 +1081,11 @@ func (nx *Nexodus) reconcileDeviceCache() error {
 			}
 			nx.addToDeviceCache(p)
 			existing = nx.deviceCache[p.PublicKey]
+		} else if existing.device.SecurityGroupId != p.SecurityGroupId || existing.device.Revision != p.Revision {
+			// Only the security group assignment changed. Keep the connection health
+			// tracking data, the peer config does not need to be redeployed.
+			existing.device = p
+			nx.deviceCache[p.PublicKey] = existing
 		}
 
 		// Store the relay IP for easy reference later
@@ -935,6 +1173,10 @@ func (nx *Nexodus) checkUnsupportedConfigs() error {
 		nx.logger.Warn("IPv6 does not appear to be enabled on this host, only IPv4 will be provisioned or restart nexd with IPv6 enabled on this host")
 	}
 
+	if nx.securityGroupLog.Enabled && nx.userspaceMode {
+		nx.logger.Warn("Logging the packets denied by the security group is not supported in userspace mode, --security-group-log is ignored")
+	}
+
 	return nil
 }
 
@@ -81,6 +81,39 @@ func (nx *Nexodus) setupInterfaceOS() error {
 	return nil
 }
 
+// addInterfaceAddressesOS adds addresses to the wireguard interface next to the existing ones, an empty
+// address leaves its family unchanged
+func (nx *Nexodus) addInterfaceAddressesOS(ip, ipv6 string) error {
+	if ip != "" {
+		if _, err := RunCommand("ifconfig", nx.tunnelIface, "inet", ip, ip, "alias"); err != nil {
+			return fmt.Errorf("failed to add %s to %s: %w", ip, nx.tunnelIface, err)
+		}
+	}
+	if nx.ipv6Supported && ipv6 != "" {
+		localAddressIPv6 := fmt.Sprintf("%s/%s", ipv6, wgOrgIPv6PrefixLen)
+		if _, err := RunCommand("ifconfig", nx.tunnelIface, "inet6", localAddressIPv6, "alias"); err != nil {
+			return fmt.Errorf("failed to add %s to %s: %w", localAddressIPv6, nx.tunnelIface, err)
+		}
+	}
+	return nil
+}
+
+// removeInterfaceAddressesOS removes addresses from the wireguard interface, an empty address leaves
+// its family unchanged
+func (nx *Nexodus) removeInterfaceAddressesOS(ip, ipv6 string) error {
+	if ip != "" {
+		if _, err := RunCommand("ifconfig", nx.tunnelIface, "inet", ip, "-alias"); err != nil {
+			return fmt.Errorf("failed to remove %s from %s: %w", ip, nx.tunnelIface, err)
+		}
+	}
+	if nx.ipv6Supported && ipv6 != "" {
+		if _, err := RunCommand("ifconfig", nx.tunnelIface, "inet6", ipv6, "-alias"); err != nil {
+			return fmt.Errorf("failed to remove %s from %s: %w", ipv6, nx.tunnelIface, err)
+		}
+	}
+	return nil
+}
+
 func (nx *Nexodus) removeExistingInterface() {
 	if ifaceExists(nx.logger, nx.tunnelIface) {
 		deleteDarwinIface(nx.logger, nx.tunnelIface)
@@ -98,6 +98,40 @@ func (nx *Nexodus) setupInterfaceOS() error {
 	return nil
 }
 
+// addInterfaceAddressesOS adds addresses to the wireguard interface next to the existing ones, an empty
+// address leaves its family unchanged
+func (nx *Nexodus) addInterfaceAddressesOS(ip, ipv6 string) error {
+	if ip != "" {
+		if _, err := RunCommand("ip", "address", "add", ip, "dev", nx.tunnelIface); err != nil {
+			return fmt.Errorf("failed to add %s to %s: %w", ip, nx.tunnelIface, err)
+		}
+	}
+	if nx.ipv6Supported && ipv6 != "" {
+		localAddressIPv6 := fmt.Sprintf("%s/%s", ipv6, wgOrgIPv6PrefixLen)
+		if _, err := RunCommand("ip", "-6", "address", "add", localAddressIPv6, "dev", nx.tunnelIface); err != nil {
+			return fmt.Errorf("failed to add %s to %s: %w", localAddressIPv6, nx.tunnelIface, err)
+		}
+	}
+	return nil
+}
+
+// removeInterfaceAddressesOS removes addresses from the wireguard interface, an empty address leaves
+// its family unchanged
+func (nx *Nexodus) removeInterfaceAddressesOS(ip, ipv6 string) error {
+	if ip != "" {
+		if _, err := RunCommand("ip", "address", "del", ip, "dev", nx.tunnelIface); err != nil {
+			return fmt.Errorf("failed to remove %s from %s: %w", ip, nx.tunnelIface, err)
+		}
+	}
+	if nx.ipv6Supported && ipv6 != "" {
+		localAddressIPv6 := fmt.Sprintf("%s/%s", ipv6, wgOrgIPv6PrefixLen)
+		if _, err := RunCommand("ip", "-6", "address", "del", localAddressIPv6, "dev", nx.tunnelIface); err != nil {
+			return fmt.Errorf("failed to remove %s from %s: %w", localAddressIPv6, nx.tunnelIface, err)
+		}
+	}
+	return nil
+}
+
 func (nx *Nexodus) removeExistingInterface() {
 	if linkExists(nx.tunnelIface) {
 		if err := delLink(nx.tunnelIface); err != nil {
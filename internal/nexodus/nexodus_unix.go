@@ -2,6 +2,32 @@
 
 package nexodus
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/nexodus-io/nexodus/internal/util"
+	"golang.zx2c4.com/wireguard/wgctrl"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
 
 var interfaceErr = errors.New("interface setup error")
+
+// applyPrivateKeyOS replaces the private key of the wireguard interface, the peers are kept
+func (nx *Nexodus) applyPrivateKeyOS() error {
+	if !ifaceExists(nx.logger, nx.tunnelIface) {
+		// the interface is set up with the new key
+		return nil
+	}
+	privateKey, err := wgtypes.ParseKey(nx.wireguardPvtKey)
+	if err != nil {
+		return err
+	}
+	c, err := wgctrl.New()
+	if err != nil {
+		return err
+	}
+	defer util.IgnoreError(c.Close)
+	return c.ConfigureDevice(nx.tunnelIface, wgtypes.Config{
+		PrivateKey: &privateKey,
+	})
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/netip"
 
+	"github.com/nexodus-io/nexodus/internal/secgroup"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.zx2c4.com/wireguard/conn"
 	"golang.zx2c4.com/wireguard/device"
 	"golang.zx2c4.com/wireguard/tun/netstack"
@@ -15,6 +17,19 @@ import (
 // This is the hardcoded default name of the netstack wireguard device
 const defaultDeviceName = "go"
 
+// applyPrivateKeyUS replaces the private key of the userspace wireguard device, the peers are kept
+func (nx *Nexodus) applyPrivateKeyUS() error {
+	if nx.userspaceDev == nil {
+		// the device is set up with the new key
+		return nil
+	}
+	pvtDecoded, err := base64.StdEncoding.DecodeString(nx.wireguardPvtKey)
+	if err != nil {
+		return err
+	}
+	return nx.userspaceDev.IpcSet(fmt.Sprintf("private_key=%s", hex.EncodeToString(pvtDecoded)))
+}
+
 func (nx *Nexodus) setupInterfaceUS() error {
 	tun, tnet, err := netstack.CreateNetTUN(
 		[]netip.Addr{
@@ -36,8 +51,12 @@ func (nx *Nexodus) setupInterfaceUS() error {
 		nx.logger.Errorf("Failed to create userspace tunnel device: %w", err)
 		return err
 	}
-	nx.userspaceTun = tun
+	nx.userspaceFilter = secgroup.NewFilterTun(tun)
+	nx.userspaceTun = nx.userspaceFilter
 	nx.userspaceNet = tnet
+	if err := nx.processSecurityGroupRulesUS(); err != nil {
+		nx.logger.Error(err)
+	}
 	logger := &device.Logger{
 		Verbosef: device.DiscardLogf,
 		Errorf:   nx.logger.Errorf,
@@ -82,3 +101,36 @@ func (nx *Nexodus) setupInterfaceUS() error {
 func (nx *Nexodus) defaultTunnelDevUS() string {
 	return defaultDeviceName
 }
+
+// processSecurityGroupRulesUS applies the security group rules to the packets of the userspace device
+func (nx *Nexodus) processSecurityGroupRulesUS() error {
+	if nx.userspaceFilter == nil {
+		// the userspace device has not been created yet, the rules are applied when it is.
+		return nil
+	}
+	if nx.securityGroup == nil {
+		nx.userspaceFilter.SetPolicy(nil)
+		return nil
+	}
+
+	if nx.logger.Level().Enabled(zapcore.DebugLevel) {
+		if err := debugSecurityGroupRules(nx.logger, nx.securityGroup.InboundRules, nx.securityGroup.OutboundRules); err != nil {
+			nx.logger.Debug(err)
+		}
+	}
+
+	policy, err := nx.securityGroupPolicy()
+	if err != nil {
+		return fmt.Errorf("userspace security group setup error: %w", err)
+	}
+	nx.userspaceFilter.SetPolicy(policy)
+	return nil
+}
+
+// securityGroupRuleStatsUS returns the counters of the packets the userspace filter evaluated against the security group
+func (nx *Nexodus) securityGroupRuleStatsUS() (secgroup.Stats, error) {
+	if nx.userspaceFilter == nil {
+		return secgroup.NewStats(nil), nil
+	}
+	return nx.userspaceFilter.Stats(), nil
+}
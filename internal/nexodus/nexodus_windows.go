@@ -60,6 +60,25 @@ func (nx *Nexodus) setupInterfaceOS() error {
 func (nx *Nexodus) removeExistingInterface() {
 }
 
+// addInterfaceAddressesOS is not supported on windows, the interface is recreated with the new addresses
+func (nx *Nexodus) addInterfaceAddressesOS(ip, ipv6 string) error {
+	return fmt.Errorf("adding addresses to the windows wireguard interface is not supported")
+}
+
+func (nx *Nexodus) removeInterfaceAddressesOS(ip, ipv6 string) error {
+	return nil
+}
+
+// applyPrivateKeyOS recreates the wireguard interface with the new private key, the peers are
+// added back by the next reconcile
+func (nx *Nexodus) applyPrivateKeyOS() error {
+	if !ifaceExists(nx.logger, nx.tunnelIface) {
+		return nil
+	}
+	nx.wgConfig.Peers = nil
+	return nx.setupInterfaceOS()
+}
+
 func (nx *Nexodus) findLocalIP() (string, error) {
 	return discoverGenericIPv4(nx.logger, nx.controllerURL.Host, "443")
 }
@@ -0,0 +1,111 @@
+package nexodus
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/nexodus-io/nexodus/internal/secgroup"
+	"go.uber.org/zap"
+)
+
+// SecurityGroupLog configures the logging of the packets denied by the security group
+type SecurityGroupLog struct {
+	// Enabled adds a log statement to the default drop rules
+	Enabled bool
+	// Nflog sends the denied packets to the nflog Group instead of the kernel log
+	Nflog bool
+	Group uint16
+}
+
+// ParseSecurityGroupLog parses the --security-group-log value, which is empty to disable
+// logging, log to write the denied packets to the kernel log or nflog:<group> to send
+// them to an nflog group.
+func ParseSecurityGroupLog(value string) (SecurityGroupLog, error) {
+	switch {
+	case value == "":
+		return SecurityGroupLog{}, nil
+	case value == "log":
+		return SecurityGroupLog{Enabled: true}, nil
+	case strings.HasPrefix(value, "nflog:"):
+		group, err := strconv.ParseUint(strings.TrimPrefix(value, "nflog:"), 10, 16)
+		if err != nil {
+			return SecurityGroupLog{}, fmt.Errorf("invalid security group log %q, the nflog group must be a number between 0 and 65535", value)
+		}
+		return SecurityGroupLog{Enabled: true, Nflog: true, Group: uint16(group)}, nil
+	}
+	return SecurityGroupLog{}, fmt.Errorf("invalid security group log %q, it must be log or nflog:<group>", value)
+}
+
+func debugSecurityGroupRules(logger *zap.SugaredLogger, inboundRules, outboundRules []public.ModelsSecurityRule) error {
+	inJson, err := json.MarshalIndent(inboundRules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to print debug json inbound rules: %w", err)
+	}
+	logger.Debugf("\nInboundRules:\n %s\n", inJson)
+
+	outJson, err := json.MarshalIndent(outboundRules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to print debug json inbound rules: %w", err)
+	}
+	logger.Debugf("\nOutboundRules:\n %s\n", outJson)
+
+	return nil
+}
+
+// securityGroupDevices returns the organization devices that the rules of the security group
+// can refer to, sorted by id. It returns nil when no rule refers to devices.
+func (nx *Nexodus) securityGroupDevices(group *public.ModelsSecurityGroup) []secgroup.Device {
+	hasReferences := false
+	for _, rule := range append(toSecGroupRules(group.InboundRules), toSecGroupRules(group.OutboundRules)...) {
+		if rule.HasReferences() {
+			hasReferences = true
+			break
+		}
+	}
+	if !hasReferences {
+		return nil
+	}
+
+	var devices []secgroup.Device
+	nx.deviceCacheIterRead(func(d deviceCacheEntry) {
+		devices = append(devices, secgroup.Device{
+			Id:              d.device.Id,
+			SecurityGroupId: d.device.SecurityGroupId,
+			TunnelIp:        d.device.TunnelIp,
+			TunnelIpV6:      d.device.TunnelIpV6,
+		})
+	})
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Id < devices[j].Id
+	})
+	return devices
+}
+
+// securityGroupPolicy resolves the device references of the current security group and compiles its rules
+func (nx *Nexodus) securityGroupPolicy() (*secgroup.Policy, error) {
+	inbound := secgroup.Resolve(toSecGroupRules(nx.securityGroup.InboundRules), nx.securityGroupRefDevices)
+	outbound := secgroup.Resolve(toSecGroupRules(nx.securityGroup.OutboundRules), nx.securityGroupRefDevices)
+	return secgroup.Compile(inbound, outbound)
+}
+
+// toSecGroupRules converts the api security rules for the secgroup policy compiler
+func toSecGroupRules(rules []public.ModelsSecurityRule) []secgroup.Rule {
+	result := make([]secgroup.Rule, 0, len(rules))
+	for _, rule := range rules {
+		result = append(result, secgroup.Rule{
+			IpProtocol: rule.IpProtocol,
+			FromPort:   int64(rule.FromPort),
+			ToPort:     int64(rule.ToPort),
+			IpRanges:   rule.IpRanges,
+
+			SecurityGroupIds: rule.SecurityGroupIds,
+			DeviceIds:        rule.DeviceIds,
+			AllDevices:       rule.AllDevices,
+		})
+	}
+	return result
+}
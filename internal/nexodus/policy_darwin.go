@@ -2,7 +2,23 @@
 
 package nexodus
 
+import (
+	"fmt"
+
+	"github.com/nexodus-io/nexodus/internal/secgroup"
+)
+
 // ProcessSecurityGroup for darwin build purposes, policy currently unsupported on darwin
 func (ax *Nexodus) processSecurityGroupRules() error {
 	return nil
 }
+
+// securityGroupRuleStats for darwin build purposes, policy currently unsupported on darwin
+func (ax *Nexodus) securityGroupRuleStats() (secgroup.Stats, error) {
+	return secgroup.Stats{}, fmt.Errorf("security groups are not supported on darwin outside of userspace mode")
+}
+
+// setupNftables for darwin build purposes, relay nodes are only supported on linux
+func setupNftables(dev string) error {
+	return nil
+}
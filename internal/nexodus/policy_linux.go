@@ -1,55 +1,65 @@
 package nexodus
 
 import (
-	"encoding/json"
 	"fmt"
-	"net"
-	"os/exec"
+	"net/netip"
 	"strings"
 
-	"github.com/nexodus-io/nexodus/internal/api/public"
+	"github.com/google/nftables"
+	"github.com/google/nftables/binaryutil"
+	"github.com/google/nftables/expr"
+	"github.com/nexodus-io/nexodus/internal/secgroup"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/unix"
 )
 
 const (
-	// Nftables keywords
+	// Nftables table and chain names
 	tableName    = "nexodus"
-	tableFamily  = "inet"
 	ingressChain = "nexodus-inbound"
 	egressChain  = "nexodus-outbound"
-	destPort     = "dport"
-	destAddr     = "daddr"
-	srcAddr      = "saddr"
-	actionAccept = "accept"
-	actionDrop   = "drop"
-	counter      = "counter"
-	// Protocols
-	protoIPv4   = "ipv4"
-	protoIPv6   = "ipv6"
-	protoICMPv4 = "icmpv4"
-	protoICMP   = "icmp"
-	protoICMPv6 = "icmpv6"
-	protoTCP    = "tcp"
-	protoUDP    = "udp"
 )
 
-var (
-	ruleInterface string
+// nfRuleset is the complete nexodus nftables table compiled from a security group.
+// Compiling does not touch the kernel, the ruleset is applied in a single netlink
+// transaction by nfApplyRuleset.
+type nfRuleset struct {
+	table  *nftables.Table
+	chains []*nftables.Chain
+	rules  []*nftables.Rule
+	// tags holds the security group rule each of the rules was compiled from
+	tags []nfRuleTag
+}
+
+// nfRuleTag identifies the security group rule an nftables rule was compiled from, a
+// security group rule expands into one nftables rule per address family, protocol and range.
+type nfRuleTag struct {
+	dir secgroup.Direction
+	// rule is the index of the security group rule, or one of nfRuleEstablished and nfRuleDenied
+	rule int
+}
+
+const (
+	nfRuleEstablished = -1
+	nfRuleDenied      = -2
 )
 
 // processSecurityGroupRules processes a security group for a Linux node
 func (nx *Nexodus) processSecurityGroupRules() error {
+	conn, err := nftables.New()
+	if err != nil {
+		return fmt.Errorf("nftables setup error, failed to open the netlink connection: %w", err)
+	}
 
-	// Delete the table if the security group is empty and attempt to drop a table if one exists
+	// Delete the table if the security group is empty
 	if nx.securityGroup == nil {
-		// Drop the existing table and return nil if a group was not found to drop
-		_ = nx.nfTableDrop()
+		if err := nfTableDrop(conn); err != nil {
+			return fmt.Errorf("nftables setup error, failed to drop the %s table: %w", tableName, err)
+		}
 		return nil
 	}
 
-	ruleInterface = fmt.Sprintf("iifname %s", wgIface)
-
 	inboundRules := nx.securityGroup.InboundRules
 	outboundRules := nx.securityGroup.OutboundRules
 
@@ -61,580 +71,429 @@ func (nx *Nexodus) processSecurityGroupRules() error {
 		}
 	}
 
-	// Drop the existing table
-	if err := nx.nfTableDrop(); err != nil {
-		return fmt.Errorf("nftables setup error, failed to flush nftables: %w", err)
+	policy, err := nx.securityGroupPolicy()
+	if err != nil {
+		return fmt.Errorf("nftables setup error, invalid security group %s: %w", nx.securityGroup.Id, err)
 	}
 
-	// Create the nftables table
-	if err := nx.nfCreateTable(); err != nil {
-		return fmt.Errorf("nftables setup error, failed to create nftables inet table: %w", err)
-	}
+	return nfApplyRuleset(nx.logger, conn, compileNfRuleset(wgIface, policy, nx.securityGroupLog))
+}
 
-	// Create the ingress nftables chains
-	if err := nx.nfCreateChain(ingressChain); err != nil {
-		return fmt.Errorf("nftables setup error, failed to create nftables chain %s: %w", ingressChain, err)
+// securityGroupRuleStats reads the counters of the running nftables rules of the security group
+func (nx *Nexodus) securityGroupRuleStats() (secgroup.Stats, error) {
+	if nx.securityGroup == nil {
+		return secgroup.NewStats(nil), nil
 	}
-
-	// Create the egress nftables chains
-	if err := nx.nfCreateChain(egressChain); err != nil {
-		return fmt.Errorf("nftables setup error, failed to create nftables chain %s: %w", egressChain, err)
+	policy, err := nx.securityGroupPolicy()
+	if err != nil {
+		return secgroup.Stats{}, fmt.Errorf("invalid security group %s: %w", nx.securityGroup.Id, err)
 	}
-
-	// Process the inbound rules
-	for _, rule := range inboundRules {
-		if len(rule.IpRanges) == 0 { // If the ip range is empty, add one
-			rule.IpRanges = append(rule.IpRanges, "")
-		}
-		if containsIPv4Range(rule.IpRanges) {
-			// if the rule is a L3 addresses in v4 family, with or without L4 port(s)
-			if err := nx.nfPermitProtoPortAddrV4(ingressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process inbound v4 rule: %w", err)
-			}
-		} else if containsIPv6Range(rule.IpRanges) {
-			// if the rule is a L3 addresses in v6 family, with or without L4 port(s)
-			if err := nx.nfPermitProtoPortAddrV6(ingressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process inbound v6 rule: %w", err)
-			}
-		} else if rule.FromPort != 0 && rule.ToPort != 0 {
-			// if the rule is L4 port(s) range with no l3 addresses
-			if err := nx.nfPermitProtoPort(ingressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process inbound destination port rule: %w", err)
-			}
-		} else {
-			// if the rule is only protocol to permit (no L4 ports or L3 addresses)
-			if err := nx.nfPermitProtoAny(ingressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process inbound destination port rule: %w", err)
-			}
-		}
+	conn, err := nftables.New()
+	if err != nil {
+		return secgroup.Stats{}, fmt.Errorf("failed to open the netlink connection: %w", err)
 	}
+	return nfRulesetStats(conn, policy, compileNfRuleset(wgIface, policy, nx.securityGroupLog))
+}
 
-	// Process the outbound rules
-	for _, rule := range outboundRules {
-		if len(rule.IpRanges) == 0 { // If the ip range is empty, add one
-			rule.IpRanges = append(rule.IpRanges, "")
-		}
-		if containsIPv4Range(rule.IpRanges) {
-			// if the rule is a L3 addresses in v4 family, with or without L4 port(s)
-			if err := nx.nfPermitProtoPortAddrV4(egressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process outbound v4 rule: %w", err)
-			}
-		} else if containsIPv6Range(rule.IpRanges) {
-			// if the rule is a L3 addresses in v6 family, with or without L4 port(s)
-			if err := nx.nfPermitProtoPortAddrV6(egressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process outbound v6 rule: %w", err)
-			}
-		} else if rule.FromPort != 0 && rule.ToPort != 0 {
-			// if the rule is L4 port(s) range with no l3 addresses
-			if err := nx.nfPermitProtoPort(egressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process inbound destination port rule: %w", err)
-			}
-		} else {
-			// if the rule is only protocol to permit (no L4 ports or L3 addresses)
-			if err := nx.nfPermitProtoAny(egressChain, rule); err != nil {
-				return fmt.Errorf("nftables setup error, failed to process inbound destination port rule: %w", err)
-			}
-		}
+// compileNfRuleset builds the nexodus inet table for the policy. Example of the resulting rules:
+// iifname "wg0" ct state established,related counter accept
+// iifname "wg0" meta nfproto ipv4 meta l4proto tcp ip saddr 100.100.0.0-100.100.255.255 tcp dport 80 counter accept
+// iifname "wg0" counter drop
+func compileNfRuleset(iface string, policy *secgroup.Policy, log SecurityGroupLog) *nfRuleset {
+	table := &nftables.Table{
+		Name:   tableName,
+		Family: nftables.TableFamilyINet,
 	}
-
-	// the ct module provides access to the connection tracking subsystem, which tracks the state of network
-	// connections. The state keyword is used to match traffic based on its connection state, in this case as
-	// established. The established state refers to traffic that is part of an existing connection that has
-	// already been established, and where both endpoints have exchanged packets.
-	nft := []string{"insert", "rule", tableFamily, tableName, ingressChain, "ct", "state", "established,related", ruleInterface, "counter", "accept"}
-	if _, err := runNftCmd(nx.logger, nft); err != nil {
-		return err
+	accept := nftables.ChainPolicyAccept
+	inbound := &nftables.Chain{
+		Name:     ingressChain,
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookInput,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &accept,
 	}
-
-	// append a default drop that appears implicit to the user only if there are any rules in the egress chain
-	if nx.securityGroup.InboundRules != nil && len(nx.securityGroup.InboundRules) != 0 {
-		if err := nx.nfIngressRuleDrop(); err != nil {
-			return fmt.Errorf("nftables setup error, failed to add ingress drop rule: %w", err)
-		}
+	outbound := &nftables.Chain{
+		Name:     egressChain,
+		Table:    table,
+		Type:     nftables.ChainTypeFilter,
+		Hooknum:  nftables.ChainHookOutput,
+		Priority: nftables.ChainPriorityFilter,
+		Policy:   &accept,
 	}
 
-	// append a drop that appears implicit to the user only if there are any user defined rules in the egress chain
-	if nx.securityGroup.OutboundRules != nil && len(nx.securityGroup.OutboundRules) != 0 {
-		if err := nx.nfEgressRuleDrop(); err != nil {
-			return fmt.Errorf("nftables setup error, failed to add egress drop rule: %w", err)
-		}
+	rs := &nfRuleset{
+		table:  table,
+		chains: []*nftables.Chain{inbound, outbound},
 	}
-
-	return nil
+	rs.compileChain(inbound, iface, secgroup.Inbound, policy, log)
+	rs.compileChain(outbound, iface, secgroup.Outbound, policy, log)
+	return rs
 }
 
-// nfPermitProtoPortAddrV4 creates a nftables rule that permits the specified rule. Example Rules handled by this method:
-// nft add rule inet nexodus nexodus-inbound meta nfproto ipv4 ip protocol icmp ip saddr 100.100.0.0/20 counter accept
-// nft add rule inet nexodus nexodus-outbound meta nfproto ipv4 ip daddr 100.100.0.1-100.100.0.100 iifname wg0 accept
-// nft add rule inet nexodus nexodus-outbound meta nfproto ipv4 ip daddr 8.8.8.8 udp dport 53 iifname "wg0" accept
-func (ax *Nexodus) nfPermitProtoPortAddrV4(chain string, rule public.ModelsSecurityRule) error {
-	var dportOption, srcOrDst string
-	var nft []string
-
-	dportOption = ax.nftPortOption(rule)
-
-	if chain == ingressChain {
-		srcOrDst = srcAddr
-	} else {
-		srcOrDst = destAddr
-	}
-
-	switch rule.IpProtocol {
-	case protoIPv4:
-		// if the specified proto is ipv4 that specifies an L3 address and does not specify ports.
-		if rule.FromPort == 0 && rule.ToPort == 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				// v4 permits for L3 src or dst
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, ruleInterface, counter, actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
+func (rs *nfRuleset) compileChain(chain *nftables.Chain, iface string, dir secgroup.Direction, policy *secgroup.Policy, log SecurityGroupLog) {
+	ifaceExprs, ifaceDesc := nfIfaceMatch(iface, dir)
+
+	// the ct module provides access to the connection tracking subsystem, return traffic of
+	// connections that have already been established is always permitted.
+	rs.addRule(chain, nfRuleTag{dir, nfRuleEstablished}, ifaceDesc+" ct state established,related counter accept", ifaceExprs,
+		&expr.Ct{Register: 1, Key: expr.CtKeySTATE},
+		&expr.Bitwise{
+			SourceRegister: 1,
+			DestRegister:   1,
+			Len:            4,
+			Mask:           binaryutil.NativeEndian.PutUint32(expr.CtStateBitESTABLISHED | expr.CtStateBitRELATED),
+			Xor:            binaryutil.NativeEndian.PutUint32(0),
+		},
+		&expr.Cmp{Op: expr.CmpOpNeq, Register: 1, Data: binaryutil.NativeEndian.PutUint32(0)},
+		&expr.Counter{},
+		&expr.Verdict{Kind: expr.VerdictAccept},
+	)
+
+	for _, match := range policy.Matches(dir) {
+		ranges := match.Ranges
+		if ranges == nil {
+			// a nil range matches any address, render the rule without an address match
+			ranges = []secgroup.AddrRange{{}}
+		}
+		for _, addrRange := range ranges {
+			exprs, desc := nfMatchExprs(dir, match, addrRange)
+			exprs = append(exprs, &expr.Counter{}, &expr.Verdict{Kind: expr.VerdictAccept})
+			rs.addRule(chain, nfRuleTag{dir, match.Rule}, fmt.Sprintf("%s %s counter accept", ifaceDesc, desc), ifaceExprs, exprs...)
 		}
-	case protoTCP:
-		// permit ipv4 tcp to src/dst L3 to any destination port
-		if rule.FromPort == 0 && rule.ToPort == 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-		// permit ipv4 tcp to L3 src/dst to specified destination port or port range
-		if rule.FromPort != 0 && rule.ToPort != 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoTCP, dportOption, ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-	case protoUDP:
-		// permit ipv4 udp to src/dst L3 to any destination port
-		if rule.FromPort == 0 && rule.ToPort == 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, protoUDP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-		// permit ipv4 udp to L3 src/dst to specified destination port or port range
-		if rule.FromPort != 0 && rule.ToPort != 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, srcOrDstOption, rule.IpProtocol, dportOption, ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-	case protoICMP, protoICMPv4:
-		// icmpv4 permits to L3 src or dst
-		for _, ipRange := range rule.IpRanges {
-			srcOrDstOption := fmt.Sprintf("ip %s %s", srcOrDst, ipRange)
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, srcOrDstOption, ruleInterface, counter, actionAccept}
-			if _, err := runNftCmd(ax.logger, nft); err != nil {
-				return err
+	}
+
+	// append a default drop that appears implicit to the user only if there are any rules in the chain
+	if policy.HasRules(dir) {
+		exprs := []expr.Any{&expr.Counter{}}
+		desc := ifaceDesc + " counter"
+		if log.Enabled {
+			prefix := fmt.Sprintf("nexodus %s denied: ", dir)
+			logExpr := &expr.Log{Key: 1 << unix.NFTA_LOG_PREFIX, Data: []byte(prefix)}
+			desc += fmt.Sprintf(" log prefix %q", prefix)
+			if log.Nflog {
+				logExpr.Key |= 1 << unix.NFTA_LOG_GROUP
+				logExpr.Group = log.Group
+				desc += fmt.Sprintf(" group %d", log.Group)
 			}
+			exprs = append(exprs, logExpr)
 		}
-	default:
-		ax.logger.Debugf("no match for permit proto dport rule: %v", rule)
-		return nil
+		exprs = append(exprs, &expr.Verdict{Kind: expr.VerdictDrop})
+		rs.addRule(chain, nfRuleTag{dir, nfRuleDenied}, desc+" drop", ifaceExprs, exprs...)
 	}
-
-	return nil
 }
 
-// nfPermitProtoPortAddrV6 creates a nftables rule that permits the specified rule. Example Rules handled by this method:
-// nft add rule inet nexodus nexodus-outbound meta nfproto ipv6 ip6 daddr 2001:4860:4860::8888-2001:4860:4860::8889 udp dport 0-65535 iifname "wg0" accept
-// nft add rule inet nexodus nexodus-outbound meta nfproto ipv6 ip6 daddr 2001:4860:4860::8888-2001:4860:4860::8889  iifname "wg0" accept
-// nft add rule inet nexodus nexodus-outbound meta nfproto ipv6 ip6 daddr 2001:4860:4860::8888-2001:4860:4860::8889 udp dport 53 iifname "wg0" accept
-// nft add rule inet nexodus nexodus-inbound meta nfproto ipv6 ip6 nexthdr ipv6-icmp ip6 saddr 200::/64 counter accept
-func (ax *Nexodus) nfPermitProtoPortAddrV6(chain string, rule public.ModelsSecurityRule) error {
-	var dportOption, srcOrDst string
-	var nft []string
-
-	dportOption = ax.nftPortOption(rule)
+// addRule appends a rule to the chain, the nft syntax description is stored in the rule's user data
+// which is used to compare the compiled rules to the rules running in the kernel.
+func (rs *nfRuleset) addRule(chain *nftables.Chain, tag nfRuleTag, desc string, prefix []expr.Any, exprs ...expr.Any) {
+	all := make([]expr.Any, 0, len(prefix)+len(exprs))
+	all = append(all, prefix...)
+	all = append(all, exprs...)
+	rs.rules = append(rs.rules, &nftables.Rule{
+		Table:    rs.table,
+		Chain:    chain,
+		Exprs:    all,
+		UserData: []byte(desc),
+	})
+	rs.tags = append(rs.tags, tag)
+}
 
-	if chain == ingressChain {
-		srcOrDst = srcAddr
-	} else {
-		srcOrDst = destAddr
-	}
-
-	switch rule.IpProtocol {
-	case protoIPv6:
-		// nft add rule inet nexodus nexodus-outbound meta nfproto ipv6 ip6 daddr 2001:4860:4860::8888-2001:4860:4860::8889  iifname "wg0" accept
-		// ipv6 that specifies an L3 src/dst and does not specify ports.
-		if rule.FromPort == 0 && rule.ToPort == 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, ruleInterface, counter, actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-	case protoTCP:
-		// permit ipv4 tcp to src/dst L3 to any destination port
-		if rule.FromPort == 0 && rule.ToPort == 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoTCP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-		// permit ipv6 udp to L3 src/dst to specified destination port or port range
-		if rule.FromPort != 0 && rule.ToPort != 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, rule.IpProtocol, dportOption, ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-	case protoUDP:
-		// permit ipv4 udp to src/dst L3 to any destination port
-		if rule.FromPort == 0 && rule.ToPort == 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstOption, protoUDP, destPort, "0-65535", ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-		// permit ipv4 udp to L3 src/dst to specified destination port or port range
-		if rule.FromPort != 0 && rule.ToPort != 0 {
-			for _, ipRange := range rule.IpRanges {
-				srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-				nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, srcOrDstIpAddrOption, protoUDP, dportOption, ruleInterface, "counter", actionAccept}
-				if _, err := runNftCmd(ax.logger, nft); err != nil {
-					return err
-				}
-			}
-		}
-	case protoICMP, protoICMPv6:
-		// icmpv4 permits to L3 src or dst
-		for _, ipRange := range rule.IpRanges {
-			srcOrDstIpAddrOption := fmt.Sprintf("ip6 %s %s", srcOrDst, ipRange)
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", srcOrDstIpAddrOption, ruleInterface, counter, actionAccept}
-			if _, err := runNftCmd(ax.logger, nft); err != nil {
-				return err
-			}
-		}
-	default:
-		ax.logger.Debugf("no match for permit proto dport rule: %v", rule)
-		return nil
+// nfIfaceMatch matches traffic arriving on the wireguard interface for inbound rules and leaving it for outbound rules.
+func nfIfaceMatch(iface string, dir secgroup.Direction) ([]expr.Any, string) {
+	key, keyword := expr.MetaKeyIIFNAME, "iifname"
+	if dir == secgroup.Outbound {
+		key, keyword = expr.MetaKeyOIFNAME, "oifname"
 	}
-
-	return nil
+	return []expr.Any{
+		&expr.Meta{Key: key, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: nfIfname(iface)},
+	}, fmt.Sprintf("%s %q", keyword, iface)
 }
 
-// nfPermitProtoPort creates a nftables rule that permits the specified rule. Example Rules handled by this method:
-// nft add rule inet nexodus nexodus-inbound meta nfproto ipv4 iifname "wg0" tcp dport 1-80 counter accept
-// nft add rule inet nexodus nexodus-inbound meta nfproto ipv6 iifname "wg0" tcp dport 1-80 counter accept
-func (ax *Nexodus) nfPermitProtoPort(chain string, rule public.ModelsSecurityRule) error {
-	var dportOption string
-	var nft []string
-	dportOption = ax.nftPortOption(rule)
-	switch rule.IpProtocol {
-	case protoIPv4, protoIPv6:
-		// if the specified proto is ipv4 or ipv6, add rules for both tcp and udp to the chain with the specified dport
-		if dportOption == "" {
-			return nil
-		}
-		// tcp permits for ports to the specified dport for v4/v6
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, protoTCP, dportOption, ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
-		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, protoTCP, dportOption, ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
-		}
-		// udp permits for ports to the specified dport for v4/v6
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, protoUDP, dportOption, ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
-		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, protoUDP, dportOption, ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
+// nfMatchExprs renders a normalized security group match for a single address range
+func nfMatchExprs(dir secgroup.Direction, match secgroup.Match, addrRange secgroup.AddrRange) ([]expr.Any, string) {
+	var exprs []expr.Any
+	var desc []string
 
-		}
-	case protoUDP, protoTCP:
-		// if the specified proto is tcp or udp, add rules for both ipv4 and ipv6 to the chain with the specified dport
-		if dportOption == "" {
-			return nil
-		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, dportOption, ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
-		}
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, dportOption, ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
-		}
-	default:
-		ax.logger.Debugf("no match for permit proto dport rule: %v", rule)
-		return nil
+	family, ipKeyword, addrLen := byte(unix.NFPROTO_IPV4), "ip", uint32(4)
+	srcOffset, dstOffset := uint32(12), uint32(16)
+	if match.IPv6 {
+		family, ipKeyword, addrLen = unix.NFPROTO_IPV6, "ip6", 16
+		srcOffset, dstOffset = 8, 24
+	}
+	exprs = append(exprs,
+		&expr.Meta{Key: expr.MetaKeyNFPROTO, Register: 1},
+		&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{family}},
+	)
+	if match.IPv6 {
+		desc = append(desc, "meta nfproto ipv6")
+	} else {
+		desc = append(desc, "meta nfproto ipv4")
 	}
 
-	return nil
-}
+	if match.Protocol != 0 {
+		exprs = append(exprs,
+			&expr.Meta{Key: expr.MetaKeyL4PROTO, Register: 1},
+			&expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{match.Protocol}},
+		)
+		desc = append(desc, "meta l4proto "+nfProtoName(match.Protocol))
+	}
 
-// nfPermitProtoAny creates a nftables rule that permits the specified rule. Example Rules handled by this method:
-// nft insert rule inet nexodus nexodus-outbound meta nfproto ipv4  iifname "wg0" counter accept
-// nft insert rule inet nexodus nexodus-outbound meta nfproto ipv6  iifname "wg0" counter accept
-// nft add rule inet nexodus nexodus-inbound meta nfproto ipv4 tcp dport 0-65535 iifname "wg0" counter accept
-// nft add rule inet nexodus nexodus-inbound meta nfproto ipv6 tcp dport 0-65535  iifname "wg0" counter accept
-func (ax *Nexodus) nfPermitProtoAny(chain string, rule public.ModelsSecurityRule) error {
-	var nft []string
-	switch rule.IpProtocol {
-	case protoIPv4, protoIPv6:
-		// permit ipv6 any
-		if rule.IpProtocol == protoIPv4 {
-			nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", rule.IpProtocol, ruleInterface, counter, actionAccept}
-			if _, err := runNftCmd(ax.logger, nft); err != nil {
-				return err
-			}
-		}
-		// permit ipv4 any
-		if rule.IpProtocol == protoIPv6 {
-			nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", rule.IpProtocol, ruleInterface, counter, actionAccept}
-			if _, err := runNftCmd(ax.logger, nft); err != nil {
-				return err
-			}
-		}
+	if addrRange.From.IsValid() {
+		offset, keyword := srcOffset, "saddr"
+		if dir == secgroup.Outbound {
+			offset, keyword = dstOffset, "daddr"
+		}
+		exprs = append(exprs, &expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseNetworkHeader,
+			Offset:       offset,
+			Len:          addrLen,
+		})
+		exprs = append(exprs, nfEqualOrRange(addrRange.From.AsSlice(), addrRange.To.AsSlice()))
+		desc = append(desc, fmt.Sprintf("%s %s %s", ipKeyword, keyword, nfRangeDesc(addrRange.From, addrRange.To)))
+	}
 
-	case "icmp", protoICMPv4, protoICMPv6:
-		// permit icmpv4 any
-		if rule.IpProtocol == protoICMPv4 || rule.IpProtocol == "icmp" {
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, "ip", "protocol", protoICMP, ruleInterface, counter, actionAccept}
-			if _, err := runNftCmd(ax.logger, nft); err != nil {
-				return err
-			}
-		}
-		// permit icmpv6 any
-		if rule.IpProtocol == protoICMPv6 {
-			// ip6 nexthdr is used instead of ip6 protocol for IPv6, because the protocol field is not directly in the IPv6 header.
-			nft = []string{"insert", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, "ip6", "nexthdr", "ipv6-icmp", ruleInterface, counter, actionAccept}
-			if _, err := runNftCmd(ax.logger, nft); err != nil {
-				return err
-			}
-		}
-	case protoTCP, protoUDP:
-		// permit ip/ip6 tcp or udp any to all ports
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv4, rule.IpProtocol, destPort, "0-65535", ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
-		}
-		// permit ipv6 tcp or udp any
-		nft = []string{"add", "rule", tableFamily, tableName, chain, "meta", "nfproto", protoIPv6, rule.IpProtocol, destPort, "0-65535", ruleInterface, counter, actionAccept}
-		if _, err := runNftCmd(ax.logger, nft); err != nil {
-			return err
+	if match.Ports != nil {
+		exprs = append(exprs, &expr.Payload{
+			DestRegister: 1,
+			Base:         expr.PayloadBaseTransportHeader,
+			Offset:       2,
+			Len:          2,
+		})
+		exprs = append(exprs, nfEqualOrRange(
+			binaryutil.BigEndian.PutUint16(match.Ports.From),
+			binaryutil.BigEndian.PutUint16(match.Ports.To),
+		))
+		if match.Ports.From == match.Ports.To {
+			desc = append(desc, fmt.Sprintf("%s dport %d", nfProtoName(match.Protocol), match.Ports.From))
+		} else {
+			desc = append(desc, fmt.Sprintf("%s dport %d-%d", nfProtoName(match.Protocol), match.Ports.From, match.Ports.To))
 		}
-	default:
-		ax.logger.Debugf("no match for permit proto any dport rule: %v", rule)
-		return nil
 	}
 
-	return nil
+	return exprs, strings.Join(desc, " ")
 }
 
-// nftPortOption returns the nftables port option for the specified rule.
-func (ax *Nexodus) nftPortOption(rule public.ModelsSecurityRule) string {
-	var portOption string
-	var portRange string
-
-	if rule.FromPort == 0 && rule.ToPort == 0 {
-		portRange = fmt.Sprintf("%d-%d", 0, 65535)
-	} else if rule.FromPort == rule.ToPort {
-		portRange = fmt.Sprintf("%d", rule.FromPort)
-	} else {
-		portRange = fmt.Sprintf("%d-%d", rule.FromPort, rule.ToPort)
+func nfEqualOrRange(from, to []byte) expr.Any {
+	if string(from) == string(to) {
+		return &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: from}
 	}
-	portOption = fmt.Sprintf("%s %s", destPort, portRange)
-
-	return portOption
+	return &expr.Range{Op: expr.CmpOpEq, Register: 1, FromData: from, ToData: to}
 }
 
-// nfIngressRuleDrop is used to append a drop rule to the ingress chain. Example rule handled by this method:
-func (ax *Nexodus) nfIngressRuleDrop() error {
-	nft := []string{"add", "rule", tableFamily, tableName, ingressChain, ruleInterface, "counter", actionDrop}
-	if _, err := runNftCmd(ax.logger, nft); err != nil {
-		return err
+func nfRangeDesc(from, to netip.Addr) string {
+	if from == to {
+		return from.String()
 	}
-
-	return nil
+	return fmt.Sprintf("%s-%s", from, to)
 }
 
-// nfEgressRuleDrop is used to append a drop rule to the egress chain
-func (ax *Nexodus) nfEgressRuleDrop() error {
-	nft := []string{"add", "rule", tableFamily, tableName, egressChain, ruleInterface, "counter", actionDrop}
-	if _, err := runNftCmd(ax.logger, nft); err != nil {
-		return err
+func nfProtoName(proto uint8) string {
+	switch proto {
+	case unix.IPPROTO_TCP:
+		return "tcp"
+	case unix.IPPROTO_UDP:
+		return "udp"
+	case unix.IPPROTO_ICMP:
+		return "icmp"
+	case unix.IPPROTO_ICMPV6:
+		return "ipv6-icmp"
 	}
+	return fmt.Sprintf("%d", proto)
+}
 
-	return nil
+// nfIfname returns the interface name in the zero padded form the kernel compares against
+func nfIfname(name string) []byte {
+	b := make([]byte, unix.IFNAMSIZ)
+	copy(b, name)
+	return b
 }
 
-// nfTableDrop is used to delete the nftables table if it exists
-func (ax *Nexodus) nfTableDrop() error {
-	// First, check if the table exists
-	exists, err := ax.nfTableExists()
+// nfApplyRuleset replaces the running nexodus table with the compiled ruleset in a single
+// netlink transaction, so a failed update never leaves a partially applied ruleset behind.
+// Nothing is done if the running table already matches the ruleset.
+func nfApplyRuleset(logger *zap.SugaredLogger, conn *nftables.Conn, rs *nfRuleset) error {
+	running, exists, err := nfRunningRules(conn)
 	if err != nil {
-		return err
+		return fmt.Errorf("nftables setup error, failed to read the running %s table: %w", tableName, err)
 	}
-
-	if !exists {
+	if exists && rs.equal(nfRuleDescriptions(running)) {
+		logger.Debugf("nftables %s table is up to date", tableName)
 		return nil
 	}
 
-	// If the table exists, proceed with deletion
-	nft := []string{"delete", "table", tableFamily, tableName}
-	if _, err := runNftCmd(ax.logger, nft); err != nil {
-		return err
+	if exists {
+		conn.DelTable(rs.table)
+	}
+	conn.AddTable(rs.table)
+	for _, chain := range rs.chains {
+		conn.AddChain(chain)
+	}
+	for _, rule := range rs.rules {
+		conn.AddRule(rule)
+		logger.Debugf("nft rule: %s %s", rule.Chain.Name, rule.UserData)
+	}
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("nftables setup error, failed to apply the %s table with %d rules: %w", tableName, len(rs.rules), err)
 	}
-
 	return nil
 }
 
-func (ax *Nexodus) nfTableExists() (bool, error) {
-	args := []string{"list", "tables"}
-	output, err := runNftCmd(ax.logger, args)
-	if err != nil {
-		return false, err
+// equal compares the compiled rules to the rule descriptions running in the kernel
+func (rs *nfRuleset) equal(running map[string][]string) bool {
+	compiled := map[string][]string{}
+	for _, chain := range rs.chains {
+		compiled[chain.Name] = []string{}
 	}
-
-	tableFullName := fmt.Sprintf("%s %s", tableFamily, tableName)
-	return strings.Contains(output, tableFullName), nil
+	for _, rule := range rs.rules {
+		compiled[rule.Chain.Name] = append(compiled[rule.Chain.Name], string(rule.UserData))
+	}
+	if len(compiled) != len(running) {
+		return false
+	}
+	for name, rules := range compiled {
+		runningRules, ok := running[name]
+		if !ok || len(rules) != len(runningRules) {
+			return false
+		}
+		for i := range rules {
+			if rules[i] != runningRules[i] {
+				return false
+			}
+		}
+	}
+	return true
 }
 
-// nfCreateTable is used to create the nftables table
-func (ax *Nexodus) nfCreateTable() error {
-	if _, err := runNftCmd(ax.logger, []string{"add", "table", tableFamily, tableName}); err != nil {
-		return err
+// nfRulesetStats adds up the counters of the running rules per security group rule. The
+// running rules have to match the ruleset, which is the case once the ruleset was applied.
+func nfRulesetStats(conn *nftables.Conn, policy *secgroup.Policy, rs *nfRuleset) (secgroup.Stats, error) {
+	running, exists, err := nfRunningRules(conn)
+	if err != nil {
+		return secgroup.Stats{}, fmt.Errorf("failed to read the running %s table: %w", tableName, err)
+	}
+	if !exists || !rs.equal(nfRuleDescriptions(running)) {
+		return secgroup.Stats{}, fmt.Errorf("the running %s table does not match the security group rules", tableName)
 	}
 
-	return nil
+	stats := secgroup.NewStats(policy)
+	position := map[string]int{}
+	for i, rule := range rs.rules {
+		runningRule := running[rule.Chain.Name][position[rule.Chain.Name]]
+		position[rule.Chain.Name]++
+
+		counter := nfRuleCounter(runningRule)
+		tag := rs.tags[i]
+		d := stats.Direction(tag.dir)
+		switch tag.rule {
+		case nfRuleEstablished:
+			d.Established.Add(counter)
+		case nfRuleDenied:
+			d.Denied.Add(counter)
+		default:
+			d.Rules[tag.rule].Add(counter)
+		}
+	}
+	return stats, nil
 }
 
-// nfCreateChain is used to create the nftables chain in the nf table
-func (ax *Nexodus) nfCreateChain(chainName string) error {
-	if _, err := runNftCmd(ax.logger, []string{"add", "chain", tableFamily, tableName, chainName, "{", "type", "filter", "hook", "input", "priority", "0", ";", "policy", "accept", ";", "}"}); err != nil {
-		return err
+// nfRuleCounter returns the packets and bytes of the counter expression of a running rule
+func nfRuleCounter(rule *nftables.Rule) secgroup.Counter {
+	for _, e := range rule.Exprs {
+		if c, ok := e.(*expr.Counter); ok {
+			return secgroup.Counter{Packets: c.Packets, Bytes: c.Bytes}
+		}
 	}
-
-	return nil
+	return secgroup.Counter{}
 }
 
-// containsIPv4Range matches the following ipv4 patterns:
-// Cidr notation 100.100.0.0/16
-// Individual address 10.100.0.2
-// Dash-separated range 100.100.0.0-100.100.10.255
-func containsIPv4Range(ipRanges []string) bool {
-	for _, ipRange := range ipRanges {
-		if strings.Contains(ipRange, "-") {
-			// Dash-separated range
-			ips := strings.Split(ipRange, "-")
-			ip1 := net.ParseIP(strings.TrimSpace(ips[0]))
-			ip2 := net.ParseIP(strings.TrimSpace(ips[1]))
-
-			if ip1 != nil && ip1.To4() != nil && ip2 != nil && ip2.To4() != nil {
-				return true
-			}
-		} else if strings.Contains(ipRange, "/") {
-			// CIDR notation
-			_, ipNet, err := net.ParseCIDR(ipRange)
-			if err == nil && ipNet.IP.To4() != nil {
-				return true
-			}
-		} else {
-			ip := net.ParseIP(ipRange)
-			// Individual IP
-			if ip != nil && ip.To4() != nil {
-				return true
-			}
+// nfRuleDescriptions returns the descriptions stored in the user data of the running rules
+func nfRuleDescriptions(running map[string][]*nftables.Rule) map[string][]string {
+	descs := map[string][]string{}
+	for name, rules := range running {
+		descs[name] = []string{}
+		for _, rule := range rules {
+			descs[name] = append(descs[name], string(rule.UserData))
 		}
 	}
-
-	return false
+	return descs
 }
 
-// containsIPv6Range matches the following ipv6 patterns:
-// Cidr notation 200::/64
-// Individual address 200::2
-// Dash-separated range Range 200::1-200::8
-// Dash-separated range 2001:0db8:0000:0000:0000:0000:0000:0000-2001:0db8:ffff:ffff:ffff:ffff:ffff:ffff
-func containsIPv6Range(ipRanges []string) bool {
-	for _, ipRange := range ipRanges {
-		if strings.Contains(ipRange, "-") {
-			// Dash-separated range
-			ips := strings.Split(ipRange, "-")
-			if len(ips) != 2 {
-				return false
-			}
-
-			ip1 := net.ParseIP(strings.TrimSpace(ips[0]))
-			ip2 := net.ParseIP(strings.TrimSpace(ips[1]))
-
-			if ip1 == nil || ip2 == nil || ip1.To16() == nil || ip2.To16() == nil {
-				return false
-			}
-		} else if strings.Contains(ipRange, "/") {
-			// CIDR notation
-			_, _, err := net.ParseCIDR(ipRange)
-			if err != nil {
-				return false
-			}
-		} else {
-			// Individual IP
-			ip := net.ParseIP(ipRange)
-			if ip == nil || ip.To16() == nil {
-				return false
-			}
+// nfRunningRules returns the rules of each chain of the running nexodus table
+func nfRunningRules(conn *nftables.Conn) (map[string][]*nftables.Rule, bool, error) {
+	table, err := nfRunningTable(conn)
+	if err != nil || table == nil {
+		return nil, false, err
+	}
+	chains, err := conn.ListChainsOfTableFamily(nftables.TableFamilyINet)
+	if err != nil {
+		return nil, true, err
+	}
+	running := map[string][]*nftables.Rule{}
+	for _, chain := range chains {
+		if chain.Table.Name != tableName {
+			continue
 		}
+		rules, err := conn.GetRules(table, chain)
+		if err != nil {
+			return nil, true, err
+		}
+		running[chain.Name] = rules
 	}
-
-	return true
+	return running, true, nil
 }
 
-// runNftCmd is used to execute nft commands
-func runNftCmd(logger *zap.SugaredLogger, cmd []string) (string, error) {
-	nft := exec.Command("nft", cmd...)
-
-	output, err := nft.CombinedOutput()
+func nfRunningTable(conn *nftables.Conn) (*nftables.Table, error) {
+	tables, err := conn.ListTablesOfFamily(nftables.TableFamilyINet)
 	if err != nil {
-		return "", fmt.Errorf("nft command: %q failed: %w\n", strings.Join(cmd, " "), err)
+		return nil, err
 	}
-	logger.Debugf("nft command: %s", strings.Join(cmd, " "))
-
-	return string(output), nil
+	for _, table := range tables {
+		if table.Name == tableName {
+			return table, nil
+		}
+	}
+	return nil, nil
 }
 
-func debugSecurityGroupRules(logger *zap.SugaredLogger, inboundRules, outboundRules []public.ModelsSecurityRule) error {
-	inJson, err := json.MarshalIndent(inboundRules, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to print debug json inbound rules: %w", err)
+// nfTableDrop is used to delete the nftables table if it exists
+func nfTableDrop(conn *nftables.Conn) error {
+	table, err := nfRunningTable(conn)
+	if err != nil || table == nil {
+		return err
 	}
-	logger.Debugf("\nInboundRules:\n %s\n", inJson)
+	conn.DelTable(table)
+	return conn.Flush()
+}
 
-	outJson, err := json.MarshalIndent(outboundRules, "", "  ")
+// setupNftables adds v4/v6 nftables rules for the relay node to forward the traffic arriving on the wireguard interface
+func setupNftables(dev string) error {
+	conn, err := nftables.New()
 	if err != nil {
-		return fmt.Errorf("failed to print debug json inbound rules: %w", err)
+		return fmt.Errorf("failed to open the netlink connection: %w", err)
+	}
+	for _, family := range []nftables.TableFamily{nftables.TableFamilyIPv4, nftables.TableFamilyIPv6} {
+		table := conn.AddTable(&nftables.Table{
+			Name:   "filter",
+			Family: family,
+		})
+		chain := conn.AddChain(&nftables.Chain{
+			Name:     "FORWARD",
+			Table:    table,
+			Type:     nftables.ChainTypeFilter,
+			Hooknum:  nftables.ChainHookForward,
+			Priority: nftables.ChainPriorityFilter,
+		})
+		ifaceExprs, _ := nfIfaceMatch(dev, secgroup.Inbound)
+		conn.AddRule(&nftables.Rule{
+			Table: table,
+			Chain: chain,
+			Exprs: append(ifaceExprs, &expr.Counter{}, &expr.Verdict{Kind: expr.VerdictAccept}),
+		})
+	}
+	if err := conn.Flush(); err != nil {
+		return fmt.Errorf("failed to add the relay forwarding rules for %s: %w", dev, err)
 	}
-	logger.Debugf("\nOutboundRules:\n %s\n", outJson)
-
 	return nil
 }
@@ -0,0 +1,158 @@
+//go:build linux
+
+package nexodus
+
+import (
+	"testing"
+
+	"github.com/google/nftables"
+	"github.com/google/nftables/expr"
+	"github.com/nexodus-io/nexodus/internal/secgroup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func ruleDescriptions(rs *nfRuleset) map[string][]string {
+	descs := map[string][]string{}
+	for _, rule := range rs.rules {
+		descs[rule.Chain.Name] = append(descs[rule.Chain.Name], string(rule.UserData))
+	}
+	return descs
+}
+
+func TestCompileNfRuleset(t *testing.T) {
+	policy, err := secgroup.Compile(
+		[]secgroup.Rule{
+			{IpProtocol: "tcp", FromPort: 22, ToPort: 22, IpRanges: []string{"100.100.0.0/16"}},
+			{IpProtocol: "udp", FromPort: 5000, ToPort: 5010},
+			{IpProtocol: "icmp"},
+		},
+		[]secgroup.Rule{
+			{IpProtocol: "ipv6", IpRanges: []string{"200::1-200::10"}},
+		},
+	)
+	require.NoError(t, err)
+
+	rs := compileNfRuleset("wg0", policy, SecurityGroupLog{})
+	assert.Equal(t, tableName, rs.table.Name)
+	assert.Equal(t, nftables.TableFamilyINet, rs.table.Family)
+	require.Len(t, rs.chains, 2)
+	assert.Equal(t, ingressChain, rs.chains[0].Name)
+	assert.Equal(t, nftables.ChainHookInput, rs.chains[0].Hooknum)
+	assert.Equal(t, egressChain, rs.chains[1].Name)
+	assert.Equal(t, nftables.ChainHookOutput, rs.chains[1].Hooknum)
+
+	assert.Equal(t, map[string][]string{
+		ingressChain: {
+			`iifname "wg0" ct state established,related counter accept`,
+			`iifname "wg0" meta nfproto ipv4 meta l4proto tcp ip saddr 100.100.0.0-100.100.255.255 tcp dport 22 counter accept`,
+			`iifname "wg0" meta nfproto ipv4 meta l4proto udp udp dport 5000-5010 counter accept`,
+			`iifname "wg0" meta nfproto ipv6 meta l4proto udp udp dport 5000-5010 counter accept`,
+			`iifname "wg0" meta nfproto ipv4 meta l4proto icmp counter accept`,
+			`iifname "wg0" counter drop`,
+		},
+		egressChain: {
+			`oifname "wg0" ct state established,related counter accept`,
+			`oifname "wg0" meta nfproto ipv6 ip6 daddr 200::1-200::10 counter accept`,
+			`oifname "wg0" counter drop`,
+		},
+	}, ruleDescriptions(rs))
+}
+
+func TestCompileNfRulesetExprs(t *testing.T) {
+	policy, err := secgroup.Compile(
+		[]secgroup.Rule{
+			{IpProtocol: "tcp", FromPort: 443, ToPort: 443, IpRanges: []string{"10.0.0.1"}},
+			{IpProtocol: "udp", FromPort: 1000, ToPort: 2000, IpRanges: []string{"10.0.0.0-10.0.0.9"}},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	rs := compileNfRuleset("wg0", policy, SecurityGroupLog{})
+	require.Len(t, rs.rules, 5)
+
+	single := rs.rules[1].Exprs
+	assert.Equal(t, &expr.Payload{DestRegister: 1, Base: expr.PayloadBaseNetworkHeader, Offset: 12, Len: 4}, single[6])
+	assert.Equal(t, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{10, 0, 0, 1}}, single[7])
+	assert.Equal(t, &expr.Payload{DestRegister: 1, Base: expr.PayloadBaseTransportHeader, Offset: 2, Len: 2}, single[8])
+	assert.Equal(t, &expr.Cmp{Op: expr.CmpOpEq, Register: 1, Data: []byte{0x01, 0xbb}}, single[9])
+	assert.Equal(t, &expr.Verdict{Kind: expr.VerdictAccept}, single[len(single)-1])
+
+	ranged := rs.rules[2].Exprs
+	assert.Equal(t, &expr.Range{Op: expr.CmpOpEq, Register: 1, FromData: []byte{10, 0, 0, 0}, ToData: []byte{10, 0, 0, 9}}, ranged[7])
+	assert.Equal(t, &expr.Range{Op: expr.CmpOpEq, Register: 1, FromData: []byte{0x03, 0xe8}, ToData: []byte{0x07, 0xd0}}, ranged[9])
+
+	drop := rs.rules[3].Exprs
+	assert.Equal(t, &expr.Verdict{Kind: expr.VerdictDrop}, drop[len(drop)-1])
+}
+
+func TestCompileNfRulesetEmptyDirection(t *testing.T) {
+	policy, err := secgroup.Compile(nil, nil)
+	require.NoError(t, err)
+
+	rs := compileNfRuleset("wg0", policy, SecurityGroupLog{})
+	// without rules the chains only accept established traffic and fall through to the accept policy
+	assert.Equal(t, map[string][]string{
+		ingressChain: {`iifname "wg0" ct state established,related counter accept`},
+		egressChain:  {`oifname "wg0" ct state established,related counter accept`},
+	}, ruleDescriptions(rs))
+}
+
+func TestNfRulesetEqual(t *testing.T) {
+	policy, err := secgroup.Compile([]secgroup.Rule{{IpProtocol: "tcp", FromPort: 80, ToPort: 80}}, nil)
+	require.NoError(t, err)
+	rs := compileNfRuleset("wg0", policy, SecurityGroupLog{})
+
+	running := ruleDescriptions(rs)
+	running[egressChain] = []string{`oifname "wg0" ct state established,related counter accept`}
+	assert.True(t, rs.equal(running))
+
+	running[ingressChain] = running[ingressChain][1:]
+	assert.False(t, rs.equal(running))
+
+	delete(running, ingressChain)
+	assert.False(t, rs.equal(running))
+}
+
+func TestCompileNfRulesetLog(t *testing.T) {
+	policy, err := secgroup.Compile(
+		[]secgroup.Rule{{IpProtocol: "tcp", FromPort: 22, ToPort: 22}},
+		[]secgroup.Rule{{IpProtocol: "udp", FromPort: 53, ToPort: 53}},
+	)
+	require.NoError(t, err)
+
+	rs := compileNfRuleset("wg0", policy, SecurityGroupLog{Enabled: true})
+	descs := ruleDescriptions(rs)
+	assert.Equal(t, `iifname "wg0" counter log prefix "nexodus inbound denied: " drop`, descs[ingressChain][len(descs[ingressChain])-1])
+	drop := rs.rules[len(descs[ingressChain])-1].Exprs
+	assert.Equal(t, &expr.Log{Key: 1 << unix.NFTA_LOG_PREFIX, Data: []byte("nexodus inbound denied: ")}, drop[len(drop)-2])
+
+	rs = compileNfRuleset("wg0", policy, SecurityGroupLog{Enabled: true, Nflog: true, Group: 5})
+	descs = ruleDescriptions(rs)
+	assert.Equal(t, `oifname "wg0" counter log prefix "nexodus outbound denied: " group 5 drop`, descs[egressChain][len(descs[egressChain])-1])
+	drop = rs.rules[len(rs.rules)-1].Exprs
+	assert.Equal(t, &expr.Log{Key: 1<<unix.NFTA_LOG_PREFIX | 1<<unix.NFTA_LOG_GROUP, Group: 5, Data: []byte("nexodus outbound denied: ")}, drop[len(drop)-2])
+}
+
+func TestCompileNfRulesetTags(t *testing.T) {
+	policy, err := secgroup.Compile(
+		[]secgroup.Rule{
+			{IpProtocol: "tcp", FromPort: 22, ToPort: 22, IpRanges: []string{"100.100.0.1", "200::1"}},
+			{IpProtocol: "icmp"},
+		},
+		nil,
+	)
+	require.NoError(t, err)
+
+	rs := compileNfRuleset("wg0", policy, SecurityGroupLog{})
+	assert.Equal(t, []nfRuleTag{
+		{secgroup.Inbound, nfRuleEstablished},
+		{secgroup.Inbound, 0},
+		{secgroup.Inbound, 0},
+		{secgroup.Inbound, 1},
+		{secgroup.Inbound, nfRuleDenied},
+		{secgroup.Outbound, nfRuleEstablished},
+	}, rs.tags)
+}
@@ -0,0 +1,29 @@
+package nexodus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSecurityGroupLog(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected SecurityGroupLog
+	}{
+		{value: "", expected: SecurityGroupLog{}},
+		{value: "log", expected: SecurityGroupLog{Enabled: true}},
+		{value: "nflog:5", expected: SecurityGroupLog{Enabled: true, Nflog: true, Group: 5}},
+	}
+	for _, test := range tests {
+		log, err := ParseSecurityGroupLog(test.value)
+		require.NoError(t, err, test.value)
+		assert.Equal(t, test.expected, log, test.value)
+	}
+
+	for _, value := range []string{"syslog", "nflog:", "nflog:65536", "nflog:-1"} {
+		_, err := ParseSecurityGroupLog(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -2,7 +2,23 @@
 
 package nexodus
 
+import (
+	"fmt"
+
+	"github.com/nexodus-io/nexodus/internal/secgroup"
+)
+
 // ProcessSecurityGroup for windows build purposes, policy currently unsupported on windows
 func (ax *Nexodus) processSecurityGroupRules() error {
 	return nil
 }
+
+// securityGroupRuleStats for windows build purposes, policy currently unsupported on windows
+func (ax *Nexodus) securityGroupRuleStats() (secgroup.Stats, error) {
+	return secgroup.Stats{}, fmt.Errorf("security groups are not supported on windows outside of userspace mode")
+}
+
+// setupNftables for windows build purposes, relay nodes are only supported on linux
+func setupNftables(dev string) error {
+	return nil
+}
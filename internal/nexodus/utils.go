@@ -76,6 +76,22 @@ func ValidateCIDR(cidr string) error {
 	return nil
 }
 
+// ParseLabels parses the device labels given in the form key=value
+func ParseLabels(labels []string) (map[string]string, error) {
+	if len(labels) == 0 {
+		return nil, nil
+	}
+	result := make(map[string]string, len(labels))
+	for _, label := range labels {
+		key, value, found := strings.Cut(label, "=")
+		if !found || key == "" {
+			return nil, fmt.Errorf("'%s' is not a valid label, use the form key=value", label)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
 // discoverGenericIPv4 opens a socket to the controller and returns the IP of the source dial
 func discoverGenericIPv4(logger *zap.SugaredLogger, controller string, port string) (string, error) {
 	controllerSocket := fmt.Sprintf("%s:%s", controller, port)
@@ -168,6 +168,29 @@ func (ax *Nexodus) handlePeerDelete(peerMap map[string]public.ModelsDevice) erro
 	return nil
 }
 
+// handlePeerKeyRotation swaps the key of the peers that rotated their keys. The device keeps its ID, so
+// only the wireguard peer with the previous key is removed, the routes to the device are kept.
+// assumes a write lock is held on deviceCacheLock
+func (ax *Nexodus) handlePeerKeyRotation(peerMap map[string]public.ModelsDevice) {
+	keys := make(map[string]string, len(peerMap))
+	for _, p := range peerMap {
+		keys[p.Id] = p.PublicKey
+	}
+	for publicKey, d := range ax.deviceCache {
+		newPublicKey, ok := keys[d.device.Id]
+		if !ok || newPublicKey == publicKey || publicKey == ax.wireguardPubKey {
+			// the local device is re-keyed when it rotates its keys
+			continue
+		}
+		ax.logger.Infof("Device %s rotated its public key from %s to %s", d.device.Id, publicKey, newPublicKey)
+		if err := ax.deletePeer(publicKey, ax.tunnelIface); err != nil {
+			ax.logger.Debugf("failed to delete the peer with the previous key: %v", err)
+		}
+		delete(ax.wgConfig.Peers, publicKey)
+		delete(ax.deviceCache, publicKey)
+	}
+}
+
 func (ax *Nexodus) deletePeer(publicKey, dev string) error {
 	if ax.userspaceMode {
 		return ax.deletePeerUS(publicKey)
@@ -20,7 +20,7 @@ func (ax *Nexodus) DeployWireguardConfig(updatedPeers map[string]public.ModelsDe
 		Peers:     ax.wgConfig.Peers,
 	}
 
-	if ax.TunnelIP != ax.getIPv4Iface(ax.tunnelIface).String() {
+	if !ax.hasIPv4Iface(ax.tunnelIface, ax.TunnelIP) {
 		if err := ax.setupInterface(); err != nil {
 			return err
 		}
@@ -4,16 +4,14 @@ import (
 	"fmt"
 	"net"
 	"os"
-	"os/exec"
 	"strings"
 
 	"go.uber.org/zap"
 )
 
 const (
-	fwdFilePathV4  = "/proc/sys/net/ipv4/ip_forward"
-	fwdFilePathV6  = "/proc/sys/net/ipv6/conf/all/forwarding"
-	nftablesBinary = "nft"
+	fwdFilePathV4 = "/proc/sys/net/ipv4/ip_forward"
+	fwdFilePathV6 = "/proc/sys/net/ipv6/conf/all/forwarding"
 )
 
 // ifaceExists returns true if the input matches a net interface
@@ -36,6 +34,45 @@ func (ax *Nexodus) getIPv4Iface(ifname string) net.IP {
 	}
 }
 
+// hasIPv4Iface returns true if the specified net interface has the IP, it may have several during a re-addressing
+func (ax *Nexodus) hasIPv4Iface(ifname, ip string) bool {
+	if ax.userspaceMode {
+		return ax.getIPv4IfaceUS(ifname).String() == ip
+	}
+	interfaces, _ := net.Interfaces()
+	for _, inter := range interfaces {
+		if inter.Name != ifname {
+			continue
+		}
+		addrs, err := inter.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil && ipNet.IP.String() == ip {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addInterfaceAddresses adds addresses to the wireguard interface without recreating it
+func (ax *Nexodus) addInterfaceAddresses(ip, ipv6 string) error {
+	if ax.userspaceMode {
+		return fmt.Errorf("the userspace interface does not support multiple addresses")
+	}
+	return ax.addInterfaceAddressesOS(ip, ipv6)
+}
+
+// removeInterfaceAddresses removes addresses from the wireguard interface
+func (ax *Nexodus) removeInterfaceAddresses(ip, ipv6 string) error {
+	if ax.userspaceMode {
+		return nil
+	}
+	return ax.removeInterfaceAddressesOS(ip, ipv6)
+}
+
 func (ax *Nexodus) getIPv4IfaceUS(ifname string) net.IP {
 	return net.ParseIP(ax.userspaceLastAddress)
 }
@@ -87,10 +124,6 @@ func (ax *Nexodus) relayPrep() error {
 		}
 	}
 
-	if !IsCommandAvailable(nftablesBinary) {
-		return fmt.Errorf("required relay command %s not found, verify %s is installed", nftablesBinary, nftablesBinary)
-	}
-
 	if err := setupNftables(wgIface); err != nil {
 		return err
 	}
@@ -132,40 +165,3 @@ func isIPForwardingEnabled(ipForwardFilePath string) (bool, error) {
 
 	return false, nil
 }
-
-// setupNftables adds v4/v6 nftables rules for the relay node
-func setupNftables(dev string) error {
-	nftV4 := []string{
-		"add table ip filter",
-		"add chain ip filter FORWARD { type filter hook forward priority 0; }",
-		fmt.Sprintf(`add rule ip filter FORWARD iifname "%s" counter accept`, dev),
-	}
-
-	for _, cmd := range nftV4 {
-		if err := runNftCommand(cmd); err != nil {
-			return err
-		}
-	}
-
-	nftV6 := []string{
-		"add table ip6 filter",
-		"add chain ip6 filter FORWARD { type filter hook forward priority 0; }",
-		fmt.Sprintf(`add rule ip6 filter FORWARD iifname "%s" counter accept`, dev),
-	}
-
-	for _, cmd := range nftV6 {
-		if err := runNftCommand(cmd); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func runNftCommand(cmd string) error {
-	nft := exec.Command("nft", cmd)
-	output, err := nft.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("nft command: %q failed: %w\noutput: %s", cmd, err, output)
-	}
-	return nil
-}
@@ -40,6 +40,13 @@ func (ax *Nexodus) buildPeersConfig() map[string]public.ModelsDevice {
 		ax.org.Cidr,
 		ax.org.CidrV6,
 	}
+	// the previous prefixes stay reachable through the relay during the grace period of a re-addressing
+	if ax.org.PreviousCidr != "" {
+		relayAllowedIP = append(relayAllowedIP, ax.org.PreviousCidr)
+	}
+	if ax.org.PreviousCidrV6 != "" {
+		relayAllowedIP = append(relayAllowedIP, ax.org.PreviousCidrV6)
+	}
 
 	ax.buildLocalConfig()
 
@@ -201,10 +208,53 @@ func (ax *Nexodus) buildLocalConfig() {
 		return
 	}
 
-	// if the local node address changed replace it on wg0
-	if ax.TunnelIP != d.device.TunnelIp {
+	// the previous addresses of a re-addressing are removed from wg0 once the grace period ended,
+	// a re-addressing may only change one address family so each one is tracked on its own
+	if ax.previousTunnelIP != "" && d.device.PreviousTunnelIp == "" {
+		ax.logger.Infof("Removing the previous local Wireguard interface address IPv4 [ %s ]", ax.previousTunnelIP)
+		if err := ax.removeInterfaceAddresses(ax.previousTunnelIP, ""); err != nil {
+			ax.logger.Infof("Failed to remove the previous address from %s: %v", ax.tunnelIface, err)
+		}
+		ax.previousTunnelIP = ""
+	}
+	if ax.previousTunnelIpV6 != "" && d.device.PreviousTunnelIpV6 == "" {
+		ax.logger.Infof("Removing the previous local Wireguard interface address IPv6 [ %s ]", ax.previousTunnelIpV6)
+		if err := ax.removeInterfaceAddresses("", ax.previousTunnelIpV6); err != nil {
+			ax.logger.Infof("Failed to remove the previous address from %s: %v", ax.tunnelIface, err)
+		}
+		ax.previousTunnelIpV6 = ""
+	}
+
+	// if the local node addresses changed replace them on wg0
+	ipChanged := ax.TunnelIP != d.device.TunnelIp
+	ipv6Changed := ax.TunnelIpV6 != d.device.TunnelIpV6
+	if ipChanged || ipv6Changed {
 		ax.logger.Infof("New local Wireguard interface addresses assigned IPv4 [ %s ] IPv6 [ %s ]", d.device.TunnelIp, d.device.TunnelIpV6)
-		if runtime.GOOS == Linux.String() && linkExists(ax.tunnelIface) {
+		// when the organization is re-addressed the new addresses are added next to the previous
+		// ones, so the existing connections are kept until the end of the grace period
+		readdressed := (!ipChanged || ax.TunnelIP != "" && d.device.PreviousTunnelIp == ax.TunnelIP) &&
+			(!ipv6Changed || ax.TunnelIpV6 != "" && d.device.PreviousTunnelIpV6 == ax.TunnelIpV6)
+		if readdressed {
+			var ip, ipv6 string
+			if ipChanged {
+				ip = d.device.TunnelIp
+			}
+			if ipv6Changed {
+				ipv6 = d.device.TunnelIpV6
+			}
+			if err := ax.addInterfaceAddresses(ip, ipv6); err != nil {
+				ax.logger.Infof("Failed to add the new addresses to %s, recreating it: %v", ax.tunnelIface, err)
+				readdressed = false
+			} else {
+				if ipChanged {
+					ax.previousTunnelIP = ax.TunnelIP
+				}
+				if ipv6Changed {
+					ax.previousTunnelIpV6 = ax.TunnelIpV6
+				}
+			}
+		}
+		if !readdressed && runtime.GOOS == Linux.String() && linkExists(ax.tunnelIface) {
 			if err := delLink(ax.tunnelIface); err != nil {
 				ax.logger.Infof("Failed to delete %s: %v", ax.tunnelIface, err)
 			}
@@ -3,6 +3,7 @@ package routers
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"github.com/go-session/redis/v3"
 	"github.com/go-session/session/v3"
 	"github.com/nexodus-io/nexodus/pkg/ginsession"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nexodus-io/nexodus/internal/handlers"
 	"github.com/nexodus-io/nexodus/internal/util"
 	"github.com/nexodus-io/nexodus/internal/util/cache"
 	"github.com/open-policy-agent/opa/rego"
@@ -35,6 +37,7 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			"user_id": data.token.user_id,
 			"user_name": data.token.user_name,
 			"full_name": data.token.full_name,
+			"scope": data.token.scope,
 		}`),
 		rego.Store(o.Store),
 		rego.Module("policy.rego", policy),
@@ -46,15 +49,6 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 	return func(c *gin.Context) {
 		logger := util.WithTrace(c.Request.Context(), o.Logger)
 
-		keySet, err := jwksCache.MemoizeCanErr(jwksURI, func() (string, error) {
-			return getURLAsText(ctx, jwksURI)
-		})
-		if err != nil {
-			logger.Error(err)
-			c.AbortWithStatus(http.StatusInternalServerError)
-			return
-		}
-
 		authz := c.Request.Header.Get("Authorization")
 		if authz == "" {
 
@@ -79,10 +73,72 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 
 		path := strings.Split(strings.TrimLeft(c.Request.URL.Path, "/"), "/")
 		input := map[string]interface{}{
-			"jwks":         keySet,
-			"access_token": parts[1],
-			"method":       c.Request.Method,
-			"path":         path,
+			"method": c.Request.Method,
+			"path":   path,
+		}
+		if strings.HasPrefix(parts[1], handlers.ApiTokenPrefix) {
+			// API tokens are looked up in the database, the policy checks their scopes like the ones of a JWT
+			apiToken, user, err := o.Api.ValidateApiToken(c.Request.Context(), parts[1])
+			if errors.Is(err, handlers.ErrInvalidApiToken) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				logger.Error(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			input["api_token"] = map[string]interface{}{
+				"sub":                user.ID,
+				"preferred_username": user.UserName,
+				"scope":              strings.Join(apiToken.Scopes, " "),
+			}
+		} else if strings.HasPrefix(parts[1], handlers.RegKeyPrefix) {
+			// registration keys register devices as the owner of the key
+			regKey, user, err := o.Api.ValidateRegKey(c.Request.Context(), parts[1])
+			if errors.Is(err, handlers.ErrInvalidRegKey) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				logger.Error(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			input["reg_key"] = map[string]interface{}{
+				"sub":                user.ID,
+				"preferred_username": user.UserName,
+				"organization_id":    regKey.OrganizationID.String(),
+			}
+			c.Set(handlers.AuthRegKey, regKey)
+		} else if strings.HasPrefix(parts[1], handlers.DeviceTokenPrefix) {
+			// device tokens authenticate a device registered with a registration key, for that device only
+			regKey, user, device, err := o.Api.ValidateDeviceToken(c.Request.Context(), parts[1])
+			if errors.Is(err, handlers.ErrInvalidDeviceToken) {
+				c.AbortWithStatus(http.StatusUnauthorized)
+				return
+			} else if err != nil {
+				logger.Error(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			input["device_token"] = map[string]interface{}{
+				"sub":                user.ID,
+				"preferred_username": user.UserName,
+				"organization_id":    regKey.OrganizationID.String(),
+				"device_ids":         []string{device.ID.String()},
+			}
+			c.Set(handlers.AuthRegKey, regKey)
+			c.Set(handlers.AuthDeviceToken, device.ID)
+		} else {
+			keySet, err := jwksCache.MemoizeCanErr(jwksURI, func() (string, error) {
+				return getURLAsText(ctx, jwksURI)
+			})
+			if err != nil {
+				logger.Error(err)
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			input["jwks"] = keySet
+			input["access_token"] = parts[1]
 		}
 
 		results, err := query.Eval(c.Request.Context(), rego.EvalInput(input))
@@ -152,7 +208,15 @@ func ValidateJWT(ctx context.Context, o APIRouterOptions, jwksURI string) (func(
 			return
 		}
 
+		scope, ok := result["scope"].(string)
+		if !ok {
+			logger.Error("scope is not a string")
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
 		c.Set(gin.AuthUserKey, userID)
+		c.Set(handlers.AuthUserScope, scope)
 		if len(username) > 0 {
 			c.Set(AuthUserName, username)
 		} else if len(fullName) > 0 {
@@ -84,10 +84,17 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 		private.GET("/organizations", api.ListOrganizations)
 		private.POST("/organizations", api.CreateOrganization)
 		private.GET("/organizations/:organization", api.GetOrganizations)
+		private.PATCH("/organizations/:organization", api.UpdateOrganization)
 		private.DELETE("/organizations/:organization", api.DeleteOrganization)
 		private.GET("/organizations/:organization/devices", api.ListDevicesInOrganization)
 		private.GET("/organizations/:organization/devices/:id", api.GetDeviceInOrganization)
+		private.GET("/organizations/:organization/connectivity", api.GetOrganizationConnectivity)
+		private.GET("/organizations/:organization/events", api.ListOrganizationEvents)
 		private.GET("/organizations/:organization/users", api.ListUsersInOrganization)
+		private.GET("/organizations/:organization/roles", api.ListOrganizationRoles)
+		private.PATCH("/organizations/:organization/roles/:id", api.UpdateOrganizationRole)
+		private.POST("/organizations/:organization/transfer", api.TransferOrganization)
+		private.POST("/organizations/:organization/readdress", api.ReaddressOrganization)
 		// Invitations
 		private.POST("/invitations", api.CreateInvitation)
 		private.GET("/invitations", api.ListInvitations)
@@ -100,6 +107,10 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 		private.PATCH("/devices/:id", api.UpdateDevice)
 		private.POST("/devices", api.CreateDevice)
 		private.DELETE("/devices/:id", api.DeleteDevice)
+		private.POST("/devices/:id/approve", api.ApproveDevice)
+		private.POST("/devices/:id/rotate_key", api.RotateDeviceKey)
+		private.PUT("/devices/:id/status", api.ReportDeviceStatus)
+		private.GET("/devices/:id/status", api.GetDeviceStatus)
 		// Users
 		private.GET("/users/:id", api.GetUser)
 		private.GET("/users", api.ListUsers)
@@ -112,9 +123,31 @@ func NewAPIRouter(ctx context.Context, o APIRouterOptions) (*gin.Engine, error)
 		private.DELETE("/organizations/:organization/security_groups/:id", api.DeleteSecurityGroup)
 		private.GET("/organizations/:organization/security_group/:id", api.GetSecurityGroup)
 		private.PATCH("/organizations/:organization/security_groups/:id", api.UpdateSecurityGroup)
+		private.POST("/organizations/:organization/security_groups/simulate", api.SimulateSecurityGroup)
+		// Webhooks
+		private.GET("/organizations/:organization/webhooks", api.ListWebhooks)
+		private.POST("/organizations/:organization/webhooks", api.CreateWebhook)
+		private.GET("/organizations/:organization/webhooks/:id", api.GetWebhook)
+		private.PATCH("/organizations/:organization/webhooks/:id", api.UpdateWebhook)
+		private.DELETE("/organizations/:organization/webhooks/:id", api.DeleteWebhook)
+		private.GET("/organizations/:organization/webhooks/:id/deliveries", api.ListWebhookDeliveries)
+		// Registration Keys
+		private.GET("/organizations/:organization/registration_keys", api.ListRegKeys)
+		private.POST("/organizations/:organization/registration_keys", api.CreateRegKey)
+		private.DELETE("/organizations/:organization/registration_keys/:id", api.DeleteRegKey)
+		// API Tokens
+		private.GET("/tokens", api.ListApiTokens)
+		private.POST("/tokens", api.CreateApiToken)
+		private.DELETE("/tokens/:id", api.DeleteApiToken)
 		// Feature Flags
 		private.GET("fflags", api.ListFeatureFlags)
 		private.GET("fflags/:name", api.GetFeatureFlag)
+		private.POST("fflags", api.CreateFeatureFlag)
+		private.PATCH("fflags/:name", api.UpdateFeatureFlag)
+		private.DELETE("fflags/:name", api.DeleteFeatureFlag)
+		private.GET("fflags/:name/definition", api.GetFeatureFlagDefinition)
+		private.PUT("fflags/:name/overrides", api.SetFeatureFlagOverride)
+		private.DELETE("fflags/:name/overrides/:id", api.DeleteFeatureFlagOverride)
 	}
 
 	r.GET("/api/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler), loggerMiddleware)
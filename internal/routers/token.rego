@@ -21,6 +21,27 @@ valid_token if {
 	allowed_email
 }
 
+# api tokens are validated by the apiserver before the policy is evaluated
+valid_token if {
+	input.api_token.sub != ""
+}
+
+# registration keys are validated by the apiserver before the policy is evaluated
+valid_token if {
+	input.reg_key.sub != ""
+}
+
+# device tokens are validated by the apiserver before the policy is evaluated
+valid_token if {
+	input.device_token.sub != ""
+}
+
+default is_admin := false
+
+is_admin if {
+	"admin" in token_payload.realm_access.roles
+}
+
 default allow := false
 
 allow if {
@@ -93,16 +114,114 @@ allow if {
 	contains(token_payload.scope, "write:organizations")
 }
 
+allow if {
+	"tokens" = input.path[1]
+	action_is_read
+	valid_token
+	contains(token_payload.scope, "read:users")
+}
+
+allow if {
+	"tokens" = input.path[1]
+	action_is_write
+	valid_token
+	contains(token_payload.scope, "write:users")
+}
+
+# registration keys only allow nexd to register a device in their organization, the registered
+# device authenticates with its device token from then on
+allow if {
+	input.reg_key
+	reg_key_allow
+}
+
+# device tokens only allow the calls nexd makes for their own device
+allow if {
+	input.device_token
+	device_token_allow
+}
+
+reg_key_allow if {
+	input.method == "POST"
+	input.path == ["api", "devices"]
+}
+
+reg_key_allow if {
+	nexd_read_allow(input.reg_key)
+}
+
+# the apiserver only lets a device token update its own device when nexd registers again after a restart
+device_token_allow if {
+	input.method == "POST"
+	input.path == ["api", "devices"]
+}
+
+device_token_allow if {
+	nexd_read_allow(input.device_token)
+}
+
+# nexd reads its device, updates its endpoints and hostname and deletes it when it leaves the organization
+device_token_allow if {
+	input.method in ["GET", "PATCH", "DELETE"]
+	"devices" = input.path[1]
+	input.path[2] in input.device_token.device_ids
+	count(input.path) == 3
+}
+
+device_token_allow if {
+	input.method in ["GET", "PUT", "POST"]
+	"devices" = input.path[1]
+	input.path[2] in input.device_token.device_ids
+	input.path[3] in ["status", "rotate_key"]
+	count(input.path) == 4
+}
+
+# the reads nexd makes before it registers its device
+nexd_read_allow(_) if {
+	input.method == "GET"
+	input.path == ["api", "users", "me"]
+}
+
+nexd_read_allow(_) if {
+	input.method == "GET"
+	input.path == ["api", "organizations"]
+}
+
+nexd_read_allow(key) if {
+	input.method == "GET"
+	"organizations" = input.path[1]
+	input.path[2] == key.organization_id
+	input.path[3] in ["devices", "security_groups"]
+	count(input.path) == 4
+}
+
 allow if {
 	"fflags" = input.path[1]
+	action_is_read
+	count(input.path) <= 3
 	valid_token
 }
 
+allow if {
+	"fflags" = input.path[1]
+	valid_token
+	is_admin
+}
+
 action_is_read if input.method in ["GET"]
 
 action_is_write := input.method in ["POST", "PATCH", "DELETE", "PUT"]
 
+token_payload := input.api_token
+
+token_payload := input.reg_key
+
+token_payload := input.device_token
+
 token_payload := payload if {
+	not input.api_token
+	not input.reg_key
+	not input.device_token
 	[_, payload, _] = io.jwt.decode(input.access_token)
 }
 
@@ -117,3 +236,7 @@ user_name = token_payload.preferred_username
 default full_name = ""
 
 full_name = token_payload.name
+
+default scope = ""
+
+scope = token_payload.scope
@@ -37,6 +37,10 @@ mock_decode_verify("user-read-jwt", _) := [true, {}, {}]
 
 mock_decode("user-read-jwt") := [{}, valid_user("openid profile email read:users"), {}]
 
+mock_decode_verify("admin-jwt", _) := [true, {}, {}]
+
+mock_decode("admin-jwt") := [{}, object.union(valid_user("openid profile email"), {"realm_access": {"roles": ["admin"]}}), {}]
+
 mock_decode_verify("bad-jwt", _) := [false, {}, {}]
 
 test_org_get_allowed if {
@@ -200,3 +204,205 @@ test_get_fflags_bad_jwt_denied if {
 		with io.jwt.decode_verify as mock_decode_verify
 		with io.jwt.decode as mock_decode
 }
+
+test_get_fflag_definition_denied if {
+	not token.allow with input.path as ["api", "fflags", "security-groups", "definition"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "user-read-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_patch_fflags_denied if {
+	not token.allow with input.path as ["api", "fflags", "security-groups"]
+		with input.method as "PATCH"
+		with input.jwks as "my-cert"
+		with input.access_token as "user-write-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_get_fflag_definition_admin if {
+	token.allow with input.path as ["api", "fflags", "security-groups", "definition"]
+		with input.method as "GET"
+		with input.jwks as "my-cert"
+		with input.access_token as "admin-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_put_fflag_overrides_admin if {
+	token.allow with input.path as ["api", "fflags", "security-groups", "overrides"]
+		with input.method as "PUT"
+		with input.jwks as "my-cert"
+		with input.access_token as "admin-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+api_token(scopes) := {
+	"sub": "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9",
+	"preferred_username": "valid-user",
+	"scope": scopes,
+}
+
+test_api_token_device_get_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "GET"
+		with input.api_token as api_token("read:devices")
+}
+
+test_api_token_device_post_denied if {
+	not token.allow with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with input.api_token as api_token("read:devices")
+}
+
+test_api_token_user_id if {
+	token.user_id == "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9" with input.api_token as api_token("read:devices")
+	token.user_name == "valid-user" with input.api_token as api_token("read:devices")
+	token.scope == "read:devices write:devices" with input.api_token as api_token("read:devices write:devices")
+}
+
+test_api_token_fflags_not_admin if {
+	not token.allow with input.path as ["api", "fflags", "security-groups", "overrides"]
+		with input.method as "PUT"
+		with input.api_token as api_token("read:users write:users")
+}
+
+test_tokens_post_allowed if {
+	token.allow with input.path as ["api", "tokens"]
+		with input.method as "POST"
+		with input.jwks as "my-cert"
+		with input.access_token as "user-write-jwt"
+		with io.jwt.decode_verify as mock_decode_verify
+		with io.jwt.decode as mock_decode
+}
+
+test_tokens_post_denied if {
+	not token.allow with input.path as ["api", "tokens"]
+		with input.method as "POST"
+		with input.api_token as api_token("read:users write:devices")
+}
+
+reg_key := {
+	"sub": "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9",
+	"preferred_username": "valid-user",
+	"organization_id": "3ecd5a56-bb30-4aee-a3c6-2e0a5fe1c1e4",
+}
+
+device_token := {
+	"sub": "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9",
+	"preferred_username": "valid-user",
+	"organization_id": "3ecd5a56-bb30-4aee-a3c6-2e0a5fe1c1e4",
+	"device_ids": ["4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5"],
+}
+
+test_reg_key_create_device_allowed if {
+	token.allow with input.path as ["api", "devices"]
+		with input.method as "POST"
+		with input.reg_key as reg_key
+}
+
+test_reg_key_bootstrap_reads_allowed if {
+	token.allow with input.path as ["api", "users", "me"]
+		with input.method as "GET"
+		with input.reg_key as reg_key
+	token.allow with input.path as ["api", "organizations"]
+		with input.method as "GET"
+		with input.reg_key as reg_key
+	token.user_id == "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9" with input.reg_key as reg_key
+}
+
+test_reg_key_device_calls_denied if {
+	not token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5", "status"]
+		with input.method as "PUT"
+		with input.reg_key as reg_key
+	not token.allow with input.path as ["api", "devices", "b3a5b6e2-7c4a-4bb5-8e7e-0f2c3f6d1e27"]
+		with input.method as "PATCH"
+		with input.reg_key as reg_key
+}
+
+test_reg_key_org_devices_allowed if {
+	token.allow with input.path as ["api", "organizations", "3ecd5a56-bb30-4aee-a3c6-2e0a5fe1c1e4", "devices"]
+		with input.method as "GET"
+		with input.reg_key as reg_key
+}
+
+test_reg_key_other_org_denied if {
+	not token.allow with input.path as ["api", "organizations", "7e552731-2f5f-421d-9266-10fa46dfe3ee", "devices"]
+		with input.method as "GET"
+		with input.reg_key as reg_key
+}
+
+test_reg_key_admin_calls_denied if {
+	not token.allow with input.path as ["api", "organizations", "3ecd5a56-bb30-4aee-a3c6-2e0a5fe1c1e4", "registration_keys"]
+		with input.method as "POST"
+		with input.reg_key as reg_key
+	not token.allow with input.path as ["api", "tokens"]
+		with input.method as "POST"
+		with input.reg_key as reg_key
+}
+
+test_reg_key_rotate_key_denied if {
+	not token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5", "rotate_key"]
+		with input.method as "POST"
+		with input.reg_key as reg_key
+}
+
+test_reg_key_device_methods_denied if {
+	not token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5", "approve"]
+		with input.method as "POST"
+		with input.reg_key as reg_key
+	not token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5"]
+		with input.method as "GET"
+		with input.reg_key as reg_key
+	not token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5"]
+		with input.method as "DELETE"
+		with input.reg_key as reg_key
+}
+
+test_device_token_own_device_allowed if {
+	token.valid_token with input.device_token as device_token
+	token.user_id == "00a7b7f4-f11f-4ea3-89de-7b1cde4316a9" with input.device_token as device_token
+	token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5"]
+		with input.method as "PATCH"
+		with input.device_token as device_token
+	token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5"]
+		with input.method as "DELETE"
+		with input.device_token as device_token
+	token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5", "status"]
+		with input.method as "PUT"
+		with input.device_token as device_token
+	token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5", "rotate_key"]
+		with input.method as "POST"
+		with input.device_token as device_token
+	token.allow with input.path as ["api", "organizations", "3ecd5a56-bb30-4aee-a3c6-2e0a5fe1c1e4", "devices"]
+		with input.method as "GET"
+		with input.device_token as device_token
+}
+
+test_device_token_other_device_denied if {
+	not token.allow with input.path as ["api", "devices", "b3a5b6e2-7c4a-4bb5-8e7e-0f2c3f6d1e27"]
+		with input.method as "DELETE"
+		with input.device_token as device_token
+	not token.allow with input.path as ["api", "devices", "b3a5b6e2-7c4a-4bb5-8e7e-0f2c3f6d1e27"]
+		with input.method as "PATCH"
+		with input.device_token as device_token
+	not token.allow with input.path as ["api", "devices", "b3a5b6e2-7c4a-4bb5-8e7e-0f2c3f6d1e27", "rotate_key"]
+		with input.method as "POST"
+		with input.device_token as device_token
+	not token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5", "approve"]
+		with input.method as "POST"
+		with input.device_token as device_token
+	not token.allow with input.path as ["api", "organizations", "3ecd5a56-bb30-4aee-a3c6-2e0a5fe1c1e4", "registration_keys"]
+		with input.method as "GET"
+		with input.device_token as device_token
+}
+
+test_device_approve_allowed if {
+	token.allow with input.path as ["api", "devices", "4fc1a9ab-d4cc-4d86-a8cb-5d7ac9e5c1a5", "approve"]
+		with input.method as "POST"
+		with input.api_token as api_token("write:devices")
+}
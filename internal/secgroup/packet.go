@@ -0,0 +1,102 @@
+package secgroup
+
+import (
+	"encoding/binary"
+	"net/netip"
+)
+
+// IPv6 extension headers which are skipped to find the upper layer protocol
+const (
+	ipv6HopByHop = 0
+	ipv6Routing  = 43
+	ipv6Fragment = 44
+	ipv6DestOpts = 60
+)
+
+// ParsePacket decodes the addresses, protocol and ports from a raw IPv4 or IPv6 packet.
+func ParsePacket(b []byte) (Packet, bool) {
+	if len(b) < 1 {
+		return Packet{}, false
+	}
+	switch b[0] >> 4 {
+	case 4:
+		return parseIPv4(b)
+	case 6:
+		return parseIPv6(b)
+	}
+	return Packet{}, false
+}
+
+func parseIPv4(b []byte) (Packet, bool) {
+	if len(b) < 20 {
+		return Packet{}, false
+	}
+	ihl := int(b[0]&0x0f) * 4
+	if ihl < 20 || len(b) < ihl {
+		return Packet{}, false
+	}
+	pkt := Packet{
+		Protocol: b[9],
+		Src:      netip.AddrFrom4([4]byte(b[12:16])),
+		Dst:      netip.AddrFrom4([4]byte(b[16:20])),
+	}
+	// only the first fragment carries the transport header
+	fragOffset := binary.BigEndian.Uint16(b[6:8]) & 0x1fff
+	if fragOffset == 0 {
+		pkt.SrcPort, pkt.DstPort = parsePorts(pkt.Protocol, b[ihl:])
+	}
+	return pkt, true
+}
+
+func parseIPv6(b []byte) (Packet, bool) {
+	if len(b) < 40 {
+		return Packet{}, false
+	}
+	pkt := Packet{
+		Src: netip.AddrFrom16([16]byte(b[8:24])),
+		Dst: netip.AddrFrom16([16]byte(b[24:40])),
+	}
+	next := b[6]
+	payload := b[40:]
+	for {
+		switch next {
+		case ipv6HopByHop, ipv6Routing, ipv6DestOpts:
+			if len(payload) < 8 {
+				return Packet{}, false
+			}
+			length := (int(payload[1]) + 1) * 8
+			if len(payload) < length {
+				return Packet{}, false
+			}
+			next = payload[0]
+			payload = payload[length:]
+			continue
+		case ipv6Fragment:
+			if len(payload) < 8 {
+				return Packet{}, false
+			}
+			fragOffset := binary.BigEndian.Uint16(payload[2:4]) >> 3
+			next = payload[0]
+			payload = payload[8:]
+			if fragOffset != 0 {
+				pkt.Protocol = next
+				return pkt, true
+			}
+			continue
+		}
+		break
+	}
+	pkt.Protocol = next
+	pkt.SrcPort, pkt.DstPort = parsePorts(pkt.Protocol, payload)
+	return pkt, true
+}
+
+func parsePorts(protocol uint8, b []byte) (uint16, uint16) {
+	if protocol != ipProtoTCP && protocol != ipProtoUDP {
+		return 0, 0
+	}
+	if len(b) < 4 {
+		return 0, 0
+	}
+	return binary.BigEndian.Uint16(b[0:2]), binary.BigEndian.Uint16(b[2:4])
+}
@@ -0,0 +1,414 @@
+package secgroup
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Protocols understood in the IpProtocol field of a security rule
+const (
+	ProtoIPv4   = "ipv4"
+	ProtoIPv6   = "ipv6"
+	ProtoICMP   = "icmp"
+	ProtoICMPv4 = "icmpv4"
+	ProtoICMPv6 = "icmpv6"
+	ProtoTCP    = "tcp"
+	ProtoUDP    = "udp"
+)
+
+// IANA protocol numbers of the L4 protocols we match on
+const (
+	ipProtoICMPv4 = 1
+	ipProtoTCP    = 6
+	ipProtoUDP    = 17
+	ipProtoICMPv6 = 58
+)
+
+// ProtocolNumber returns the IANA protocol number of a tcp, udp or icmp protocol name
+// for the given address family.
+func ProtocolNumber(name string, ipv6 bool) (uint8, error) {
+	switch strings.ToLower(name) {
+	case ProtoTCP:
+		return ipProtoTCP, nil
+	case ProtoUDP:
+		return ipProtoUDP, nil
+	case ProtoICMP, ProtoICMPv4, ProtoICMPv6:
+		if ipv6 {
+			return ipProtoICMPv6, nil
+		}
+		return ipProtoICMPv4, nil
+	}
+	return 0, fmt.Errorf("unsupported protocol %q", name)
+}
+
+// protocolAliases maps alternate spellings accepted from users to the protocol names above.
+var protocolAliases = map[string]string{
+	"icmp4": ProtoICMPv4,
+	"icmp6": ProtoICMPv6,
+	"ip4":   ProtoIPv4,
+	"ip6":   ProtoIPv6,
+}
+
+// NormalizeProtocol returns the canonical name of a protocol, it is case insensitive and
+// accepts common aliases such as icmp6 for icmpv6.
+func NormalizeProtocol(name string) (string, error) {
+	protocol := strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := protocolAliases[protocol]; ok {
+		protocol = alias
+	}
+	switch protocol {
+	case ProtoIPv4, ProtoIPv6, ProtoICMP, ProtoICMPv4, ProtoICMPv6, ProtoTCP, ProtoUDP:
+		return protocol, nil
+	}
+	return "", fmt.Errorf("unsupported ip protocol %q", name)
+}
+
+// Direction is the direction of a packet relative to the local device.
+type Direction int
+
+const (
+	// Inbound packets are received by the local device, rules match on the source address.
+	Inbound Direction = iota
+	// Outbound packets are sent by the local device, rules match on the destination address.
+	Outbound
+)
+
+func (d Direction) String() string {
+	if d == Inbound {
+		return "inbound"
+	}
+	return "outbound"
+}
+
+// Rule is the transport independent form of a security group rule. The
+// public API and the database models both convert into this type.
+type Rule struct {
+	IpProtocol string
+	FromPort   int64
+	ToPort     int64
+	IpRanges   []string
+	// SecurityGroupIds, DeviceIds and AllDevices refer to the tunnel addresses of devices,
+	// Resolve adds the current addresses of the referenced devices to IpRanges.
+	SecurityGroupIds []string
+	DeviceIds        []string
+	AllDevices       bool
+}
+
+// HasReferences reports whether the rule refers to devices instead of, or in addition to, literal ip ranges.
+func (r Rule) HasReferences() bool {
+	return r.AllDevices || len(r.SecurityGroupIds) > 0 || len(r.DeviceIds) > 0
+}
+
+// Packet holds the fields of a packet that security rules match on.
+type Packet struct {
+	Src      netip.Addr
+	Dst      netip.Addr
+	Protocol uint8
+	SrcPort  uint16
+	DstPort  uint16
+}
+
+// Policy is a compiled set of inbound and outbound rules. A nil *Policy allows all traffic.
+type Policy struct {
+	inbound       []Match
+	outbound      []Match
+	inboundRules  int
+	outboundRules int
+}
+
+// Match is one normalized match of a security rule. A rule expands into one Match per
+// address family and L4 protocol it applies to, so the Go evaluator and the backends
+// that render rules for the kernel packet filter share the same semantics.
+type Match struct {
+	// Rule is the index of the rule the match was expanded from.
+	Rule int
+	// IPv6 selects the address family of the match.
+	IPv6 bool
+	// Protocol is the IANA protocol number, 0 matches any protocol.
+	Protocol uint8
+	// Ports is the destination port range, nil matches any port.
+	Ports *PortRange
+	// Ranges are matched against the source address of inbound packets and against the
+	// destination address of outbound packets, nil matches any address.
+	Ranges []AddrRange
+}
+
+// PortRange is an inclusive range of ports.
+type PortRange struct {
+	From uint16
+	To   uint16
+}
+
+// AddrRange is an inclusive range of addresses of the same family.
+type AddrRange struct {
+	From netip.Addr
+	To   netip.Addr
+}
+
+// Compile validates and compiles the inbound and outbound rules of a security group.
+func Compile(inbound, outbound []Rule) (*Policy, error) {
+	p := &Policy{
+		inboundRules:  len(inbound),
+		outboundRules: len(outbound),
+	}
+	for i, rule := range inbound {
+		matches, err := compileRule(i, rule)
+		if err != nil {
+			return nil, fmt.Errorf("inbound rule %d: %w", i, err)
+		}
+		p.inbound = append(p.inbound, matches...)
+	}
+	for i, rule := range outbound {
+		matches, err := compileRule(i, rule)
+		if err != nil {
+			return nil, fmt.Errorf("outbound rule %d: %w", i, err)
+		}
+		p.outbound = append(p.outbound, matches...)
+	}
+	return p, nil
+}
+
+// Matches returns the normalized matches of the given direction in rule order.
+func (p *Policy) Matches(dir Direction) []Match {
+	if p == nil {
+		return nil
+	}
+	if dir == Outbound {
+		return p.outbound
+	}
+	return p.inbound
+}
+
+// HasRules reports whether any rules were defined for the given direction. A direction
+// without rules permits all traffic, once a rule is present all other traffic is dropped.
+func (p *Policy) HasRules(dir Direction) bool {
+	if p == nil {
+		return false
+	}
+	if dir == Outbound {
+		return p.outboundRules > 0
+	}
+	return p.inboundRules > 0
+}
+
+// RuleCount returns the number of rules defined for the given direction.
+func (p *Policy) RuleCount(dir Direction) int {
+	if p == nil {
+		return 0
+	}
+	if dir == Outbound {
+		return p.outboundRules
+	}
+	return p.inboundRules
+}
+
+func compileRule(index int, rule Rule) ([]Match, error) {
+	protocol := strings.ToLower(rule.IpProtocol)
+	switch protocol {
+	case ProtoIPv4, ProtoIPv6, ProtoICMP, ProtoICMPv4, ProtoICMPv6, ProtoTCP, ProtoUDP:
+	default:
+		return nil, fmt.Errorf("unsupported ip protocol %q", rule.IpProtocol)
+	}
+
+	if rule.FromPort < 0 || rule.FromPort > 65535 || rule.ToPort < 0 || rule.ToPort > 65535 {
+		return nil, fmt.Errorf("port range %d-%d is out of bounds", rule.FromPort, rule.ToPort)
+	}
+	if rule.FromPort > rule.ToPort {
+		return nil, fmt.Errorf("from port %d is greater than to port %d", rule.FromPort, rule.ToPort)
+	}
+	var ports *PortRange
+	if rule.FromPort != 0 || rule.ToPort != 0 {
+		ports = &PortRange{From: uint16(rule.FromPort), To: uint16(rule.ToPort)}
+	}
+
+	var v4Ranges, v6Ranges []AddrRange
+	for _, ipRange := range rule.IpRanges {
+		if strings.TrimSpace(ipRange) == "" {
+			continue
+		}
+		r, err := ParseAddrRange(ipRange)
+		if err != nil {
+			return nil, err
+		}
+		if r.From.Is4() {
+			v4Ranges = append(v4Ranges, r)
+		} else {
+			v6Ranges = append(v6Ranges, r)
+		}
+	}
+	// a rule that refers to devices only matches their addresses, even when none resolved
+	anyAddr := len(v4Ranges) == 0 && len(v6Ranges) == 0 && !rule.HasReferences()
+
+	var matches []Match
+	addFamily := func(ipv6 bool, ranges []AddrRange) {
+		if !anyAddr && len(ranges) == 0 {
+			// the rule only lists addresses of the other family
+			return
+		}
+		var protocols []uint8
+		matchPorts := ports
+		switch protocol {
+		case ProtoIPv4, ProtoIPv6:
+			// a port range on an ip rule applies to both tcp and udp
+			if ports == nil {
+				protocols = []uint8{0}
+			} else {
+				protocols = []uint8{ipProtoTCP, ipProtoUDP}
+			}
+		case ProtoTCP:
+			protocols = []uint8{ipProtoTCP}
+		case ProtoUDP:
+			protocols = []uint8{ipProtoUDP}
+		case ProtoICMP, ProtoICMPv4, ProtoICMPv6:
+			matchPorts = nil
+			if ipv6 {
+				protocols = []uint8{ipProtoICMPv6}
+			} else {
+				protocols = []uint8{ipProtoICMPv4}
+			}
+		}
+		for _, proto := range protocols {
+			matches = append(matches, Match{
+				Rule:     index,
+				IPv6:     ipv6,
+				Protocol: proto,
+				Ports:    matchPorts,
+				Ranges:   ranges,
+			})
+		}
+	}
+
+	switch protocol {
+	case ProtoIPv4, ProtoICMPv4:
+		addFamily(false, v4Ranges)
+	case ProtoIPv6, ProtoICMPv6:
+		addFamily(true, v6Ranges)
+	case ProtoICMP:
+		// without an address range icmp only refers to icmpv4, same as the nftables rules.
+		addFamily(false, v4Ranges)
+		if !anyAddr {
+			addFamily(true, v6Ranges)
+		}
+	default:
+		addFamily(false, v4Ranges)
+		addFamily(true, v6Ranges)
+	}
+	return matches, nil
+}
+
+// ParseAddrRange parses an address range in one of the following forms:
+// Cidr notation 100.100.0.0/16
+// Individual address 10.100.0.2
+// Dash-separated range 100.100.0.0-100.100.10.255
+func ParseAddrRange(s string) (AddrRange, error) {
+	s = strings.TrimSpace(s)
+	if from, to, found := strings.Cut(s, "-"); found {
+		start, err := netip.ParseAddr(strings.TrimSpace(from))
+		if err != nil {
+			return AddrRange{}, fmt.Errorf("invalid ip range %q: %w", s, err)
+		}
+		end, err := netip.ParseAddr(strings.TrimSpace(to))
+		if err != nil {
+			return AddrRange{}, fmt.Errorf("invalid ip range %q: %w", s, err)
+		}
+		if start.Is4() != end.Is4() {
+			return AddrRange{}, fmt.Errorf("invalid ip range %q: mixed address families", s)
+		}
+		if end.Less(start) {
+			return AddrRange{}, fmt.Errorf("invalid ip range %q: start address is after the end address", s)
+		}
+		return AddrRange{From: start, To: end}, nil
+	}
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return AddrRange{}, fmt.Errorf("invalid ip range %q: %w", s, err)
+		}
+		prefix = prefix.Masked()
+		return AddrRange{From: prefix.Addr(), To: lastAddr(prefix)}, nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return AddrRange{}, fmt.Errorf("invalid ip range %q: %w", s, err)
+	}
+	return AddrRange{From: addr, To: addr}, nil
+}
+
+// lastAddr returns the last address of the prefix.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	bits := prefix.Bits()
+	for i := range b {
+		for bit := 0; bit < 8; bit++ {
+			if i*8+bit >= bits {
+				b[i] |= 0x80 >> bit
+			}
+		}
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// Overlaps reports whether the ranges have any address in common.
+func (r AddrRange) Overlaps(o AddrRange) bool {
+	if r.From.Is4() != o.From.Is4() {
+		return false
+	}
+	return !r.To.Less(o.From) && !o.To.Less(r.From)
+}
+
+// Contains reports whether the address is within the range.
+func (r AddrRange) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.Is4() != r.From.Is4() {
+		return false
+	}
+	return !addr.Less(r.From) && !r.To.Less(addr)
+}
+
+// Allow reports whether the packet is permitted by the rules of the given direction.
+// A direction without any rules permits all traffic, once a rule is present all
+// traffic that does not match a rule is dropped.
+func (p *Policy) Allow(dir Direction, pkt Packet) bool {
+	if !p.HasRules(dir) {
+		return true
+	}
+	_, ok := p.Match(dir, pkt)
+	return ok
+}
+
+// Match returns the index of the first rule in the given direction that permits the packet.
+func (p *Policy) Match(dir Direction, pkt Packet) (int, bool) {
+	addr := pkt.Src
+	if dir == Outbound {
+		addr = pkt.Dst
+	}
+	for _, m := range p.Matches(dir) {
+		if m.matches(addr, pkt) {
+			return m.Rule, true
+		}
+	}
+	return -1, false
+}
+
+func (m Match) matches(addr netip.Addr, pkt Packet) bool {
+	if addr.Unmap().Is4() == m.IPv6 {
+		return false
+	}
+	if m.Protocol != 0 && m.Protocol != pkt.Protocol {
+		return false
+	}
+	if m.Ports != nil && (pkt.DstPort < m.Ports.From || pkt.DstPort > m.Ports.To) {
+		return false
+	}
+	if m.Ranges == nil {
+		return true
+	}
+	for _, r := range m.Ranges {
+		if r.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
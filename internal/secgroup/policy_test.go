@@ -0,0 +1,253 @@
+package secgroup
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tcpPacket(src, dst string, dport uint16) Packet {
+	return Packet{Src: netip.MustParseAddr(src), Dst: netip.MustParseAddr(dst), Protocol: ipProtoTCP, SrcPort: 40000, DstPort: dport}
+}
+
+func udpPacket(src, dst string, dport uint16) Packet {
+	return Packet{Src: netip.MustParseAddr(src), Dst: netip.MustParseAddr(dst), Protocol: ipProtoUDP, SrcPort: 40000, DstPort: dport}
+}
+
+func icmpPacket(src, dst string) Packet {
+	pkt := Packet{Src: netip.MustParseAddr(src), Dst: netip.MustParseAddr(dst), Protocol: ipProtoICMPv4}
+	if pkt.Src.Is6() {
+		pkt.Protocol = ipProtoICMPv6
+	}
+	return pkt
+}
+
+func TestPolicyAllow(t *testing.T) {
+	tests := []struct {
+		name   string
+		rules  []Rule
+		dir    Direction
+		packet Packet
+		allow  bool
+	}{
+		{
+			name:   "no rules permits everything",
+			dir:    Inbound,
+			packet: tcpPacket("100.100.0.2", "100.100.0.1", 22),
+			allow:  true,
+		},
+		{
+			name:   "tcp port match",
+			rules:  []Rule{{IpProtocol: "tcp", FromPort: 80, ToPort: 80}},
+			dir:    Inbound,
+			packet: tcpPacket("100.100.0.2", "100.100.0.1", 80),
+			allow:  true,
+		},
+		{
+			name:   "tcp port mismatch",
+			rules:  []Rule{{IpProtocol: "tcp", FromPort: 80, ToPort: 80}},
+			dir:    Inbound,
+			packet: tcpPacket("100.100.0.2", "100.100.0.1", 81),
+			allow:  false,
+		},
+		{
+			name:   "tcp rule does not match udp",
+			rules:  []Rule{{IpProtocol: "tcp", FromPort: 53, ToPort: 53}},
+			dir:    Inbound,
+			packet: udpPacket("100.100.0.2", "100.100.0.1", 53),
+			allow:  false,
+		},
+		{
+			name:   "udp port range",
+			rules:  []Rule{{IpProtocol: "udp", FromPort: 5000, ToPort: 6000}},
+			dir:    Inbound,
+			packet: udpPacket("200::2", "200::1", 5500),
+			allow:  true,
+		},
+		{
+			name:   "tcp any port with cidr source",
+			rules:  []Rule{{IpProtocol: "tcp", IpRanges: []string{"100.100.0.0/16"}}},
+			dir:    Inbound,
+			packet: tcpPacket("100.100.3.4", "100.100.0.1", 8443),
+			allow:  true,
+		},
+		{
+			name:   "source outside of cidr",
+			rules:  []Rule{{IpProtocol: "tcp", IpRanges: []string{"100.100.0.0/16"}}},
+			dir:    Inbound,
+			packet: tcpPacket("100.101.0.1", "100.100.0.1", 8443),
+			allow:  false,
+		},
+		{
+			name:   "dash separated range",
+			rules:  []Rule{{IpProtocol: "tcp", FromPort: 22, ToPort: 22, IpRanges: []string{"100.100.0.1-100.100.0.100"}}},
+			dir:    Inbound,
+			packet: tcpPacket("100.100.0.50", "100.100.0.1", 22),
+			allow:  true,
+		},
+		{
+			name:   "outbound matches the destination address",
+			rules:  []Rule{{IpProtocol: "udp", FromPort: 53, ToPort: 53, IpRanges: []string{"8.8.8.8"}}},
+			dir:    Outbound,
+			packet: udpPacket("100.100.0.1", "8.8.8.8", 53),
+			allow:  true,
+		},
+		{
+			name:   "outbound does not match the source address",
+			rules:  []Rule{{IpProtocol: "udp", FromPort: 53, ToPort: 53, IpRanges: []string{"100.100.0.1"}}},
+			dir:    Outbound,
+			packet: udpPacket("100.100.0.1", "8.8.8.8", 53),
+			allow:  false,
+		},
+		{
+			name:   "ipv4 permits all ipv4 traffic",
+			rules:  []Rule{{IpProtocol: "ipv4"}},
+			dir:    Inbound,
+			packet: icmpPacket("100.100.0.2", "100.100.0.1"),
+			allow:  true,
+		},
+		{
+			name:   "ipv4 does not permit ipv6 traffic",
+			rules:  []Rule{{IpProtocol: "ipv4"}},
+			dir:    Inbound,
+			packet: tcpPacket("200::2", "200::1", 80),
+			allow:  false,
+		},
+		{
+			name:   "ipv6 with a v6 range",
+			rules:  []Rule{{IpProtocol: "ipv6", IpRanges: []string{"200::1-200::5"}}},
+			dir:    Inbound,
+			packet: tcpPacket("200::3", "200::1", 80),
+			allow:  true,
+		},
+		{
+			name:   "icmp without ranges only permits icmpv4",
+			rules:  []Rule{{IpProtocol: "icmp"}},
+			dir:    Inbound,
+			packet: icmpPacket("200::2", "200::1"),
+			allow:  false,
+		},
+		{
+			name:   "icmp with a v6 range permits icmpv6",
+			rules:  []Rule{{IpProtocol: "icmp", IpRanges: []string{"200::/64"}}},
+			dir:    Inbound,
+			packet: icmpPacket("200::2", "200::1"),
+			allow:  true,
+		},
+		{
+			name:   "icmpv6",
+			rules:  []Rule{{IpProtocol: "icmpv6"}},
+			dir:    Inbound,
+			packet: icmpPacket("200::2", "200::1"),
+			allow:  true,
+		},
+		{
+			name:   "outbound rules do not affect inbound traffic",
+			rules:  nil,
+			dir:    Inbound,
+			packet: tcpPacket("100.100.0.2", "100.100.0.1", 22),
+			allow:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var inbound, outbound []Rule
+			if test.dir == Inbound {
+				inbound = test.rules
+				outbound = []Rule{{IpProtocol: "tcp", FromPort: 1, ToPort: 1}}
+			} else {
+				outbound = test.rules
+			}
+			p, err := Compile(inbound, outbound)
+			require.NoError(t, err)
+			assert.Equal(t, test.allow, p.Allow(test.dir, test.packet))
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{name: "unknown protocol", rule: Rule{IpProtocol: "sctp"}},
+		{name: "port out of range", rule: Rule{IpProtocol: "tcp", FromPort: 1, ToPort: 70000}},
+		{name: "inverted port range", rule: Rule{IpProtocol: "tcp", FromPort: 90, ToPort: 80}},
+		{name: "bad cidr", rule: Rule{IpProtocol: "tcp", IpRanges: []string{"100.100.0.0/40"}}},
+		{name: "mixed range", rule: Rule{IpProtocol: "tcp", IpRanges: []string{"100.100.0.1-200::1"}}},
+		{name: "inverted range", rule: Rule{IpProtocol: "tcp", IpRanges: []string{"100.100.0.9-100.100.0.1"}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Compile([]Rule{test.rule}, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestNilPolicyAllows(t *testing.T) {
+	var p *Policy
+	assert.True(t, p.Allow(Inbound, tcpPacket("100.100.0.2", "100.100.0.1", 22)))
+	assert.True(t, p.Allow(Outbound, tcpPacket("100.100.0.1", "100.100.0.2", 22)))
+}
+
+func TestProtocolNumber(t *testing.T) {
+	proto, err := ProtocolNumber("TCP", false)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(ipProtoTCP), proto)
+
+	proto, err = ProtocolNumber("icmp", true)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(ipProtoICMPv6), proto)
+
+	_, err = ProtocolNumber("ipv4", false)
+	assert.Error(t, err)
+}
+
+func TestNormalizeProtocol(t *testing.T) {
+	for name, expected := range map[string]string{
+		"TCP":     ProtoTCP,
+		" udp ":   ProtoUDP,
+		"ICMP6":   ProtoICMPv6,
+		"icmp4":   ProtoICMPv4,
+		"icmpv6":  ProtoICMPv6,
+		"IPv6":    ProtoIPv6,
+		"ipv4":    ProtoIPv4,
+		"icmp":    ProtoICMP,
+		"ip6":     ProtoIPv6,
+		"Icmpv4 ": ProtoICMPv4,
+	} {
+		protocol, err := NormalizeProtocol(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, protocol, name)
+	}
+
+	_, err := NormalizeProtocol("sctp")
+	assert.Error(t, err)
+	_, err = NormalizeProtocol("")
+	assert.Error(t, err)
+}
+
+func TestAddrRangeOverlaps(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		overlaps bool
+	}{
+		{"100.100.0.0/16", "100.100.3.4", true},
+		{"100.100.0.1-100.100.0.9", "100.100.0.9-100.100.0.20", true},
+		{"100.100.0.1-100.100.0.9", "100.100.0.10-100.100.0.20", false},
+		{"100.100.0.0/16", "100.101.0.0/16", false},
+		{"0.0.0.0/0", "::/0", false},
+		{"200::/64", "200::1", true},
+	}
+	for _, test := range tests {
+		a, err := ParseAddrRange(test.a)
+		require.NoError(t, err)
+		b, err := ParseAddrRange(test.b)
+		require.NoError(t, err)
+		assert.Equal(t, test.overlaps, a.Overlaps(b), "%s %s", test.a, test.b)
+		assert.Equal(t, test.overlaps, b.Overlaps(a), "%s %s", test.b, test.a)
+	}
+}
@@ -0,0 +1,50 @@
+package secgroup
+
+// Device holds the fields of an organization device that rules can refer to.
+type Device struct {
+	Id              string
+	SecurityGroupId string
+	TunnelIp        string
+	TunnelIpV6      string
+}
+
+// Resolve returns a copy of the rules with the tunnel addresses of the devices each
+// rule refers to appended to its IpRanges. It has to be called again whenever the
+// devices of the organization or their security groups change.
+func Resolve(rules []Rule, devices []Device) []Rule {
+	if rules == nil {
+		return nil
+	}
+	result := make([]Rule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.HasReferences() {
+			result = append(result, rule)
+			continue
+		}
+
+		groups := map[string]bool{}
+		for _, id := range rule.SecurityGroupIds {
+			groups[id] = true
+		}
+		ids := map[string]bool{}
+		for _, id := range rule.DeviceIds {
+			ids[id] = true
+		}
+
+		ipRanges := append([]string{}, rule.IpRanges...)
+		for _, device := range devices {
+			if !rule.AllDevices && !ids[device.Id] && !groups[device.SecurityGroupId] {
+				continue
+			}
+			if device.TunnelIp != "" {
+				ipRanges = append(ipRanges, device.TunnelIp)
+			}
+			if device.TunnelIpV6 != "" {
+				ipRanges = append(ipRanges, device.TunnelIpV6)
+			}
+		}
+		rule.IpRanges = ipRanges
+		result = append(result, rule)
+	}
+	return result
+}
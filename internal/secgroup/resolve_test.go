@@ -0,0 +1,78 @@
+package secgroup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testDevices = []Device{
+	{Id: "device-1", SecurityGroupId: "group-web", TunnelIp: "100.100.0.1", TunnelIpV6: "200::1"},
+	{Id: "device-2", SecurityGroupId: "group-web", TunnelIp: "100.100.0.2", TunnelIpV6: "200::2"},
+	{Id: "device-3", SecurityGroupId: "group-db", TunnelIp: "100.100.0.3", TunnelIpV6: "200::3"},
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     Rule
+		ipRanges []string
+	}{
+		{
+			name:     "literal ranges are kept as is",
+			rule:     Rule{IpProtocol: "tcp", IpRanges: []string{"10.0.0.0/8"}},
+			ipRanges: []string{"10.0.0.0/8"},
+		},
+		{
+			name:     "security group",
+			rule:     Rule{IpProtocol: "tcp", SecurityGroupIds: []string{"group-web"}},
+			ipRanges: []string{"100.100.0.1", "200::1", "100.100.0.2", "200::2"},
+		},
+		{
+			name:     "device and literal range",
+			rule:     Rule{IpProtocol: "tcp", DeviceIds: []string{"device-3"}, IpRanges: []string{"10.0.0.1"}},
+			ipRanges: []string{"10.0.0.1", "100.100.0.3", "200::3"},
+		},
+		{
+			name:     "all devices",
+			rule:     Rule{IpProtocol: "tcp", AllDevices: true},
+			ipRanges: []string{"100.100.0.1", "200::1", "100.100.0.2", "200::2", "100.100.0.3", "200::3"},
+		},
+		{
+			name:     "unknown security group",
+			rule:     Rule{IpProtocol: "tcp", SecurityGroupIds: []string{"group-gone"}},
+			ipRanges: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved := Resolve([]Rule{tt.rule}, testDevices)
+			require.Len(t, resolved, 1)
+			assert.Equal(t, tt.ipRanges, resolved[0].IpRanges)
+		})
+	}
+}
+
+func TestResolveDoesNotModifyRules(t *testing.T) {
+	rules := []Rule{{IpProtocol: "tcp", IpRanges: []string{"10.0.0.1"}, DeviceIds: []string{"device-1"}}}
+	Resolve(rules, testDevices)
+	assert.Equal(t, []string{"10.0.0.1"}, rules[0].IpRanges)
+}
+
+func TestUnresolvedReferencesMatchNothing(t *testing.T) {
+	rules := Resolve([]Rule{{IpProtocol: "tcp", FromPort: 22, ToPort: 22, SecurityGroupIds: []string{"group-gone"}}}, testDevices)
+	policy, err := Compile(rules, nil)
+	require.NoError(t, err)
+
+	// the rule still counts, so the direction drops everything instead of permitting all traffic
+	assert.True(t, policy.HasRules(Inbound))
+	assert.False(t, policy.Allow(Inbound, tcpPacket("100.100.0.1", "100.100.0.9", 22)))
+
+	rules = Resolve([]Rule{{IpProtocol: "tcp", FromPort: 22, ToPort: 22, SecurityGroupIds: []string{"group-db"}}}, testDevices)
+	policy, err = Compile(rules, nil)
+	require.NoError(t, err)
+	assert.True(t, policy.Allow(Inbound, tcpPacket("100.100.0.3", "100.100.0.9", 22)))
+	assert.True(t, policy.Allow(Inbound, tcpPacket("200::3", "200::9", 22)))
+	assert.False(t, policy.Allow(Inbound, tcpPacket("100.100.0.1", "100.100.0.9", 22)))
+}
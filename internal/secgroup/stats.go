@@ -0,0 +1,90 @@
+package secgroup
+
+import "sync/atomic"
+
+// Counter holds the number of packets and bytes that hit a rule.
+type Counter struct {
+	Packets uint64
+	Bytes   uint64
+}
+
+// Add adds the packets and bytes of the other counter.
+func (c *Counter) Add(o Counter) {
+	c.Packets += o.Packets
+	c.Bytes += o.Bytes
+}
+
+// DirectionStats holds the counters of the rules of one direction of a security group.
+type DirectionStats struct {
+	// Established counts the return traffic of established flows, which is always permitted.
+	Established Counter
+	// Rules holds one counter per rule of the security group, in rule order.
+	Rules []Counter
+	// Denied counts the packets dropped because no rule permitted them.
+	Denied Counter
+}
+
+// Stats holds the counters of the inbound and outbound rules of a security group.
+type Stats struct {
+	Inbound  DirectionStats
+	Outbound DirectionStats
+}
+
+// NewStats returns zeroed stats with a counter for every rule of the policy.
+func NewStats(p *Policy) Stats {
+	return Stats{
+		Inbound:  DirectionStats{Rules: make([]Counter, p.RuleCount(Inbound))},
+		Outbound: DirectionStats{Rules: make([]Counter, p.RuleCount(Outbound))},
+	}
+}
+
+// Direction returns the stats of the given direction.
+func (s *Stats) Direction(dir Direction) *DirectionStats {
+	if dir == Outbound {
+		return &s.Outbound
+	}
+	return &s.Inbound
+}
+
+// atomicCounter is a Counter that is safe for concurrent use.
+type atomicCounter struct {
+	packets atomic.Uint64
+	bytes   atomic.Uint64
+}
+
+func (c *atomicCounter) add(bytes int) {
+	c.packets.Add(1)
+	c.bytes.Add(uint64(bytes))
+}
+
+func (c *atomicCounter) load() Counter {
+	return Counter{Packets: c.packets.Load(), Bytes: c.bytes.Load()}
+}
+
+// policyCounters counts the packets evaluated against a policy, it is replaced along with the policy.
+type policyCounters struct {
+	policy      *Policy
+	established [2]atomicCounter
+	rules       [2][]atomicCounter
+	denied      [2]atomicCounter
+}
+
+func newPolicyCounters(p *Policy) *policyCounters {
+	c := &policyCounters{policy: p}
+	c.rules[Inbound] = make([]atomicCounter, p.RuleCount(Inbound))
+	c.rules[Outbound] = make([]atomicCounter, p.RuleCount(Outbound))
+	return c
+}
+
+func (c *policyCounters) stats() Stats {
+	stats := NewStats(c.policy)
+	for _, dir := range []Direction{Inbound, Outbound} {
+		d := stats.Direction(dir)
+		d.Established = c.established[dir].load()
+		for i := range c.rules[dir] {
+			d.Rules[i] = c.rules[dir][i].load()
+		}
+		d.Denied = c.denied[dir].load()
+	}
+	return stats
+}
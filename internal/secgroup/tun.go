@@ -0,0 +1,188 @@
+package secgroup
+
+import (
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.zx2c4.com/wireguard/tun"
+)
+
+// flowTimeout is how long an idle tracked flow permits return traffic.
+const flowTimeout = 5 * time.Minute
+
+// flowKey identifies a flow from the point of view of the local device.
+type flowKey struct {
+	protocol   uint8
+	local      netip.Addr
+	localPort  uint16
+	remote     netip.Addr
+	remotePort uint16
+}
+
+// FilterTun wraps the tun.Device of a userspace (netstack) wireguard device and applies
+// a security group Policy to the packets passing through it. Packets read from the
+// device are leaving the netstack and are outbound, packets written to the device
+// are arriving from wireguard peers and are inbound.
+//
+// Like the nftables rules, return traffic of established flows is always permitted.
+type FilterTun struct {
+	tun.Device
+	policy atomic.Pointer[Policy]
+	// counters of the current policy, replaced along with it
+	counters atomic.Pointer[policyCounters]
+
+	mu        sync.Mutex
+	flows     map[flowKey]time.Time
+	lastPrune time.Time
+}
+
+// NewFilterTun creates a FilterTun without a policy, all traffic is permitted until SetPolicy is called.
+func NewFilterTun(dev tun.Device) *FilterTun {
+	return &FilterTun{
+		Device: dev,
+		flows:  map[flowKey]time.Time{},
+	}
+}
+
+// SetPolicy replaces the policy applied to the packets, a nil policy permits all traffic.
+func (t *FilterTun) SetPolicy(p *Policy) {
+	t.counters.Store(newPolicyCounters(p))
+	t.policy.Store(p)
+}
+
+// Stats returns the counters of the packets evaluated against the current policy
+// since it was set. Packets permitted without a policy are not counted.
+func (t *FilterTun) Stats() Stats {
+	counters := t.counters.Load()
+	if counters == nil {
+		return NewStats(nil)
+	}
+	return counters.stats()
+}
+
+// Policy returns the policy currently applied to the packets.
+func (t *FilterTun) Policy() *Policy {
+	return t.policy.Load()
+}
+
+// Read reads packets sent by the netstack, dropping the outbound packets the policy does not permit.
+func (t *FilterTun) Read(bufs [][]byte, sizes []int, offset int) (int, error) {
+	for {
+		n, err := t.Device.Read(bufs, sizes, offset)
+		if n == 0 || err != nil {
+			return n, err
+		}
+		policy := t.policy.Load()
+		if policy == nil {
+			return n, nil
+		}
+		count := 0
+		for i := 0; i < n; i++ {
+			if !t.allow(policy, Outbound, bufs[i][offset:offset+sizes[i]]) {
+				continue
+			}
+			if i != count {
+				copy(bufs[count][offset:], bufs[i][offset:offset+sizes[i]])
+				sizes[count] = sizes[i]
+			}
+			count++
+		}
+		if count > 0 {
+			return count, nil
+		}
+	}
+}
+
+// Write writes packets received from the peers to the netstack, dropping the inbound packets the policy does not permit.
+func (t *FilterTun) Write(bufs [][]byte, offset int) (int, error) {
+	policy := t.policy.Load()
+	if policy == nil {
+		return t.Device.Write(bufs, offset)
+	}
+	permitted := make([][]byte, 0, len(bufs))
+	for _, buf := range bufs {
+		if t.allow(policy, Inbound, buf[offset:]) {
+			permitted = append(permitted, buf)
+		}
+	}
+	if len(permitted) > 0 {
+		if _, err := t.Device.Write(permitted, offset); err != nil {
+			return 0, err
+		}
+	}
+	// dropped packets are reported as written, same as a firewall silently dropping them.
+	return len(bufs), nil
+}
+
+func (t *FilterTun) allow(policy *Policy, dir Direction, b []byte) bool {
+	pkt, ok := ParsePacket(b)
+	if !ok {
+		return false
+	}
+	key := flowKey{
+		protocol:   pkt.Protocol,
+		local:      pkt.Src,
+		localPort:  pkt.SrcPort,
+		remote:     pkt.Dst,
+		remotePort: pkt.DstPort,
+	}
+	if dir == Inbound {
+		key = flowKey{
+			protocol:   pkt.Protocol,
+			local:      pkt.Dst,
+			localPort:  pkt.DstPort,
+			remote:     pkt.Src,
+			remotePort: pkt.SrcPort,
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.pruneFlows(now)
+
+	counters := t.counters.Load()
+	if counters != nil && counters.policy != policy {
+		// the policy is being replaced, the packet is counted against the new policy only
+		counters = nil
+	}
+
+	if lastSeen, ok := t.flows[key]; ok && now.Sub(lastSeen) < flowTimeout {
+		t.flows[key] = now
+		if counters != nil {
+			counters.established[dir].add(len(b))
+		}
+		return true
+	}
+	if !policy.HasRules(dir) {
+		t.flows[key] = now
+		return true
+	}
+	rule, ok := policy.Match(dir, pkt)
+	if !ok {
+		if counters != nil {
+			counters.denied[dir].add(len(b))
+		}
+		return false
+	}
+	if counters != nil {
+		counters.rules[dir][rule].add(len(b))
+	}
+	t.flows[key] = now
+	return true
+}
+
+// pruneFlows removes expired flows, at most once per timeout period. Assumes t.mu is held.
+func (t *FilterTun) pruneFlows(now time.Time) {
+	if now.Sub(t.lastPrune) < flowTimeout {
+		return
+	}
+	t.lastPrune = now
+	for key, lastSeen := range t.flows {
+		if now.Sub(lastSeen) >= flowTimeout {
+			delete(t.flows, key)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package secgroup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.zx2c4.com/wireguard/tun"
+	"golang.zx2c4.com/wireguard/tun/netstack"
+)
+
+type peer struct {
+	filter *FilterTun
+	net    *netstack.Net
+	addr   netip.Addr
+}
+
+func newPeer(t *testing.T, addr string) *peer {
+	dev, tnet, err := netstack.CreateNetTUN([]netip.Addr{netip.MustParseAddr(addr)}, nil, 1420)
+	require.NoError(t, err)
+	return &peer{filter: NewFilterTun(dev), net: tnet, addr: netip.MustParseAddr(addr)}
+}
+
+// link moves the packets read from one device to the other, playing the role of the wireguard tunnel.
+type link struct {
+	mu     sync.Mutex
+	closed bool
+}
+
+func (l *link) pump(from, to tun.Device) {
+	bufs := [][]byte{make([]byte, 1500)}
+	sizes := []int{0}
+	for {
+		n, err := from.Read(bufs, sizes, 0)
+		if err != nil {
+			return
+		}
+		l.mu.Lock()
+		if l.closed {
+			l.mu.Unlock()
+			return
+		}
+		for i := 0; i < n; i++ {
+			_, _ = to.Write([][]byte{bufs[i][:sizes[i]]}, 0)
+		}
+		l.mu.Unlock()
+	}
+}
+
+func connectPeers(t *testing.T, a, b *peer) {
+	l := &link{}
+	go l.pump(a.filter, b.filter)
+	go l.pump(b.filter, a.filter)
+	t.Cleanup(func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.closed = true
+		_ = a.filter.Close()
+		_ = b.filter.Close()
+	})
+}
+
+func listenAndEcho(t *testing.T, p *peer, port int) {
+	l, err := p.net.ListenTCP(&net.TCPAddr{Port: port})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+}
+
+func dialAndEcho(from *peer, to *peer, port int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	conn, err := from.net.DialContextTCPAddrPort(ctx, netip.AddrPortFrom(to.addr, uint16(port)))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Write([]byte("hello")); err != nil {
+		return err
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return err
+	}
+	if string(buf) != "hello" {
+		return errors.New("unexpected echo response")
+	}
+	return nil
+}
+
+func TestFilterTunBetweenNetstackPeers(t *testing.T) {
+	require := require.New(t)
+
+	client := newPeer(t, "100.100.0.1")
+	server := newPeer(t, "100.100.0.2")
+	connectPeers(t, client, server)
+
+	listenAndEcho(t, server, 80)
+	listenAndEcho(t, server, 8080)
+	listenAndEcho(t, client, 9000)
+
+	// without a policy everything is reachable
+	require.NoError(dialAndEcho(client, server, 80))
+	require.NoError(dialAndEcho(client, server, 8080))
+	require.NoError(dialAndEcho(server, client, 9000))
+
+	policy, err := Compile(
+		[]Rule{{IpProtocol: "tcp", FromPort: 80, ToPort: 80, IpRanges: []string{"100.100.0.0/24"}}},
+		[]Rule{{IpProtocol: "udp", FromPort: 53, ToPort: 53}},
+	)
+	require.NoError(err)
+	server.filter.SetPolicy(policy)
+
+	// inbound port 80 is permitted, the replies are permitted by the tracked flow
+	// even though the outbound rules only permit dns.
+	require.NoError(dialAndEcho(client, server, 80))
+	// inbound port 8080 is dropped
+	require.Error(dialAndEcho(client, server, 8080))
+	// outbound tcp from the server is dropped
+	require.Error(dialAndEcho(server, client, 9000))
+
+	stats := server.filter.Stats()
+	require.Len(stats.Inbound.Rules, 1)
+	require.NotZero(stats.Inbound.Rules[0].Packets)
+	require.NotZero(stats.Inbound.Rules[0].Bytes)
+	require.NotZero(stats.Inbound.Denied.Packets)
+	require.Len(stats.Outbound.Rules, 1)
+	require.Zero(stats.Outbound.Rules[0].Packets)
+	require.NotZero(stats.Outbound.Established.Packets)
+	require.NotZero(stats.Outbound.Denied.Packets)
+
+	// removing the policy permits all traffic again
+	server.filter.SetPolicy(nil)
+	require.NoError(dialAndEcho(client, server, 8080))
+	require.NoError(dialAndEcho(server, client, 9000))
+	require.Equal(Stats{Inbound: DirectionStats{Rules: []Counter{}}, Outbound: DirectionStats{Rules: []Counter{}}}, server.filter.Stats())
+}